@@ -2,11 +2,13 @@ package main
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
 	"net/http"
 	"time"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
 	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -28,6 +30,11 @@ func newAccountResponse(account db.Account) accountResponse {
 	}
 }
 
+var (
+	errUserNotVerified = errors.New("user has not completed identity verification")
+	errUserInactive    = errors.New("user is deactivated")
+)
+
 type createAccountRequest struct {
 	Currency string `json:"currency"`
 	UserID   string `json:"user_id"`
@@ -39,6 +46,26 @@ func (server *Server) createAccount(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	logging.AddFields(ctx, logging.UserID(req.UserID))
+
+	user, err := server.userDirectory.GetUser(ctx, req.UserID)
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "account-createAccount", logging.StatusCode(http.StatusBadGateway), logging.Err(err))
+		ctx.JSON(http.StatusBadGateway, errorResponse(err))
+		return
+	}
+	if !user.IsActive {
+		ctx.JSON(http.StatusForbidden, errorResponse(errUserInactive))
+		return
+	}
+	if user.KYCStatus != users.KYCStatusVerified {
+		ctx.JSON(http.StatusForbidden, errorResponse(errUserNotVerified))
+		return
+	}
 
 	payload := db.CreateAccountParams{
 		AccountID: server.createUUID(),
@@ -49,10 +76,7 @@ func (server *Server) createAccount(ctx *gin.Context) {
 
 	account, err := server.store.CreateAccount(ctx, payload)
 	if err != nil {
-		server.sendErrorLog("account-createAccount", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-createAccount", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -80,10 +104,7 @@ func (server *Server) addAccountBalance(ctx *gin.Context) {
 
 	account, err := server.store.AddAccountBalance(ctx, payload)
 	if err != nil {
-		server.sendErrorLog("account-addAccountBalance", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-addAccountBalance", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -109,10 +130,7 @@ func (server *Server) getAccount(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-getAccount", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-getAccount", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -134,10 +152,7 @@ func (server *Server) getAccountBalance(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-getAccountBalance", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-getAccountBalance", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -168,10 +183,7 @@ func (server *Server) updateAccount(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-updateAccount", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-updateAccount", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -197,10 +209,7 @@ func (server *Server) deleteAccount(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-deleteAccount", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-deleteAccount", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -215,10 +224,28 @@ func (server *Server) listAccounts(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-listAccounts", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-listAccounts", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, accounts)
+}
+
+type listUserAccountsRequest struct {
+	UserID string `uri:"user_id" binding:"required,min=1"`
+}
+
+func (server *Server) listUserAccounts(ctx *gin.Context) {
+	var req listUserAccountsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	accounts, err := server.store.ListAccountsByUser(ctx, req.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "account-listUserAccounts", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
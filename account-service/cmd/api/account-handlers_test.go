@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -12,9 +13,14 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
+	mockusers "github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users/mock"
 	mockdb "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/mock"
 	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
 	"github.com/golang/mock/gomock"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestGetAccount(t *testing.T) {
@@ -86,7 +92,7 @@ func TestGetAccount(t *testing.T) {
 			store := mockdb.NewMockStore(ctrl)
 			tc.buildStubs(store)
 
-			server := newTestServer(t, store)
+			server := newTestServer(t, store, mockusers.NewMockDirectory(ctrl))
 			recorder := httptest.NewRecorder()
 
 			url := fmt.Sprintf("/accounts/%s", tc.accountID)
@@ -109,17 +115,25 @@ func createRandomAccount() db.Account {
 
 func TestCreateAccount(t *testing.T) {
 	account := createRandomAccount()
+	user := users.User{
+		UserID:    account.UserID,
+		IsActive:  true,
+		KYCStatus: users.KYCStatusVerified,
+	}
 
 	testCases := []struct {
 		name          string
 		body          gin.H
-		buildStubs    func(store *mockdb.MockStore)
+		buildStubs    func(store *mockdb.MockStore, userDirectory *mockusers.MockDirectory)
 		checkResponse func(t *testing.T, recorder *httptest.ResponseRecorder)
 	}{
 		{
 			name: "Created",
 			body: gin.H{"currency": account.Currency, "user_id": account.UserID},
-			buildStubs: func(store *mockdb.MockStore) {
+			buildStubs: func(store *mockdb.MockStore, userDirectory *mockusers.MockDirectory) {
+				userDirectory.EXPECT().GetUser(gomock.Any(), gomock.Eq(account.UserID)).
+					Times(1).
+					Return(user, nil)
 				arg := db.CreateAccountParams{
 					AccountID: account.AccountID,
 					UserID:    account.UserID,
@@ -135,6 +149,52 @@ func TestCreateAccount(t *testing.T) {
 				requireBodyMatchAccount(t, recorder.Body, account)
 			},
 		},
+		{
+			name: "User Not Verified",
+			body: gin.H{"currency": account.Currency, "user_id": account.UserID},
+			buildStubs: func(store *mockdb.MockStore, userDirectory *mockusers.MockDirectory) {
+				unverified := user
+				unverified.KYCStatus = "pending"
+				userDirectory.EXPECT().GetUser(gomock.Any(), gomock.Eq(account.UserID)).
+					Times(1).
+					Return(unverified, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "User Inactive",
+			body: gin.H{"currency": account.Currency, "user_id": account.UserID},
+			buildStubs: func(store *mockdb.MockStore, userDirectory *mockusers.MockDirectory) {
+				inactive := user
+				inactive.IsActive = false
+				userDirectory.EXPECT().GetUser(gomock.Any(), gomock.Eq(account.UserID)).
+					Times(1).
+					Return(inactive, nil)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusForbidden, recorder.Code)
+			},
+		},
+		{
+			name: "User Not Found",
+			body: gin.H{"currency": account.Currency, "user_id": account.UserID},
+			buildStubs: func(store *mockdb.MockStore, userDirectory *mockusers.MockDirectory) {
+				userDirectory.EXPECT().GetUser(gomock.Any(), gomock.Eq(account.UserID)).
+					Times(1).
+					Return(users.User{}, users.ErrUserNotFound)
+				store.EXPECT().CreateAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			checkResponse: func(t *testing.T, recorder *httptest.ResponseRecorder) {
+				require.Equal(t, http.StatusNotFound, recorder.Code)
+			},
+		},
 	}
 
 	for i := 0; i < len(testCases); i++ {
@@ -145,9 +205,10 @@ func TestCreateAccount(t *testing.T) {
 			defer ctrl.Finish()
 
 			store := mockdb.NewMockStore(ctrl)
-			tc.buildStubs(store)
+			userDirectory := mockusers.NewMockDirectory(ctrl)
+			tc.buildStubs(store, userDirectory)
 
-			server := NewServer(store)
+			server := NewServer(Config{}, store, userDirectory)
 			recorder := httptest.NewRecorder()
 
 			data, err := json.Marshal(tc.body)
@@ -172,3 +233,161 @@ func requireBodyMatchAccount(t *testing.T, body *bytes.Buffer, account db.Accoun
 	require.NoError(t, err)
 	require.Equal(t, gotAccount, account)
 }
+
+func TestGRPCGetAccount(t *testing.T) {
+	account := createRandomAccount()
+
+	testCases := []struct {
+		name       string
+		accountID  string
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name:      "OK",
+			accountID: account.AccountID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.AccountID)).
+					Times(1).
+					Return(account, nil)
+			},
+			code: codes.OK,
+		},
+		{
+			name:      "InvalidArgument",
+			accountID: "",
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name:      "NotFound",
+			accountID: account.AccountID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.AccountID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+			},
+			code: codes.NotFound,
+		},
+		{
+			name:      "Internal",
+			accountID: account.AccountID,
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(account.AccountID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrConnDone)
+			},
+			code: codes.Internal,
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := &accountServer{Server: newTestServer(t, store, mockusers.NewMockDirectory(ctrl))}
+			resp, err := server.GetAccount(context.Background(), &pb.GetAccountRequest{AccountId: tc.accountID})
+			require.Equal(t, tc.code, status.Code(err))
+			if tc.code == codes.OK {
+				require.Equal(t, account.AccountID, resp.GetAccountId())
+				require.Equal(t, account.UserID, resp.GetUserId())
+			}
+		})
+	}
+}
+
+func TestGRPCCreateTransaction(t *testing.T) {
+	from := createRandomAccount()
+	from.Balance = 50
+	to := createRandomAccount()
+
+	testCases := []struct {
+		name       string
+		req        *pb.CreateTransactionRequest
+		buildStubs func(store *mockdb.MockStore)
+		code       codes.Code
+	}{
+		{
+			name: "MissingAccount",
+			req:  &pb.CreateTransactionRequest{FromAccountId: from.AccountID, TransactionAmount: 10},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "NegativeAmount",
+			req:  &pb.CreateTransactionRequest{FromAccountId: from.AccountID, ToAccountId: to.AccountID, TransactionAmount: -10},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			code: codes.InvalidArgument,
+		},
+		{
+			name: "SenderNotFound",
+			req:  &pb.CreateTransactionRequest{FromAccountId: from.AccountID, ToAccountId: to.AccountID, TransactionAmount: 10},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.AccountID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			code: codes.NotFound,
+		},
+		{
+			name: "InsufficientBalance",
+			req:  &pb.CreateTransactionRequest{FromAccountId: from.AccountID, ToAccountId: to.AccountID, TransactionAmount: 100},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.AccountID)).
+					Times(1).
+					Return(from, nil)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			code: codes.FailedPrecondition,
+		},
+		{
+			name: "ReceiverNotFound",
+			req:  &pb.CreateTransactionRequest{FromAccountId: from.AccountID, ToAccountId: to.AccountID, TransactionAmount: 10},
+			buildStubs: func(store *mockdb.MockStore) {
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(from.AccountID)).
+					Times(1).
+					Return(from, nil)
+				store.EXPECT().GetAccount(gomock.Any(), gomock.Eq(to.AccountID)).
+					Times(1).
+					Return(db.Account{}, sql.ErrNoRows)
+				store.EXPECT().TransferTx(gomock.Any(), gomock.Any()).
+					Times(0)
+			},
+			code: codes.NotFound,
+		},
+	}
+
+	for i := 0; i < len(testCases); i++ {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			store := mockdb.NewMockStore(ctrl)
+			tc.buildStubs(store)
+
+			server := &transactionServer{Server: newTestServer(t, store, mockusers.NewMockDirectory(ctrl))}
+			_, err := server.CreateTransaction(context.Background(), tc.req)
+			require.Equal(t, tc.code, status.Code(err))
+		})
+	}
+}
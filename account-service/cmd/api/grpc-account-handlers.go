@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
+	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *accountServer) CreateAccount(ctx context.Context, req *pb.CreateAccountRequest) (*pb.Account, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := server.userDirectory.GetUser(ctx, req.GetUserId())
+	if err != nil {
+		if errors.Is(err, users.ErrUserNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	if !user.IsActive {
+		return nil, status.Error(codes.PermissionDenied, errUserInactive.Error())
+	}
+	if user.KYCStatus != users.KYCStatusVerified {
+		return nil, status.Error(codes.PermissionDenied, errUserNotVerified.Error())
+	}
+
+	account, err := server.store.CreateAccount(ctx, db.CreateAccountParams{
+		AccountID: server.createUUID(),
+		UserID:    req.GetUserId(),
+		Currency:  req.GetCurrency(),
+		Balance:   0,
+	})
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcCreateAccount", err)
+	}
+
+	return convertAccount(account), nil
+}
+
+func (server *accountServer) GetAccount(ctx context.Context, req *pb.GetAccountRequest) (*pb.Account, error) {
+	if req.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "account_id is required")
+	}
+
+	account, err := server.store.GetAccount(ctx, req.GetAccountId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		return nil, server.internalError(ctx, "account-grpcGetAccount", err)
+	}
+
+	return convertAccount(account), nil
+}
+
+func (server *accountServer) UpdateAccount(ctx context.Context, req *pb.UpdateAccountRequest) (*pb.Account, error) {
+	if req.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "account_id is required")
+	}
+
+	account, err := server.store.UpdateAccount(ctx, db.UpdateAccountParams{
+		AccountID: req.GetAccountId(),
+		Balance:   req.GetBalance(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		return nil, server.internalError(ctx, "account-grpcUpdateAccount", err)
+	}
+
+	return convertAccount(account), nil
+}
+
+func (server *accountServer) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
+	if req.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "account_id is required")
+	}
+
+	err := server.store.DeleteAccount(ctx, req.GetAccountId())
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcDeleteAccount", err)
+	}
+
+	return &pb.DeleteAccountResponse{}, nil
+}
+
+func (server *accountServer) ListAccounts(ctx context.Context, req *pb.ListAccountsRequest) (*pb.ListAccountsResponse, error) {
+	accounts, err := server.store.ListAccounts(ctx)
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcListAccounts", err)
+	}
+
+	return convertAccounts(accounts), nil
+}
+
+func (server *accountServer) ListUserAccounts(ctx context.Context, req *pb.ListUserAccountsRequest) (*pb.ListAccountsResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	accounts, err := server.store.ListAccountsByUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcListUserAccounts", err)
+	}
+
+	return convertAccounts(accounts), nil
+}
+
+func (server *accountServer) AddBalance(ctx context.Context, req *pb.AddBalanceRequest) (*pb.Account, error) {
+	if req.GetAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "account_id is required")
+	}
+
+	account, err := server.store.AddAccountBalance(ctx, db.AddAccountBalanceParams{
+		AccountID: req.GetAccountId(),
+		Amount:    req.GetAmount(),
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "account not found")
+		}
+		return nil, server.internalError(ctx, "account-grpcAddBalance", err)
+	}
+
+	return convertAccount(account), nil
+}
+
+func convertAccounts(accounts []db.Account) *pb.ListAccountsResponse {
+	resp := &pb.ListAccountsResponse{Accounts: make([]*pb.Account, 0, len(accounts))}
+	for _, account := range accounts {
+		resp.Accounts = append(resp.Accounts, convertAccount(account))
+	}
+	return resp
+}
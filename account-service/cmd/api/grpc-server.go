@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// accountServer serves the AccountService of the internal gRPC api
+type accountServer struct {
+	pb.UnimplementedAccountServiceServer
+	*Server
+}
+
+// transactionServer serves the TransactionService of the internal gRPC api
+type transactionServer struct {
+	pb.UnimplementedTransactionServiceServer
+	*Server
+}
+
+// StartGRPC serves the internal gRPC api, which the gateway uses, on address. It shares the store and the user
+// directory with the Gin router. It stops once ctx is done, letting the calls in flight finish.
+func (server *Server) StartGRPC(ctx context.Context, address string) error {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor(), requestid.UnaryServerInterceptor()))
+	pb.RegisterAccountServiceServer(grpcServer, &accountServer{Server: server})
+	pb.RegisterTransactionServiceServer(grpcServer, &transactionServer{Server: server})
+	health.RegisterGRPC(grpcServer)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", address, err)
+	}
+
+	return shutdown.ServeGRPC(ctx, grpcServer, listener, server.config.ShutdownTimeout)
+}
+
+// internalError logs an unexpected error of a gRPC method and returns it with the Internal code
+func (server *Server) internalError(ctx context.Context, name string, err error) error {
+	server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+	return status.Error(codes.Internal, err.Error())
+}
+
+func convertAccount(account db.Account) *pb.Account {
+	return &pb.Account{
+		AccountId: account.AccountID,
+		UserId:    account.UserID,
+		Balance:   account.Balance,
+		Currency:  account.Currency,
+		CreatedAt: timestamppb.New(account.CreatedAt),
+		UpdatedAt: timestamppb.New(account.UpdatedAt),
+	}
+}
+
+func convertTransaction(transaction db.Transaction) *pb.Transaction {
+	resp := &pb.Transaction{
+		TransactionId:     transaction.TransactionID,
+		FromAccountId:     transaction.FromAccountID,
+		ToAccountId:       transaction.ToAccountID,
+		TransactionAmount: transaction.TransactionAmount,
+		Commission:        transaction.Commission,
+		CreatedAt:         timestamppb.New(transaction.CreatedAt),
+		UpdatedAt:         timestamppb.New(transaction.UpdatedAt),
+	}
+	if transaction.Description.Valid {
+		resp.Description = &transaction.Description.String
+	}
+	return resp
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (server *transactionServer) CreateTransaction(ctx context.Context, req *pb.CreateTransactionRequest) (*pb.CreateTransactionResponse, error) {
+	if req.GetFromAccountId() == "" || req.GetToAccountId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "from_account_id and to_account_id are required")
+	}
+	if req.GetTransactionAmount() <= 0 {
+		return nil, status.Error(codes.InvalidArgument, "transaction_amount must be positive")
+	}
+
+	fromAccount, err := server.store.GetAccount(ctx, req.GetFromAccountId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "sender account not found")
+		}
+		return nil, server.internalError(ctx, "account-grpcCreateTransaction", err)
+	}
+	if fromAccount.Balance < req.GetTransactionAmount() {
+		return nil, status.Error(codes.FailedPrecondition, "sender doesn't have enough money")
+	}
+
+	_, err = server.store.GetAccount(ctx, req.GetToAccountId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "receiver account not found")
+		}
+		return nil, server.internalError(ctx, "account-grpcCreateTransaction", err)
+	}
+
+	arg := db.TransferTxParams{
+		TransactionID:     server.createUUID(),
+		FromAccountID:     req.GetFromAccountId(),
+		ToAccountID:       req.GetToAccountId(),
+		TransactionAmount: req.GetTransactionAmount(),
+	}
+	if req.Description != nil {
+		arg.Description = sql.NullString{String: req.GetDescription(), Valid: true}
+	}
+
+	result, err := server.store.TransferTx(ctx, arg)
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcCreateTransaction", err)
+	}
+	observeTransfer(result)
+
+	return &pb.CreateTransactionResponse{
+		Transaction: convertTransaction(result.Transaction),
+		FromAccount: convertAccount(result.FromAccount),
+		ToAccount:   convertAccount(result.ToAccount),
+	}, nil
+}
+
+func (server *transactionServer) GetTransaction(ctx context.Context, req *pb.GetTransactionRequest) (*pb.Transaction, error) {
+	if req.GetTransactionId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "transaction_id is required")
+	}
+
+	transaction, err := server.store.GetTransaction(ctx, req.GetTransactionId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "transaction not found")
+		}
+		return nil, server.internalError(ctx, "account-grpcGetTransaction", err)
+	}
+
+	return convertTransaction(transaction), nil
+}
+
+func (server *transactionServer) ListTransactions(ctx context.Context, req *pb.ListTransactionsRequest) (*pb.ListTransactionsResponse, error) {
+	transactions, err := server.store.ListTransactions(ctx)
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcListTransactions", err)
+	}
+
+	return convertTransactions(transactions), nil
+}
+
+func (server *transactionServer) ListUserTransactions(ctx context.Context, req *pb.ListUserTransactionsRequest) (*pb.ListTransactionsResponse, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	transactions, err := server.store.ListTransactionsByUser(ctx, req.GetUserId())
+	if err != nil {
+		return nil, server.internalError(ctx, "account-grpcListUserTransactions", err)
+	}
+
+	return convertTransactions(transactions), nil
+}
+
+func convertTransactions(transactions []db.Transaction) *pb.ListTransactionsResponse {
+	resp := &pb.ListTransactionsResponse{Transactions: make([]*pb.Transaction, 0, len(transactions))}
+	for _, transaction := range transactions {
+		resp.Transactions = append(resp.Transactions, convertTransaction(transaction))
+	}
+	return resp
+}
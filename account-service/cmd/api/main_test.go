@@ -1,6 +1,7 @@
 package main
 
 import (
+	"github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
 	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/require"
@@ -8,8 +9,8 @@ import (
 	"testing"
 )
 
-func newTestServer(t *testing.T, store db.Store) *Server {
-	server := NewServer(store)
+func newTestServer(t *testing.T, store db.Store, userDirectory users.Directory) *Server {
+	server := NewServer(Config{}, store, userDirectory)
 	require.NotEmpty(t, server)
 
 	return server
@@ -0,0 +1,32 @@
+package main
+
+import (
+	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	transfersCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transfers_created_total",
+		Help: "Transfers between accounts that were completed.",
+	})
+
+	transferAmount = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "transfer_amount",
+		Help:    "Amounts of the completed transfers.",
+		Buckets: []float64{10, 50, 100, 500, 1000, 5000, 10000, 50000, 100000},
+	})
+
+	commissionCollected = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "transfer_commission_collected_total",
+		Help: "Commission collected on the completed transfers.",
+	})
+)
+
+// observeTransfer counts a completed transfer, its amount and the commission collected on it
+func observeTransfer(result db.TransferTxResult) {
+	transfersCreated.Inc()
+	transferAmount.Observe(result.Transaction.TransactionAmount)
+	commissionCollected.Add(result.Transaction.Commission)
+}
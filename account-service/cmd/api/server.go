@@ -1,19 +1,46 @@
 package main
 
 import (
+	"context"
+	"github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
 	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
 )
 
 type Server struct {
-	store    db.Store
-	router   *gin.Engine
-	transfer db.SQLStore
+	config        Config
+	store         db.Store
+	userDirectory users.Directory
+	router        *gin.Engine
+	transfer      db.SQLStore
+	logs          *logship.Shipper
+	logger        *slog.Logger
 }
 
-func NewServer(store db.Store) *Server {
-	server := &Server{store: store}
+func NewServer(config Config, store db.Store, userDirectory users.Directory) *Server {
+	server := &Server{
+		config:        config,
+		store:         store,
+		userDirectory: userDirectory,
+		logs:          logship.New(logship.HTTPSink(nil, config.LoggerServiceURL), config.logShipConfig()),
+	}
+	stdoutLevel, shipLevel := config.logLevels()
+	server.logger = logging.New("account-service", logging.Stdout(stdoutLevel), logging.Ship(server.logs, shipLevel))
 	router := gin.Default()
+	// handlers pass their *gin.Context on as a context.Context, which has to reach the values of the request context
+	router.ContextWithFallback = true
+	router.Use(traceRequest, observeRequest, requestID, logFields)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	router.POST("/accounts/create", server.createAccount)
 	router.GET("/accounts/:account_id", server.getAccount)
@@ -21,20 +48,68 @@ func NewServer(store db.Store) *Server {
 	router.PUT("/accounts/update", server.updateAccount)
 	router.DELETE("/accounts/delete/:account_id", server.deleteAccount)
 	router.GET("/accounts", server.listAccounts)
+	router.GET("/accounts/user/:user_id", server.listUserAccounts)
 	router.POST("/accounts/add-balance", server.addAccountBalance)
 
 	router.POST("/transactions/create", server.createTransfer)
 	router.GET("/transactions/:transaction_id", server.getTransaction)
 	router.GET("/transactions", server.listTransactions)
+	router.GET("/transactions/user/:user_id", server.listUserTransactions)
 
 	server.router = router
 	return server
 }
 
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// ServeHealth serves the liveness of the service at /healthz and the checks of checker at /readyz
+func (server *Server) ServeHealth(checker *health.Checker) {
+	server.router.GET("/healthz", gin.WrapH(health.LiveHandler()))
+	server.router.GET("/readyz", gin.WrapH(checker.ReadyHandler()))
+}
+
+// Start serves the HTTP api on address until ctx is done, then lets the requests in flight finish
+func (server *Server) Start(ctx context.Context, address string) error {
+	srv := &http.Server{Addr: address, Handler: server.router}
+	return shutdown.ServeHTTP(ctx, srv, server.config.ShutdownTimeout)
 }
 
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// traceRequest starts the span of the request, which continues the trace of the caller
+func traceRequest(ctx *gin.Context) {
+	spanCtx, span := tracing.StartHTTPServer(ctx.Request, "account-service", ctx.FullPath())
+	ctx.Request = ctx.Request.WithContext(spanCtx)
+	ctx.Next()
+	tracing.EndHTTPServer(span, ctx.Writer.Status())
+}
+
+// observeRequest counts the request and the time taken to serve it, by the route it matched
+func observeRequest(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+	metrics.ObserveHTTP(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+}
+
+// requestID puts the id of the X-Request-ID header, or a new one, into the context of the request and the header of
+// its response, so that the logs of the request can be matched with the ones of the other services
+func requestID(ctx *gin.Context) {
+	id := requestid.FromRequest(ctx.Request)
+	ctx.Request = ctx.Request.WithContext(requestid.NewContext(ctx.Request.Context(), id))
+	ctx.Header(requestid.Header, id)
+	ctx.Next()
+}
+
+// logFields puts the route of the request into the context of its logs, together with the account or user its path
+// names
+func logFields(ctx *gin.Context) {
+	attrs := []slog.Attr{logging.Route(ctx.FullPath())}
+	if id := ctx.Param("account_id"); id != "" {
+		attrs = append(attrs, logging.AccountID(id))
+	}
+	if id := ctx.Param("user_id"); id != "" {
+		attrs = append(attrs, logging.UserID(id))
+	}
+	ctx.Request = ctx.Request.WithContext(logging.NewContext(ctx.Request.Context(), attrs...))
+	ctx.Next()
+}
@@ -3,10 +3,10 @@ package main
 import (
 	"database/sql"
 	"errors"
-	"fmt"
 	"net/http"
 
 	db "github.com/bugrakocabay/dummy-bank-microservice/account-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
 	"github.com/gin-gonic/gin"
 )
 
@@ -24,6 +24,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 		ctx.JSON(http.StatusBadRequest, errorResponse(err))
 		return
 	}
+	logging.AddFields(ctx, logging.AccountID(req.FromAccountID))
 
 	account1, err := server.store.GetAccount(ctx, req.FromAccountID)
 	if err != nil {
@@ -31,10 +32,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-createTransfer", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-createTransfer", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -49,10 +47,7 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-createTransfer", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-createTransfer", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -66,13 +61,11 @@ func (server *Server) createTransfer(ctx *gin.Context) {
 	}
 	transaction, err := server.store.TransferTx(ctx, payload)
 	if err != nil {
-		server.sendErrorLog("account-createTransfer", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-createTransfer", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
+	observeTransfer(transaction)
 
 	ctx.JSON(http.StatusCreated, transaction)
 }
@@ -95,10 +88,7 @@ func (server *Server) getTransaction(ctx *gin.Context) {
 			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("account-getTransaction", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-getTransaction", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -109,10 +99,29 @@ func (server *Server) getTransaction(ctx *gin.Context) {
 func (server *Server) listTransactions(ctx *gin.Context) {
 	transactions, err := server.store.ListTransactions(ctx)
 	if err != nil {
-		server.sendErrorLog("account-listTransactions", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "account-listTransactions", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, transactions)
+}
+
+type listUserTransactionsRequest struct {
+	UserID string `uri:"user_id" binding:"required,min=1"`
+}
+
+// listUserTransactions returns the transactions sent from or to any account of the user
+func (server *Server) listUserTransactions(ctx *gin.Context) {
+	var req listUserTransactionsRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	transactions, err := server.store.ListTransactionsByUser(ctx, req.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "account-listUserTransactions", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -0,0 +1,61 @@
+package users
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type cachedUser struct {
+	user      User
+	expiresAt time.Time
+}
+
+// CachedDirectory is a Directory remembering lookups of another Directory for a while. Only users that can open
+// accounts are cached: pending or deactivated users are likely to change state soon, so they are always looked up.
+type CachedDirectory struct {
+	next  Directory
+	ttl   time.Duration
+	mu    sync.Mutex
+	users map[string]cachedUser
+}
+
+// NewCachedDirectory creates a CachedDirectory keeping users of next for ttl
+func NewCachedDirectory(next Directory, ttl time.Duration) Directory {
+	return &CachedDirectory{
+		next:  next,
+		ttl:   ttl,
+		users: make(map[string]cachedUser),
+	}
+}
+
+func (directory *CachedDirectory) GetUser(ctx context.Context, userID string) (User, error) {
+	now := time.Now()
+
+	directory.mu.Lock()
+	cached, ok := directory.users[userID]
+	directory.mu.Unlock()
+	if ok && now.Before(cached.expiresAt) {
+		return cached.user, nil
+	}
+
+	user, err := directory.next.GetUser(ctx, userID)
+	if err != nil {
+		return User{}, err
+	}
+
+	directory.mu.Lock()
+	defer directory.mu.Unlock()
+	if user.CanOpenAccounts() {
+		directory.users[userID] = cachedUser{user: user, expiresAt: now.Add(directory.ttl)}
+	} else {
+		delete(directory.users, userID)
+	}
+	for id, entry := range directory.users {
+		if now.After(entry.expiresAt) {
+			delete(directory.users, id)
+		}
+	}
+
+	return user, nil
+}
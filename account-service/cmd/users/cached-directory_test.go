@@ -0,0 +1,65 @@
+package users_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
+	mockusers "github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users/mock"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCachedDirectory(t *testing.T) {
+	verified := users.User{UserID: "verified", IsActive: true, KYCStatus: users.KYCStatusVerified}
+	pending := users.User{UserID: "pending", IsActive: true, KYCStatus: "pending"}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	next := mockusers.NewMockDirectory(ctrl)
+	next.EXPECT().GetUser(gomock.Any(), gomock.Eq(verified.UserID)).
+		Times(1).
+		Return(verified, nil)
+	next.EXPECT().GetUser(gomock.Any(), gomock.Eq(pending.UserID)).
+		Times(2).
+		Return(pending, nil)
+	next.EXPECT().GetUser(gomock.Any(), gomock.Eq("missing")).
+		Times(2).
+		Return(users.User{}, users.ErrUserNotFound)
+
+	directory := users.NewCachedDirectory(next, time.Minute)
+	for i := 0; i < 2; i++ {
+		user, err := directory.GetUser(context.Background(), verified.UserID)
+		require.NoError(t, err)
+		require.Equal(t, verified, user)
+
+		user, err = directory.GetUser(context.Background(), pending.UserID)
+		require.NoError(t, err)
+		require.Equal(t, pending, user)
+
+		_, err = directory.GetUser(context.Background(), "missing")
+		require.ErrorIs(t, err, users.ErrUserNotFound)
+	}
+}
+
+func TestCachedDirectoryExpiry(t *testing.T) {
+	verified := users.User{UserID: "verified", IsActive: true, KYCStatus: users.KYCStatusVerified}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	next := mockusers.NewMockDirectory(ctrl)
+	next.EXPECT().GetUser(gomock.Any(), gomock.Eq(verified.UserID)).
+		Times(2).
+		Return(verified, nil)
+
+	directory := users.NewCachedDirectory(next, time.Millisecond)
+	_, err := directory.GetUser(context.Background(), verified.UserID)
+	require.NoError(t, err)
+
+	time.Sleep(5 * time.Millisecond)
+	_, err = directory.GetUser(context.Background(), verified.UserID)
+	require.NoError(t, err)
+}
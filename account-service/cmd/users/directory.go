@@ -0,0 +1,28 @@
+package users
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUserNotFound is returned when no user exists with the requested user_id
+var ErrUserNotFound = errors.New("user not found")
+
+const KYCStatusVerified = "verified"
+
+// User is the part of a user-service user that account-service relies on
+type User struct {
+	UserID    string `json:"user_id"`
+	IsActive  bool   `json:"is_active"`
+	KYCStatus string `json:"kyc_status"`
+}
+
+// CanOpenAccounts reports whether the user is active and has completed identity verification
+func (user User) CanOpenAccounts() bool {
+	return user.IsActive && user.KYCStatus == KYCStatusVerified
+}
+
+type Directory interface {
+	// GetUser looks up a user by user_id
+	GetUser(ctx context.Context, userID string) (User, error)
+}
@@ -0,0 +1,67 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCDirectory is a Directory asking the UserService of user-service for every lookup
+type GRPCDirectory struct {
+	conn    *grpc.ClientConn
+	client  pb.UserServiceClient
+	health  health.Check
+	timeout time.Duration
+}
+
+// NewGRPCDirectory creates a GRPCDirectory for the user-service reachable at address, whose lookups may take up to
+// timeout. The connection is established lazily, so user-service doesn't have to be up yet.
+func NewGRPCDirectory(address string, timeout time.Duration) (*GRPCDirectory, error) {
+	conn, err := grpc.Dial(address,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(requestid.UnaryClientInterceptor(), tracing.UnaryClientInterceptor()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("cannot dial user-service: %w", err)
+	}
+
+	return &GRPCDirectory{conn: conn, client: pb.NewUserServiceClient(conn), health: health.GRPC(conn), timeout: timeout}, nil
+}
+
+// Close closes the connection to user-service, once account-service served its last request
+func (directory *GRPCDirectory) Close() error {
+	return directory.conn.Close()
+}
+
+// Check checks that user-service is serving, which account-service needs for every account and transfer
+func (directory *GRPCDirectory) Check(ctx context.Context) error {
+	return directory.health(ctx)
+}
+
+func (directory *GRPCDirectory) GetUser(ctx context.Context, userID string) (User, error) {
+	ctx, cancel := context.WithTimeout(ctx, directory.timeout)
+	defer cancel()
+
+	user, err := directory.client.GetUser(ctx, &pb.GetUserRequest{UserId: userID})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return User{}, ErrUserNotFound
+		}
+		return User{}, fmt.Errorf("cannot reach user-service: %w", err)
+	}
+
+	return User{
+		UserID:    user.GetUserId(),
+		IsActive:  user.GetIsActive(),
+		KYCStatus: user.GetKycStatus(),
+	}, nil
+}
@@ -0,0 +1,51 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users (interfaces: Directory)
+
+// Package mockusers is a generated GoMock package.
+package mockusers
+
+import (
+	context "context"
+	reflect "reflect"
+
+	users "github.com/bugrakocabay/dummy-bank-microservice/account-service/cmd/users"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDirectory is a mock of Directory interface.
+type MockDirectory struct {
+	ctrl     *gomock.Controller
+	recorder *MockDirectoryMockRecorder
+}
+
+// MockDirectoryMockRecorder is the mock recorder for MockDirectory.
+type MockDirectoryMockRecorder struct {
+	mock *MockDirectory
+}
+
+// NewMockDirectory creates a new mock instance.
+func NewMockDirectory(ctrl *gomock.Controller) *MockDirectory {
+	mock := &MockDirectory{ctrl: ctrl}
+	mock.recorder = &MockDirectoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDirectory) EXPECT() *MockDirectoryMockRecorder {
+	return m.recorder
+}
+
+// GetUser mocks base method.
+func (m *MockDirectory) GetUser(arg0 context.Context, arg1 string) (users.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUser", arg0, arg1)
+	ret0, _ := ret[0].(users.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUser indicates an expected call of GetUser.
+func (mr *MockDirectoryMockRecorder) GetUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUser", reflect.TypeOf((*MockDirectory)(nil).GetUser), arg0, arg1)
+}
@@ -154,6 +154,21 @@ func (mr *MockStoreMockRecorder) ListAccounts(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccounts", reflect.TypeOf((*MockStore)(nil).ListAccounts), arg0)
 }
 
+// ListAccountsByUser mocks base method.
+func (m *MockStore) ListAccountsByUser(arg0 context.Context, arg1 string) ([]db.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListAccountsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListAccountsByUser indicates an expected call of ListAccountsByUser.
+func (mr *MockStoreMockRecorder) ListAccountsByUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListAccountsByUser", reflect.TypeOf((*MockStore)(nil).ListAccountsByUser), arg0, arg1)
+}
+
 // ListTransactions mocks base method.
 func (m *MockStore) ListTransactions(arg0 context.Context) ([]db.Transaction, error) {
 	m.ctrl.T.Helper()
@@ -169,6 +184,21 @@ func (mr *MockStoreMockRecorder) ListTransactions(arg0 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactions", reflect.TypeOf((*MockStore)(nil).ListTransactions), arg0)
 }
 
+// ListTransactionsByUser mocks base method.
+func (m *MockStore) ListTransactionsByUser(arg0 context.Context, arg1 string) ([]db.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListTransactionsByUser", arg0, arg1)
+	ret0, _ := ret[0].([]db.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListTransactionsByUser indicates an expected call of ListTransactionsByUser.
+func (mr *MockStoreMockRecorder) ListTransactionsByUser(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListTransactionsByUser", reflect.TypeOf((*MockStore)(nil).ListTransactionsByUser), arg0, arg1)
+}
+
 // TransferTx mocks base method.
 func (m *MockStore) TransferTx(arg0 context.Context, arg1 db.TransferTxParams) (db.TransferTxResult, error) {
 	m.ctrl.T.Helper()
@@ -12,6 +12,12 @@ SELECT *
 FROM accounts
 ORDER BY id;
 
+-- name: ListAccountsByUser :many
+SELECT *
+FROM accounts
+WHERE user_id = $1
+ORDER BY id;
+
 -- name: UpdateAccount :one
 UPDATE accounts
 set balance = $2
@@ -42,4 +48,13 @@ WHERE transaction_id = $1 LIMIT 1;
 
 -- name: ListTransactions :many
 SELECT *
-FROM transactions;
\ No newline at end of file
+FROM transactions;
+
+-- name: ListTransactionsByUser :many
+SELECT t.*
+FROM transactions t
+WHERE EXISTS (SELECT 1
+              FROM accounts a
+              WHERE a.user_id = sqlc.arg(user_id)
+                AND a.account_id IN (t.from_account_id, t.to_account_id))
+ORDER BY t.id;
\ No newline at end of file
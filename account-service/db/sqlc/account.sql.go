@@ -215,6 +215,44 @@ func (q *Queries) ListAccounts(ctx context.Context) ([]Account, error) {
 	return items, nil
 }
 
+const listAccountsByUser = `-- name: ListAccountsByUser :many
+SELECT id, account_id, user_id, balance, currency, created_at, updated_at
+FROM accounts
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListAccountsByUser(ctx context.Context, userID string) ([]Account, error) {
+	rows, err := q.db.QueryContext(ctx, listAccountsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Account{}
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.AccountID,
+			&i.UserID,
+			&i.Balance,
+			&i.Currency,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listTransactions = `-- name: ListTransactions :many
 SELECT id, transaction_id, from_account_id, to_account_id, transaction_amount, commission, description, created_at, updated_at
 FROM transactions
@@ -253,6 +291,49 @@ func (q *Queries) ListTransactions(ctx context.Context) ([]Transaction, error) {
 	return items, nil
 }
 
+const listTransactionsByUser = `-- name: ListTransactionsByUser :many
+SELECT t.id, t.transaction_id, t.from_account_id, t.to_account_id, t.transaction_amount, t.commission, t.description, t.created_at, t.updated_at
+FROM transactions t
+WHERE EXISTS (SELECT 1
+              FROM accounts a
+              WHERE a.user_id = $1
+                AND a.account_id IN (t.from_account_id, t.to_account_id))
+ORDER BY t.id
+`
+
+func (q *Queries) ListTransactionsByUser(ctx context.Context, userID string) ([]Transaction, error) {
+	rows, err := q.db.QueryContext(ctx, listTransactionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Transaction{}
+	for rows.Next() {
+		var i Transaction
+		if err := rows.Scan(
+			&i.ID,
+			&i.TransactionID,
+			&i.FromAccountID,
+			&i.ToAccountID,
+			&i.TransactionAmount,
+			&i.Commission,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateAccount = `-- name: UpdateAccount :one
 UPDATE accounts
 set balance = $2
@@ -180,3 +180,29 @@ func TestListTransactions(t *testing.T) {
 		require.NotEmpty(t, transaction)
 	}
 }
+
+func TestListAccountsByUser(t *testing.T) {
+	account1 := createRandomAccount(t)
+	createRandomAccount(t)
+
+	accounts, err := testQueries.ListAccountsByUser(context.Background(), account1.UserID)
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	require.Equal(t, account1.AccountID, accounts[0].AccountID)
+}
+
+func TestListTransactionsByUser(t *testing.T) {
+	transaction := createRandomTransaction(t)
+
+	from, err := testQueries.GetAccount(context.Background(), transaction.FromAccountID)
+	require.NoError(t, err)
+	to, err := testQueries.GetAccount(context.Background(), transaction.ToAccountID)
+	require.NoError(t, err)
+
+	for _, userID := range []string{from.UserID, to.UserID} {
+		transactions, err := testQueries.ListTransactionsByUser(context.Background(), userID)
+		require.NoError(t, err)
+		require.Len(t, transactions, 1)
+		require.Equal(t, transaction.TransactionID, transactions[0].TransactionID)
+	}
+}
@@ -17,7 +17,9 @@ type Querier interface {
 	GetAccountBalance(ctx context.Context, accountID string) (GetAccountBalanceRow, error)
 	GetTransaction(ctx context.Context, transactionID string) (Transaction, error)
 	ListAccounts(ctx context.Context) ([]Account, error)
+	ListAccountsByUser(ctx context.Context, userID string) ([]Account, error)
 	ListTransactions(ctx context.Context) ([]Transaction, error)
+	ListTransactionsByUser(ctx context.Context, userID string) ([]Transaction, error)
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (Account, error)
 }
 
@@ -6,6 +6,8 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 )
 
 type Store interface {
@@ -21,7 +23,7 @@ type SQLStore struct {
 func NewStore(db *sql.DB) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(tracing.WrapDB(db, "postgresql")),
 	}
 }
 
@@ -32,7 +34,7 @@ func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) erro
 		return err
 	}
 
-	q := New(tx)
+	q := New(tracing.WrapDB(tx, "postgresql"))
 	err = fn(q)
 	if err != nil {
 		if rbErr := tx.Rollback(); rbErr != nil {
@@ -65,6 +67,9 @@ type TransferTxResult struct {
 func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (TransferTxResult, error) {
 	var result TransferTxResult
 
+	// the span of the transfer holds the spans of its queries, and shows the time spent waiting for row locks
+	ctx, span := tracing.Tracer().Start(ctx, "TransferTx")
+
 	err := store.execTx(ctx, func(q *Queries) error {
 		var err error
 		commission := float64(arg.TransactionAmount) * 0.03
@@ -89,6 +94,7 @@ func (store *SQLStore) TransferTx(ctx context.Context, arg TransferTxParams) (Tr
 		}
 		return nil
 	})
+	tracing.End(span, err)
 
 	return result, err
 }
@@ -2,22 +2,32 @@ package event
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
 	"net/http"
+	"sync"
 )
 
 type Consumer struct {
 	conn      *amqp.Connection
 	queueName string
+	loggerURL string
+	client    *http.Client
 }
 
-// NewConsumer creates a connection for consumer
-func NewConsumer(conn *amqp.Connection) (Consumer, error) {
+// NewConsumer creates a connection for consumer, which passes the consumed events on to the logger-service at
+// loggerURL
+func NewConsumer(conn *amqp.Connection, loggerURL string) (Consumer, error) {
 	consumer := Consumer{
-		conn: conn,
+		conn:      conn,
+		loggerURL: loggerURL,
+		client:    &http.Client{Transport: tracing.Transport(nil)},
 	}
 
 	err := consumer.setup()
@@ -38,12 +48,19 @@ func (consumer *Consumer) setup() error {
 	return declareExchange(channel)
 }
 
+// Payload is a log published to logs_topic. Data is passed on to logger-service as it was published.
 type Payload struct {
-	Name string `json:"name"`
-	Data string `json:"data"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	RequestID string          `json:"request_id,omitempty"`
 }
 
-func (consumer *Consumer) Listen(topics []string) error {
+// consumerTag names the consumer of Listen, which cancels it by this tag
+const consumerTag = "logs"
+
+// Listen consumes the messages of topics until ctx is done. It then cancels the consumer, so that rabbitmq stops
+// delivering, and returns once the messages already delivered are passed on to logger-service.
+func (consumer *Consumer) Listen(ctx context.Context, topics []string) error {
 	ch, err := consumer.conn.Channel()
 	if err != nil {
 		return err
@@ -62,52 +79,78 @@ func (consumer *Consumer) Listen(topics []string) error {
 		}
 	}
 
-	messages, err := ch.Consume(q.Name, "", true, false, false, false, nil)
+	messages, err := ch.Consume(q.Name, consumerTag, true, false, false, false, nil)
 	if err != nil {
 		return err
 	}
 
-	forever := make(chan bool)
 	go func() {
-		for d := range messages {
-			var payload Payload
-			_ = json.Unmarshal(d.Body, &payload)
-
-			go handlePayload(payload)
+		<-ctx.Done()
+		// messages is closed once the deliveries in flight are received
+		if err := ch.Cancel(consumerTag, false); err != nil {
+			log.Println("cannot cancel consumer:", err)
 		}
 	}()
 
 	fmt.Printf("waiting for message on [Exchange, Queue] [logs_topic, %s]", q.Name)
-	<-forever
 
+	var handling sync.WaitGroup
+	for d := range messages {
+		var payload Payload
+		_ = json.Unmarshal(d.Body, &payload)
+		if id, ok := d.Headers[requestid.Key].(string); ok && payload.RequestID == "" {
+			payload.RequestID = id
+		}
+
+		// the span of the message continues the trace of the request that published it
+		exchange, routingKey := d.Exchange, d.RoutingKey
+		msgCtx, span := tracing.StartConsume(context.Background(), d.Headers, exchange, routingKey)
+		handling.Add(1)
+		go func() {
+			defer handling.Done()
+			err := consumer.handlePayload(msgCtx, routingKey, payload)
+			if err != nil {
+				log.Println(err)
+			}
+			metrics.ObserveConsume(exchange, routingKey, err)
+			tracing.End(span, err)
+		}()
+	}
+	handling.Wait()
+
+	if ctx.Err() == nil {
+		// the channel or the connection was closed by rabbitmq
+		return amqp.ErrClosed
+	}
 	return nil
 }
 
-func handlePayload(payload Payload) {
-	switch payload.Name {
-	case "log", "event":
-		err := logEvent(payload)
-		if err != nil {
-			log.Println(err)
-		}
+// handlePayload stores the logs of responses, published with the log.REQUEST routing key, as request logs and all
+// others as error logs
+func (consumer *Consumer) handlePayload(ctx context.Context, routingKey string, payload Payload) error {
+	switch routingKey {
+	case "log.REQUEST":
+		return consumer.logEvent(ctx, "create-request", payload)
 	default:
-		err := logEvent(payload)
-		if err != nil {
-			log.Println(err)
-		}
+		return consumer.logEvent(ctx, "create-error", payload)
 	}
 }
 
-func logEvent(entry Payload) error {
+func (consumer *Consumer) logEvent(ctx context.Context, endpoint string, entry Payload) error {
 	jsonData, err := json.Marshal(entry)
 	if err != nil {
 		log.Println("sendErrorLog error: cant marshal json:", err)
 		return err
 	}
-	request, err := http.NewRequest(http.MethodPost, "http://logger-service/logs/create-error", bytes.NewBuffer(jsonData))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("%s/logs/%s", consumer.loggerURL, endpoint), bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	if entry.RequestID != "" {
+		request.Header.Set(requestid.Header, entry.RequestID)
+	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	response, err := consumer.client.Do(request)
 	if err != nil {
 		log.Println("sendErrorLog error: cant send response:", err)
 		return err
@@ -115,8 +158,7 @@ func logEvent(entry Payload) error {
 	defer response.Body.Close()
 
 	if response.StatusCode != http.StatusCreated {
-		log.Println("sendErrorLog error: calling auth service:", err)
-		return err
+		return fmt.Errorf("logger-service responded with status %d", response.StatusCode)
 	}
 
 	log.Println("logged successfully")
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// API is the client of the REST api the gateway serves under /api/v1. Calls made as a user take the access token
+// from the login response.
+type API struct {
+	*Client
+}
+
+// NewAPI creates a client of the gateway at baseURL, such as "http://localhost:8080"
+func NewAPI(baseURL string, options ...Option) *API {
+	return &API{Client: New(baseURL, options...)}
+}
+
+// envelope is the body the gateway wraps every response in
+type envelope struct {
+	Error   bool            `json:"error"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+// call sends a request to the /api/v1 path and decodes the data of the response into out
+func (a *API) call(ctx context.Context, method, path, accessToken string, payload any, out any) error {
+	header := http.Header{}
+	if accessToken != "" {
+		header.Set("Authorization", fmt.Sprintf("Bearer %s", accessToken))
+	}
+
+	var env envelope
+	resp, err := a.Do(ctx, Request{
+		Method: method,
+		Path:   "/api/v1" + path,
+		Header: header,
+		Body:   payload,
+	}, &env)
+	if err != nil {
+		return err
+	}
+
+	if out != nil && len(env.Data) > 0 {
+		if err = json.Unmarshal(env.Data, out); err != nil {
+			return &Error{
+				StatusCode: http.StatusBadGateway,
+				Message:    "error reading response data",
+				Header:     resp.Header,
+				Body:       resp.Body,
+				Err:        err,
+			}
+		}
+	}
+	return nil
+}
+
+func (a *API) CreateUser(ctx context.Context, req CreateUserRequest) (*User, error) {
+	var user User
+	if err := a.call(ctx, http.MethodPost, "/users", "", req, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Login exchanges the credentials of a user for an access token, or for a challenge token when the user has
+// enabled two-factor authentication
+func (a *API) Login(ctx context.Context, req LoginRequest) (*LoginResponse, error) {
+	var resp LoginResponse
+	if err := a.call(ctx, http.MethodPost, "/auth/login", "", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// ListAccounts returns the accounts of the logged-in user
+func (a *API) ListAccounts(ctx context.Context, accessToken string) ([]Account, error) {
+	var accounts []Account
+	if err := a.call(ctx, http.MethodGet, "/accounts", accessToken, nil, &accounts); err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// CreateAccount opens an account in the given currency for the logged-in user
+func (a *API) CreateAccount(ctx context.Context, accessToken, currency string) (*Account, error) {
+	payload := struct {
+		Currency string `json:"currency"`
+	}{Currency: currency}
+
+	var account Account
+	if err := a.call(ctx, http.MethodPost, "/accounts", accessToken, payload, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (a *API) GetAccount(ctx context.Context, accessToken, accountID string) (*Account, error) {
+	var account Account
+	path := fmt.Sprintf("/accounts/%s", url.PathEscape(accountID))
+	if err := a.call(ctx, http.MethodGet, path, accessToken, nil, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// Deposit adds amount to the balance of an account of the logged-in user
+func (a *API) Deposit(ctx context.Context, accessToken, accountID string, amount float64) (*Account, error) {
+	payload := struct {
+		Amount float64 `json:"amount"`
+	}{Amount: amount}
+
+	var account Account
+	path := fmt.Sprintf("/accounts/%s/deposits", url.PathEscape(accountID))
+	if err := a.call(ctx, http.MethodPost, path, accessToken, payload, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// ListTransactions returns the transactions of the accounts of the logged-in user
+func (a *API) ListTransactions(ctx context.Context, accessToken string) ([]Transaction, error) {
+	var transactions []Transaction
+	if err := a.call(ctx, http.MethodGet, "/transactions", accessToken, nil, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// CreateTransaction transfers money from an account of the logged-in user to any other account
+func (a *API) CreateTransaction(ctx context.Context, accessToken string, req CreateTransactionRequest) (*Transfer, error) {
+	var transfer Transfer
+	if err := a.call(ctx, http.MethodPost, "/transactions", accessToken, req, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
@@ -0,0 +1,184 @@
+// Package client contains the typed Go clients of the dummy bank services. UserService talks to the internal HTTP
+// api of user-service, which the gateway uses for the flows that aren't part of the gRPC api. API talks to the public
+// REST api of the gateway, through which clients such as the scripts reach account-service and user-service.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the time a call may take, including reading the response body, unless WithTimeout is given
+	DefaultTimeout = 30 * time.Second
+
+	// MaxResponseBytes is the size of the largest response body a call reads
+	MaxResponseBytes = 1048576
+)
+
+// Client sends JSON requests to a service and maps failed calls to an *Error
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithTimeout limits the time a call may take. A timeout of 0 leaves calls limited by their context only.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// WithHTTPClient sends the requests with the given http.Client instead of a new one. Its timeout is kept.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a Client of the service at baseURL, such as "http://user-service"
+func New(baseURL string, options ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Request is a call to a path of the service. Path may contain a query string. Body is encoded as JSON unless it
+// is nil.
+type Request struct {
+	Method string
+	Path   string
+	Header http.Header
+	Body   any
+}
+
+// Response is the response of a call, with its body read
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       json.RawMessage
+}
+
+// Do sends the request and decodes a successful response body into out, unless out is nil. When the service
+// responds with a status of 400 or above, the response is returned together with an *Error describing it, so that
+// it can still be relayed. Calls that fail before a response arrives return an *Error with a 502 or 504 status.
+func (c *Client) Do(ctx context.Context, req Request, out any) (*Response, error) {
+	var body io.Reader
+	if req.Body != nil {
+		jsonData, err := json.Marshal(req.Body)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode request body: %w", err)
+		}
+		body = bytes.NewReader(jsonData)
+	}
+
+	request, err := c.newRequest(ctx, req.Method, req.Path, req.Header, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.send(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(response.Body, MaxResponseBytes+1))
+	if err != nil {
+		return nil, transportError(req.Method, req.Path, err)
+	}
+	if len(respBody) > MaxResponseBytes {
+		return nil, &Error{
+			StatusCode: http.StatusBadGateway,
+			Message:    fmt.Sprintf("%s %s: response body is larger than %d bytes", req.Method, req.Path, MaxResponseBytes),
+		}
+	}
+
+	resp := &Response{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       respBody,
+	}
+	if response.StatusCode >= http.StatusBadRequest {
+		return resp, newError(req.Method, req.Path, resp)
+	}
+
+	if out != nil && len(respBody) > 0 {
+		if err = json.Unmarshal(respBody, out); err != nil {
+			return resp, &Error{
+				StatusCode: http.StatusBadGateway,
+				Message:    "error reading response body",
+				Header:     resp.Header,
+				Body:       resp.Body,
+				Err:        err,
+			}
+		}
+	}
+
+	return resp, nil
+}
+
+// Send streams body to a path of the service and returns the response as it is, for requests that aren't JSON such
+// as uploads and form posts. The caller has to close the response body. Only calls that fail before a response
+// arrives return an error, which is an *Error with a 502 or 504 status.
+func (c *Client) Send(ctx context.Context, method, path string, header http.Header, body io.Reader) (*http.Response, error) {
+	request, err := c.newRequest(ctx, method, path, header, body)
+	if err != nil {
+		return nil, err
+	}
+	return c.send(request)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, header http.Header, body io.Reader) (*http.Request, error) {
+	request, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create request: %w", err)
+	}
+	for key, values := range header {
+		request.Header[key] = values
+	}
+	return request, nil
+}
+
+func (c *Client) send(request *http.Request) (*http.Response, error) {
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, transportError(request.Method, request.URL.Path, err)
+	}
+	return response, nil
+}
+
+// transportError wraps an error of a call that got no complete response. Calls that ran out of time are reported
+// as 504, everything else as 502.
+func transportError(method, path string, err error) *Error {
+	status := http.StatusBadGateway
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		status = http.StatusGatewayTimeout
+	}
+	return &Error{
+		StatusCode: status,
+		Message:    fmt.Sprintf("%s %s: %v", method, path, err),
+		Err:        err,
+	}
+}
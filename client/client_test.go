@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		status        int
+		body          string
+		checkResponse func(t *testing.T, resp *Response, err error)
+	}{
+		{
+			name:   "OK",
+			status: http.StatusOK,
+			body:   `{"user_id":"user-1","email":"user@mail.com"}`,
+			checkResponse: func(t *testing.T, resp *Response, err error) {
+				require.NoError(t, err)
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+			},
+		},
+		{
+			name:   "ServiceError",
+			status: http.StatusNotFound,
+			body:   `{"error":"user not found"}`,
+			checkResponse: func(t *testing.T, resp *Response, err error) {
+				require.ErrorIs(t, err, ErrNotFound)
+				require.EqualError(t, err, "user not found")
+				require.Equal(t, http.StatusNotFound, StatusCode(err))
+				require.JSONEq(t, `{"error":"user not found"}`, string(resp.Body))
+			},
+		},
+		{
+			name:   "GatewayError",
+			status: http.StatusForbidden,
+			body:   `{"error":true,"message":"this is not yours"}`,
+			checkResponse: func(t *testing.T, resp *Response, err error) {
+				require.ErrorIs(t, err, ErrForbidden)
+				require.EqualError(t, err, "this is not yours")
+			},
+		},
+		{
+			name:   "NoMessage",
+			status: http.StatusServiceUnavailable,
+			body:   `unavailable`,
+			checkResponse: func(t *testing.T, resp *Response, err error) {
+				require.ErrorIs(t, err, ErrUnavailable)
+				require.EqualError(t, err, "GET /users/user-1 responded with status 503")
+			},
+		},
+		{
+			name:   "InvalidBody",
+			status: http.StatusOK,
+			body:   `{"user_id":`,
+			checkResponse: func(t *testing.T, resp *Response, err error) {
+				require.Error(t, err)
+				require.Equal(t, http.StatusBadGateway, StatusCode(err))
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/users/user-1", r.URL.Path)
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			}))
+			defer server.Close()
+
+			var user User
+			resp, err := New(server.URL).Do(context.Background(), Request{Method: http.MethodGet, Path: "/users/user-1"}, &user)
+			tc.checkResponse(t, resp, err)
+		})
+	}
+}
+
+func TestDoTransportErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	_, err := New(server.URL, WithTimeout(10*time.Millisecond)).Do(context.Background(), Request{Method: http.MethodGet, Path: "/"}, nil)
+	require.ErrorIs(t, err, ErrUnavailable)
+	require.Equal(t, http.StatusGatewayTimeout, StatusCode(err))
+
+	server.Close()
+	_, err = New(server.URL).Do(context.Background(), Request{Method: http.MethodGet, Path: "/"}, nil)
+	require.Equal(t, http.StatusBadGateway, StatusCode(err))
+
+	require.Equal(t, http.StatusInternalServerError, StatusCode(errors.New("other")))
+	require.Equal(t, http.StatusOK, StatusCode(nil))
+}
+
+func TestAPI(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/accounts/account-1/deposits", r.URL.Path)
+		require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"error":false,"message":"success","data":{"account_id":"account-1","balance":1000,"currency":"EUR"}}`))
+	}))
+	defer server.Close()
+
+	account, err := NewAPI(server.URL).Deposit(context.Background(), "token", "account-1", 1000)
+	require.NoError(t, err)
+	require.Equal(t, "account-1", account.AccountID)
+	require.Equal(t, float64(1000), account.Balance)
+}
@@ -0,0 +1,96 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Errors to compare an *Error with, as in errors.Is(err, client.ErrNotFound)
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrNotFound        = errors.New("not found")
+	ErrConflict        = errors.New("conflict")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrUnavailable     = errors.New("service unavailable")
+)
+
+// Error is a failed call. StatusCode is the status of the response, or 502 and 504 when no response arrived, so it
+// can always be used to respond with.
+type Error struct {
+	StatusCode int
+	Message    string
+	Header     http.Header
+	Body       json.RawMessage
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the status of the error matches one of the Err values of the package
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrTooManyRequests:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrUnavailable:
+		return e.StatusCode == http.StatusBadGateway || e.StatusCode == http.StatusServiceUnavailable ||
+			e.StatusCode == http.StatusGatewayTimeout
+	}
+	return false
+}
+
+// StatusCode returns the status to respond with for an error of a call: the status of an *Error, 200 for nil and
+// 500 for anything else
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var clientErr *Error
+	if errors.As(err, &clientErr) {
+		return clientErr.StatusCode
+	}
+	return http.StatusInternalServerError
+}
+
+// newError describes a response with a failed status. The message is taken from the body, where the services put
+// it in "error" and the gateway in "message".
+func newError(method, path string, resp *Response) *Error {
+	var body struct {
+		Error   any    `json:"error"`
+		Message string `json:"message"`
+	}
+	message := fmt.Sprintf("%s %s responded with status %d", method, path, resp.StatusCode)
+	if err := json.Unmarshal(resp.Body, &body); err == nil {
+		if text, ok := body.Error.(string); ok && text != "" {
+			message = text
+		} else if body.Message != "" {
+			message = body.Message
+		}
+	}
+
+	return &Error{
+		StatusCode: resp.StatusCode,
+		Message:    message,
+		Header:     resp.Header,
+		Body:       resp.Body,
+	}
+}
@@ -0,0 +1,96 @@
+package client
+
+import "time"
+
+type User struct {
+	Firstname     string    `json:"firstname"`
+	Lastname      string    `json:"lastname"`
+	UserID        string    `json:"user_id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
+	IsActive      bool      `json:"is_active"`
+	KYCStatus     string    `json:"kyc_status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UserExport is the profile of a user as user-service exports it
+type UserExport struct {
+	User
+	UpdatedAt     time.Time  `json:"updated_at"`
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+	KYC           KYC        `json:"kyc"`
+}
+
+// KYC is the kyc status of a user together with the metadata of the identity documents they submitted
+type KYC struct {
+	Status          string        `json:"status"`
+	RejectionReason string        `json:"rejection_reason,omitempty"`
+	ReviewedAt      *time.Time    `json:"reviewed_at,omitempty"`
+	Documents       []KYCDocument `json:"documents"`
+}
+
+type KYCDocument struct {
+	DocumentID   string    `json:"document_id"`
+	DocumentType string    `json:"document_type"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CreateUserRequest struct {
+	Firstname string `json:"firstname"`
+	Lastname  string `json:"lastname"`
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// LoginResponse holds either the access token of the user, or the challenge token to complete the login with when
+// the user has enabled two-factor authentication
+type LoginResponse struct {
+	AccessToken       string    `json:"access_token,omitempty"`
+	User              *User     `json:"user,omitempty"`
+	TwoFactorRequired bool      `json:"two_factor_required,omitempty"`
+	ChallengeToken    string    `json:"challenge_token,omitempty"`
+	ExpiresAt         time.Time `json:"expires_at,omitempty"`
+}
+
+type Account struct {
+	AccountID string    `json:"account_id"`
+	UserID    string    `json:"user_id"`
+	Balance   float64   `json:"balance"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Transaction struct {
+	TransactionID     string    `json:"transaction_id"`
+	FromAccountID     string    `json:"from_account_id"`
+	ToAccountID       string    `json:"to_account_id"`
+	TransactionAmount float64   `json:"transaction_amount"`
+	Commission        float64   `json:"commission"`
+	Description       *string   `json:"description,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Transfer is a created transaction together with both accounts after the transfer
+type Transfer struct {
+	Transaction Transaction `json:"transaction"`
+	FromAccount Account     `json:"from_account"`
+	ToAccount   Account     `json:"to_account"`
+}
+
+// CreateTransactionRequest is a transfer between two accounts. OTP is the one-time password of the sender, which the
+// gateway asks for above its transfer threshold.
+type CreateTransactionRequest struct {
+	FromAccountID     string  `json:"from_account_id"`
+	ToAccountID       string  `json:"to_account_id"`
+	TransactionAmount float64 `json:"transaction_amount"`
+	OTP               string  `json:"otp,omitempty"`
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// UserService is the client of the internal HTTP api of user-service. Calls made on behalf of a user take the
+// Authorization header value the user sent, so that user-service can identify them.
+type UserService struct {
+	*Client
+}
+
+// NewUserService creates a client of the user-service at baseURL, such as "http://user-service"
+func NewUserService(baseURL string, options ...Option) *UserService {
+	return &UserService{Client: New(baseURL, options...)}
+}
+
+// Forward sends payload to a path of user-service with the Authorization header and the client ip of a user, and
+// returns the response to be relayed as it is. A failed status comes with both the response and an *Error.
+func (s *UserService) Forward(ctx context.Context, method, path, authorization, clientIP string, payload any) (*Response, error) {
+	return s.Do(ctx, Request{
+		Method: method,
+		Path:   path,
+		Header: userHeader(authorization, clientIP),
+		Body:   payload,
+	}, nil)
+}
+
+// Login exchanges the credentials of a user for an access token. User-service counts failed logins per clientIP.
+func (s *UserService) Login(ctx context.Context, req LoginRequest, clientIP string) (*LoginResponse, error) {
+	var resp LoginResponse
+	_, err := s.Do(ctx, Request{
+		Method: http.MethodPost,
+		Path:   "/users/login",
+		Header: userHeader("", clientIP),
+		Body:   req,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+type verifyOTPRequest struct {
+	Code string `json:"code"`
+}
+
+// VerifyOTP checks a fresh one-time password of the user the authorization belongs to
+func (s *UserService) VerifyOTP(ctx context.Context, authorization, code string) error {
+	_, err := s.Do(ctx, Request{
+		Method: http.MethodPost,
+		Path:   "/users/2fa/verify",
+		Header: userHeader(authorization, ""),
+		Body:   verifyOTPRequest{Code: code},
+	}, nil)
+	return err
+}
+
+// ExportUser returns the profile of the user the authorization belongs to
+func (s *UserService) ExportUser(ctx context.Context, authorization string) (*UserExport, error) {
+	var resp UserExport
+	_, err := s.Do(ctx, Request{
+		Method: http.MethodGet,
+		Path:   "/users/export",
+		Header: userHeader(authorization, ""),
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func userHeader(authorization, clientIP string) http.Header {
+	header := http.Header{}
+	if authorization != "" {
+		header.Set("Authorization", authorization)
+	}
+	if clientIP != "" {
+		header.Set("X-Forwarded-For", clientIP)
+	}
+	return header
+}
@@ -0,0 +1,164 @@
+// Package config loads the configuration of the dummy bank services. A service describes its settings as a struct,
+// and every field can be set, from lowest to highest precedence, by its default, a config file, an environment
+// variable and a command line flag:
+//
+//	type EnvConfig struct {
+//		WebPort     string        `mapstructure:"WEB_PORT" default:"80" validate:"port" usage:"port of the HTTP api"`
+//		DBConn      string        `mapstructure:"DB_CONN_STRING" validate:"required" usage:"postgres connection string"`
+//		CallTimeout time.Duration `mapstructure:"CALL_TIMEOUT" default:"5s" validate:"positive"`
+//	}
+//
+// The mapstructure tag is the key of the setting in the config file and the name of its environment variable. The
+// flag is the key in lower case with dashes, such as --web-port. The config file is read from the path of the
+// --config flag or the CONFIG_FILE environment variable, or from ./app/config.json when it exists.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultFile is the config file that is read when no other is given. Unlike a given file, it may be missing.
+	DefaultFile = "./app/config.json"
+
+	fileFlag = "config"
+	fileEnv  = "CONFIG_FILE"
+)
+
+// Validator is implemented by settings that need checks beyond the validate tags, such as ones that depend on each
+// other. Validate is called after the tags are checked.
+type Validator interface {
+	Validate() error
+}
+
+// Load fills cfg, a pointer to a settings struct, from the command line arguments, the environment and the config
+// file, and validates it
+func Load(cfg any) error {
+	return Parse(cfg, os.Args[1:])
+}
+
+// Parse is Load with the given command line arguments
+func Parse(cfg any, args []string) error {
+	settings, err := fieldsOf(cfg)
+	if err != nil {
+		return err
+	}
+
+	v := viper.New()
+	flags := pflag.NewFlagSet(os.Args[0], pflag.ContinueOnError)
+	file := flags.String(fileFlag, "", fmt.Sprintf("path of the config file, also set by %s (default %s)", fileEnv, DefaultFile))
+
+	for _, setting := range settings {
+		if setting.def != "" {
+			v.SetDefault(setting.key, setting.def)
+		}
+		if err = v.BindEnv(setting.key); err != nil {
+			return err
+		}
+
+		usage := fmt.Sprintf("%s (env %s)", setting.usage, setting.key)
+		if setting.kind == reflect.Bool {
+			flags.Bool(setting.flag, false, usage)
+		} else {
+			flags.String(setting.flag, "", usage)
+		}
+		if err = v.BindPFlag(setting.key, flags.Lookup(setting.flag)); err != nil {
+			return err
+		}
+	}
+
+	if err = flags.Parse(args); err != nil {
+		return err
+	}
+
+	if err = readFile(v, *file); err != nil {
+		return err
+	}
+
+	if err = v.Unmarshal(cfg); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+
+	return validate(cfg, settings)
+}
+
+// readFile reads the config file of the flag, of the environment or the default one. Only the default file may be
+// missing.
+func readFile(v *viper.Viper, path string) error {
+	explicit := true
+	if path == "" {
+		path = os.Getenv(fileEnv)
+	}
+	if path == "" {
+		path = DefaultFile
+		explicit = false
+	}
+
+	v.SetConfigFile(path)
+	err := v.ReadInConfig()
+	if err != nil {
+		if !explicit && errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("cannot read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// setting is a field of a settings struct
+type setting struct {
+	index int
+	key   string
+	flag  string
+	def   string
+	usage string
+	rules []string
+	kind  reflect.Kind
+}
+
+func fieldsOf(cfg any) ([]setting, error) {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a pointer to a struct, got %T", cfg)
+	}
+
+	structType := value.Elem().Type()
+	settings := make([]setting, 0, structType.NumField())
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		key := field.Tag.Get("mapstructure")
+		if key == "" || key == "-" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64, reflect.Float64:
+		default:
+			return nil, fmt.Errorf("config field %s has unsupported type %s", field.Name, field.Type)
+		}
+
+		var rules []string
+		if tag := field.Tag.Get("validate"); tag != "" {
+			rules = strings.Split(tag, ",")
+		}
+		settings = append(settings, setting{
+			index: i,
+			key:   key,
+			flag:  strings.ToLower(strings.ReplaceAll(key, "_", "-")),
+			def:   field.Tag.Get("default"),
+			usage: field.Tag.Get("usage"),
+			rules: rules,
+			kind:  field.Type.Kind(),
+		})
+	}
+
+	return settings, nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	WebPort    string        `mapstructure:"TEST_WEB_PORT" default:"80" validate:"port"`
+	DBConn     string        `mapstructure:"TEST_DB_CONN_STRING" validate:"required"`
+	LoggerURL  string        `mapstructure:"TEST_LOGGER_URL" default:"http://logger-service" validate:"url"`
+	UserAddr   string        `mapstructure:"TEST_USER_ADDRESS" default:"user-service:50001" validate:"address"`
+	Timeout    time.Duration `mapstructure:"TEST_TIMEOUT" default:"5s" validate:"positive"`
+	Threshold  float64       `mapstructure:"TEST_THRESHOLD" default:"10000"`
+	Legacy     bool          `mapstructure:"TEST_LEGACY" default:"true"`
+	unexported string
+}
+
+func TestParsePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.json")
+	err := os.WriteFile(file, []byte(`{"TEST_DB_CONN_STRING":"from-file","TEST_WEB_PORT":"8000","TEST_TIMEOUT":"2s"}`), 0o600)
+	require.NoError(t, err)
+
+	t.Setenv("TEST_WEB_PORT", "9000")
+	t.Setenv("TEST_LEGACY", "false")
+
+	var cfg testConfig
+	err = Parse(&cfg, []string{"--config", file, "--test-web-port", "9100", "--test-threshold=0"})
+	require.NoError(t, err)
+
+	require.Equal(t, "9100", cfg.WebPort)
+	require.Equal(t, "from-file", cfg.DBConn)
+	require.Equal(t, "http://logger-service", cfg.LoggerURL)
+	require.Equal(t, "user-service:50001", cfg.UserAddr)
+	require.Equal(t, 2*time.Second, cfg.Timeout)
+	require.Equal(t, float64(0), cfg.Threshold)
+	require.False(t, cfg.Legacy)
+}
+
+func TestParseValidation(t *testing.T) {
+	t.Setenv("TEST_LOGGER_URL", "logger-service")
+	t.Setenv("TEST_USER_ADDRESS", "user-service")
+
+	var cfg testConfig
+	err := Parse(&cfg, []string{"--test-web-port", "0", "--test-timeout", "-1s"})
+	require.EqualError(t, err, `invalid config: TEST_WEB_PORT must be a port between 1 and 65535, got "0"; `+
+		`TEST_DB_CONN_STRING is required; TEST_LOGGER_URL must be an absolute URL, got "logger-service"; `+
+		`TEST_USER_ADDRESS must be a host:port address, got "user-service"; TEST_TIMEOUT must be positive`)
+}
+
+func TestParseMissingFile(t *testing.T) {
+	var cfg testConfig
+
+	t.Setenv("TEST_DB_CONN_STRING", "from-env")
+	require.NoError(t, Parse(&cfg, nil))
+
+	err := Parse(&cfg, []string{"--config", filepath.Join(t.TempDir(), "missing.json")})
+	require.ErrorContains(t, err, "cannot read config file")
+}
+
+type crossConfig struct {
+	Min int `mapstructure:"TEST_MIN" default:"2"`
+	Max int `mapstructure:"TEST_MAX" default:"1"`
+}
+
+func (c crossConfig) Validate() error {
+	if c.Min > c.Max {
+		return os.ErrInvalid
+	}
+	return nil
+}
+
+func TestParseValidator(t *testing.T) {
+	var cfg crossConfig
+	require.ErrorContains(t, Parse(&cfg, nil), os.ErrInvalid.Error())
+	require.NoError(t, Parse(&cfg, []string{"--test-max", "3"}))
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// validate checks the rules of the validate tags and then the Validate method of cfg. All failed settings are
+// reported together, so that a misconfigured service can be fixed in one go.
+func validate(cfg any, settings []setting) error {
+	value := reflect.ValueOf(cfg).Elem()
+
+	var problems []string
+	for _, setting := range settings {
+		field := value.Field(setting.index)
+		for _, rule := range setting.rules {
+			if err := checkRule(rule, field); err != nil {
+				problems = append(problems, fmt.Sprintf("%s %v", setting.key, err))
+				break
+			}
+		}
+	}
+
+	if validator, ok := cfg.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func checkRule(rule string, field reflect.Value) error {
+	if rule == "required" {
+		if field.IsZero() {
+			return errors.New("is required")
+		}
+		return nil
+	}
+
+	// the other rules only apply to settings that are set
+	if field.IsZero() {
+		return nil
+	}
+
+	switch rule {
+	case "url":
+		return CheckURL(field.String())
+	case "port":
+		return CheckPort(field.String())
+	case "address":
+		return CheckAddress(field.String())
+	case "positive":
+		if field.Kind() == reflect.Float64 && field.Float() > 0 || field.Kind() != reflect.Float64 && field.Int() > 0 {
+			return nil
+		}
+		return errors.New("must be positive")
+	default:
+		return fmt.Errorf("has unknown validate rule %q", rule)
+	}
+}
+
+// CheckURL checks that value is an absolute URL, such as http://logger-service
+func CheckURL(value string) error {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("must be an absolute URL, got %q", value)
+	}
+	return nil
+}
+
+// CheckPort checks that value is a TCP port number
+func CheckPort(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("must be a port between 1 and 65535, got %q", value)
+	}
+	return nil
+}
+
+// CheckAddress checks that value is a host:port address, such as user-service:50001
+func CheckAddress(value string) error {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" {
+		return fmt.Errorf("must be a host:port address, got %q", value)
+	}
+	return CheckPort(port)
+}
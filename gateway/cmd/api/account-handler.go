@@ -1,21 +1,16 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
 	"github.com/go-chi/chi/v5"
 )
 
-const (
-	accountServiceURL = "http://account-service"
-	maxBytes          = 10485376
-)
+const maxBytes = 10485376
 
 type AccountRequestPayload struct {
 	Action  string        `json:"action"`
@@ -40,6 +35,10 @@ func (app *Config) HandleAccounts(w http.ResponseWriter, r *http.Request) {
 		app.errorJSON(w, "HandleAccounts", err)
 	}
 
+	if !app.authorizeAction(w, r, "HandleAccounts", accountActionScopes, requestPayload.Action) {
+		return
+	}
+
 	switch requestPayload.Action {
 	case "create":
 		app.createAccountRequest(w, r, requestPayload.Create)
@@ -63,89 +62,54 @@ func (app *Config) deleteAccountRequest(w http.ResponseWriter, r *http.Request)
 	userID := fmt.Sprintf("%v", r.Context().Value("user_id"))
 
 	// Check if the user is authorized to delete the account
-	accountUserID, err := getAccountUserID(id)
+	accountUserID, status, err := app.getAccountUserID(r, id)
 	if err != nil {
-		return app.errorJSON(w, "deleteAccountRequest", err, http.StatusInternalServerError)
+		return app.errorJSON(w, "deleteAccountRequest", err, status)
 	}
 	if accountUserID != userID {
 		return app.errorJSON(w, "deleteAccountRequest", errors.New("you are not authorized to delete this account"), http.StatusForbidden)
 	}
 
-	// Send DELETE request to account service
-	reqURL := fmt.Sprintf("%s/accounts/delete/%s", accountServiceURL, id)
-	req, err := http.NewRequest(http.MethodDelete, reqURL, nil)
-	if err != nil {
-		return app.errorJSON(w, "deleteAccountRequest", err, http.StatusInternalServerError)
-	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return app.errorJSON(w, "deleteAccountRequest", err, resp.StatusCode)
-	}
-	defer resp.Body.Close()
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	// Decode JSON response
-	var respBody struct {
-		Error   bool        `json:"error"`
-		Data    interface{} `json:"data"`
-		Message string      `json:"message"`
-	}
-	err = json.NewDecoder(http.MaxBytesReader(w, resp.Body, maxBytes)).Decode(&respBody)
+	_, err = app.accounts.DeleteAccount(ctx, &pb.DeleteAccountRequest{AccountId: id})
 	if err != nil {
-		return app.errorJSON(w, "deleteAccountRequest", err, resp.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "deleteAccountRequest", err, status)
 	}
 
-	// Write JSON response
-	statusCode := resp.StatusCode
-	if statusCode != http.StatusOK {
-		respBody.Message = "fail"
-	}
-	return app.writeJSON(w, "deleteAccountRequest", statusCode, respBody)
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = "success"
+
+	return app.writeJSON(w, "deleteAccountRequest", http.StatusOK, resp)
 }
 
-// getAccountRequest sends an HTTP request to account-service for fetching an existing account
+// getAccountRequest fetches an existing account of the logged-in user from account-service
 func (app *Config) getAccountRequest(w http.ResponseWriter, r *http.Request) error {
 	id := chi.URLParam(r, "account_id")
 
-	reqURL := fmt.Sprintf("%s/accounts/%s", accountServiceURL, id)
-	request, err := http.NewRequest(http.MethodGet, reqURL, strings.NewReader(""))
-	if err != nil {
-		return app.errorJSON(w, "getAccountRequest", err, 500)
-	}
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "getAccountRequest", err, response.StatusCode)
-
-	}
-	defer response.Body.Close()
-
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	var jsonResponseBody accountResponse
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	account, err := app.accounts.GetAccount(ctx, &pb.GetAccountRequest{AccountId: id})
 	if err != nil {
-		return app.errorJSON(w, "getAccountRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "getAccountRequest", err, status)
 	}
 
 	idInHeader := r.Context().Value("user_id")
-	if jsonResponseBody.UserID != idInHeader {
+	if !isServiceAccount(r) && account.GetUserId() != idInHeader {
 		return app.errorJSON(w, "getAccountRequest", errors.New("this is not yours"), 403)
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
-
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
-	}
+	resp.Message = "success"
+	resp.Data = newAccountResponse(account)
 
-	return app.writeJSON(w, "getAccountRequest", response.StatusCode, resp)
+	return app.writeJSON(w, "getAccountRequest", http.StatusOK, resp)
 }
 
 type UpdatePayload struct {
@@ -153,49 +117,35 @@ type UpdatePayload struct {
 	Balance   int32  `json:"balance" binding:"required"`
 }
 
-// updateAccountRequest sends an HTTP request to account-service for updating an existing account
+// updateAccountRequest updates an existing account of the logged-in user in account-service
 func (app *Config) updateAccountRequest(w http.ResponseWriter, r *http.Request, payload UpdatePayload) error {
-	jsonData, _ := json.Marshal(payload)
-
 	idInHeader := fmt.Sprintf("%v", r.Context().Value("user_id"))
-	userID, err := getAccountUserID(payload.AccountID)
-	if userID != idInHeader {
-		return app.errorJSON(w, "updateAccountRequest", errors.New("this is not yours"), 403)
-	}
-
-	reqURL := fmt.Sprintf("%s/accounts/update", accountServiceURL)
-	request, err := http.NewRequest(http.MethodPut, reqURL, bytes.NewBuffer(jsonData))
+	userID, status, err := app.getAccountUserID(r, payload.AccountID)
 	if err != nil {
-		return app.errorJSON(w, "updateAccountRequest", err, 500)
+		return app.errorJSON(w, "updateAccountRequest", err, status)
 	}
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "updateAccountRequest", err, response.StatusCode)
+	if userID != idInHeader {
+		return app.errorJSON(w, "updateAccountRequest", errors.New("this is not yours"), 403)
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	var jsonResponseBody accountResponse
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	account, err := app.accounts.UpdateAccount(ctx, &pb.UpdateAccountRequest{
+		AccountId: payload.AccountID,
+		Balance:   float64(payload.Balance),
+	})
 	if err != nil {
-		return app.errorJSON(w, "updateAccountRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "updateAccountRequest", err, status)
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
-
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
-	}
+	resp.Message = "success"
+	resp.Data = newAccountResponse(account)
 
-	return app.writeJSON(w, "updateAccountRequest", response.StatusCode, resp)
+	return app.writeJSON(w, "updateAccountRequest", http.StatusOK, resp)
 }
 
 type CreatePayload struct {
@@ -203,84 +153,46 @@ type CreatePayload struct {
 	UserID   any    `json:"user_id"`
 }
 
-// createAccountRequest sends an HTTP request to account-service for creating a new account
+// createAccountRequest opens a new account for the logged-in user in account-service
 func (app *Config) createAccountRequest(w http.ResponseWriter, r *http.Request, payload CreatePayload) error {
-	requestBody := CreatePayload{
-		Currency: payload.Currency,
-		UserID:   r.Context().Value("user_id"),
-	}
-	jsonData, _ := json.Marshal(requestBody)
-
-	reqURL := fmt.Sprintf("%s/accounts/create", accountServiceURL)
-	request, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return app.errorJSON(w, "createAccountRequest", err, 500)
-	}
+	userID, _ := r.Context().Value("user_id").(string)
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "createAccountRequest", err, response.StatusCode)
-	}
-	defer response.Body.Close()
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
-
-	var jsonResponseBody any
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	account, err := app.accounts.CreateAccount(ctx, &pb.CreateAccountRequest{
+		UserId:   userID,
+		Currency: payload.Currency,
+	})
 	if err != nil {
-		return app.errorJSON(w, "createAccountRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "createAccountRequest", err, status)
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
+	resp.Message = "success"
+	resp.Data = newAccountResponse(account)
 
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
-	}
-
-	return app.writeJSON(w, "createAccountRequest", response.StatusCode, resp)
+	return app.writeJSON(w, "createAccountRequest", http.StatusCreated, resp)
 }
 
 func (app *Config) listAccountRequest(w http.ResponseWriter, r *http.Request) error {
-	reqURL := fmt.Sprintf("%s/accounts", accountServiceURL)
-	request, err := http.NewRequest(http.MethodGet, reqURL, nil)
-	if err != nil {
-		return app.errorJSON(w, "listAccountRequest", err, 500)
-	}
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "listAccountRequest", err, response.StatusCode)
-
-	}
-	defer response.Body.Close()
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
-
-	var jsonResponseBody any
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	accounts, err := app.accounts.ListAccounts(ctx, &pb.ListAccountsRequest{})
 	if err != nil {
-		return app.errorJSON(w, "listAccountRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "listAccountRequest", err, status)
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
-
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
-	}
+	resp.Message = "success"
+	resp.Data = newAccountResponses(accounts.GetAccounts())
 
-	return app.writeJSON(w, "listAccountRequest", response.StatusCode, resp)
+	return app.writeJSON(w, "listAccountRequest", http.StatusOK, resp)
 }
 
 type AddBalance struct {
@@ -288,44 +200,105 @@ type AddBalance struct {
 	Amount    float64 `json:"amount"`
 }
 
+// addBalanceRequest adds to the balance of an account of the logged-in user in account-service
 func (app *Config) addBalanceRequest(w http.ResponseWriter, r *http.Request, payload AddBalance) error {
-	requestBody := AddBalance{
-		AccountID: payload.AccountID,
-		Amount:    payload.Amount,
+	idInHeader := fmt.Sprintf("%v", r.Context().Value("user_id"))
+	userID, status, err := app.getAccountUserID(r, payload.AccountID)
+	if err != nil {
+		return app.errorJSON(w, "addBalanceRequest", err, status)
+	}
+	if userID != idInHeader {
+		return app.errorJSON(w, "addBalanceRequest", errors.New("this is not yours"), 403)
 	}
-	jsonData, _ := json.Marshal(requestBody)
 
-	reqURL := fmt.Sprintf("%s/accounts/add-balance", accountServiceURL)
-	request, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(jsonData))
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
+
+	account, err := app.accounts.AddBalance(ctx, &pb.AddBalanceRequest{
+		AccountId: payload.AccountID,
+		Amount:    payload.Amount,
+	})
 	if err != nil {
-		return app.errorJSON(w, "addBalanceRequest", err, 500)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "addBalanceRequest", err, status)
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "addBalanceRequest", err, response.StatusCode)
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = "success"
+	resp.Data = newAccountResponse(account)
+
+	return app.writeJSON(w, "addBalanceRequest", http.StatusCreated, resp)
+}
+
+// ListAccounts returns the accounts of the logged-in user, or the accounts of every user for service accounts
+func (app *Config) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	if isServiceAccount(r) {
+		app.listAccountRequest(w, r)
+		return
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	userID, _ := r.Context().Value("user_id").(string)
 
-	var jsonResponseBody accountResponse
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
+
+	accounts, err := app.accounts.ListUserAccounts(ctx, &pb.ListUserAccountsRequest{UserId: userID})
 	if err != nil {
-		return app.errorJSON(w, "createAccountRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		app.errorJSON(w, "ListAccounts", err, status)
+		return
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
+	resp.Message = "success"
+	resp.Data = newAccountResponses(accounts.GetAccounts())
+
+	app.writeJSON(w, "ListAccounts", http.StatusOK, resp)
+}
+
+// CreateAccount opens an account for the logged-in user
+func (app *Config) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var payload CreatePayload
+	if err := app.readJSON(w, r, &payload); err != nil {
+		app.errorJSON(w, "CreateAccount", err, http.StatusBadRequest)
+		return
+	}
+
+	app.createAccountRequest(w, r, payload)
+}
+
+// GetAccount returns an account of the logged-in user
+func (app *Config) GetAccount(w http.ResponseWriter, r *http.Request) {
+	app.getAccountRequest(w, r)
+}
+
+// UpdateAccount updates the account of the path
+func (app *Config) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	var payload UpdatePayload
+	if err := app.readJSON(w, r, &payload); err != nil {
+		app.errorJSON(w, "UpdateAccount", err, http.StatusBadRequest)
+		return
+	}
+	payload.AccountID = chi.URLParam(r, "account_id")
+
+	app.updateAccountRequest(w, r, payload)
+}
+
+// DeleteAccount deletes an account of the logged-in user
+func (app *Config) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	app.deleteAccountRequest(w, r)
+}
 
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
+// DepositToAccount adds to the balance of the account of the path
+func (app *Config) DepositToAccount(w http.ResponseWriter, r *http.Request) {
+	var payload AddBalance
+	if err := app.readJSON(w, r, &payload); err != nil {
+		app.errorJSON(w, "DepositToAccount", err, http.StatusBadRequest)
+		return
 	}
+	payload.AccountID = chi.URLParam(r, "account_id")
 
-	return app.writeJSON(w, "addBalanceRequest", response.StatusCode, resp)
+	app.addBalanceRequest(w, r, payload)
 }
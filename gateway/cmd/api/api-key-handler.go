@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// authorizationAPIKey is the scheme of the Authorization header that machine clients send their api key with,
+// as in "Authorization: ApiKey dbk_..."
+const authorizationAPIKey = "apikey"
+
+type APIKeyRequestPayload struct {
+	Action string              `json:"action"`
+	Create CreateAPIKeyPayload `json:"create,omitempty"`
+}
+
+type CreateAPIKeyPayload struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays int      `json:"expires_in_days,omitempty"`
+}
+
+type CreateServiceAccountPayload struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// isAPIKey reports whether the request was authenticated with an api key
+func isAPIKey(r *http.Request) bool {
+	keyID, _ := r.Context().Value("api_key_id").(string)
+	return keyID != ""
+}
+
+// isServiceAccount reports whether the request was authenticated with an api key of a service account. Service
+// accounts act for back-office jobs rather than for a user, so they read the accounts and transactions of every user.
+func isServiceAccount(r *http.Request) bool {
+	serviceAccountID, _ := r.Context().Value("service_account_id").(string)
+	return serviceAccountID != ""
+}
+
+// HandleAPIKeys dispatches the api key actions of logged-in users to user-service
+func (app *Config) HandleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	var requestPayload APIKeyRequestPayload
+
+	err := app.readJSON(w, r, &requestPayload)
+	if err != nil {
+		app.errorJSON(w, "HandleAPIKeys", err, http.StatusBadRequest)
+		return
+	}
+
+	switch requestPayload.Action {
+	case "create":
+		app.forwardUserRequest(w, r, "createAPIKeyRequest", http.MethodPost, "/api-keys", requestPayload.Create)
+	case "list":
+		app.forwardUserRequest(w, r, "listAPIKeysRequest", http.MethodGet, "/api-keys", nil)
+	case "revoke":
+		path := fmt.Sprintf("/api-keys/%s", url.PathEscape(chi.URLParam(r, "key_id")))
+		app.forwardUserRequest(w, r, "revokeAPIKeyRequest", http.MethodDelete, path, nil)
+	default:
+		app.errorJSON(w, "HandleAPIKeys", errors.New(fmt.Sprintf("unknown action type: %s", requestPayload.Action)), http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/resilience"
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// connectServices creates the gRPC clients of the backend services. Connections are established lazily and
+// re-established by gRPC, so the services don't have to be up when the gateway starts. Every attempt of a call is
+// traced on its own, so that retries show up in the trace of the request. The readiness of the gateway depends on
+// the gRPC health service of both.
+func (app *Config) connectServices() error {
+	accountConn, err := grpc.Dial(app.config.AccountServiceAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			requestid.UnaryClientInterceptor(),
+			app.upstreams.accounts.UnaryClientInterceptor(idempotentRPC),
+			tracing.UnaryClientInterceptor(),
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot dial account-service: %w", err)
+	}
+	userConn, err := grpc.Dial(app.config.UserServiceAddress,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(
+			requestid.UnaryClientInterceptor(),
+			app.upstreams.users.UnaryClientInterceptor(idempotentRPC),
+			tracing.UnaryClientInterceptor(),
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("cannot dial user-service: %w", err)
+	}
+
+	app.accounts = pb.NewAccountServiceClient(accountConn)
+	app.transactions = pb.NewTransactionServiceClient(accountConn)
+	app.users = pb.NewUserServiceClient(userConn)
+	app.health.Add("account-service", health.GRPC(accountConn))
+	app.health.Add("user-service", health.GRPC(userConn))
+	return nil
+}
+
+// rpcContext derives the context of a gRPC call from the incoming request, so that calls are cancelled together
+// with the request and never outlive the configured RPC_TIMEOUT
+func (app *Config) rpcContext(r *http.Request) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.Context(), app.config.RPCTimeout)
+}
+
+// rpcStatus maps the error of a gRPC call to the HTTP status the gateway responds with, and to an error carrying
+// only the message of the backend service. Calls rejected by a circuit breaker keep their error, so that errorJSON
+// can tell the client when to retry.
+func rpcStatus(err error) (int, error) {
+	var open *resilience.OpenError
+	if errors.As(err, &open) {
+		return http.StatusServiceUnavailable, open
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return http.StatusBadGateway, err
+	}
+
+	message := errors.New(st.Message())
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest, message
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized, message
+	case codes.PermissionDenied:
+		return http.StatusForbidden, message
+	case codes.NotFound:
+		return http.StatusNotFound, message
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict, message
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests, message
+	case codes.Canceled:
+		// the client went away, as in the nginx convention
+		return 499, message
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout, message
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable, message
+	case codes.Unimplemented:
+		return http.StatusNotImplemented, message
+	default:
+		return http.StatusInternalServerError, message
+	}
+}
+
+type transactionResponse struct {
+	TransactionID     string    `json:"transaction_id"`
+	FromAccountID     string    `json:"from_account_id"`
+	ToAccountID       string    `json:"to_account_id"`
+	TransactionAmount float64   `json:"transaction_amount"`
+	Commission        float64   `json:"commission"`
+	Description       *string   `json:"description,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type transferResponse struct {
+	Transaction transactionResponse `json:"transaction"`
+	FromAccount accountResponse     `json:"from_account"`
+	ToAccount   accountResponse     `json:"to_account"`
+}
+
+type userResponse struct {
+	Firstname     string    `json:"firstname"`
+	Lastname      string    `json:"lastname"`
+	UserID        string    `json:"user_id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
+	IsActive      bool      `json:"is_active"`
+	KYCStatus     string    `json:"kyc_status"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+func newAccountResponse(account *pb.Account) accountResponse {
+	return accountResponse{
+		Balance:   account.GetBalance(),
+		Currency:  account.GetCurrency(),
+		AccountID: account.GetAccountId(),
+		UserID:    account.GetUserId(),
+		CreatedAt: account.GetCreatedAt().AsTime(),
+	}
+}
+
+func newAccountResponses(accounts []*pb.Account) []accountResponse {
+	resp := make([]accountResponse, 0, len(accounts))
+	for _, account := range accounts {
+		resp = append(resp, newAccountResponse(account))
+	}
+	return resp
+}
+
+func newTransactionResponse(transaction *pb.Transaction) transactionResponse {
+	return transactionResponse{
+		TransactionID:     transaction.GetTransactionId(),
+		FromAccountID:     transaction.GetFromAccountId(),
+		ToAccountID:       transaction.GetToAccountId(),
+		TransactionAmount: transaction.GetTransactionAmount(),
+		Commission:        transaction.GetCommission(),
+		Description:       transaction.Description,
+		CreatedAt:         transaction.GetCreatedAt().AsTime(),
+	}
+}
+
+func newTransactionResponses(transactions []*pb.Transaction) []transactionResponse {
+	resp := make([]transactionResponse, 0, len(transactions))
+	for _, transaction := range transactions {
+		resp = append(resp, newTransactionResponse(transaction))
+	}
+	return resp
+}
+
+func newUserResponse(user *pb.User) userResponse {
+	return userResponse{
+		Firstname:     user.GetFirstname(),
+		Lastname:      user.GetLastname(),
+		UserID:        user.GetUserId(),
+		Email:         user.GetEmail(),
+		EmailVerified: user.GetEmailVerified(),
+		TwoFactor:     user.GetTwoFactorEnabled(),
+		IsActive:      user.GetIsActive(),
+		KYCStatus:     user.GetKycStatus(),
+		CreatedAt:     user.GetCreatedAt().AsTime(),
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/resilience"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRPCStatus(t *testing.T) {
+	open := &resilience.OpenError{Upstream: "account-service", RetryAfter: time.Second}
+
+	testCases := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{name: "InvalidArgument", err: status.Error(codes.InvalidArgument, "account_id is required"), status: http.StatusBadRequest, message: "account_id is required"},
+		{name: "FailedPrecondition", err: status.Error(codes.FailedPrecondition, "sender doesn't have enough money"), status: http.StatusBadRequest, message: "sender doesn't have enough money"},
+		{name: "OutOfRange", err: status.Error(codes.OutOfRange, "out of range"), status: http.StatusBadRequest, message: "out of range"},
+		{name: "Unauthenticated", err: status.Error(codes.Unauthenticated, "who are you"), status: http.StatusUnauthorized, message: "who are you"},
+		{name: "PermissionDenied", err: status.Error(codes.PermissionDenied, "user is not verified"), status: http.StatusForbidden, message: "user is not verified"},
+		{name: "NotFound", err: status.Error(codes.NotFound, "account not found"), status: http.StatusNotFound, message: "account not found"},
+		{name: "AlreadyExists", err: status.Error(codes.AlreadyExists, "exists"), status: http.StatusConflict, message: "exists"},
+		{name: "Aborted", err: status.Error(codes.Aborted, "aborted"), status: http.StatusConflict, message: "aborted"},
+		{name: "ResourceExhausted", err: status.Error(codes.ResourceExhausted, "slow down"), status: http.StatusTooManyRequests, message: "slow down"},
+		{name: "Canceled", err: status.Error(codes.Canceled, "canceled"), status: 499, message: "canceled"},
+		{name: "DeadlineExceeded", err: status.Error(codes.DeadlineExceeded, "too slow"), status: http.StatusGatewayTimeout, message: "too slow"},
+		{name: "Unavailable", err: status.Error(codes.Unavailable, "down"), status: http.StatusServiceUnavailable, message: "down"},
+		{name: "Unimplemented", err: status.Error(codes.Unimplemented, "unknown method"), status: http.StatusNotImplemented, message: "unknown method"},
+		{name: "Internal", err: status.Error(codes.Internal, "sql: database is closed"), status: http.StatusInternalServerError, message: "sql: database is closed"},
+		{name: "CircuitOpen", err: fmt.Errorf("get account: %w", open), status: http.StatusServiceUnavailable, message: "account-service is unavailable"},
+		{name: "NotStatus", err: errors.New("connection reset"), status: http.StatusBadGateway, message: "connection reset"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := rpcStatus(tc.err)
+			require.Equal(t, tc.status, code)
+			require.EqualError(t, err, tc.message)
+		})
+	}
+
+	// the open breaker is passed on, so that the response tells when to retry
+	_, err := rpcStatus(open)
+	require.ErrorIs(t, err, resilience.ErrOpen)
+}
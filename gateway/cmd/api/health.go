@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// checkRabbitMQ checks the connection events are pushed to, which amqp091 doesn't re-establish once it is closed
+func (app *Config) checkRabbitMQ(ctx context.Context) error {
+	if app.rabbit.IsClosed() {
+		return amqp.ErrClosed
+	}
+	return nil
+}
+
+// services are the backend services whose readiness the status view collects, by the base URL of their HTTP api
+func (app *Config) services() map[string]string {
+	return map[string]string{
+		"account-service": app.config.AccountServiceURL,
+		"user-service":    app.config.UserServiceURL,
+		"report-service":  app.config.ReportServiceURL,
+		"logger-service":  app.config.LoggerServiceURL,
+		"amqp-service":    app.config.AMQPServiceURL,
+	}
+}
+
+// ServeStatus collects the readiness reports of the gateway and of every backend service, with status 200 when all
+// of them are ready and 503 otherwise
+func (app *Config) ServeStatus(w http.ResponseWriter, r *http.Request) {
+	services := app.services()
+	reports := make(map[string]health.Report, len(services)+1)
+	reports["gateway"] = app.health.Run(r.Context())
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for name, url := range services {
+		name, url := name, url
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report := health.Fetch(r.Context(), app.statusClient, url+"/readyz")
+
+			mu.Lock()
+			defer mu.Unlock()
+			reports[name] = report
+		}()
+	}
+	wg.Wait()
+
+	status := http.StatusOK
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = health.StatusOK
+	resp.Data = reports
+	for _, report := range reports {
+		if report.Status != health.StatusOK {
+			status = http.StatusServiceUnavailable
+			resp.Error = true
+			resp.Message = "degraded"
+		}
+	}
+
+	app.writeJSON(w, "serveStatus", status, resp)
+}
@@ -1,14 +1,15 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"fmt"
-	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/event"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"golang.org/x/exp/slog"
 	"io"
-	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 )
 
@@ -36,12 +37,6 @@ func (app *Config) readJSON(w http.ResponseWriter, r *http.Request, data any) er
 }
 
 func (app *Config) writeJSON(w http.ResponseWriter, name string, status int, data any, headers ...http.Header) error {
-	logPayload := Log{
-		StatusCode: status,
-		Message:    data,
-	}
-	defer app.sendRequestLog(name, logPayload)
-
 	out, err := json.Marshal(data)
 	if err != nil {
 		return err
@@ -64,18 +59,22 @@ func (app *Config) writeJSON(w http.ResponseWriter, name string, status int, dat
 }
 
 func (app *Config) errorJSON(w http.ResponseWriter, name string, err error, status ...int) error {
-	logPayload := Log{
-		StatusCode: status[0],
-		Message:    fmt.Sprintf("%v", err),
-	}
-	defer app.sendErrorLog(name, logPayload)
-
 	statusCode := http.StatusBadRequest
 
 	if len(status) > 0 {
 		statusCode = status[0]
 	}
 
+	// errors of the gateway and its upstreams are errors, the ones of the client warnings
+	level := slog.LevelWarn
+	if statusCode >= http.StatusInternalServerError {
+		level = slog.LevelError
+	}
+	app.logger.LogAttrs(context.Background(), level, name,
+		logging.RequestID(w.Header().Get(requestid.Header)), logging.StatusCode(statusCode), logging.Err(err))
+
+	retryAfter(w, err)
+
 	var payload jsonResponse
 	payload.Error = true
 	payload.Message = "fail"
@@ -84,116 +83,54 @@ func (app *Config) errorJSON(w http.ResponseWriter, name string, err error, stat
 	return app.writeJSON(w, "error", statusCode, payload)
 }
 
-type JSONPayload struct {
-	Name string `json:"name"`
-	Data Log    `json:"data"`
-}
-
-type Log struct {
-	Message    any `json:"message"`
-	StatusCode int `json:"status_code"`
-}
-
-func (app *Config) sendErrorLog(name string, payload Log) {
-	arg := JSONPayload{
-		Name: name,
-		Data: payload,
-	}
-	jsonData, err := json.Marshal(arg)
+// clientIP returns the address of the client that sent the request. Forwarding headers sent by the client are
+// ignored, since the gateway is the edge of the system.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
 	if err != nil {
-		log.Println("sendErrorLog error: cant marshal json:", err)
-		return
+		return r.RemoteAddr
 	}
-	request, err := http.NewRequest(http.MethodPost, "http://logger-service/logs/create-error", bytes.NewBuffer(jsonData))
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		log.Println("sendErrorLog error: cant send response:", err)
-		return
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusCreated {
-		log.Println("sendErrorLog error: calling auth service:", err)
-		return
-	}
-
-	log.Println("logged successfully")
-	return
+	return host
 }
 
-func (app *Config) sendRequestLog(name string, payload Log) {
-	arg := JSONPayload{
-		Name: name,
-		Data: payload,
-	}
-	jsonData, err := json.Marshal(arg)
-	if err != nil {
-		log.Println("sendErrorLog error: cant marshal json:", err)
-		return
-	}
-	request, err := http.NewRequest(http.MethodPost, "http://logger-service/logs/create-request", bytes.NewBuffer(jsonData))
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		log.Println("sendErrorLog error: cant send response:", err)
-		return
+// retryAfterHeader copies the Retry-After header of an upstream response, so that it can be passed to writeJSON
+func retryAfterHeader(header http.Header) http.Header {
+	headers := http.Header{}
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		headers.Set("Retry-After", retryAfter)
 	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusCreated {
-		log.Println("sendErrorLog error: calling auth service:", err)
-		return
-	}
-
-	log.Println("logged successfully")
-	return
+	return headers
 }
 
-// getAccountUserID fetches the user ID of the given account
-func getAccountUserID(accountID string) (string, error) {
-	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://account-service/accounts/%s", accountID), nil)
-	if err != nil {
-		return "", err
-	}
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	defer response.Body.Close()
+// getAccountUserID fetches the user ID of the given account. On failure, the status to respond with is returned.
+func (app *Config) getAccountUserID(r *http.Request, accountID string) (string, int, error) {
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	body, err := ioutil.ReadAll(response.Body)
+	account, err := app.accounts.GetAccount(ctx, &pb.GetAccountRequest{AccountId: accountID})
 	if err != nil {
-		return "", err
+		status, err := rpcStatus(err)
+		return "", status, err
 	}
 
-	var account accountResponse
-	err = json.Unmarshal(body, &account)
-	if err != nil {
-		return "", err
-	}
-
-	// Return the user ID
-	return account.UserID, nil
+	return account.GetUserId(), http.StatusOK, nil
 }
 
-func (app *Config) pushToQueue(name string, payload Log) error {
-	emitter, err := event.NewEventEmitter(app.rabbit)
-	if err != nil {
-		return err
-	}
-
-	arg := JSONPayload{
-		Name: name,
-		Data: payload,
-	}
-	jsonData, _ := json.Marshal(&arg)
-
-	err = emitter.Push(string(jsonData), "log.ERROR")
-	if err != nil {
-		return err
+// ownsAnyAccount reports whether any of the given accounts belongs to the logged-in user. Accounts that no longer
+// exist belong to nobody. On failure, the status to respond with is returned.
+func (app *Config) ownsAnyAccount(r *http.Request, accountIDs ...string) (bool, int, error) {
+	idInHeader, _ := r.Context().Value("user_id").(string)
+	for _, accountID := range accountIDs {
+		userID, status, err := app.getAccountUserID(r, accountID)
+		if err != nil {
+			if status == http.StatusNotFound {
+				continue
+			}
+			return false, status, err
+		}
+		if userID == idInHeader {
+			return true, http.StatusOK, nil
+		}
 	}
-
-	return nil
-}
\ No newline at end of file
+	return false, http.StatusOK, nil
+}
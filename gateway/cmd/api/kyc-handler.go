@@ -0,0 +1,67 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// maxKYCUploadBytes bounds the multipart upload of an identity document passed through to user-service
+const maxKYCUploadBytes = 11 << 20
+
+type KYCRequestPayload struct {
+	Action  string                `json:"action"`
+	Reviews ListKYCReviewsPayload `json:"reviews,omitempty"`
+	Reject  RejectKYCPayload      `json:"reject,omitempty"`
+}
+
+type ListKYCReviewsPayload struct {
+	PageID   int32 `json:"page_id"`
+	PageSize int32 `json:"page_size"`
+}
+
+type RejectKYCPayload struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// HandleKYC dispatches the kyc actions of customers and reviewers to user-service
+func (app *Config) HandleKYC(w http.ResponseWriter, r *http.Request) {
+	var requestPayload KYCRequestPayload
+
+	err := app.readJSON(w, r, &requestPayload)
+	if err != nil {
+		app.errorJSON(w, "HandleKYC", err, http.StatusBadRequest)
+		return
+	}
+
+	userID := url.PathEscape(chi.URLParam(r, "user_id"))
+	switch requestPayload.Action {
+	case "status":
+		app.forwardUserRequest(w, r, "kycStatusRequest", http.MethodGet, "/users/kyc", nil)
+	case "list_reviews":
+		path := fmt.Sprintf("/users/kyc/reviews?page_id=%d&page_size=%d", requestPayload.Reviews.PageID, requestPayload.Reviews.PageSize)
+		app.forwardUserRequest(w, r, "listKYCReviewsRequest", http.MethodGet, path, nil)
+	case "get_review":
+		app.forwardUserRequest(w, r, "getKYCReviewRequest", http.MethodGet, fmt.Sprintf("/users/kyc/reviews/%s", userID), nil)
+	case "approve":
+		app.forwardUserRequest(w, r, "approveKYCRequest", http.MethodPost, fmt.Sprintf("/users/kyc/reviews/%s/approve", userID), struct{}{})
+	case "reject":
+		app.forwardUserRequest(w, r, "rejectKYCRequest", http.MethodPost, fmt.Sprintf("/users/kyc/reviews/%s/reject", userID), requestPayload.Reject)
+	default:
+		app.errorJSON(w, "HandleKYC", errors.New(fmt.Sprintf("unknown action type: %s", requestPayload.Action)), http.StatusBadRequest)
+	}
+}
+
+// HandleKYCDocuments passes document uploads and downloads through to user-service as they are, since they are
+// multipart forms and files rather than JSON
+func (app *Config) HandleKYCDocuments(w http.ResponseWriter, r *http.Request) {
+	path := "/users/kyc/documents"
+	if documentID := chi.URLParam(r, "document_id"); documentID != "" {
+		path = fmt.Sprintf("%s/%s", path, url.PathEscape(documentID))
+	}
+
+	app.proxyUserRequest(w, r, "HandleKYCDocuments", path, http.MaxBytesReader(w, r.Body, maxKYCUploadBytes))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/event"
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+)
+
+// Transports of the logs of the gateway
+const (
+	logTransportHTTP = "http"
+	logTransportAMQP = "amqp"
+)
+
+// logSink returns the sink the logs of the gateway are shipped through: straight to logger-service, or to rabbitmq,
+// from which amqp-service passes them on
+func (app *Config) logSink() (logship.Sink, error) {
+	if app.config.LogTransport != logTransportAMQP {
+		return logship.HTTPSink(app.loggerClient, app.config.LoggerServiceURL), nil
+	}
+
+	emitter, err := event.NewEventEmitter(app.rabbit)
+	if err != nil {
+		return nil, err
+	}
+	return queueSink{emitter: emitter}, nil
+}
+
+// queueSink publishes entries to the logs_topic exchange, error logs with the log.ERROR routing key and request logs
+// with log.REQUEST
+type queueSink struct {
+	emitter event.Emitter
+}
+
+func (s queueSink) Ship(ctx context.Context, batch []logship.Entry) (int, error) {
+	for i, entry := range batch {
+		severity := "log.ERROR"
+		if entry.Kind == logship.KindRequest {
+			severity = "log.REQUEST"
+		}
+
+		body, err := json.Marshal(entry)
+		if err != nil {
+			return i, err
+		}
+		err = s.emitter.Push(requestid.NewContext(ctx, entry.RequestID), string(body), severity)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(batch), nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	scopeAccountsRead   = "accounts:read"
+	scopeTransfersWrite = "transfers:write"
+	// accountsWrite can only be granted to api keys
+	scopeAccountsWrite = "accounts:write"
+)
+
+// accountActionScopes and transactionActionScopes are the actions that oauth clients and api keys may perform,
+// together with the scope they need for them. Every other action is reserved to first-party tokens, such as the
+// legacy list actions, which list the accounts and transactions of all users. Delegated callers list their own
+// through the REST routes.
+var (
+	accountActionScopes = map[string]string{
+		"get":     scopeAccountsRead,
+		"create":  scopeAccountsWrite,
+		"update":  scopeAccountsWrite,
+		"delete":  scopeAccountsWrite,
+		"balance": scopeAccountsWrite,
+	}
+	transactionActionScopes = map[string]string{
+		"get":    scopeAccountsRead,
+		"create": scopeTransfersWrite,
+	}
+)
+
+var errFirstPartyOnly = errors.New("this endpoint is not available to oauth clients and api keys")
+
+type OAuthRequestPayload struct {
+	Action    string                     `json:"action"`
+	Client    RegisterOAuthClientPayload `json:"client,omitempty"`
+	Authorize AuthorizePayload           `json:"authorize,omitempty"`
+	Approve   *bool                      `json:"approve,omitempty"`
+}
+
+type RegisterOAuthClientPayload struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required"`
+	Scopes       []string `json:"scopes" binding:"required"`
+	Confidential bool     `json:"confidential"`
+}
+
+type AuthorizePayload struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope"`
+	State               string `json:"state,omitempty"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+type approveAuthorizationPayload struct {
+	AuthorizePayload
+	Approve *bool `json:"approve"`
+}
+
+// isDelegated reports whether the request was authenticated with a token issued to an oauth client
+func isDelegated(r *http.Request) bool {
+	clientID, _ := r.Context().Value("client_id").(string)
+	return clientID != ""
+}
+
+// hasScope reports whether the token of the request was granted the scope
+func hasScope(r *http.Request, scope string) bool {
+	scopes, _ := r.Context().Value("scopes").([]string)
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// isScoped reports whether the request is limited to the scopes of its credentials, which is the case for tokens
+// of oauth clients and for api keys
+func isScoped(r *http.Request) bool {
+	return isDelegated(r) || isAPIKey(r)
+}
+
+// firstPartyOnly rejects requests made with tokens of oauth clients or with api keys
+func (app *Config) firstPartyOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isScoped(r) {
+			app.errorJSON(w, "firstPartyOnly", errFirstPartyOnly, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authorizeAction checks that a delegated token or api key has the scope an action requires. First-party tokens may
+// perform every action.
+func (app *Config) authorizeAction(w http.ResponseWriter, r *http.Request, name string, actionScopes map[string]string, action string) bool {
+	if !isScoped(r) {
+		return true
+	}
+
+	scope, ok := actionScopes[action]
+	if !ok {
+		app.errorJSON(w, name, fmt.Errorf("action %s is not available to oauth clients and api keys", action), http.StatusForbidden)
+		return false
+	}
+	if !hasScope(r, scope) {
+		app.errorJSON(w, name, fmt.Errorf("action %s requires the %s scope", action, scope), http.StatusForbidden)
+		return false
+	}
+
+	return true
+}
+
+// requireScope limits a REST route to first-party tokens and to oauth clients and api keys that hold the scope
+func (app *Config) requireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isScoped(r) && !hasScope(r, scope) {
+				app.errorJSON(w, "requireScope", fmt.Errorf("this endpoint requires the %s scope", scope), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HandleOAuth dispatches the oauth actions of logged-in users to user-service: registering clients, answering
+// authorization requests and managing consents
+func (app *Config) HandleOAuth(w http.ResponseWriter, r *http.Request) {
+	var requestPayload OAuthRequestPayload
+
+	err := app.readJSON(w, r, &requestPayload)
+	if err != nil {
+		app.errorJSON(w, "HandleOAuth", err, http.StatusBadRequest)
+		return
+	}
+
+	switch requestPayload.Action {
+	case "register_client":
+		app.forwardUserRequest(w, r, "registerOAuthClientRequest", http.MethodPost, "/oauth/clients", requestPayload.Client)
+	case "list_clients":
+		app.forwardUserRequest(w, r, "listOAuthClientsRequest", http.MethodGet, "/oauth/clients", nil)
+	case "get_authorization":
+		path := fmt.Sprintf("/oauth/authorize?%s", authorizeQuery(requestPayload.Authorize).Encode())
+		app.forwardUserRequest(w, r, "getAuthorizationRequest", http.MethodGet, path, nil)
+	case "authorize":
+		payload := approveAuthorizationPayload{AuthorizePayload: requestPayload.Authorize, Approve: requestPayload.Approve}
+		app.forwardUserRequest(w, r, "authorizeClientRequest", http.MethodPost, "/oauth/authorize", payload)
+	case "list_consents":
+		app.forwardUserRequest(w, r, "listOAuthConsentsRequest", http.MethodGet, "/oauth/consents", nil)
+	case "revoke_consent":
+		path := fmt.Sprintf("/oauth/consents/%s", url.PathEscape(chi.URLParam(r, "client_id")))
+		app.forwardUserRequest(w, r, "revokeOAuthConsentRequest", http.MethodDelete, path, nil)
+	default:
+		app.errorJSON(w, "HandleOAuth", errors.New(fmt.Sprintf("unknown action type: %s", requestPayload.Action)), http.StatusBadRequest)
+	}
+}
+
+func authorizeQuery(payload AuthorizePayload) url.Values {
+	query := url.Values{}
+	query.Set("response_type", payload.ResponseType)
+	query.Set("client_id", payload.ClientID)
+	query.Set("redirect_uri", payload.RedirectURI)
+	query.Set("scope", payload.Scope)
+	query.Set("code_challenge", payload.CodeChallenge)
+	query.Set("code_challenge_method", payload.CodeChallengeMethod)
+	if payload.State != "" {
+		query.Set("state", payload.State)
+	}
+	return query
+}
+
+// HandleOAuthToken passes token requests of oauth clients through to user-service as they are, since the token
+// endpoint speaks form-encoded OAuth rather than the JSON actions of the gateway
+func (app *Config) HandleOAuthToken(w http.ResponseWriter, r *http.Request) {
+	app.proxyUserRequest(w, r, "HandleOAuthToken", "/oauth/token", http.MaxBytesReader(w, r.Body, maxBytes))
+}
+
+// proxyUserRequest streams a request to a user-service path and its response back, without wrapping either in JSON
+func (app *Config) proxyUserRequest(w http.ResponseWriter, r *http.Request, name, path string, body io.Reader) {
+	header := http.Header{}
+	for _, key := range []string{"Authorization", "Content-Type"} {
+		if value := r.Header.Get(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+	header.Set("X-Forwarded-For", clientIP(r))
+
+	response, err := app.userService.Send(r.Context(), r.Method, path, header, body)
+	if err != nil {
+		app.errorJSON(w, name, err, client.StatusCode(err))
+		return
+	}
+	defer response.Body.Close()
+
+	for _, key := range []string{"Content-Type", "Content-Length", "Content-Disposition", "Cache-Control", "Pragma", "Retry-After"} {
+		if value := response.Header.Get(key); value != "" {
+			w.Header().Set(key, value)
+		}
+	}
+	w.WriteHeader(response.StatusCode)
+	_, _ = io.Copy(w, response.Body)
+}
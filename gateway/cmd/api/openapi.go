@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/mail"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// openAPIDocument is the contract of the gateway, served at /openapi.json and used to validate incoming requests
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// openAPISpec holds the parts of the OpenAPI document needed for request validation
+type openAPISpec struct {
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type openAPIOperation struct {
+	Parameters  []openAPIParameter  `json:"parameters"`
+	RequestBody *openAPIRequestBody `json:"requestBody"`
+}
+
+type openAPIParameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *schema `json:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool `json:"required"`
+	Content  map[string]struct {
+		Schema *schema `json:"schema"`
+	} `json:"content"`
+}
+
+// schema is the subset of the OpenAPI schema object the gateway validates against
+type schema struct {
+	Ref              string             `json:"$ref"`
+	Type             string             `json:"type"`
+	Format           string             `json:"format"`
+	Properties       map[string]*schema `json:"properties"`
+	Required         []string           `json:"required"`
+	Items            *schema            `json:"items"`
+	Enum             []any              `json:"enum"`
+	MinLength        *int               `json:"minLength"`
+	MaxLength        *int               `json:"maxLength"`
+	MinItems         *int               `json:"minItems"`
+	Minimum          *float64           `json:"minimum"`
+	Maximum          *float64           `json:"maximum"`
+	ExclusiveMinimum bool               `json:"exclusiveMinimum"`
+	Nullable         bool               `json:"nullable"`
+}
+
+// validationError describes one way in which a request doesn't match the specification
+type validationError struct {
+	In      string `json:"in"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// loadOpenAPISpec parses the embedded OpenAPI document
+func loadOpenAPISpec() (*openAPISpec, error) {
+	var spec openAPISpec
+	err := json.Unmarshal(openAPIDocument, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse openapi.json: %v", err)
+	}
+	return &spec, nil
+}
+
+// ServeOpenAPI returns the OpenAPI document of the gateway
+func (app *Config) ServeOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocument)
+}
+
+// validateRequest rejects requests whose parameters or JSON body don't match the operation of the specification with
+// a 400 listing every problem found. Requests for paths the specification doesn't know are passed on unchanged. It
+// buffers bodies of up to maxBytes, so on the routes that need a logged-in client it goes after the middlewares that
+// authenticate and authorize the client.
+func (app *Config) validateRequest(spec *openAPISpec) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			operation, pathParams := spec.findOperation(r.Method, r.URL.Path)
+			if operation == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			errs := spec.validateParameters(operation, r, pathParams)
+
+			if operation.RequestBody != nil {
+				bodyErrs, err := spec.validateBody(operation.RequestBody, w, r)
+				if err != nil {
+					app.errorJSON(w, "validateRequest", err, http.StatusBadRequest)
+					return
+				}
+				errs = append(errs, bodyErrs...)
+			}
+
+			if len(errs) > 0 {
+				var payload jsonResponse
+				payload.Error = true
+				payload.Message = "request does not match the api specification"
+				payload.Data = errs
+
+				app.writeJSON(w, "validateRequest", http.StatusBadRequest, payload)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// findOperation looks up the operation for a request. Static path segments take precedence over parameters, so
+// that /users/me is preferred to /users/{user_id}.
+func (spec *openAPISpec) findOperation(method, path string) (*openAPIOperation, map[string]string) {
+	var found *openAPIOperation
+	var foundParams map[string]string
+	bestStatic := -1
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for template, operations := range spec.Paths {
+		operation, ok := operations[strings.ToLower(method)]
+		if !ok {
+			continue
+		}
+
+		templateSegments := strings.Split(strings.Trim(template, "/"), "/")
+		if len(templateSegments) != len(segments) {
+			continue
+		}
+
+		params := map[string]string{}
+		static := 0
+		matched := true
+		for i, segment := range templateSegments {
+			if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+				params[segment[1:len(segment)-1]] = segments[i]
+				continue
+			}
+			if segment != segments[i] {
+				matched = false
+				break
+			}
+			static++
+		}
+
+		if matched && static > bestStatic {
+			found, foundParams, bestStatic = operation, params, static
+		}
+	}
+
+	return found, foundParams
+}
+
+// validateParameters checks the path and query parameters of a request
+func (spec *openAPISpec) validateParameters(operation *openAPIOperation, r *http.Request, pathParams map[string]string) []validationError {
+	var errs []validationError
+	query := r.URL.Query()
+
+	for _, param := range operation.Parameters {
+		var value string
+		var present bool
+		switch param.In {
+		case "path":
+			value, present = pathParams[param.Name]
+			present = present && value != ""
+		case "query":
+			present = query.Has(param.Name)
+			value = query.Get(param.Name)
+		default:
+			continue
+		}
+
+		if !present {
+			if param.Required {
+				errs = append(errs, validationError{In: param.In, Field: param.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		typed, err := parseParameter(value, spec.resolve(param.Schema))
+		if err != nil {
+			errs = append(errs, validationError{In: param.In, Field: param.Name, Message: err.Error()})
+			continue
+		}
+		errs = append(errs, spec.validateValue(param.In, param.Name, typed, param.Schema)...)
+	}
+
+	return errs
+}
+
+// parseParameter converts a path or query parameter to the type of its schema
+func parseParameter(value string, s *schema) (any, error) {
+	if s == nil {
+		return value, nil
+	}
+
+	switch s.Type {
+	case "integer", "number":
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("must be %s", article(s.Type))
+		}
+		return number, nil
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be a boolean")
+		}
+		return b, nil
+	default:
+		return value, nil
+	}
+}
+
+// validateBody checks a JSON request body and puts it back, so that the handlers can read it. Bodies of other
+// content types, such as uploads and oauth forms, are only checked for being present.
+func (spec *openAPISpec) validateBody(requestBody *openAPIRequestBody, w http.ResponseWriter, r *http.Request) ([]validationError, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if requestBody.Required {
+			return []validationError{{In: "body", Field: "", Message: "is required"}}, nil
+		}
+		return nil, nil
+	}
+
+	content, ok := requestBody.Content["application/json"]
+	if !ok {
+		return nil, nil
+	}
+	// a JSON body sent with another declared content type is left to the handler
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil && mediaType != "application/json" {
+		if _, declared := requestBody.Content[mediaType]; declared {
+			return nil, nil
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value any
+	if err = decoder.Decode(&value); err != nil {
+		return []validationError{{In: "body", Field: "", Message: "must be valid JSON"}}, nil
+	}
+
+	return spec.validateValue("body", "", value, content.Schema), nil
+}
+
+// resolve follows a reference to the components of the specification
+func (spec *openAPISpec) resolve(s *schema) *schema {
+	for s != nil && s.Ref != "" {
+		s = spec.Components.Schemas[strings.TrimPrefix(s.Ref, "#/components/schemas/")]
+	}
+	return s
+}
+
+// validateValue checks a decoded JSON value against a schema. field is the dotted path of the value, used in the
+// reported errors.
+func (spec *openAPISpec) validateValue(in, field string, value any, s *schema) []validationError {
+	s = spec.resolve(s)
+	if s == nil {
+		return nil
+	}
+
+	fail := func(format string, args ...any) []validationError {
+		return []validationError{{In: in, Field: field, Message: fmt.Sprintf(format, args...)}}
+	}
+
+	if value == nil {
+		if s.Nullable || s.Type == "" {
+			return nil
+		}
+		return fail("must not be null")
+	}
+
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		return fail("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fail("must be an object")
+		}
+
+		var errs []validationError
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				errs = append(errs, validationError{In: in, Field: joinField(field, name), Message: "is required"})
+			}
+		}
+
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if propertyValue, ok := object[name]; ok {
+				errs = append(errs, spec.validateValue(in, joinField(field, name), propertyValue, s.Properties[name])...)
+			}
+		}
+		return errs
+
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fail("must be an array")
+		}
+		if s.MinItems != nil && len(array) < *s.MinItems {
+			return fail("must have at least %d items", *s.MinItems)
+		}
+
+		var errs []validationError
+		for i, item := range array {
+			errs = append(errs, spec.validateValue(in, fmt.Sprintf("%s[%d]", field, i), item, s.Items)...)
+		}
+		return errs
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fail("must be a string")
+		}
+		if s.MinLength != nil && len(str) < *s.MinLength {
+			return fail("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && len(str) > *s.MaxLength {
+			return fail("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.Format == "email" {
+			if _, err := mail.ParseAddress(str); err != nil {
+				return fail("must be an email address")
+			}
+		}
+		return nil
+
+	case "integer", "number":
+		number, ok := toFloat(value)
+		if !ok {
+			return fail("must be %s", article(s.Type))
+		}
+		if s.Type == "integer" && number != float64(int64(number)) {
+			return fail("must be an integer")
+		}
+		if s.Minimum != nil {
+			if s.ExclusiveMinimum && number <= *s.Minimum {
+				return fail("must be greater than %v", *s.Minimum)
+			}
+			if number < *s.Minimum {
+				return fail("must be at least %v", *s.Minimum)
+			}
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			return fail("must be at most %v", *s.Maximum)
+		}
+		return nil
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fail("must be a boolean")
+		}
+		return nil
+	}
+
+	return nil
+}
+
+// article prefixes a schema type with its indefinite article for error messages
+func article(schemaType string) string {
+	if schemaType == "integer" || schemaType == "object" || schemaType == "array" {
+		return "an " + schemaType
+	}
+	return "a " + schemaType
+}
+
+func joinField(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+// toFloat converts JSON numbers, which are decoded as json.Number, and parsed parameters to float64
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
+
+func inEnum(value any, enum []any) bool {
+	for _, allowed := range enum {
+		if fmt.Sprint(allowed) == fmt.Sprint(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []any) string {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+	return strings.Join(values, ", ")
+}
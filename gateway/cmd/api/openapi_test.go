@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestFindOperation(t *testing.T) {
+	me := &openAPIOperation{}
+	user := &openAPIOperation{}
+	account := &openAPIOperation{}
+	spec := &openAPISpec{Paths: map[string]map[string]*openAPIOperation{
+		"/users/me":               {"get": me},
+		"/users/{user_id}":        {"get": user, "delete": user},
+		"/accounts/{account_id}/": {"get": account},
+	}}
+
+	testCases := []struct {
+		name      string
+		method    string
+		path      string
+		operation *openAPIOperation
+		params    map[string]string
+	}{
+		{name: "StaticWins", method: http.MethodGet, path: "/users/me", operation: me, params: map[string]string{}},
+		{name: "Parameter", method: http.MethodGet, path: "/users/42", operation: user, params: map[string]string{"user_id": "42"}},
+		{name: "ParameterForOtherMethod", method: http.MethodDelete, path: "/users/me", operation: user, params: map[string]string{"user_id": "me"}},
+		{name: "TrailingSlash", method: http.MethodGet, path: "/accounts/7", operation: account, params: map[string]string{"account_id": "7"}},
+		{name: "UnknownMethod", method: http.MethodPost, path: "/users/42"},
+		{name: "UnknownPath", method: http.MethodGet, path: "/users/42/accounts"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			operation, params := spec.findOperation(tc.method, tc.path)
+			require.True(t, tc.operation == operation)
+			if tc.operation != nil {
+				require.Equal(t, tc.params, params)
+			}
+		})
+	}
+}
+
+func TestFindOperationEmbeddedSpec(t *testing.T) {
+	spec, err := loadOpenAPISpec()
+	require.NoError(t, err)
+
+	// /handle/users/kyc is a static path next to /handle/users/{user_id}
+	operation, _ := spec.findOperation(http.MethodGet, "/handle/users/kyc")
+	require.True(t, spec.Paths["/handle/users/kyc"]["get"] == operation)
+
+	operation, _ = spec.findOperation(http.MethodPatch, "/api/v1/users/me")
+	require.True(t, spec.Paths["/api/v1/users/me"]["patch"] == operation)
+
+	operation, params := spec.findOperation(http.MethodGet, "/api/v1/users/me")
+	require.True(t, spec.Paths["/api/v1/users/{user_id}"]["get"] == operation)
+	require.Equal(t, map[string]string{"user_id": "me"}, params)
+}
+
+func TestValidateValue(t *testing.T) {
+	spec := &openAPISpec{}
+	spec.Components.Schemas = map[string]*schema{
+		"Transfer": {
+			Type:     "object",
+			Required: []string{"from_account_id", "amount"},
+			Properties: map[string]*schema{
+				"from_account_id": {Type: "string", MinLength: intPtr(1), MaxLength: intPtr(8)},
+				"amount":          {Type: "number", Minimum: floatPtr(0), ExclusiveMinimum: true, Maximum: floatPtr(1000)},
+				"currency":        {Type: "string", Enum: []any{"EUR", "USD"}},
+				"count":           {Type: "integer", Minimum: floatPtr(1)},
+				"description":     {Type: "string", Nullable: true},
+				"email":           {Type: "string", Format: "email"},
+				"tags":            {Type: "array", MinItems: intPtr(1), Items: &schema{Type: "string"}},
+				"instant":         {Type: "boolean"},
+			},
+		},
+	}
+	transfer := &schema{Ref: "#/components/schemas/Transfer"}
+
+	testCases := []struct {
+		name string
+		body string
+		errs []validationError
+	}{
+		{name: "Valid", body: `{"from_account_id":"1","amount":10,"currency":"EUR","count":2,"description":null,"email":"a@b.c","tags":["x"],"instant":true}`},
+		{name: "Required", body: `{}`, errs: []validationError{
+			{In: "body", Field: "from_account_id", Message: "is required"},
+			{In: "body", Field: "amount", Message: "is required"},
+		}},
+		{name: "NotObject", body: `[]`, errs: []validationError{{In: "body", Message: "must be an object"}}},
+		{name: "Enum", body: `{"from_account_id":"1","amount":1,"currency":"GBP"}`, errs: []validationError{
+			{In: "body", Field: "currency", Message: "must be one of EUR, USD"},
+		}},
+		{name: "ExclusiveMinimum", body: `{"from_account_id":"1","amount":0}`, errs: []validationError{
+			{In: "body", Field: "amount", Message: "must be greater than 0"},
+		}},
+		{name: "Minimum", body: `{"from_account_id":"1","amount":1,"count":0}`, errs: []validationError{
+			{In: "body", Field: "count", Message: "must be at least 1"},
+		}},
+		{name: "Maximum", body: `{"from_account_id":"1","amount":1000.5}`, errs: []validationError{
+			{In: "body", Field: "amount", Message: "must be at most 1000"},
+		}},
+		{name: "Integer", body: `{"from_account_id":"1","amount":1,"count":1.5}`, errs: []validationError{
+			{In: "body", Field: "count", Message: "must be an integer"},
+		}},
+		{name: "NumberType", body: `{"from_account_id":"1","amount":"1"}`, errs: []validationError{
+			{In: "body", Field: "amount", Message: "must be a number"},
+		}},
+		{name: "MinLength", body: `{"from_account_id":"","amount":1}`, errs: []validationError{
+			{In: "body", Field: "from_account_id", Message: "must be at least 1 characters long"},
+		}},
+		{name: "MaxLength", body: `{"from_account_id":"123456789","amount":1}`, errs: []validationError{
+			{In: "body", Field: "from_account_id", Message: "must be at most 8 characters long"},
+		}},
+		{name: "NotNullable", body: `{"from_account_id":null,"amount":1}`, errs: []validationError{
+			{In: "body", Field: "from_account_id", Message: "must not be null"},
+		}},
+		{name: "Email", body: `{"from_account_id":"1","amount":1,"email":"nope"}`, errs: []validationError{
+			{In: "body", Field: "email", Message: "must be an email address"},
+		}},
+		{name: "MinItems", body: `{"from_account_id":"1","amount":1,"tags":[]}`, errs: []validationError{
+			{In: "body", Field: "tags", Message: "must have at least 1 items"},
+		}},
+		{name: "Items", body: `{"from_account_id":"1","amount":1,"tags":["x",2]}`, errs: []validationError{
+			{In: "body", Field: "tags[1]", Message: "must be a string"},
+		}},
+		{name: "Boolean", body: `{"from_account_id":"1","amount":1,"instant":"yes"}`, errs: []validationError{
+			{In: "body", Field: "instant", Message: "must be a boolean"},
+		}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			decoder := json.NewDecoder(strings.NewReader(tc.body))
+			decoder.UseNumber()
+			var value any
+			require.NoError(t, decoder.Decode(&value))
+
+			require.Equal(t, tc.errs, spec.validateValue("body", "", value, transfer))
+		})
+	}
+}
+
+func TestValidateParameters(t *testing.T) {
+	spec := &openAPISpec{}
+	operation := &openAPIOperation{Parameters: []openAPIParameter{
+		{Name: "account_id", In: "path", Required: true, Schema: &schema{Type: "integer", Minimum: floatPtr(1)}},
+		{Name: "limit", In: "query", Schema: &schema{Type: "integer", Maximum: floatPtr(100)}},
+		{Name: "status", In: "query", Required: true, Schema: &schema{Type: "string", Enum: []any{"pending", "approved"}}},
+	}}
+
+	testCases := []struct {
+		name   string
+		query  string
+		params map[string]string
+		errs   []validationError
+	}{
+		{name: "Valid", query: "limit=10&status=pending", params: map[string]string{"account_id": "7"}},
+		{name: "Required", params: map[string]string{"account_id": ""}, errs: []validationError{
+			{In: "path", Field: "account_id", Message: "is required"},
+			{In: "query", Field: "status", Message: "is required"},
+		}},
+		{name: "Types", query: "limit=ten&status=pending", params: map[string]string{"account_id": "0"}, errs: []validationError{
+			{In: "path", Field: "account_id", Message: "must be at least 1"},
+			{In: "query", Field: "limit", Message: "must be an integer"},
+		}},
+		{name: "Enum", query: "limit=101&status=done", params: map[string]string{"account_id": "7"}, errs: []validationError{
+			{In: "query", Field: "limit", Message: "must be at most 100"},
+			{In: "query", Field: "status", Message: "must be one of pending, approved"},
+		}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/accounts?"+tc.query, nil)
+			require.Equal(t, tc.errs, spec.validateParameters(operation, r, tc.params))
+		})
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	spec := &openAPISpec{Paths: map[string]map[string]*openAPIOperation{
+		"/accounts": {"post": {RequestBody: &openAPIRequestBody{
+			Required: true,
+			Content: map[string]struct {
+				Schema *schema `json:"schema"`
+			}{
+				"application/json":    {Schema: &schema{Type: "object", Required: []string{"currency"}}},
+				"multipart/form-data": {},
+			},
+		}}},
+	}}
+
+	testCases := []struct {
+		name        string
+		path        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{name: "Valid", path: "/accounts", contentType: "application/json", body: `{"currency":"EUR"}`, status: http.StatusOK},
+		{name: "Invalid", path: "/accounts", contentType: "application/json", body: `{}`, status: http.StatusBadRequest},
+		{name: "NotJSON", path: "/accounts", contentType: "application/json", body: `{`, status: http.StatusBadRequest},
+		{name: "Missing", path: "/accounts", contentType: "application/json", status: http.StatusBadRequest},
+		{name: "OtherContentType", path: "/accounts", contentType: "multipart/form-data; boundary=x", body: "--x--", status: http.StatusOK},
+		{name: "UnknownPath", path: "/unknown", contentType: "application/json", body: `{}`, status: http.StatusOK},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app := &Config{}
+			var received string
+			handler := app.validateRequest(spec)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the handler reads the body the validation read before it
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				received = string(body)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			require.Equal(t, tc.status, w.Code)
+			if tc.status == http.StatusOK {
+				require.Equal(t, tc.body, received)
+			}
+		})
+	}
+}
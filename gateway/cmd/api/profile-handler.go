@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+)
+
+type UpdateProfilePayload struct {
+	Firstname *string `json:"firstname,omitempty"`
+	Lastname  *string `json:"lastname,omitempty"`
+}
+
+type UpdateEmailPayload struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmPasswordPayload struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// userExport is the data export of a user, collected from all services that store data about them
+type userExport struct {
+	User         *client.UserExport    `json:"user"`
+	Accounts     []accountResponse     `json:"accounts"`
+	Transactions []transactionResponse `json:"transactions"`
+}
+
+// exportUserRequest collects the profile of the logged-in user from user-service together with their accounts and
+// transactions from account-service
+func (app *Config) exportUserRequest(w http.ResponseWriter, r *http.Request) error {
+	userID, _ := r.Context().Value("user_id").(string)
+
+	var export userExport
+	user, err := app.userService.ExportUser(r.Context(), r.Header.Get("Authorization"))
+	if err != nil {
+		return app.errorJSON(w, "exportUserRequest", err, client.StatusCode(err))
+	}
+	export.User = user
+
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
+
+	accounts, err := app.accounts.ListUserAccounts(ctx, &pb.ListUserAccountsRequest{UserId: userID})
+	if err != nil {
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "exportUserRequest", err, status)
+	}
+	export.Accounts = newAccountResponses(accounts.GetAccounts())
+
+	transactions, err := app.transactions.ListUserTransactions(ctx, &pb.ListUserTransactionsRequest{UserId: userID})
+	if err != nil {
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "exportUserRequest", err, status)
+	}
+	export.Transactions = newTransactionResponses(transactions.GetTransactions())
+
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = "success"
+	resp.Data = export
+
+	return app.writeJSON(w, "exportUserRequest", http.StatusOK, resp)
+}
+
+// eraseUserRequest removes the personal data of the logged-in user. Users who still hold money can't be erased,
+// their accounts have to be emptied first.
+func (app *Config) eraseUserRequest(w http.ResponseWriter, r *http.Request, payload ConfirmPasswordPayload) error {
+	userID, _ := r.Context().Value("user_id").(string)
+
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
+
+	accounts, err := app.accounts.ListUserAccounts(ctx, &pb.ListUserAccountsRequest{UserId: userID})
+	if err != nil {
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "eraseUserRequest", err, status)
+	}
+
+	for _, account := range accounts.GetAccounts() {
+		if account.GetBalance() != 0 {
+			err = fmt.Errorf("account %s still has a balance of %.2f %s", account.GetAccountId(), account.GetBalance(), account.GetCurrency())
+			return app.errorJSON(w, "eraseUserRequest", err, http.StatusConflict)
+		}
+	}
+
+	return app.forwardUserRequest(w, r, "eraseUserRequest", http.MethodPost, "/users/erase", payload)
+}
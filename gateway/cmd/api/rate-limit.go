@@ -0,0 +1,126 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/ratelimit"
+	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/resilience"
+)
+
+// rateLimits are the limits of the gateway. Every client ip has a bucket, and authenticated requests also take a
+// token from the bucket of their user or api key. Logins and transfers have stricter buckets of their own.
+type rateLimits struct {
+	ip       ratelimit.Limit
+	user     ratelimit.Limit
+	apiKey   ratelimit.Limit
+	login    ratelimit.Limit
+	transfer ratelimit.Limit
+}
+
+func newRateLimits(config EnvConfig) (rateLimits, error) {
+	var limits rateLimits
+	for _, setting := range []struct {
+		name  string
+		value string
+		limit *ratelimit.Limit
+	}{
+		{"RATE_LIMIT_IP", config.RateLimitIP, &limits.ip},
+		{"RATE_LIMIT_USER", config.RateLimitUser, &limits.user},
+		{"RATE_LIMIT_API_KEY", config.RateLimitAPIKey, &limits.apiKey},
+		{"RATE_LIMIT_LOGIN", config.RateLimitLogin, &limits.login},
+		{"RATE_LIMIT_TRANSFER", config.RateLimitTransfer, &limits.transfer},
+	} {
+		limit, err := ratelimit.ParseLimit(setting.value)
+		if err != nil {
+			return rateLimits{}, fmt.Errorf("%s %w", setting.name, err)
+		}
+		*setting.limit = limit
+	}
+	return limits, nil
+}
+
+// limitByIP limits the requests of every client ip
+func (app *Config) limitByIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.takeToken(w, r, "ip", app.rateLimits.ip, clientIP(r)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitByClient limits the requests of the user or the api key that authenticate found
+func (app *Config) limitByClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var allowed bool
+		if isAPIKey(r) {
+			allowed = app.takeToken(w, r, "api-key", app.rateLimits.apiKey, fmt.Sprintf("%v", r.Context().Value("api_key_id")))
+		} else {
+			allowed = app.takeToken(w, r, "user", app.rateLimits.user, fmt.Sprintf("%v", r.Context().Value("user_id")))
+		}
+		if !allowed {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitLogin limits the login attempts of every client ip, which slows down guessing passwords across accounts
+func (app *Config) limitLogin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !app.takeToken(w, r, "login", app.rateLimits.login, clientIP(r)) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// limitTransfer limits the transfers of the user or api key of the request
+func (app *Config) limitTransfer(w http.ResponseWriter, r *http.Request) bool {
+	if isAPIKey(r) {
+		return app.takeToken(w, r, "transfer", app.rateLimits.transfer, fmt.Sprintf("api-key:%v", r.Context().Value("api_key_id")))
+	}
+	return app.takeToken(w, r, "transfer", app.rateLimits.transfer, fmt.Sprintf("user:%v", r.Context().Value("user_id")))
+}
+
+// takeToken takes a token from the bucket of key in the named limit and sets the RateLimit headers. When the bucket
+// is empty, it responds with 429 and returns false. A failing store lets requests through, as the limits protect
+// the services but must not take the gateway down with them.
+func (app *Config) takeToken(w http.ResponseWriter, r *http.Request, name string, limit ratelimit.Limit, key string) bool {
+	if !limit.Enabled() {
+		return true
+	}
+
+	result, err := app.limiter.Take(r.Context(), name+":"+key, limit)
+	if err != nil {
+		log.Printf("rate limit %s: %v", name, err)
+		return true
+	}
+
+	setRateLimitHeaders(w, limit, result)
+	if !result.Allowed {
+		w.Header().Set("Retry-After", resilience.RetryAfterSeconds(result.RetryAfter))
+		app.errorJSON(w, "rateLimit", errors.New("rate limit exceeded, retry later"), http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// setRateLimitHeaders describes the bucket of a request in the RateLimit headers. When several limits apply, the one
+// with the fewest remaining requests is described.
+func setRateLimitHeaders(w http.ResponseWriter, limit ratelimit.Limit, result ratelimit.Result) {
+	if current := w.Header().Get("RateLimit-Remaining"); current != "" {
+		if remaining, err := strconv.Atoi(current); err == nil && remaining <= result.Remaining {
+			return
+		}
+	}
+
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", resilience.RetryAfterSeconds(result.Reset))
+	w.Header().Set("RateLimit-Policy", limit.Policy())
+}
@@ -2,32 +2,207 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/exp/slog"
 )
 
 // routes is responsible for creating new routes for the API and specifying who is allowed to connect with specific
 // origins, methods and headers.
 func (app *Config) routes() http.Handler {
 	mux := chi.NewRouter()
+	mux.Use(traceRoute, metrics.Middleware(routePattern), requestid.Handler, app.logRequests, app.limitByIP)
 
-	mux.Group(func(r chi.Router) {
-		r.Use(authenticate)
-		r.Mount("/handle", app.handleRouter())
+	spec, err := loadOpenAPISpec()
+	if err != nil {
+		log.Panic(err)
+	}
+	// Requests are validated once they were authenticated, so that anonymous clients can't make the gateway read
+	// bodies meant for the authenticated routes
+	validate := app.validateRequest(spec)
+
+	mux.Get("/openapi.json", app.ServeOpenAPI)
+	mux.Get("/upstreams", app.ServeUpstreams)
+	mux.Method(http.MethodGet, "/metrics", metrics.Handler())
+	mux.Method(http.MethodGet, "/healthz", health.LiveHandler())
+	mux.Method(http.MethodGet, "/readyz", app.health.ReadyHandler())
+	mux.Get("/status", app.ServeStatus)
+	mux.Mount("/api/v1", app.apiRouter(validate))
+
+	// The /handle routes pick the operation from the action field of the body. They are kept for clients that
+	// haven't moved to /api/v1 yet, and can be turned off with LEGACY_ACTION_ROUTES=false.
+	if app.config.LegacyActionRoutes {
+		mux.Group(func(r chi.Router) {
+			r.Use(deprecated)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.authenticate, app.limitByClient, validate)
+				r.Mount("/handle", app.handleRouter())
+			})
+
+			r.With(app.limitLogin, validate).Post("/handle/users/login", app.HandleUsers)
+			r.With(validate).Post("/handle/users", app.HandleUsers)
+			r.With(validate).Post("/handle/users/verify-email", app.HandleUsers)
+			r.With(validate).Post("/handle/users/forgot-password", app.HandleUsers)
+			r.With(validate).Post("/handle/users/reset-password", app.HandleUsers)
+			r.With(app.limitLogin, validate).Post("/handle/users/login/2fa", app.HandleUsers)
+			r.With(validate).Get("/handle/accounts", app.HandleAccounts)
+			r.With(validate).Post("/handle/oauth/token", app.HandleOAuthToken)
+		})
+	}
+
+	return tracing.Handler(mux, "gateway")
+}
+
+// traceRoute names the span of a request after the route it matched, which chi only knows once it routed the request
+func traceRoute(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if pattern := routePattern(r); pattern != "" {
+			tracing.SetRoute(r, pattern)
+		}
+	})
+}
+
+// logRequests logs every request once it was served, as the request log of logger-service. The context of the
+// request carries the fields of its logs, which the handlers further down, such as authenticate, add to.
+func (app *Config) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := logging.NewContext(r.Context())
+		recorder := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= http.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// requests that matched no route are named by their path
+		pattern := routePattern(r)
+		name := r.Method + " " + pattern
+		if pattern == "" {
+			name = r.Method + " " + r.URL.Path
+		}
+		app.logger.LogAttrs(ctx, level, name, logging.RequestLog, logging.Route(pattern),
+			logging.StatusCode(status), slog.Duration("duration", time.Since(start)))
 	})
+}
+
+// routePattern returns the pattern of the route a request matched, such as /api/v1/accounts/{account_id}, once chi
+// routed it
+func routePattern(r *http.Request) string {
+	return chi.RouteContext(r.Context()).RoutePattern()
+}
+
+// apiRouter serves the REST resources of the gateway, where the method and path of a request select the operation.
+// validate checks requests against the specification once the client was allowed to make them.
+func (app *Config) apiRouter(validate func(http.Handler) http.Handler) http.Handler {
+	mux := chi.NewRouter()
+
+	mux.With(validate).Post("/users", app.CreateUser)
+	mux.With(app.limitLogin, validate).Post("/auth/login", app.userRoute("loginUserRequest", http.MethodPost, "/users/login", func() any { return &LoginUserPayload{} }))
+	mux.With(app.limitLogin, validate).Post("/auth/login/2fa", app.userRoute("loginTwoFactorRequest", http.MethodPost, "/users/login/2fa", func() any { return &LoginTwoFactorPayload{} }))
+	mux.With(validate).Post("/auth/forgot-password", app.userRoute("forgotPasswordRequest", http.MethodPost, "/users/forgot-password", func() any { return &ForgotPasswordPayload{} }))
+	mux.With(validate).Post("/auth/reset-password", app.userRoute("resetPasswordRequest", http.MethodPost, "/users/reset-password", func() any { return &ResetPasswordPayload{} }))
+	mux.With(validate).Post("/auth/verify-email", app.userRoute("verifyEmailRequest", http.MethodPost, "/users/verify-email", func() any { return &VerifyEmailPayload{} }))
+	mux.With(validate).Post("/oauth/token", app.HandleOAuthToken)
+
+	mux.Group(func(r chi.Router) {
+		r.Use(app.authenticate, app.limitByClient)
+
+		// Accounts and transactions are open to oauth clients and api keys with the matching scope
+		r.With(app.requireScope(scopeAccountsRead), validate).Get("/accounts", app.ListAccounts)
+		r.With(app.requireScope(scopeAccountsWrite), validate).Post("/accounts", app.CreateAccount)
+		r.With(app.requireScope(scopeAccountsRead), validate).Get("/accounts/{account_id}", app.GetAccount)
+		r.With(app.requireScope(scopeAccountsWrite), validate).Patch("/accounts/{account_id}", app.UpdateAccount)
+		r.With(app.requireScope(scopeAccountsWrite), validate).Delete("/accounts/{account_id}", app.DeleteAccount)
+		r.With(app.requireScope(scopeAccountsWrite), validate).Post("/accounts/{account_id}/deposits", app.DepositToAccount)
+		r.With(app.requireScope(scopeAccountsRead), validate).Get("/transactions", app.ListTransactions)
+		r.With(app.requireScope(scopeTransfersWrite), validate).Post("/transactions", app.CreateTransaction)
+		r.With(app.requireScope(scopeAccountsRead), validate).Get("/transactions/{transaction_id}", app.GetTransaction)
 
-	mux.Post("/handle/users/login", app.HandleUsers)
-	mux.Post("/handle/users", app.HandleUsers)
-	mux.Get("/handle/accounts", app.HandleAccounts)
+		r.Group(func(r chi.Router) {
+			r.Use(app.firstPartyOnly, validate)
+
+			// Users
+			r.Get("/users/{user_id}", app.GetUser)
+			r.Patch("/users/me", app.userRoute("updateProfileRequest", http.MethodPut, "/users/update", func() any { return &UpdateProfilePayload{} }))
+			r.Delete("/users/me", app.EraseUser)
+			r.Put("/users/me/email", app.userRoute("updateEmailRequest", http.MethodPut, "/users/update-email", func() any { return &UpdateEmailPayload{} }))
+			r.Put("/users/me/password", app.userRoute("changePasswordRequest", http.MethodPost, "/users/change-password", func() any { return &ChangePasswordPayload{} }))
+			r.Post("/users/me/verification-email", app.userRoute("resendVerificationRequest", http.MethodPost, "/users/resend-verification", nil))
+			r.Post("/users/me/deactivate", app.userRoute("deactivateUserRequest", http.MethodPost, "/users/deactivate", func() any { return &ConfirmPasswordPayload{} }))
+			r.Get("/users/me/export", app.ExportUser)
+
+			// Two-factor authentication
+			r.Post("/users/me/2fa", app.userRoute("enrollTwoFactorRequest", http.MethodPost, "/users/2fa/enroll", nil))
+			r.Post("/users/me/2fa/confirm", app.userRoute("confirmTwoFactorRequest", http.MethodPost, "/users/2fa/confirm", func() any { return &TwoFactorPayload{} }))
+			r.Delete("/users/me/2fa", app.userRoute("disableTwoFactorRequest", http.MethodPost, "/users/2fa/disable", func() any { return &TwoFactorPayload{} }))
+			r.Post("/users/me/2fa/recovery-codes", app.userRoute("recoveryCodesRequest", http.MethodPost, "/users/2fa/recovery-codes", func() any { return &TwoFactorPayload{} }))
+
+			// KYC
+			r.Get("/users/me/kyc", app.userRoute("kycStatusRequest", http.MethodGet, "/users/kyc", nil))
+			r.Post("/users/me/kyc/documents", app.HandleKYCDocuments)
+			r.Get("/users/me/kyc/documents/{document_id}", app.HandleKYCDocuments)
+			r.Get("/kyc/reviews", app.userRoute("listKYCReviewsRequest", http.MethodGet, "/users/kyc/reviews", nil))
+			r.Get("/kyc/reviews/{user_id}", app.userRoute("getKYCReviewRequest", http.MethodGet, "/users/kyc/reviews/{user_id}", nil))
+			r.Post("/kyc/reviews/{user_id}/approve", app.userRoute("approveKYCRequest", http.MethodPost, "/users/kyc/reviews/{user_id}/approve", nil))
+			r.Post("/kyc/reviews/{user_id}/reject", app.userRoute("rejectKYCRequest", http.MethodPost, "/users/kyc/reviews/{user_id}/reject", func() any { return &RejectKYCPayload{} }))
+
+			// OAuth
+			r.Post("/oauth/clients", app.userRoute("registerOAuthClientRequest", http.MethodPost, "/oauth/clients", func() any { return &RegisterOAuthClientPayload{} }))
+			r.Get("/oauth/clients", app.userRoute("listOAuthClientsRequest", http.MethodGet, "/oauth/clients", nil))
+			r.Get("/oauth/authorize", app.userRoute("getAuthorizationRequest", http.MethodGet, "/oauth/authorize", nil))
+			r.Post("/oauth/authorize", app.userRoute("authorizeClientRequest", http.MethodPost, "/oauth/authorize", func() any { return &approveAuthorizationPayload{} }))
+			r.Get("/oauth/consents", app.userRoute("listOAuthConsentsRequest", http.MethodGet, "/oauth/consents", nil))
+			r.Delete("/oauth/consents/{client_id}", app.userRoute("revokeOAuthConsentRequest", http.MethodDelete, "/oauth/consents/{client_id}", nil))
+
+			// API keys
+			r.Post("/api-keys", app.userRoute("createAPIKeyRequest", http.MethodPost, "/api-keys", func() any { return &CreateAPIKeyPayload{} }))
+			r.Get("/api-keys", app.userRoute("listAPIKeysRequest", http.MethodGet, "/api-keys", nil))
+			r.Delete("/api-keys/{key_id}", app.userRoute("revokeAPIKeyRequest", http.MethodDelete, "/api-keys/{key_id}", nil))
+
+			// Service accounts
+			r.Post("/service-accounts", app.userRoute("createServiceAccountRequest", http.MethodPost, "/service-accounts", func() any { return &CreateServiceAccountPayload{} }))
+			r.Get("/service-accounts", app.userRoute("listServiceAccountsRequest", http.MethodGet, "/service-accounts", nil))
+			r.Delete("/service-accounts/{service_account_id}", app.userRoute("disableServiceAccountRequest", http.MethodDelete, "/service-accounts/{service_account_id}", nil))
+			r.Post("/service-accounts/{service_account_id}/api-keys", app.userRoute("createServiceAccountAPIKeyRequest", http.MethodPost, "/service-accounts/{service_account_id}/api-keys", func() any { return &CreateAPIKeyPayload{} }))
+			r.Get("/service-accounts/{service_account_id}/api-keys", app.userRoute("listServiceAccountAPIKeysRequest", http.MethodGet, "/service-accounts/{service_account_id}/api-keys", nil))
+			r.Delete("/service-accounts/{service_account_id}/api-keys/{key_id}", app.userRoute("revokeServiceAccountAPIKeyRequest", http.MethodDelete, "/service-accounts/{service_account_id}/api-keys/{key_id}", nil))
+		})
+	})
 
 	return mux
 }
 
+// deprecated marks the responses of the legacy action routes, pointing clients to their successor
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", `</api/v1>; rel="successor-version"`)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *Config) handleRouter() http.Handler {
 	mux := chi.NewRouter()
 
@@ -43,25 +218,55 @@ func (app *Config) handleRouter() http.Handler {
 	mux.Get("/transactions/{transaction_id}", app.HandleTransactions)
 	mux.Get("/transactions", app.HandleTransactions)
 
-	// Users-services
-	mux.Get("/users/{user_id}", app.HandleUsers)
+	// Routes below are reserved to first-party tokens, oauth clients and api keys are limited to the scoped actions
+	// of accounts and transactions
+	mux.Group(func(r chi.Router) {
+		r.Use(app.firstPartyOnly)
 
-	return mux
-}
+		// Users-services
+		r.Get("/users/{user_id}", app.HandleUsers)
+		r.Post("/users/change-password", app.HandleUsers)
+		r.Post("/users/resend-verification", app.HandleUsers)
+		r.Post("/users/2fa/enroll", app.HandleUsers)
+		r.Post("/users/2fa/confirm", app.HandleUsers)
+		r.Post("/users/2fa/disable", app.HandleUsers)
+		r.Post("/users/2fa/recovery-codes", app.HandleUsers)
+		r.Put("/users/update", app.HandleUsers)
+		r.Put("/users/update-email", app.HandleUsers)
+		r.Post("/users/deactivate", app.HandleUsers)
+		r.Get("/users/export", app.HandleUsers)
+		r.Post("/users/erase", app.HandleUsers)
+		r.Get("/users/kyc", app.HandleKYC)
+		r.Post("/users/kyc/documents", app.HandleKYCDocuments)
+		r.Get("/users/kyc/documents/{document_id}", app.HandleKYCDocuments)
+		r.Get("/users/kyc/reviews", app.HandleKYC)
+		r.Get("/users/kyc/reviews/{user_id}", app.HandleKYC)
+		r.Post("/users/kyc/reviews/{user_id}/approve", app.HandleKYC)
+		r.Post("/users/kyc/reviews/{user_id}/reject", app.HandleKYC)
 
-type authenticateResponse struct {
-	Status  string          `json:"status"`
-	Payload responsePayload `json:"payload"`
-}
+		// OAuth
+		r.Post("/oauth/clients", app.HandleOAuth)
+		r.Get("/oauth/clients", app.HandleOAuth)
+		r.Get("/oauth/authorize", app.HandleOAuth)
+		r.Post("/oauth/authorize", app.HandleOAuth)
+		r.Get("/oauth/consents", app.HandleOAuth)
+		r.Delete("/oauth/consents/{client_id}", app.HandleOAuth)
+
+		// API keys
+		r.Post("/api-keys", app.HandleAPIKeys)
+		r.Get("/api-keys", app.HandleAPIKeys)
+		r.Delete("/api-keys/{key_id}", app.HandleAPIKeys)
+	})
 
-type responsePayload struct {
-	ID        string    `json:"id"`
-	UserID    string    `json:"user_id"`
-	IssuedAt  time.Time `json:"issued_at"`
-	ExpiredAt time.Time `json:"expired_at"`
+	return mux
 }
 
-func authenticate(next http.Handler) http.Handler {
+// authorizationBearer is the scheme of the Authorization header for access tokens, as in "Authorization: Bearer ..."
+const authorizationBearer = "bearer"
+
+// authenticate asks user-service who a request is made by, from the access token or the api key of machine clients
+// in its Authorization header, and stores the result in the request context
+func (app *Config) authenticate(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := r.Header.Get("Authorization")
 		if token == "" {
@@ -70,33 +275,47 @@ func authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// Call the Authentication service to validate the access token
-		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://user-service/users/authenticate"), strings.NewReader(""))
-		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		fields := strings.Fields(token)
+		if len(fields) != 2 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		request.Header.Set("Authorization", token)
 
-		client := &http.Client{}
-		response, err := client.Do(request)
-		if err != nil {
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-			return
-		}
-		defer response.Body.Close()
+		ctx, cancel := app.rpcContext(r)
+		defer cancel()
 
-		if response.StatusCode != http.StatusOK {
+		var payload *pb.AuthPayload
+		var err error
+		switch strings.ToLower(fields[0]) {
+		case authorizationAPIKey:
+			payload, err = app.users.AuthenticateAPIKey(ctx, &pb.AuthenticateAPIKeyRequest{
+				ApiKey:   fields[1],
+				ClientIp: clientIP(r),
+			})
+		case authorizationBearer:
+			payload, err = app.users.Authenticate(ctx, &pb.AuthenticateRequest{AccessToken: fields[1]})
+		default:
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
+		if err != nil {
+			status, err := rpcStatus(err)
+			if status == http.StatusUnauthorized {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			retryAfter(w, err)
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
 
-		var jsonResponseBody authenticateResponse
-		decoder := json.NewDecoder(response.Body)
-		err = decoder.Decode(&jsonResponseBody)
-
-		ctx := context.WithValue(r.Context(), "user_id", jsonResponseBody.Payload.UserID)
+		logging.AddFields(r.Context(), logging.UserID(payload.GetUserId()))
+		reqCtx := context.WithValue(r.Context(), "user_id", payload.GetUserId())
+		reqCtx = context.WithValue(reqCtx, "client_id", payload.GetClientId())
+		reqCtx = context.WithValue(reqCtx, "api_key_id", payload.GetKeyId())
+		reqCtx = context.WithValue(reqCtx, "service_account_id", payload.GetServiceAccountId())
+		reqCtx = context.WithValue(reqCtx, "scopes", payload.GetScopes())
 
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(reqCtx))
 	})
 }
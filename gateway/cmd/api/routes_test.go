@@ -0,0 +1,412 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// stubUsers authenticates the tokens and api keys it was given, the other methods of user-service aren't stubbed
+type stubUsers struct {
+	pb.UserServiceClient
+	tokens  map[string]*pb.AuthPayload
+	apiKeys map[string]*pb.AuthPayload
+}
+
+func (s *stubUsers) Authenticate(_ context.Context, req *pb.AuthenticateRequest, _ ...grpc.CallOption) (*pb.AuthPayload, error) {
+	if payload, ok := s.tokens[req.GetAccessToken()]; ok {
+		return payload, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "token is invalid")
+}
+
+func (s *stubUsers) AuthenticateAPIKey(_ context.Context, req *pb.AuthenticateAPIKeyRequest, _ ...grpc.CallOption) (*pb.AuthPayload, error) {
+	if payload, ok := s.apiKeys[req.GetApiKey()]; ok {
+		return payload, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "api key is invalid, revoked or has expired")
+}
+
+func (s *stubUsers) GetUser(_ context.Context, req *pb.GetUserRequest, _ ...grpc.CallOption) (*pb.User, error) {
+	return &pb.User{UserId: req.GetUserId()}, nil
+}
+
+// stubAccounts serves the accounts it was given, which are all the accounts there are
+type stubAccounts struct {
+	pb.AccountServiceClient
+	accounts map[string]*pb.Account
+}
+
+func (s *stubAccounts) GetAccount(_ context.Context, req *pb.GetAccountRequest, _ ...grpc.CallOption) (*pb.Account, error) {
+	if account, ok := s.accounts[req.GetAccountId()]; ok {
+		return account, nil
+	}
+	return nil, status.Error(codes.NotFound, "account not found")
+}
+
+func (s *stubAccounts) ListAccounts(_ context.Context, _ *pb.ListAccountsRequest, _ ...grpc.CallOption) (*pb.ListAccountsResponse, error) {
+	resp := &pb.ListAccountsResponse{}
+	for _, account := range s.accounts {
+		resp.Accounts = append(resp.Accounts, account)
+	}
+	return resp, nil
+}
+
+func (s *stubAccounts) ListUserAccounts(_ context.Context, req *pb.ListUserAccountsRequest, _ ...grpc.CallOption) (*pb.ListAccountsResponse, error) {
+	resp := &pb.ListAccountsResponse{}
+	for _, account := range s.accounts {
+		if account.GetUserId() == req.GetUserId() {
+			resp.Accounts = append(resp.Accounts, account)
+		}
+	}
+	return resp, nil
+}
+
+func (s *stubAccounts) UpdateAccount(ctx context.Context, req *pb.UpdateAccountRequest, _ ...grpc.CallOption) (*pb.Account, error) {
+	return s.GetAccount(ctx, &pb.GetAccountRequest{AccountId: req.GetAccountId()})
+}
+
+func (s *stubAccounts) DeleteAccount(_ context.Context, _ *pb.DeleteAccountRequest, _ ...grpc.CallOption) (*pb.DeleteAccountResponse, error) {
+	return &pb.DeleteAccountResponse{}, nil
+}
+
+func (s *stubAccounts) AddBalance(ctx context.Context, req *pb.AddBalanceRequest, _ ...grpc.CallOption) (*pb.Account, error) {
+	return s.GetAccount(ctx, &pb.GetAccountRequest{AccountId: req.GetAccountId()})
+}
+
+// stubTransactions serves the transactions it was given and makes every transfer it is asked for
+type stubTransactions struct {
+	pb.TransactionServiceClient
+	transactions map[string]*pb.Transaction
+	transfers    []*pb.CreateTransactionRequest
+}
+
+func (s *stubTransactions) GetTransaction(_ context.Context, req *pb.GetTransactionRequest, _ ...grpc.CallOption) (*pb.Transaction, error) {
+	if transaction, ok := s.transactions[req.GetTransactionId()]; ok {
+		return transaction, nil
+	}
+	return nil, status.Error(codes.NotFound, "transaction not found")
+}
+
+func (s *stubTransactions) ListTransactions(_ context.Context, _ *pb.ListTransactionsRequest, _ ...grpc.CallOption) (*pb.ListTransactionsResponse, error) {
+	resp := &pb.ListTransactionsResponse{}
+	for _, transaction := range s.transactions {
+		resp.Transactions = append(resp.Transactions, transaction)
+	}
+	return resp, nil
+}
+
+func (s *stubTransactions) ListUserTransactions(_ context.Context, _ *pb.ListUserTransactionsRequest, _ ...grpc.CallOption) (*pb.ListTransactionsResponse, error) {
+	return &pb.ListTransactionsResponse{}, nil
+}
+
+func (s *stubTransactions) CreateTransaction(_ context.Context, req *pb.CreateTransactionRequest, _ ...grpc.CallOption) (*pb.CreateTransactionResponse, error) {
+	s.transfers = append(s.transfers, req)
+	return &pb.CreateTransactionResponse{
+		Transaction: &pb.Transaction{FromAccountId: req.GetFromAccountId(), ToAccountId: req.GetToAccountId()},
+	}, nil
+}
+
+// Credentials of the test app, as sent in the Authorization header
+const (
+	aliceToken      = "Bearer alice"
+	aliceOAuthToken = "Bearer alice-oauth"
+	aliceAPIKey     = "ApiKey dbk_alice"
+	backOfficeKey   = "ApiKey dbk_backoffice"
+)
+
+// newTestApp returns a gateway whose backends are stubs. Alice owns account 1 and bob account 2, account 404
+// doesn't exist. Transaction 10 was sent by alice to bob, 20 by bob to an account that was deleted since and 30
+// between two deleted accounts. Requests forwarded to user-service over HTTP fail the test.
+func newTestApp(t *testing.T) (*Config, *stubTransactions) {
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to user-service: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(userService.Close)
+
+	transactions := &stubTransactions{transactions: map[string]*pb.Transaction{
+		"10": {TransactionId: "10", FromAccountId: "1", ToAccountId: "2", TransactionAmount: 5},
+		"20": {TransactionId: "20", FromAccountId: "2", ToAccountId: "404", TransactionAmount: 5},
+		"30": {TransactionId: "30", FromAccountId: "404", ToAccountId: "405", TransactionAmount: 5},
+	}}
+
+	app := &Config{
+		config: EnvConfig{RPCTimeout: time.Second},
+		logger: slog.New(slog.HandlerOptions{}.NewJSONHandler(io.Discard)),
+		users: &stubUsers{
+			tokens: map[string]*pb.AuthPayload{
+				"alice":       {UserId: "alice"},
+				"alice-oauth": {UserId: "alice", ClientId: "budget-app", Scopes: []string{scopeAccountsRead}},
+			},
+			apiKeys: map[string]*pb.AuthPayload{
+				"dbk_alice":      {UserId: "alice", KeyId: "key-1", Scopes: []string{scopeAccountsRead, scopeAccountsWrite, scopeTransfersWrite}},
+				"dbk_backoffice": {ServiceAccountId: "reporting", KeyId: "key-2", Scopes: []string{scopeAccountsRead}},
+			},
+		},
+		accounts: &stubAccounts{accounts: map[string]*pb.Account{
+			"1": {AccountId: "1", UserId: "alice", Balance: 100, Currency: "EUR"},
+			"2": {AccountId: "2", UserId: "bob", Balance: 100, Currency: "EUR"},
+		}},
+		transactions: transactions,
+		userService:  client.NewUserService(userService.URL),
+	}
+	return app, transactions
+}
+
+// serve sends a request with the credentials of authorization to the /api/v1 routes of app
+func serve(t *testing.T, app *Config, method, path, authorization, body string) *httptest.ResponseRecorder {
+	return serveBody(t, app, method, path, authorization, strings.NewReader(body))
+}
+
+// serveBody sends a request like serve, with a body of any reader. The routes validate it against the embedded
+// specification.
+func serveBody(t *testing.T, app *Config, method, path, authorization string, body io.Reader) *httptest.ResponseRecorder {
+	spec, err := loadOpenAPISpec()
+	require.NoError(t, err)
+	mux := chi.NewRouter()
+	mux.Mount("/api/v1", app.apiRouter(app.validateRequest(spec)))
+
+	r := httptest.NewRequest(method, "/api/v1"+path, body)
+	r.Header.Set("Content-Type", "application/json")
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestAccountOwnership(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		body          string
+		status        int
+	}{
+		{name: "GetOwn", method: http.MethodGet, path: "/accounts/1", authorization: aliceToken, status: http.StatusOK},
+		{name: "GetForeign", method: http.MethodGet, path: "/accounts/2", authorization: aliceToken, status: http.StatusForbidden},
+		{name: "GetMissing", method: http.MethodGet, path: "/accounts/404", authorization: aliceToken, status: http.StatusNotFound},
+		{name: "GetForeignWithOAuthToken", method: http.MethodGet, path: "/accounts/2", authorization: aliceOAuthToken, status: http.StatusForbidden},
+		{name: "GetForeignWithAPIKey", method: http.MethodGet, path: "/accounts/2", authorization: aliceAPIKey, status: http.StatusForbidden},
+		{name: "UpdateOwn", method: http.MethodPatch, path: "/accounts/1", authorization: aliceToken, body: `{"balance":5}`, status: http.StatusOK},
+		{name: "UpdateForeign", method: http.MethodPatch, path: "/accounts/2", authorization: aliceToken, body: `{"balance":5}`, status: http.StatusForbidden},
+		{name: "UpdateMissing", method: http.MethodPatch, path: "/accounts/404", authorization: aliceToken, body: `{"balance":5}`, status: http.StatusNotFound},
+		{name: "DeleteForeign", method: http.MethodDelete, path: "/accounts/2", authorization: aliceToken, status: http.StatusForbidden},
+		{name: "DeleteForeignWithAPIKey", method: http.MethodDelete, path: "/accounts/2", authorization: aliceAPIKey, status: http.StatusForbidden},
+		{name: "DeleteMissing", method: http.MethodDelete, path: "/accounts/404", authorization: aliceToken, status: http.StatusNotFound},
+		{name: "DepositOwn", method: http.MethodPost, path: "/accounts/1/deposits", authorization: aliceToken, body: `{"amount":5}`, status: http.StatusCreated},
+		{name: "DepositForeign", method: http.MethodPost, path: "/accounts/2/deposits", authorization: aliceToken, body: `{"amount":5}`, status: http.StatusForbidden},
+		{name: "DepositMissing", method: http.MethodPost, path: "/accounts/404/deposits", authorization: aliceToken, body: `{"amount":5}`, status: http.StatusNotFound},
+		// service accounts read the accounts of every user, but can't change them
+		{name: "GetForeignWithServiceAccount", method: http.MethodGet, path: "/accounts/2", authorization: backOfficeKey, status: http.StatusOK},
+		{name: "UpdateWithServiceAccount", method: http.MethodPatch, path: "/accounts/2", authorization: backOfficeKey, body: `{"balance":5}`, status: http.StatusForbidden},
+		{name: "DepositWithServiceAccount", method: http.MethodPost, path: "/accounts/2/deposits", authorization: backOfficeKey, body: `{"amount":5}`, status: http.StatusForbidden},
+		{name: "Unauthenticated", method: http.MethodGet, path: "/accounts/1", status: http.StatusUnauthorized},
+		{name: "UnknownAPIKey", method: http.MethodGet, path: "/accounts/1", authorization: "ApiKey dbk_revoked", status: http.StatusUnauthorized},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			w := serve(t, app, tc.method, tc.path, tc.authorization, tc.body)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestListAccounts(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		accounts      []string
+	}{
+		{name: "User", authorization: aliceToken, accounts: []string{"1"}},
+		{name: "APIKey", authorization: aliceAPIKey, accounts: []string{"1"}},
+		{name: "ServiceAccount", authorization: backOfficeKey, accounts: []string{"1", "2"}},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			w := serve(t, app, http.MethodGet, "/accounts", tc.authorization, "")
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Data []accountResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			ids := []string{}
+			for _, account := range resp.Data {
+				ids = append(ids, account.AccountID)
+			}
+			require.ElementsMatch(t, tc.accounts, ids)
+		})
+	}
+}
+
+func TestTransactionOwnership(t *testing.T) {
+	testCases := []struct {
+		name          string
+		path          string
+		authorization string
+		status        int
+	}{
+		{name: "Sent", path: "/transactions/10", authorization: aliceToken, status: http.StatusOK},
+		{name: "SentWithAPIKey", path: "/transactions/10", authorization: aliceAPIKey, status: http.StatusOK},
+		{name: "Foreign", path: "/transactions/20", authorization: aliceToken, status: http.StatusForbidden},
+		{name: "ForeignWithOAuthToken", path: "/transactions/20", authorization: aliceOAuthToken, status: http.StatusForbidden},
+		{name: "ForeignWithAPIKey", path: "/transactions/20", authorization: aliceAPIKey, status: http.StatusForbidden},
+		// accounts that were deleted belong to nobody
+		{name: "DeletedAccounts", path: "/transactions/30", authorization: aliceToken, status: http.StatusForbidden},
+		{name: "Missing", path: "/transactions/40", authorization: aliceToken, status: http.StatusNotFound},
+		{name: "ForeignWithServiceAccount", path: "/transactions/20", authorization: backOfficeKey, status: http.StatusOK},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app, _ := newTestApp(t)
+			w := serve(t, app, http.MethodGet, tc.path, tc.authorization, "")
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+		})
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		from          string
+		status        int
+	}{
+		{name: "Own", authorization: aliceToken, from: "1", status: http.StatusCreated},
+		{name: "OwnWithAPIKey", authorization: aliceAPIKey, from: "1", status: http.StatusCreated},
+		{name: "Foreign", authorization: aliceToken, from: "2", status: http.StatusForbidden},
+		{name: "ForeignWithAPIKey", authorization: aliceAPIKey, from: "2", status: http.StatusForbidden},
+		{name: "Missing", authorization: aliceToken, from: "404", status: http.StatusNotFound},
+		{name: "OAuthTokenWithoutScope", authorization: aliceOAuthToken, from: "1", status: http.StatusForbidden},
+		{name: "ServiceAccount", authorization: backOfficeKey, from: "2", status: http.StatusForbidden},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app, transactions := newTestApp(t)
+			body := `{"from_account_id":"` + tc.from + `","to_account_id":"2","transaction_amount":5}`
+			w := serve(t, app, http.MethodPost, "/transactions", tc.authorization, body)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			if tc.status != http.StatusCreated {
+				require.Empty(t, transactions.transfers)
+			}
+		})
+	}
+}
+
+func TestFirstPartyOnly(t *testing.T) {
+	routes := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodGet, path: "/users/alice"},
+		{method: http.MethodPatch, path: "/users/me", body: `{"firstname":"Alice"}`},
+		{method: http.MethodDelete, path: "/users/me", body: `{"password":"secret"}`},
+		{method: http.MethodPut, path: "/users/me/password", body: `{"old_password":"a","new_password":"b"}`},
+		{method: http.MethodGet, path: "/users/me/export"},
+		{method: http.MethodPost, path: "/users/me/2fa"},
+		{method: http.MethodGet, path: "/kyc/reviews"},
+		{method: http.MethodPost, path: "/oauth/clients", body: `{"name":"app"}`},
+		{method: http.MethodPost, path: "/api-keys", body: `{"name":"key","scopes":["accounts:read"]}`},
+		{method: http.MethodGet, path: "/api-keys"},
+		{method: http.MethodDelete, path: "/api-keys/key-1"},
+		{method: http.MethodPost, path: "/service-accounts", body: `{"name":"reporting"}`},
+		{method: http.MethodPost, path: "/service-accounts/reporting/api-keys", body: `{"name":"key","scopes":["accounts:read"]}`},
+	}
+
+	// api keys and tokens of oauth clients are turned away before user-service is asked
+	for _, authorization := range []string{aliceAPIKey, backOfficeKey, aliceOAuthToken} {
+		for _, route := range routes {
+			t.Run(authorization+" "+route.method+" "+route.path, func(t *testing.T) {
+				app, _ := newTestApp(t)
+				w := serve(t, app, route.method, route.path, authorization, route.body)
+				require.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+				require.Contains(t, w.Body.String(), errFirstPartyOnly.Error())
+			})
+		}
+	}
+
+	// first-party tokens pass
+	app, _ := newTestApp(t)
+	w := serve(t, app, http.MethodGet, "/users/alice", aliceToken, "")
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+// trackedBody is a request body that remembers whether it was read
+type trackedBody struct {
+	io.Reader
+	read bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	b.read = true
+	return b.Reader.Read(p)
+}
+
+func TestValidateAfterAuthorization(t *testing.T) {
+	invalid := `{"from_account_id":1}`
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		status        int
+		read          bool
+	}{
+		// the body isn't looked at before the client is known to be allowed to send it
+		{name: "Anonymous", method: http.MethodPost, path: "/transactions", status: http.StatusUnauthorized},
+		{name: "UnknownAPIKey", method: http.MethodPost, path: "/transactions", authorization: "ApiKey dbk_revoked", status: http.StatusUnauthorized},
+		{name: "MissingScope", method: http.MethodPost, path: "/transactions", authorization: backOfficeKey, status: http.StatusForbidden},
+		{name: "FirstPartyOnly", method: http.MethodPost, path: "/api-keys", authorization: aliceAPIKey, status: http.StatusForbidden},
+		{name: "Scoped", method: http.MethodPost, path: "/transactions", authorization: aliceAPIKey, status: http.StatusBadRequest, read: true},
+		{name: "FirstParty", method: http.MethodPost, path: "/api-keys", authorization: aliceToken, status: http.StatusBadRequest, read: true},
+		// public routes are validated for everyone
+		{name: "Public", method: http.MethodPost, path: "/auth/login", status: http.StatusBadRequest, read: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app, transactions := newTestApp(t)
+			body := &trackedBody{Reader: strings.NewReader(invalid)}
+			w := serveBody(t, app, tc.method, tc.path, tc.authorization, body)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			require.Equal(t, tc.read, body.read)
+			require.Empty(t, transactions.transfers)
+		})
+	}
+}
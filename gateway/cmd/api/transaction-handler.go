@@ -1,19 +1,18 @@
 package main
 
 import (
-	"bytes"
 	"database/sql"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
 	"github.com/go-chi/chi/v5"
 	"net/http"
-	"strings"
 )
 
 type TransactionRequestPayload struct {
 	Action string                   `json:"action"`
 	Create CreateTransactionPayload `json:"create,omitempty"`
+	OTP    string                   `json:"otp,omitempty"`
 }
 
 func (app *Config) HandleTransactions(w http.ResponseWriter, r *http.Request) {
@@ -27,9 +26,13 @@ func (app *Config) HandleTransactions(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !app.authorizeAction(w, r, "HandleTransactions", transactionActionScopes, requestPayload.Action) {
+		return
+	}
+
 	switch requestPayload.Action {
 	case "create":
-		app.createTransactionRequest(w, r, requestPayload.Create)
+		app.createTransactionRequest(w, r, requestPayload.Create, requestPayload.OTP)
 	case "get":
 		app.getTransactionRequest(w, r)
 	case "list":
@@ -49,112 +52,160 @@ type CreateTransactionPayload struct {
 	Description       sql.NullString `json:"description"`
 }
 
-func (app *Config) createTransactionRequest(w http.ResponseWriter, r *http.Request, payload CreateTransactionPayload) error {
-	jsonData, _ := json.Marshal(payload)
+func (app *Config) createTransactionRequest(w http.ResponseWriter, r *http.Request, payload CreateTransactionPayload, otp string) error {
+	if !app.limitTransfer(w, r) {
+		return nil
+	}
 
-	request, err := http.NewRequest(http.MethodPost, "http://account-service/transactions/create", bytes.NewBuffer(jsonData))
+	// Money can only be sent from accounts of the user, whoever the token or api key was issued to
+	idInHeader, _ := r.Context().Value("user_id").(string)
+	userID, status, err := app.getAccountUserID(r, payload.FromAccountID)
 	if err != nil {
-		return app.errorJSON(w, "createTransactionRequest", err, 500)
+		return app.errorJSON(w, "createTransactionRequest", err, status)
+	}
+	if userID != idInHeader {
+		return app.errorJSON(w, "createTransactionRequest", errors.New("this is not yours"), http.StatusForbidden)
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "createTransactionRequest", err, response.StatusCode)
+	// Transfers above the threshold need a fresh one-time password of the user
+	if app.config.TransferOTPThreshold > 0 && payload.TransactionAmount > app.config.TransferOTPThreshold {
+		if isAPIKey(r) {
+			return app.errorJSON(w, "createTransactionRequest", fmt.Errorf("transfers above %.2f require a one-time password and can't be made with api keys", app.config.TransferOTPThreshold), http.StatusForbidden)
+		}
+		if otp == "" {
+			return app.errorJSON(w, "createTransactionRequest", fmt.Errorf("transfers above %.2f require a one-time password", app.config.TransferOTPThreshold), http.StatusForbidden)
+		}
+		status, err := app.verifyOTP(r, otp)
+		if err != nil {
+			return app.errorJSON(w, "createTransactionRequest", err, status)
+		}
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	arg := &pb.CreateTransactionRequest{
+		FromAccountId:     payload.FromAccountID,
+		ToAccountId:       payload.ToAccountID,
+		TransactionAmount: payload.TransactionAmount,
+	}
+	if payload.Description.Valid {
+		arg.Description = &payload.Description.String
+	}
+
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	var jsonResponseBody any
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	transfer, err := app.transactions.CreateTransaction(ctx, arg)
 	if err != nil {
-		return app.errorJSON(w, "createTransactionRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "createTransactionRequest", err, status)
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
-
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
+	resp.Message = "success"
+	resp.Data = transferResponse{
+		Transaction: newTransactionResponse(transfer.GetTransaction()),
+		FromAccount: newAccountResponse(transfer.GetFromAccount()),
+		ToAccount:   newAccountResponse(transfer.GetToAccount()),
 	}
 
-	return app.writeJSON(w, "createTransactionRequest", response.StatusCode, resp)
+	return app.writeJSON(w, "createTransactionRequest", http.StatusCreated, resp)
 }
 
 func (app *Config) getTransactionRequest(w http.ResponseWriter, r *http.Request) error {
 	id := chi.URLParam(r, "transaction_id")
 
-	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://account-service/transactions/%s", id), nil)
-	if err != nil {
-		return app.errorJSON(w, "getTransactionRequest", err, 500)
-	}
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+	transaction, err := app.transactions.GetTransaction(ctx, &pb.GetTransactionRequest{TransactionId: id})
 	if err != nil {
-		return app.errorJSON(w, "getTransactionRequest", err, response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "getTransactionRequest", err, status)
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
-
-	var jsonResponseBody any
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
-	if err != nil {
-		return app.errorJSON(w, "getTransactionRequest", errors.New("error reading response body"), response.StatusCode)
+	// The transaction is only shown to the users of the accounts it was sent from or to, and to service accounts
+	if !isServiceAccount(r) {
+		owned, status, err := app.ownsAnyAccount(r, transaction.GetFromAccountId(), transaction.GetToAccountId())
+		if err != nil {
+			return app.errorJSON(w, "getTransactionRequest", err, status)
+		}
+		if !owned {
+			return app.errorJSON(w, "getTransactionRequest", errors.New("this is not yours"), http.StatusForbidden)
+		}
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
+	resp.Message = "success"
+	resp.Data = newTransactionResponse(transaction)
 
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
-	}
-
-	return app.writeJSON(w, "getTransactionRequest", response.StatusCode, resp)
+	return app.writeJSON(w, "getTransactionRequest", http.StatusOK, resp)
 }
 
 func (app *Config) listTransactionsRequest(w http.ResponseWriter, r *http.Request) error {
-	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://account-service/transactions"), strings.NewReader(""))
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
+	transactions, err := app.transactions.ListTransactions(ctx, &pb.ListTransactionsRequest{})
 	if err != nil {
-		return app.errorJSON(w, "listTransactionRequest", err, 500)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "listTransactionRequest", err, status)
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "listTransactionRequest", err, response.StatusCode)
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = "success"
+	resp.Data = newTransactionResponses(transactions.GetTransactions())
+
+	return app.writeJSON(w, "listTransactionsRequest", http.StatusOK, resp)
+}
+
+// createTransactionBody is the body of a transfer on the REST routes. The one-time password for large transfers is
+// a field of the transfer itself.
+type createTransactionBody struct {
+	CreateTransactionPayload
+	OTP string `json:"otp,omitempty"`
+}
+
+// ListTransactions returns the transactions of the accounts of the logged-in user, or all of them for service accounts
+func (app *Config) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	if isServiceAccount(r) {
+		app.listTransactionsRequest(w, r)
+		return
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	userID, _ := r.Context().Value("user_id").(string)
+
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	var jsonResponseBody any
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	transactions, err := app.transactions.ListUserTransactions(ctx, &pb.ListUserTransactionsRequest{UserId: userID})
 	if err != nil {
-		return app.errorJSON(w, "listTransactionRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		app.errorJSON(w, "ListTransactions", err, status)
+		return
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
+	resp.Message = "success"
+	resp.Data = newTransactionResponses(transactions.GetTransactions())
 
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
+	app.writeJSON(w, "ListTransactions", http.StatusOK, resp)
+}
+
+// CreateTransaction transfers money between two accounts
+func (app *Config) CreateTransaction(w http.ResponseWriter, r *http.Request) {
+	var payload createTransactionBody
+	if err := app.readJSON(w, r, &payload); err != nil {
+		app.errorJSON(w, "CreateTransaction", err, http.StatusBadRequest)
+		return
 	}
 
-	return app.writeJSON(w, "listTransactionsRequest", response.StatusCode, resp)
+	app.createTransactionRequest(w, r, payload.CreateTransactionPayload, payload.OTP)
+}
+
+// GetTransaction returns a single transaction
+func (app *Config) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	app.getTransactionRequest(w, r)
 }
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransferOTPThreshold(t *testing.T) {
+	const validOTP = "123456"
+
+	testCases := []struct {
+		name          string
+		threshold     float64
+		authorization string
+		amount        float64
+		otp           string
+		status        int
+		verified      bool
+	}{
+		{name: "BelowThreshold", threshold: 1000, authorization: aliceToken, amount: 1000, status: http.StatusCreated},
+		{name: "WithoutOTP", threshold: 1000, authorization: aliceToken, amount: 1000.01, status: http.StatusForbidden},
+		{name: "WithOTP", threshold: 1000, authorization: aliceToken, amount: 5000, otp: validOTP, status: http.StatusCreated, verified: true},
+		{name: "WrongOTP", threshold: 1000, authorization: aliceToken, amount: 5000, otp: "654321", status: http.StatusUnauthorized, verified: true},
+		// api keys can't come with a one-time password of the user, so the otp isn't even checked
+		{name: "APIKey", threshold: 1000, authorization: aliceAPIKey, amount: 5000, otp: validOTP, status: http.StatusForbidden},
+		{name: "Disabled", authorization: aliceToken, amount: 5000, status: http.StatusCreated},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			verified := false
+			userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				verified = true
+				require.Equal(t, "/users/2fa/verify", r.URL.Path)
+				require.Equal(t, tc.authorization, r.Header.Get("Authorization"))
+
+				var req struct {
+					Code string `json:"code"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				w.Header().Set("Content-Type", "application/json")
+				if req.Code != validOTP {
+					w.WriteHeader(http.StatusUnauthorized)
+					w.Write([]byte(`{"error":"one-time password is invalid"}`))
+					return
+				}
+				w.Write([]byte(`{}`))
+			}))
+			defer userService.Close()
+
+			app, transactions := newTestApp(t)
+			app.config.TransferOTPThreshold = tc.threshold
+			app.userService = client.NewUserService(userService.URL)
+
+			body := fmt.Sprintf(`{"from_account_id":"1","to_account_id":"2","transaction_amount":%v,"otp":%q}`, tc.amount, tc.otp)
+			w := serve(t, app, http.MethodPost, "/transactions", tc.authorization, body)
+			require.Equal(t, tc.status, w.Code, w.Body.String())
+			require.Equal(t, tc.verified, verified)
+			if tc.status == http.StatusCreated {
+				require.Len(t, transactions.transfers, 1)
+			} else {
+				require.Empty(t, transactions.transfers)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+	"github.com/bugrakocabay/dummy-bank-microservice/gateway/cmd/resilience"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+)
+
+// upstreams are the backend services the gateway calls. The HTTP api of user-service has its own circuit breaker,
+// since it is served apart from the gRPC api.
+type upstreams struct {
+	accounts *resilience.Upstream
+	users    *resilience.Upstream
+	userHTTP *resilience.Upstream
+	logger   *resilience.Upstream
+}
+
+func newUpstreams(config EnvConfig) upstreams {
+	policy := func(timeout time.Duration) resilience.Policy {
+		return resilience.Policy{
+			Timeout:          timeout,
+			Retries:          config.UpstreamRetries,
+			Backoff:          config.UpstreamBackoff,
+			MaxBackoff:       config.UpstreamMaxBackoff,
+			FailureThreshold: config.BreakerFailures,
+			OpenTimeout:      config.BreakerOpenTimeout,
+			HalfOpenProbes:   config.BreakerProbes,
+		}
+	}
+
+	return upstreams{
+		accounts: resilience.NewUpstream("account-service", policy(config.AccountServiceTimeout)),
+		users:    resilience.NewUpstream("user-service", policy(config.UserServiceTimeout)),
+		userHTTP: resilience.NewUpstream("user-service-http", policy(config.HTTPTimeout)),
+		logger:   resilience.NewUpstream("logger-service", policy(config.LoggerServiceTimeout)),
+	}
+}
+
+func (u upstreams) all() []*resilience.Upstream {
+	return []*resilience.Upstream{u.accounts, u.users, u.userHTTP, u.logger}
+}
+
+// idempotentRPCs are the gRPC methods that only read, so that a failed call can be sent again. Authenticating an
+// api key records when it was last used, which a retry only moves by a moment.
+var idempotentRPCs = map[string]bool{
+	pb.AccountService_GetAccount_FullMethodName:               true,
+	pb.AccountService_ListAccounts_FullMethodName:             true,
+	pb.AccountService_ListUserAccounts_FullMethodName:         true,
+	pb.TransactionService_GetTransaction_FullMethodName:       true,
+	pb.TransactionService_ListTransactions_FullMethodName:     true,
+	pb.TransactionService_ListUserTransactions_FullMethodName: true,
+	pb.UserService_GetUser_FullMethodName:                     true,
+	pb.UserService_Authenticate_FullMethodName:                true,
+	pb.UserService_AuthenticateAPIKey_FullMethodName:          true,
+}
+
+func idempotentRPC(method string) bool {
+	return idempotentRPCs[method]
+}
+
+// retryAfter sets the Retry-After header for the error of a call rejected by an open circuit breaker, or of a
+// user-service response that came with one
+func retryAfter(w http.ResponseWriter, err error) {
+	var open *resilience.OpenError
+	var clientErr *client.Error
+	switch {
+	case errors.As(err, &open):
+		w.Header().Set("Retry-After", resilience.RetryAfterSeconds(open.RetryAfter))
+	case errors.As(err, &clientErr) && clientErr.Header.Get("Retry-After") != "":
+		w.Header().Set("Retry-After", clientErr.Header.Get("Retry-After"))
+	}
+}
+
+// ServeUpstreams reports the circuit breaker state of every backend service, for monitoring
+func (app *Config) ServeUpstreams(w http.ResponseWriter, r *http.Request) {
+	upstreams := app.upstreams.all()
+	snapshots := make([]resilience.Snapshot, 0, len(upstreams))
+	for _, upstream := range upstreams {
+		snapshots = append(snapshots, upstream.Snapshot())
+	}
+
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = "success"
+	resp.Data = snapshots
+
+	app.writeJSON(w, "serveUpstreams", http.StatusOK, resp)
+}
@@ -1,23 +1,30 @@
 package main
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
 	"github.com/go-chi/chi/v5"
 	"net/http"
+	"net/url"
 	"strings"
 )
 
-const (
-	userServiceURL = "http://user-service"
-)
-
 type UserRequestPayload struct {
-	Action string            `json:"action"`
-	Create CreateUserPayload `json:"create,omitempty"`
-	Login  LoginUserPayload  `json:"login,omitempty"`
+	Action          string                 `json:"action"`
+	Create          CreateUserPayload      `json:"create,omitempty"`
+	Login           LoginUserPayload       `json:"login,omitempty"`
+	ChangePassword  ChangePasswordPayload  `json:"change_password,omitempty"`
+	ForgotPassword  ForgotPasswordPayload  `json:"forgot_password,omitempty"`
+	ResetPassword   ResetPasswordPayload   `json:"reset_password,omitempty"`
+	VerifyEmail     VerifyEmailPayload     `json:"verify_email,omitempty"`
+	TwoFactor       TwoFactorPayload       `json:"two_factor,omitempty"`
+	LoginTwoFactor  LoginTwoFactorPayload  `json:"login_two_factor,omitempty"`
+	UpdateProfile   UpdateProfilePayload   `json:"update_profile,omitempty"`
+	UpdateEmail     UpdateEmailPayload     `json:"update_email,omitempty"`
+	ConfirmPassword ConfirmPasswordPayload `json:"confirm_password,omitempty"`
 }
 
 func (app *Config) HandleUsers(w http.ResponseWriter, r *http.Request) {
@@ -33,11 +40,41 @@ func (app *Config) HandleUsers(w http.ResponseWriter, r *http.Request) {
 
 	switch requestPayload.Action {
 	case "create":
-		app.createUserRequest(w, requestPayload.Create)
+		app.createUserRequest(w, r, requestPayload.Create)
 	case "get":
 		app.getUserRequest(w, r)
 	case "login":
-		app.loginUserRequest(w, requestPayload.Login)
+		app.forwardUserRequest(w, r, "loginUserRequest", http.MethodPost, "/users/login", requestPayload.Login)
+	case "change_password":
+		app.forwardUserRequest(w, r, "changePasswordRequest", http.MethodPost, "/users/change-password", requestPayload.ChangePassword)
+	case "forgot_password":
+		app.forwardUserRequest(w, r, "forgotPasswordRequest", http.MethodPost, "/users/forgot-password", requestPayload.ForgotPassword)
+	case "reset_password":
+		app.forwardUserRequest(w, r, "resetPasswordRequest", http.MethodPost, "/users/reset-password", requestPayload.ResetPassword)
+	case "verify_email":
+		app.forwardUserRequest(w, r, "verifyEmailRequest", http.MethodPost, "/users/verify-email", requestPayload.VerifyEmail)
+	case "resend_verification":
+		app.forwardUserRequest(w, r, "resendVerificationRequest", http.MethodPost, "/users/resend-verification", struct{}{})
+	case "login_2fa":
+		app.forwardUserRequest(w, r, "loginTwoFactorRequest", http.MethodPost, "/users/login/2fa", requestPayload.LoginTwoFactor)
+	case "enroll_2fa":
+		app.forwardUserRequest(w, r, "enrollTwoFactorRequest", http.MethodPost, "/users/2fa/enroll", struct{}{})
+	case "confirm_2fa":
+		app.forwardUserRequest(w, r, "confirmTwoFactorRequest", http.MethodPost, "/users/2fa/confirm", requestPayload.TwoFactor)
+	case "disable_2fa":
+		app.forwardUserRequest(w, r, "disableTwoFactorRequest", http.MethodPost, "/users/2fa/disable", requestPayload.TwoFactor)
+	case "recovery_codes":
+		app.forwardUserRequest(w, r, "recoveryCodesRequest", http.MethodPost, "/users/2fa/recovery-codes", requestPayload.TwoFactor)
+	case "update_profile":
+		app.forwardUserRequest(w, r, "updateProfileRequest", http.MethodPut, "/users/update", requestPayload.UpdateProfile)
+	case "update_email":
+		app.forwardUserRequest(w, r, "updateEmailRequest", http.MethodPut, "/users/update-email", requestPayload.UpdateEmail)
+	case "deactivate":
+		app.forwardUserRequest(w, r, "deactivateUserRequest", http.MethodPost, "/users/deactivate", requestPayload.ConfirmPassword)
+	case "export":
+		app.exportUserRequest(w, r)
+	case "erase":
+		app.eraseUserRequest(w, r, requestPayload.ConfirmPassword)
 	default:
 		if err = app.errorJSON(w, "HandleUsers", errors.New(fmt.Sprintf("unknown action type: %s", requestPayload.Action))); err != nil {
 			return
@@ -51,74 +88,111 @@ type LoginUserPayload struct {
 	Password string `json:"password" binding:"required"`
 }
 
-func (app *Config) loginUserRequest(w http.ResponseWriter, payload LoginUserPayload) error {
-	jsonData, _ := json.Marshal(payload)
+type CreateUserPayload struct {
+	Firstname string `json:"firstname" binding:"required"`
+	Lastname  string `json:"lastname" binding:"required"`
+	Email     string `json:"email" binding:"required"`
+	Password  string `json:"password" binding:"required"`
+}
+
+func (app *Config) createUserRequest(w http.ResponseWriter, r *http.Request, payload CreateUserPayload) error {
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	reqURL := fmt.Sprintf("%s/users/login", userServiceURL)
-	request, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(jsonData))
+	user, err := app.users.CreateUser(ctx, &pb.CreateUserRequest{
+		Firstname: payload.Firstname,
+		Lastname:  payload.Lastname,
+		Email:     payload.Email,
+		Password:  payload.Password,
+	})
 	if err != nil {
-		return app.errorJSON(w, "loginUserRequest", err, 500)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "createUserRequest", err, status)
 	}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "loginUserRequest", err, response.StatusCode)
+	var resp jsonResponse
+	resp.Error = false
+	resp.Message = "success"
+	resp.Data = newUserResponse(user)
+
+	return app.writeJSON(w, "createUserRequest", http.StatusCreated, resp)
+}
+
+func (app *Config) getUserRequest(w http.ResponseWriter, r *http.Request) error {
+	id := chi.URLParam(r, "user_id")
+	idInHeader := r.Context().Value("user_id")
+	if id != idInHeader {
+		return app.errorJSON(w, "getUserRequest", errors.New("this is not yours"), 403)
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	ctx, cancel := app.rpcContext(r)
+	defer cancel()
 
-	var jsonResponseBody any
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	user, err := app.users.GetUser(ctx, &pb.GetUserRequest{UserId: id})
 	if err != nil {
-		return app.errorJSON(w, "loginUserRequest", errors.New("error reading response body"), response.StatusCode)
+		status, err := rpcStatus(err)
+		return app.errorJSON(w, "getUserRequest", err, status)
 	}
 
 	var resp jsonResponse
 	resp.Error = false
-	resp.Data = jsonResponseBody
+	resp.Message = "success"
+	resp.Data = newUserResponse(user)
 
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
-	}
+	return app.writeJSON(w, "getUserRequest", http.StatusOK, resp)
+}
 
-	return app.writeJSON(w, "loginUserRequest", response.StatusCode, resp)
+type ChangePasswordPayload struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
-type CreateUserPayload struct {
-	Firstname string `json:"firstname" binding:"required"`
-	Lastname  string `json:"lastname" binding:"required"`
-	Email     string `json:"email" binding:"required"`
-	Password  string `json:"password" binding:"required"`
+type ForgotPasswordPayload struct {
+	Email string `json:"email" binding:"required"`
 }
 
-func (app *Config) createUserRequest(w http.ResponseWriter, payload CreateUserPayload) error {
-	jsonData, _ := json.Marshal(payload)
+type ResetPasswordPayload struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
 
-	reqURL := fmt.Sprintf("%s/users/create", userServiceURL)
-	request, err := http.NewRequest(http.MethodPost, reqURL, bytes.NewBuffer(jsonData))
-	if err != nil {
-		return app.errorJSON(w, "createUserRequest", err, 500)
-	}
+type VerifyEmailPayload struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type TwoFactorPayload struct {
+	Code     string `json:"code"`
+	Password string `json:"password,omitempty"`
+}
+
+type LoginTwoFactorPayload struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
+// verifyOTP asks user-service to check a fresh one-time password of the user the request is authenticated as
+func (app *Config) verifyOTP(r *http.Request, code string) (int, error) {
+	err := app.userService.VerifyOTP(r.Context(), r.Header.Get("Authorization"), code)
 	if err != nil {
-		return app.errorJSON(w, "createUserRequest", err, response.StatusCode)
+		return client.StatusCode(err), err
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	return http.StatusOK, nil
+}
+
+// forwardUserRequest sends the payload with the given method to a user-service path. The Authorization header of
+// the incoming request is passed along, so that user-service can identify the logged-in user.
+func (app *Config) forwardUserRequest(w http.ResponseWriter, r *http.Request, name, method, path string, payload any) error {
+	response, err := app.userService.Forward(r.Context(), method, path, r.Header.Get("Authorization"), clientIP(r), payload)
+	if response == nil {
+		return app.errorJSON(w, name, err, client.StatusCode(err))
+	}
 
-	var jsonResponseBody any // TODO change any to proper struct
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
+	var jsonResponseBody any
+	err = json.Unmarshal(response.Body, &jsonResponseBody)
 	if err != nil {
-		return app.errorJSON(w, "createUserRequest", errors.New("error reading response body"), response.StatusCode)
+		return app.errorJSON(w, name, errors.New("error reading response body"), http.StatusBadGateway)
 	}
 
 	var resp jsonResponse
@@ -131,46 +205,71 @@ func (app *Config) createUserRequest(w http.ResponseWriter, payload CreateUserPa
 		resp.Message = "success"
 	}
 
-	return app.writeJSON(w, "createUserRequest", response.StatusCode, resp)
+	return app.writeJSON(w, name, response.StatusCode, resp, retryAfterHeader(response.Header))
 }
 
-func (app *Config) getUserRequest(w http.ResponseWriter, r *http.Request) error {
-	id := chi.URLParam(r, "user_id")
-	idInHeader := r.Context().Value("user_id")
-	if id != idInHeader {
-		return app.errorJSON(w, "getUserRequest", errors.New("this is not yours"), 403)
+// userRoute forwards a REST request to a user-service path. The JSON body is decoded into the value returned by
+// payload, so that only known fields are passed on; routes without a body pass nil. Route parameters such as
+// {user_id} in path are filled in from the request, and the query string is passed along.
+func (app *Config) userRoute(name, method, path string, payload func() any) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var body any = struct{}{}
+		if payload != nil {
+			body = payload()
+			if err := app.readJSON(w, r, body); err != nil {
+				app.errorJSON(w, name, err, http.StatusBadRequest)
+				return
+			}
+		}
+
+		path := expandPath(r, path)
+		if r.URL.RawQuery != "" {
+			path = fmt.Sprintf("%s?%s", path, r.URL.RawQuery)
+		}
+		app.forwardUserRequest(w, r, name, method, path, body)
 	}
+}
 
-	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://user-service/users/%s", id), strings.NewReader(""))
-	if err != nil {
-		return app.errorJSON(w, "getUserRequest", err, 500)
+// expandPath replaces the {param} placeholders of path with the escaped route parameters of the request
+func expandPath(r *http.Request, path string) string {
+	rctx := chi.RouteContext(r.Context())
+	if rctx == nil {
+		return path
+	}
+	for i, key := range rctx.URLParams.Keys {
+		path = strings.ReplaceAll(path, fmt.Sprintf("{%s}", key), url.PathEscape(rctx.URLParams.Values[i]))
 	}
+	return path
+}
 
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		return app.errorJSON(w, "getUserRequest", err, response.StatusCode)
+// CreateUser registers a new user
+func (app *Config) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload CreateUserPayload
+	if err := app.readJSON(w, r, &payload); err != nil {
+		app.errorJSON(w, "CreateUser", err, http.StatusBadRequest)
+		return
 	}
-	defer response.Body.Close()
 
-	response.Body = http.MaxBytesReader(w, response.Body, int64(maxBytes))
+	app.createUserRequest(w, r, payload)
+}
 
-	var jsonResponseBody any // TODO change any to proper struct
-	decoder := json.NewDecoder(response.Body)
-	err = decoder.Decode(&jsonResponseBody)
-	if err != nil {
-		return app.errorJSON(w, "getUserRequest", errors.New("error reading response body"), response.StatusCode)
-	}
+// GetUser returns a user, which has to be the logged-in user
+func (app *Config) GetUser(w http.ResponseWriter, r *http.Request) {
+	app.getUserRequest(w, r)
+}
 
-	var resp jsonResponse
-	resp.Error = false
-	resp.Data = jsonResponseBody
+// ExportUser returns the data export of the logged-in user
+func (app *Config) ExportUser(w http.ResponseWriter, r *http.Request) {
+	app.exportUserRequest(w, r)
+}
 
-	if response.StatusCode != http.StatusOK {
-		resp.Message = "fail"
-	} else {
-		resp.Message = "success"
+// EraseUser removes the personal data of the logged-in user after confirming their password
+func (app *Config) EraseUser(w http.ResponseWriter, r *http.Request) {
+	var payload ConfirmPasswordPayload
+	if err := app.readJSON(w, r, &payload); err != nil {
+		app.errorJSON(w, "EraseUser", err, http.StatusBadRequest)
+		return
 	}
 
-	return app.writeJSON(w, "getUserRequest", response.StatusCode, resp)
+	app.eraseUserRequest(w, r, payload)
 }
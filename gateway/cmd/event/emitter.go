@@ -1,6 +1,10 @@
 package event
 
 import (
+	"context"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	amqp "github.com/rabbitmq/amqp091-go"
 	"log"
 )
@@ -19,7 +23,9 @@ func (e *Emitter) setup() error {
 	return declareExchange(channel)
 }
 
-func (e *Emitter) Push(event string, severity string) error {
+// Push publishes event with the severity as its routing key. The id of the request ctx belongs to is sent in the
+// x-request-id header, unless it is empty, and the trace context of ctx in the traceparent header.
+func (e *Emitter) Push(ctx context.Context, event string, severity string) error {
 	channel, err := e.connection.Channel()
 	if err != nil {
 		return err
@@ -28,10 +34,19 @@ func (e *Emitter) Push(event string, severity string) error {
 
 	log.Println("pushing to channel...")
 
-	err = channel.Publish("log_topic", severity, false, false, amqp.Publishing{
-		Type: "text/plain",
-		Body: []byte(event),
-	})
+	publishing := amqp.Publishing{
+		Type:    "text/plain",
+		Body:    []byte(event),
+		Headers: amqp.Table{},
+	}
+	if requestID := requestid.FromContext(ctx); requestID != "" {
+		publishing.Headers[requestid.Key] = requestID
+	}
+
+	_, span := tracing.StartPublish(ctx, publishing.Headers, "logs_topic", severity)
+	err = channel.Publish("logs_topic", severity, false, false, publishing)
+	tracing.End(span, err)
+	metrics.ObservePublish("logs_topic", severity, err)
 	if err != nil {
 		return err
 	}
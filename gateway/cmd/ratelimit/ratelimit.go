@@ -0,0 +1,176 @@
+// Package ratelimit limits how often clients may call the gateway, with a token bucket per client. A bucket holds up
+// to a number of tokens and is refilled at a steady rate; every request takes a token and is rejected when none is
+// left, so that clients may send bursts but not exceed the rate over time.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket of Burst tokens, which is refilled with Burst tokens every Period
+type Limit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParseLimit parses a limit such as "300/m", which allows 300 requests a minute that may all come at once. The period
+// is s, m, h or a duration such as 10s. An empty limit or "off" allows every request.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "off" {
+		return Limit{}, nil
+	}
+
+	count, period, found := strings.Cut(s, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("limit %q must be requests per period, such as 300/m", s)
+	}
+
+	burst, err := strconv.Atoi(count)
+	if err != nil || burst < 1 {
+		return Limit{}, fmt.Errorf("limit %q must allow a positive number of requests", s)
+	}
+
+	var d time.Duration
+	switch period {
+	case "s":
+		d = time.Second
+	case "m":
+		d = time.Minute
+	case "h":
+		d = time.Hour
+	default:
+		d, err = time.ParseDuration(period)
+		if err != nil || d <= 0 {
+			return Limit{}, fmt.Errorf("limit %q must have a period of s, m, h or a positive duration", s)
+		}
+	}
+
+	return Limit{Burst: burst, Period: d}, nil
+}
+
+// Enabled reports whether the limit rejects any request
+func (l Limit) Enabled() bool {
+	return l.Burst > 0 && l.Period > 0
+}
+
+// Policy describes the limit as in the RateLimit-Policy header, such as "300;w=60"
+func (l Limit) Policy() string {
+	return fmt.Sprintf("%d;w=%d", l.Burst, int(math.Ceil(l.Period.Seconds())))
+}
+
+// rate returns the tokens added to a bucket per second
+func (l Limit) rate() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
+
+// Result is the state of a bucket after a request took a token from it
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until a rejected request can be sent again
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets of the clients. The gateway uses a MemoryStore, a store shared by several gateway
+// instances can be plugged in to enforce limits across them.
+type Store interface {
+	// Take takes a token from the bucket of key, which is created full when it doesn't exist
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// ErrDisabled is returned when a token is taken with a limit that isn't enabled
+var ErrDisabled = errors.New("limit is disabled")
+
+// sweepInterval is how often a MemoryStore drops the buckets that are full again
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets in memory, which limits the clients of a single gateway instance
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// full is when the bucket is refilled completely, after which it is the same as a new one
+	full time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:       time.Now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take takes a token from the bucket of key
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	if !limit.Enabled() {
+		return Result{}, ErrDisabled
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	rate := limit.rate()
+	capacity := float64(limit.Burst)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, updated: now}
+		s.buckets[key] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*rate)
+	b.updated = now
+
+	var result Result
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((capacity - b.tokens) / rate)
+	b.full = now.Add(result.Reset)
+
+	return result, nil
+}
+
+// sweep drops the buckets that are full again, so that clients that went away don't take up memory
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
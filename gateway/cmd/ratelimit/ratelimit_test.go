@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		value string
+		limit Limit
+		err   bool
+	}{
+		{value: "300/m", limit: Limit{Burst: 300, Period: time.Minute}},
+		{value: "5/s", limit: Limit{Burst: 5, Period: time.Second}},
+		{value: "1000/h", limit: Limit{Burst: 1000, Period: time.Hour}},
+		{value: "10/30s", limit: Limit{Burst: 10, Period: 30 * time.Second}},
+		{value: "off", limit: Limit{}},
+		{value: "", limit: Limit{}},
+		{value: "300", err: true},
+		{value: "0/m", err: true},
+		{value: "10/week", err: true},
+		{value: "10/-1s", err: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.value, func(t *testing.T) {
+			limit, err := ParseLimit(tc.value)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.limit, limit)
+		})
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	limit := Limit{Burst: 3, Period: 3 * time.Second}
+
+	// the burst is allowed at once
+	for i := 2; i >= 0; i-- {
+		result, err := store.Take(ctx, "ip:10.0.0.1", limit)
+		require.NoError(t, err)
+		require.True(t, result.Allowed)
+		require.Equal(t, i, result.Remaining)
+	}
+
+	result, err := store.Take(ctx, "ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	require.False(t, result.Allowed)
+	require.Equal(t, time.Second, result.RetryAfter)
+	require.Equal(t, 3*time.Second, result.Reset)
+
+	// other keys have buckets of their own
+	result, err = store.Take(ctx, "ip:10.0.0.2", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+
+	// a token is added every second
+	now = now.Add(time.Second)
+	result, err = store.Take(ctx, "ip:10.0.0.1", limit)
+	require.NoError(t, err)
+	require.True(t, result.Allowed)
+	require.Zero(t, result.Remaining)
+
+	// full buckets are dropped
+	now = now.Add(sweepInterval)
+	_, err = store.Take(ctx, "ip:10.0.0.3", limit)
+	require.NoError(t, err)
+	require.Len(t, store.buckets, 1)
+
+	_, err = store.Take(ctx, "ip:10.0.0.3", Limit{})
+	require.ErrorIs(t, err, ErrDisabled)
+}
@@ -0,0 +1,143 @@
+package resilience
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// Closed lets every call through
+	Closed State = iota
+	// Open rejects every call until the open timeout has passed
+	Open
+	// HalfOpen lets a few probe calls through to find out whether the upstream has recovered
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// MarshalText encodes the state by its name, as in "half-open"
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Snapshot is the state of the circuit breaker of an upstream at a point in time
+type Snapshot struct {
+	Upstream            string     `json:"upstream"`
+	State               State      `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+}
+
+// breaker opens after failureThreshold consecutive failures and rejects calls for openTimeout. It then lets
+// halfOpenProbes calls through, and closes again once all of them succeeded. A failed probe opens it again.
+type breaker struct {
+	name             string
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	now              func() time.Time
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	probes    int
+	successes int
+	openedAt  time.Time
+}
+
+// allow reports whether a call may go through. When it may not, the time until the breaker lets probes through is
+// returned.
+func (b *breaker) allow() (bool, time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open {
+		wait := b.openedAt.Add(b.openTimeout).Sub(b.now())
+		if wait > 0 {
+			return false, wait
+		}
+		b.setState(HalfOpen)
+	}
+
+	if b.state == HalfOpen {
+		if b.probes+b.successes >= b.halfOpenProbes {
+			// the probes are in flight, the others wait for their outcome
+			return false, time.Second
+		}
+		b.probes++
+	}
+
+	return true, 0
+}
+
+// record counts the outcome of a call that was allowed through
+func (b *breaker) record(outcome Outcome) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+
+	switch outcome {
+	case Success:
+		b.failures = 0
+		if b.state == HalfOpen {
+			b.successes++
+			if b.successes >= b.halfOpenProbes {
+				b.setState(Closed)
+			}
+		}
+	case Failure, RetryableFailure:
+		b.failures++
+		if b.state == HalfOpen || b.state == Closed && b.failures >= b.failureThreshold {
+			b.openedAt = b.now()
+			b.setState(Open)
+		}
+	}
+}
+
+func (b *breaker) setState(state State) {
+	if state == b.state {
+		return
+	}
+	log.Printf("circuit breaker of %s is %s, was %s", b.name, state, b.state)
+
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+	if state == Closed {
+		b.failures = 0
+	}
+}
+
+func (b *breaker) snapshot() Snapshot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	snapshot := Snapshot{
+		Upstream:            b.name,
+		State:               b.state,
+		ConsecutiveFailures: b.failures,
+	}
+	if b.state != Closed {
+		openedAt := b.openedAt
+		snapshot.OpenedAt = &openedAt
+	}
+	return snapshot
+}
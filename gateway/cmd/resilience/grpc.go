@@ -0,0 +1,37 @@
+package resilience
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryClientInterceptor makes the unary gRPC calls of a connection through the upstream. Methods for which
+// idempotent returns true are retried. Calls rejected by the circuit breaker fail with codes.Unavailable.
+func (u *Upstream) UnaryClientInterceptor(idempotent func(method string) bool) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return u.Do(ctx, idempotent(method), classifyRPC, func(ctx context.Context) error {
+			ctx, cancel := u.attemptContext(ctx)
+			defer cancel()
+
+			return invoker(ctx, method, req, reply, cc, opts...)
+		})
+	}
+}
+
+// classifyRPC counts the errors the upstream is responsible for as failures. Errors about the call itself, such as
+// NotFound or InvalidArgument, are answers of a healthy upstream.
+func classifyRPC(err error) Outcome {
+	switch status.Code(err) {
+	case codes.OK:
+		return Success
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return RetryableFailure
+	case codes.Internal, codes.Unknown, codes.DataLoss:
+		return Failure
+	default:
+		return Success
+	}
+}
@@ -0,0 +1,166 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Transport makes the requests of an http.Client through the upstream, using base to send them. Requests with an
+// idempotent method are retried when their body can be sent again. A response with a 5xx status counts as a failure
+// of the upstream but is returned as it is once no retry is left.
+//
+// Requests rejected by the circuit breaker get a 503 response with a Retry-After header, as a proxy in front of the
+// upstream would send, so that callers handle both alike.
+func (u *Upstream) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{upstream: u, base: base}
+}
+
+type transport struct {
+	upstream *Upstream
+	base     http.RoundTripper
+}
+
+// statusError carries a 5xx response between the attempts of a request
+type statusError struct {
+	response *http.Response
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("upstream responded with status %d", e.response.StatusCode)
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+	idempotent := isIdempotent(req.Method) && replayable
+
+	var response *http.Response
+	attempts := 0
+	err := t.upstream.Do(req.Context(), idempotent, classifyHTTP, func(ctx context.Context) error {
+		if response != nil {
+			// the response of the failed attempt before is replaced
+			response.Body.Close()
+			response = nil
+		}
+
+		attemptReq := req.WithContext(ctx)
+		if attempts > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return err
+			}
+			attemptReq.Body = body
+		}
+		attempts++
+
+		ctx, cancel := t.upstream.attemptContext(ctx)
+		attemptReq = attemptReq.WithContext(ctx)
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			cancel()
+			return err
+		}
+
+		// the attempt ends when the caller is done reading the body
+		resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+		response = resp
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return &statusError{response: resp}
+		}
+		return nil
+	})
+
+	var open *OpenError
+	var statusErr *statusError
+	switch {
+	case errors.As(err, &open):
+		if response != nil {
+			response.Body.Close()
+		}
+		return open.response(req), nil
+	case errors.As(err, &statusErr):
+		return statusErr.response, nil
+	case err != nil:
+		return nil, err
+	}
+	return response, nil
+}
+
+// classifyHTTP counts errors of the transport and 5xx statuses as failures. Only the ones that mean the upstream
+// couldn't be reached or answered in time are worth another attempt.
+func classifyHTTP(err error) Outcome {
+	if err == nil {
+		return Success
+	}
+
+	var statusErr *statusError
+	if !errors.As(err, &statusErr) {
+		return RetryableFailure
+	}
+	switch statusErr.response.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return RetryableFailure
+	default:
+		return Failure
+	}
+}
+
+// isIdempotent reports whether sending a request with the method twice has the same effect as sending it once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// response builds the 503 response for a request rejected by the breaker
+func (e *OpenError) response(req *http.Request) *http.Response {
+	body := fmt.Sprintf(`{"error":%q}`, e.Error())
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Retry-After", RetryAfterSeconds(e.RetryAfter))
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)),
+		StatusCode:    http.StatusServiceUnavailable,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+// RetryAfterSeconds formats d as the value of a Retry-After header, rounded up to whole seconds
+func RetryAfterSeconds(d time.Duration) string {
+	seconds := int(math.Ceil(d.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.Itoa(seconds)
+}
+
+// cancelBody releases the context of an attempt once its response body is closed
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
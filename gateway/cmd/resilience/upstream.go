@@ -0,0 +1,182 @@
+// Package resilience guards the calls of the gateway to the backend services. Every backend is an Upstream with its
+// own timeout, retries and circuit breaker, so that a service that hangs or fails doesn't tie up the gateway, and
+// clients get a 503 or 504 response they can retry on instead of waiting.
+package resilience
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrOpen is matched by the errors of calls rejected by an open circuit breaker, as in errors.Is(err, ErrOpen)
+var ErrOpen = errors.New("circuit breaker is open")
+
+// OpenError is the error of a call rejected by the circuit breaker of an upstream
+type OpenError struct {
+	Upstream string
+	// RetryAfter is the time until the breaker lets calls through again
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s is unavailable", e.Upstream)
+}
+
+func (e *OpenError) Unwrap() error {
+	return ErrOpen
+}
+
+// GRPCStatus reports a rejected gRPC call as unavailable
+func (e *OpenError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+// Outcome is how an attempt of a call went, from the point of view of the upstream's health
+type Outcome int
+
+const (
+	// Success means that the upstream handled the call, whatever its answer was
+	Success Outcome = iota
+	// Failure means that the upstream failed to handle the call
+	Failure
+	// RetryableFailure is a Failure that another attempt may not run into, such as a timeout
+	RetryableFailure
+	// Ignored means that the call was abandoned by the caller, which says nothing about the upstream
+	Ignored
+)
+
+// Policy configures the calls to an upstream
+type Policy struct {
+	// Timeout limits a single attempt of a call, 0 leaves it to the context of the call
+	Timeout time.Duration
+	// Retries is the number of attempts made after the first one, for idempotent calls only
+	Retries int
+	// Backoff is the wait before the first retry, which doubles with every retry up to MaxBackoff. Half of each
+	// wait is random, so that the retries of many calls don't hit the upstream at once.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// FailureThreshold is the number of consecutive failures that opens the circuit breaker
+	FailureThreshold int
+	// OpenTimeout is the time an open breaker rejects calls before it lets probes through
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of probe calls that have to succeed to close the breaker again
+	HalfOpenProbes int
+}
+
+// Upstream is a backend service the gateway calls
+type Upstream struct {
+	name    string
+	policy  Policy
+	breaker *breaker
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+// NewUpstream creates the upstream with the given name, such as "account-service"
+func NewUpstream(name string, policy Policy) *Upstream {
+	if policy.FailureThreshold < 1 {
+		policy.FailureThreshold = 1
+	}
+	if policy.HalfOpenProbes < 1 {
+		policy.HalfOpenProbes = 1
+	}
+
+	return &Upstream{
+		name:   name,
+		policy: policy,
+		breaker: &breaker{
+			name:             name,
+			failureThreshold: policy.FailureThreshold,
+			openTimeout:      policy.OpenTimeout,
+			halfOpenProbes:   policy.HalfOpenProbes,
+			now:              time.Now,
+		},
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Name returns the name of the upstream
+func (u *Upstream) Name() string {
+	return u.name
+}
+
+// Snapshot returns the state of the circuit breaker of the upstream
+func (u *Upstream) Snapshot() Snapshot {
+	return u.breaker.snapshot()
+}
+
+// Do makes a call to the upstream, where attempt sends the call once and classify tells how an attempt went. Failed
+// attempts of idempotent calls are retried. Calls rejected by the circuit breaker return an *OpenError without
+// reaching the upstream.
+func (u *Upstream) Do(ctx context.Context, idempotent bool, classify func(error) Outcome, attempt func(context.Context) error) error {
+	for retry := 0; ; retry++ {
+		allowed, retryAfter := u.breaker.allow()
+		if !allowed {
+			return &OpenError{Upstream: u.name, RetryAfter: retryAfter}
+		}
+
+		err := attempt(ctx)
+
+		outcome := classify(err)
+		if err != nil && errors.Is(ctx.Err(), context.Canceled) {
+			outcome = Ignored
+		}
+		u.breaker.record(outcome)
+
+		if outcome != RetryableFailure || !idempotent || retry >= u.policy.Retries {
+			return err
+		}
+		if !u.wait(ctx, u.backoff(retry)) {
+			return err
+		}
+	}
+}
+
+// attemptContext limits an attempt to the timeout of the policy
+func (u *Upstream) attemptContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if u.policy.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, u.policy.Timeout)
+}
+
+// backoff returns the wait before the given retry, counted from 0
+func (u *Upstream) backoff(retry int) time.Duration {
+	backoff := u.policy.Backoff << retry
+	if backoff > u.policy.MaxBackoff && u.policy.MaxBackoff > 0 || backoff <= 0 {
+		backoff = u.policy.MaxBackoff
+	}
+	if backoff < 2 {
+		return backoff
+	}
+
+	u.randMu.Lock()
+	defer u.randMu.Unlock()
+	return backoff/2 + time.Duration(u.rand.Int63n(int64(backoff/2)))
+}
+
+// wait sleeps for d, unless the context is done or its deadline is closer than d, in which case a retry would be in
+// vain and false is returned
+func (u *Upstream) wait(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= d {
+		return false
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
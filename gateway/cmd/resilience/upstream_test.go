@@ -0,0 +1,150 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func testPolicy() Policy {
+	return Policy{
+		Timeout:          time.Second,
+		Retries:          2,
+		Backoff:          time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		FailureThreshold: 3,
+		OpenTimeout:      time.Minute,
+		HalfOpenProbes:   1,
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	unavailable := status.Error(codes.Unavailable, "connection refused")
+
+	testCases := []struct {
+		name       string
+		idempotent bool
+		err        error
+		attempts   int
+	}{
+		{name: "Idempotent", idempotent: true, err: unavailable, attempts: 3},
+		{name: "NotIdempotent", idempotent: false, err: unavailable, attempts: 1},
+		{name: "NotRetryable", idempotent: true, err: status.Error(codes.NotFound, "account not found"), attempts: 1},
+		{name: "OK", idempotent: true, err: nil, attempts: 1},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			upstream := NewUpstream("account-service", testPolicy())
+
+			attempts := 0
+			err := upstream.Do(context.Background(), tc.idempotent, classifyRPC, func(ctx context.Context) error {
+				attempts++
+				return tc.err
+			})
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.attempts, attempts)
+		})
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	upstream := NewUpstream("account-service", testPolicy())
+	now := time.Now()
+	upstream.breaker.now = func() time.Time { return now }
+
+	failing := func(ctx context.Context) error { return status.Error(codes.Internal, "database is down") }
+	for i := 0; i < 3; i++ {
+		require.Error(t, upstream.Do(context.Background(), false, classifyRPC, failing))
+	}
+	require.Equal(t, Open, upstream.Snapshot().State)
+
+	err := upstream.Do(context.Background(), false, classifyRPC, func(ctx context.Context) error {
+		t.Fatal("an open breaker let a call through")
+		return nil
+	})
+	var open *OpenError
+	require.ErrorAs(t, err, &open)
+	require.ErrorIs(t, err, ErrOpen)
+	require.Equal(t, time.Minute, open.RetryAfter)
+	require.Equal(t, codes.Unavailable, status.Code(err))
+
+	// after the open timeout a failed probe opens the breaker again, a successful one closes it
+	now = now.Add(time.Minute)
+	require.Error(t, upstream.Do(context.Background(), false, classifyRPC, failing))
+	require.Equal(t, Open, upstream.Snapshot().State)
+
+	now = now.Add(time.Minute)
+	require.NoError(t, upstream.Do(context.Background(), false, classifyRPC, func(ctx context.Context) error { return nil }))
+	require.Equal(t, Closed, upstream.Snapshot().State)
+	require.Zero(t, upstream.Snapshot().ConsecutiveFailures)
+}
+
+func TestBreakerIgnoresCanceledCalls(t *testing.T) {
+	upstream := NewUpstream("account-service", testPolicy())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for i := 0; i < 5; i++ {
+		err := upstream.Do(ctx, true, classifyRPC, func(ctx context.Context) error { return status.FromContextError(ctx.Err()).Err() })
+		require.Error(t, err)
+	}
+	require.Equal(t, Closed, upstream.Snapshot().State)
+}
+
+func TestTransport(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	upstream := NewUpstream("user-service-http", testPolicy())
+	httpClient := &http.Client{Transport: upstream.Transport(nil)}
+
+	// a GET request is retried, a POST request isn't
+	response, err := httpClient.Get(server.URL)
+	require.NoError(t, err)
+	response.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	require.EqualValues(t, 3, atomic.LoadInt32(&requests))
+
+	// the failures of the GET request opened the breaker, which answers itself
+	response, err = httpClient.Post(server.URL, "application/json", nil)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.EqualValues(t, 3, atomic.LoadInt32(&requests))
+	require.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	require.Equal(t, "60", response.Header.Get("Retry-After"))
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"error":"user-service-http is unavailable"}`, string(body))
+}
+
+func TestTransportTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	policy := testPolicy()
+	policy.Timeout = 10 * time.Millisecond
+	policy.Retries = 0
+	httpClient := &http.Client{Transport: NewUpstream("logger-service", policy).Transport(nil)}
+
+	_, err := httpClient.Get(server.URL)
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
@@ -3,12 +3,79 @@ module github.com/bugrakocabay/dummy-bank-microservice/gateway
 go 1.19
 
 require (
-	github.com/go-chi/chi/v5 v5.0.8 // indirect
-	github.com/go-chi/cors v1.2.1 // indirect
-	github.com/golang/mock v1.6.0 // indirect
-	github.com/rabbitmq/amqp091-go v1.8.0 // indirect
-	golang.org/x/mod v0.4.2 // indirect
-	golang.org/x/sys v0.0.0-20210510120138-977fb7262007 // indirect
-	golang.org/x/tools v0.1.1 // indirect
-	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1 // indirect
+	github.com/bugrakocabay/dummy-bank-microservice/client v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/config v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/health v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/logging v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/logship v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/metrics v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/proto v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/requestid v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/shutdown v0.0.0
+	github.com/bugrakocabay/dummy-bank-microservice/tracing v0.0.0
+	github.com/go-chi/chi/v5 v5.0.8
+	github.com/rabbitmq/amqp091-go v1.8.0
+	github.com/stretchr/testify v1.8.2
+	golang.org/x/exp v0.0.0-20230321023759-10a507213a29
+	google.golang.org/grpc v1.53.0
+)
+
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cenkalti/backoff/v4 v4.2.0 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/felixge/httpsnoop v1.0.3 // indirect
+	github.com/fsnotify/fsnotify v1.6.0 // indirect
+	github.com/go-logr/logr v1.2.3 // indirect
+	github.com/go-logr/stdr v1.2.2 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.7.0 // indirect
+	github.com/hashicorp/hcl v1.0.0 // indirect
+	github.com/magiconair/properties v1.8.7 // indirect
+	github.com/matttproud/golang_protobuf_extensions v1.0.1 // indirect
+	github.com/mitchellh/mapstructure v1.5.0 // indirect
+	github.com/pelletier/go-toml/v2 v2.0.6 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/prometheus/client_golang v1.14.0 // indirect
+	github.com/prometheus/client_model v0.3.0 // indirect
+	github.com/prometheus/common v0.37.0 // indirect
+	github.com/prometheus/procfs v0.8.0 // indirect
+	github.com/spf13/afero v1.9.3 // indirect
+	github.com/spf13/cast v1.5.0 // indirect
+	github.com/spf13/jwalterweatherman v1.1.0 // indirect
+	github.com/spf13/pflag v1.0.5 // indirect
+	github.com/spf13/viper v1.15.0 // indirect
+	github.com/subosito/gotenv v1.4.2 // indirect
+	go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc v0.40.0 // indirect
+	go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp v0.40.0 // indirect
+	go.opentelemetry.io/otel v1.14.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/internal/retry v1.14.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.14.0 // indirect
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.14.0 // indirect
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.14.0 // indirect
+	go.opentelemetry.io/otel/metric v0.37.0 // indirect
+	go.opentelemetry.io/otel/sdk v1.14.0 // indirect
+	go.opentelemetry.io/otel/trace v1.14.0 // indirect
+	go.opentelemetry.io/proto/otlp v0.19.0 // indirect
+	golang.org/x/net v0.7.0 // indirect
+	golang.org/x/sys v0.5.0 // indirect
+	golang.org/x/text v0.7.0 // indirect
+	google.golang.org/genproto v0.0.0-20230110181048-76db0878b65f // indirect
+	google.golang.org/protobuf v1.28.1 // indirect
+	gopkg.in/ini.v1 v1.67.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace (
+	github.com/bugrakocabay/dummy-bank-microservice/client => ../client
+	github.com/bugrakocabay/dummy-bank-microservice/config => ../config
+	github.com/bugrakocabay/dummy-bank-microservice/health => ../health
+	github.com/bugrakocabay/dummy-bank-microservice/logging => ../logging
+	github.com/bugrakocabay/dummy-bank-microservice/logship => ../logship
+	github.com/bugrakocabay/dummy-bank-microservice/metrics => ../metrics
+	github.com/bugrakocabay/dummy-bank-microservice/proto => ../proto
+	github.com/bugrakocabay/dummy-bank-microservice/requestid => ../requestid
+	github.com/bugrakocabay/dummy-bank-microservice/shutdown => ../shutdown
+	github.com/bugrakocabay/dummy-bank-microservice/tracing => ../tracing
 )
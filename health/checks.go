@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// DB checks that a connection to db can be established, which sql.Open doesn't do
+func DB(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// GRPC checks an upstream with the standard gRPC health service, which every gRPC server registers with RegisterGRPC
+func GRPC(conn grpc.ClientConnInterface) Check {
+	client := healthpb.NewHealthClient(conn)
+	return func(ctx context.Context) error {
+		resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			return err
+		}
+		if resp.GetStatus() != healthpb.HealthCheckResponse_SERVING {
+			return fmt.Errorf("upstream is %s", resp.GetStatus())
+		}
+		return nil
+	}
+}
+
+// RegisterGRPC registers the standard gRPC health service on server, reporting it as serving
+func RegisterGRPC(server grpc.ServiceRegistrar) {
+	healthpb.RegisterHealthServer(server, grpchealth.NewServer())
+}
+
+// HTTP checks that url answers a GET request with a 2xx status
+func HTTP(client *http.Client, url string) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		_, _ = io.Copy(io.Discard, resp.Body)
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s responded with status %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+// Fetch gets the report of the service whose /readyz is at url. A service that is not ready still reports which of
+// its dependencies are down; a service that can't be reached is reported down with the error.
+func Fetch(ctx context.Context, client *http.Client, url string) Report {
+	down := func(err error) Report {
+		return Report{Status: StatusDown, Checks: map[string]Result{"reachable": {Status: StatusDown, Error: err.Error()}}}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*CheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return down(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return down(err)
+	}
+	defer resp.Body.Close()
+
+	var report Report
+	if err = json.NewDecoder(resp.Body).Decode(&report); err != nil || report.Status == "" {
+		return down(fmt.Errorf("%s responded with status %d and no report", url, resp.StatusCode))
+	}
+	return report
+}
@@ -0,0 +1,122 @@
+// Package health reports whether a dummy bank service is alive and whether it is ready to serve requests. Every
+// service serves LiveHandler at /healthz, which only tells that the process responds, and the ReadyHandler of a
+// Checker at /readyz, which checks the dependencies the service can't work without, such as its database, the
+// message broker and the upstream services it calls.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CheckTimeout is how long a single check may take before its dependency is reported down
+const CheckTimeout = 2 * time.Second
+
+// Statuses of a check and of a report
+const (
+	StatusOK   = "ok"
+	StatusDown = "down"
+)
+
+// Check checks a dependency, which is down when it returns an error
+type Check func(ctx context.Context) error
+
+// Result is the outcome of a check
+type Result struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// Report is the outcome of the checks of a service. Its status is ok only when every check is.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker runs the checks of the dependencies of a service
+type Checker struct {
+	names  []string
+	checks map[string]Check
+}
+
+// NewChecker creates a Checker without checks, which is always ready
+func NewChecker() *Checker {
+	return &Checker{checks: make(map[string]Check)}
+}
+
+// Add adds the check of the dependency name, replacing an earlier one of the same name
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+		sort.Strings(c.names)
+	}
+	c.checks[name] = check
+}
+
+// Run runs the checks concurrently, each with a timeout of CheckTimeout
+func (c *Checker) Run(ctx context.Context) Report {
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(c.names))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, name := range c.names {
+		name, check := name, c.checks[name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+			result := Result{Status: StatusOK, Duration: time.Since(start).Round(time.Millisecond).String()}
+			if err != nil {
+				result.Status = StatusDown
+				result.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[name] = result
+			if err != nil {
+				report.Status = StatusDown
+			}
+		}()
+	}
+	wg.Wait()
+
+	return report
+}
+
+// ReadyHandler responds with the report of the checks, with status 200 when the service is ready and 503 when a
+// dependency is down
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Run(r.Context())
+		status := http.StatusOK
+		if report.Status != StatusOK {
+			status = http.StatusServiceUnavailable
+		}
+		writeReport(w, status, report)
+	})
+}
+
+// LiveHandler responds with status 200 as long as the service can handle requests at all
+func LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, http.StatusOK, Report{Status: StatusOK})
+	})
+}
+
+func writeReport(w http.ResponseWriter, status int, report Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(report)
+}
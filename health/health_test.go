@@ -0,0 +1,95 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestReadyHandler(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("postgres", func(ctx context.Context) error { return nil })
+
+	w := httptest.NewRecorder()
+	checker.ReadyHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var report Report
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Equal(t, StatusOK, report.Status)
+	require.Equal(t, StatusOK, report.Checks["postgres"].Status)
+
+	checker.Add("rabbitmq", func(ctx context.Context) error { return errors.New("connection is closed") })
+
+	w = httptest.NewRecorder()
+	checker.ReadyHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, StatusOK, report.Checks["postgres"].Status)
+	require.Equal(t, Result{Status: StatusDown, Error: "connection is closed", Duration: report.Checks["rabbitmq"].Duration}, report.Checks["rabbitmq"])
+}
+
+func TestCheckTimeout(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := checker.Run(ctx)
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, context.Canceled.Error(), report.Checks["slow"].Error)
+}
+
+func TestHTTPAndFetch(t *testing.T) {
+	checker := NewChecker()
+	checker.Add("mongo", func(ctx context.Context) error { return errors.New("no reachable servers") })
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", LiveHandler())
+	mux.Handle("/readyz", checker.ReadyHandler())
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ctx := context.Background()
+	require.NoError(t, HTTP(server.Client(), server.URL+"/healthz")(ctx))
+	require.Error(t, HTTP(server.Client(), server.URL+"/readyz")(ctx))
+
+	report := Fetch(ctx, server.Client(), server.URL+"/readyz")
+	require.Equal(t, StatusDown, report.Status)
+	require.Equal(t, "no reachable servers", report.Checks["mongo"].Error)
+
+	report = Fetch(ctx, server.Client(), server.URL+"/missing")
+	require.Equal(t, StatusDown, report.Status)
+	require.Contains(t, report.Checks, "reachable")
+}
+
+func TestGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	RegisterGRPC(server)
+	go server.Serve(listener)
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, GRPC(conn)(context.Background()))
+
+	server.Stop()
+	require.Error(t, GRPC(conn)(context.Background()))
+}
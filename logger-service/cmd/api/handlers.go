@@ -1,15 +1,41 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
 	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 type JSONPayload struct {
-	Name string `json:"name"`
-	Data any    `json:"data"`
+	Name      string `json:"name"`
+	Data      any    `json:"data"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// logEntry builds the entry of a payload. The id of the request the log is about is taken from the payload, or
+// from the X-Request-ID header of senders that don't put it there.
+func logEntry(r *http.Request, payload JSONPayload) data.LogEntry {
+	requestID := payload.RequestID
+	if requestID == "" {
+		requestID = r.Header.Get(requestid.Header)
+	}
+	if !requestid.Valid(requestID) {
+		requestID = ""
+	}
+
+	return data.LogEntry{
+		Name:      payload.Name,
+		Data:      payload.Data,
+		RequestID: requestID,
+	}
 }
 
 func (app *Config) WriteErrorLog(w http.ResponseWriter, r *http.Request) {
@@ -21,42 +47,84 @@ func (app *Config) WriteErrorLog(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	event := data.LogEntry{
-		Name: requestPayload.Name,
-		Data: requestPayload.Data,
-	}
+	event := logEntry(r, requestPayload)
+	event.Kind = "error"
 
-	err = app.Models.LogEntry.InsertError(event)
+	app.writeLogs(w, r, []data.LogEntry{event}, false)
+}
+
+func (app *Config) WriteRequestLog(w http.ResponseWriter, r *http.Request) {
+	var requestPayload JSONPayload
+
+	err := app.readJSON(w, r, &requestPayload)
 	if err != nil {
 		app.errorJSON(w, err)
 		return
 	}
 
-	resp := jsonResponse{
-		Error:   false,
-		Message: "success",
-	}
+	event := logEntry(r, requestPayload)
+	event.Kind = "request"
 
-	app.writeJSON(w, http.StatusCreated, resp)
+	app.writeLogs(w, r, []data.LogEntry{event}, false)
 }
 
-func (app *Config) WriteRequestLog(w http.ResponseWriter, r *http.Request) {
-	var requestPayload JSONPayload
+// batchPayload is a log of a batch, whose kind tells whether it is an error or a request log
+type batchPayload struct {
+	JSONPayload
+	Kind string `json:"kind"`
+}
 
-	err := app.readJSON(w, r, &requestPayload)
+// WriteLogs stores a batch of error and request logs, sent as a JSON array or as NDJSON, one log per line
+func (app *Config) WriteLogs(w http.ResponseWriter, r *http.Request) {
+	payloads, err := app.readBatch(w, r)
 	if err != nil {
 		app.errorJSON(w, err)
 		return
 	}
 
-	event := data.LogEntry{
-		Name: requestPayload.Name,
-		Data: requestPayload.Data,
+	events := make([]data.LogEntry, len(payloads))
+	for i, payload := range payloads {
+		if payload.Kind != "error" && payload.Kind != "request" {
+			app.errorJSON(w, fmt.Errorf("log %d: invalid kind %q", i, payload.Kind))
+			return
+		}
+		events[i] = logEntry(r, payload.JSONPayload)
+		events[i].Kind = payload.Kind
 	}
 
-	err = app.Models.LogEntry.InsertRequest(event)
-	if err != nil {
-		app.errorJSON(w, err)
+	app.writeLogs(w, r, events, true)
+}
+
+// batchResult tells how many logs of a batch were stored, and the indexes of the ones that weren't, which are the
+// ones to send again
+type batchResult struct {
+	Stored int   `json:"stored"`
+	Failed []int `json:"failed,omitempty"`
+}
+
+// writeLogs stores events through the writer and responds with whether they were stored. Logs that can't be
+// stored right now are answered with 429 if too many logs are waiting, or 503 if mongo is slow or failing, and a
+// Retry-After header, so that the shippers of the services back off and send them again. A batch is answered with
+// the logs that were stored, as some logs of a batch can be stored while others fail.
+func (app *Config) writeLogs(w http.ResponseWriter, r *http.Request, events []data.LogEntry, batch bool) {
+	result := app.writer.Write(r.Context(), events)
+	if result.err != nil {
+		status := http.StatusServiceUnavailable
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(app.writer.timeout.Seconds()))))
+		if errors.Is(result.err, errWriterFull) {
+			status = http.StatusTooManyRequests
+			w.Header().Set("Retry-After", "1")
+		}
+
+		log.Println(result.err)
+		resp := jsonResponse{
+			Error:   true,
+			Message: result.err.Error(),
+		}
+		if batch {
+			resp.Data = batchResult{Stored: len(events) - len(result.failed), Failed: result.failed}
+		}
+		app.writeJSON(w, status, resp)
 		return
 	}
 
@@ -64,21 +132,41 @@ func (app *Config) WriteRequestLog(w http.ResponseWriter, r *http.Request) {
 		Error:   false,
 		Message: "success",
 	}
+	if batch {
+		resp.Data = batchResult{Stored: len(events)}
+	}
 
 	app.writeJSON(w, http.StatusCreated, resp)
 }
 
+// logsPage is a page of logs and the cursor of the next one, empty after the last page
+type logsPage struct {
+	Logs       []*data.LogEntry `json:"logs"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// ReadLogs returns a page of the error and request logs, newest first, filtered by the query parameters
 func (app *Config) ReadLogs(w http.ResponseWriter, r *http.Request) {
-	logs, err := app.Models.LogEntry.All()
+	query, err := logQuery(r)
 	if err != nil {
 		app.errorJSON(w, err)
 		return
 	}
 
+	logs, next, err := app.Models.LogEntry.Query(r.Context(), query)
+	if err != nil {
+		if errors.Is(err, data.ErrInvalidCursor) {
+			app.errorJSON(w, err)
+			return
+		}
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
 	resp := jsonResponse{
 		Error:   false,
 		Message: "success",
-		Data:    logs,
+		Data:    logsPage{Logs: logs, NextCursor: next},
 	}
 
 	app.writeJSON(w, http.StatusOK, resp)
@@ -89,6 +177,10 @@ func (app *Config) ReadOne(w http.ResponseWriter, r *http.Request) {
 
 	record, err := app.Models.LogEntry.GetOne(id)
 	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			app.errorJSON(w, err, http.StatusNotFound)
+			return
+		}
 		app.errorJSON(w, err)
 		return
 	}
@@ -101,3 +193,26 @@ func (app *Config) ReadOne(w http.ResponseWriter, r *http.Request) {
 
 	app.writeJSON(w, http.StatusOK, resp)
 }
+
+// ReadRequestLogs returns the error and request logs of all services about a single request
+func (app *Config) ReadRequestLogs(w http.ResponseWriter, r *http.Request) {
+	requestID := chi.URLParam(r, "request_id")
+	if !requestid.Valid(requestID) {
+		app.errorJSON(w, errors.New("invalid request id"))
+		return
+	}
+
+	logs, err := app.Models.LogEntry.ByRequest(requestID)
+	if err != nil {
+		app.errorJSON(w, err)
+		return
+	}
+
+	resp := jsonResponse{
+		Error:   false,
+		Message: "success",
+		Data:    logs,
+	}
+
+	app.writeJSON(w, http.StatusOK, resp)
+}
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
@@ -32,6 +34,44 @@ func (app *Config) readJSON(w http.ResponseWriter, r *http.Request, data any) er
 	return nil
 }
 
+// maxBatchLogs is the number of logs a batch may hold
+const maxBatchLogs = 1000
+
+// readBatch reads the logs of a batch, which is either a JSON array of logs or NDJSON, one log per line
+func (app *Config) readBatch(w http.ResponseWriter, r *http.Request) ([]batchPayload, error) {
+	maxBytes := 10485376 // 1mgb
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	payloads := []batchPayload{}
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err = json.Unmarshal(trimmed, &payloads); err != nil {
+			return nil, err
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		for dec.More() {
+			var payload batchPayload
+			if err = dec.Decode(&payload); err != nil {
+				return nil, fmt.Errorf("line %d: %w", len(payloads)+1, err)
+			}
+			payloads = append(payloads, payload)
+		}
+	}
+
+	if len(payloads) == 0 {
+		return nil, errors.New("batch must hold at least one log")
+	}
+	if len(payloads) > maxBatchLogs {
+		return nil, fmt.Errorf("batch must hold at most %d logs", maxBatchLogs)
+	}
+	return payloads, nil
+}
+
 func (app *Config) writeJSON(w http.ResponseWriter, status int, data any, headers ...http.Header) error {
 	out, err := json.Marshal(data)
 	if err != nil {
@@ -1,23 +1,107 @@
 package main
 
 import (
-	"github.com/spf13/viper"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/config"
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"golang.org/x/exp/slog"
 )
 
 type EnvConfig struct {
-	MongoURI string `mapstructure:"MONGO_URI"`
-	Username string `mapstructure:"MONGO_USERNAME"`
-	Password string `mapstructure:"MONGO_PASSWORD"`
+	MongoURI        string        `mapstructure:"MONGO_URI" validate:"required,url" usage:"connection URI of the mongo database"`
+	Username        string        `mapstructure:"MONGO_USERNAME" usage:"user to authenticate with mongo"`
+	Password        string        `mapstructure:"MONGO_PASSWORD" usage:"password to authenticate with mongo"`
+	MongoTimeout    time.Duration `mapstructure:"MONGO_TIMEOUT" default:"15s" validate:"positive" usage:"time the connection to mongo may take to close"`
+	WebPort         string        `mapstructure:"WEB_PORT" default:"80" validate:"port" usage:"port of the HTTP api"`
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" default:"10s" validate:"positive" usage:"time the requests in flight may take to finish once the service is asked to stop"`
+	LogLevel        string        `mapstructure:"LOG_LEVEL" default:"info" usage:"level from which logs are written to stdout: debug, info, warn or error"`
+
+	WriteBatchSize     int           `mapstructure:"WRITE_BATCH_SIZE" default:"500" validate:"positive" usage:"number of logs stored with a single insert"`
+	WriteFlushInterval time.Duration `mapstructure:"WRITE_FLUSH_INTERVAL" default:"100ms" validate:"positive" usage:"time a log waits at most for its batch to fill up before it is stored"`
+	WriteQueueSize     int           `mapstructure:"WRITE_QUEUE_SIZE" default:"10000" validate:"positive" usage:"number of logs waiting to be stored, beyond which logs are turned away with 429"`
+	WriteTimeout       time.Duration `mapstructure:"WRITE_TIMEOUT" default:"5s" validate:"positive" usage:"time logs may take to be stored, beyond which they are turned away with 503"`
+
+	ErrorLogRetention        time.Duration `mapstructure:"ERROR_LOG_RETENTION" default:"720h" validate:"positive" usage:"time error logs are kept for"`
+	ErrorLogLevelRetention   string        `mapstructure:"ERROR_LOG_LEVEL_RETENTION" usage:"time error logs of some levels are kept for instead, such as debug=24h,error=2160h"`
+	RequestLogRetention      time.Duration `mapstructure:"REQUEST_LOG_RETENTION" default:"168h" validate:"positive" usage:"time request logs are kept for"`
+	RequestLogLevelRetention string        `mapstructure:"REQUEST_LOG_LEVEL_RETENTION" usage:"time request logs of some levels are kept for instead, such as info=72h"`
+	RetentionInterval        time.Duration `mapstructure:"RETENTION_INTERVAL" default:"1h" validate:"positive" usage:"time between the runs of the job that archives and expires logs"`
+	LogArchiveDir            string        `mapstructure:"LOG_ARCHIVE_DIR" usage:"directory logs are archived to as gzip compressed NDJSON before they expire, no archive if empty"`
+}
+
+// Validate checks the log level, the size of the queue of logs and the retention of the logs
+func (c EnvConfig) Validate() error {
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("LOG_LEVEL: %w", err)
+	}
+	if c.WriteQueueSize < maxBatchLogs {
+		return fmt.Errorf("WRITE_QUEUE_SIZE must be at least %d, the number of logs of a batch", maxBatchLogs)
+	}
+	if _, err := parseLevelRetention(c.ErrorLogLevelRetention); err != nil {
+		return fmt.Errorf("ERROR_LOG_LEVEL_RETENTION: %w", err)
+	}
+	if _, err := parseLevelRetention(c.RequestLogLevelRetention); err != nil {
+		return fmt.Errorf("REQUEST_LOG_LEVEL_RETENTION: %w", err)
+	}
+	if c.LogArchiveDir != "" {
+		// logs are archived archiveAhead runs of the retention job before they expire
+		for _, r := range c.retentions() {
+			if r.Shortest() <= archiveAhead*c.RetentionInterval {
+				return fmt.Errorf("%s logs are kept for %s, which must be longer than %d times RETENTION_INTERVAL to archive them",
+					r.Kind, r.Shortest(), archiveAhead)
+			}
+		}
+	}
+	return nil
+}
+
+// logLevel returns the level from which logs are written to stdout, which Validate checked
+func (c EnvConfig) logLevel() slog.Level {
+	level, _ := logging.ParseLevel(c.LogLevel)
+	return level
 }
 
-func LoadConfig() (config EnvConfig, err error) {
-	viper.SetConfigFile("./app/config.json")
+// retentions returns the retention of the error and request logs, whose levels Validate checked
+func (c EnvConfig) retentions() []data.Retention {
+	errorLevels, _ := parseLevelRetention(c.ErrorLogLevelRetention)
+	requestLevels, _ := parseLevelRetention(c.RequestLogLevelRetention)
+	return []data.Retention{
+		{Kind: "error", Default: c.ErrorLogRetention, Levels: errorLevels},
+		{Kind: "request", Default: c.RequestLogRetention, Levels: requestLevels},
+	}
+}
 
-	err = viper.ReadInConfig()
-	if err != nil {
-		return
+// parseLevelRetention parses retentions by level such as debug=24h,error=2160h into the level names logs are
+// stored with, such as DEBUG
+func parseLevelRetention(s string) (map[string]time.Duration, error) {
+	levels := map[string]time.Duration{}
+	if s == "" {
+		return levels, nil
 	}
+	for _, setting := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(setting), "=")
+		if !ok {
+			return nil, fmt.Errorf("%q must be level=duration", setting)
+		}
+		level, err := logging.ParseLevel(name)
+		if err != nil {
+			return nil, err
+		}
+		retention, err := time.ParseDuration(value)
+		if err != nil || retention <= 0 {
+			return nil, fmt.Errorf("retention of %s must be a positive duration", name)
+		}
+		levels[level.String()] = retention
+	}
+	return levels, nil
+}
 
-	err = viper.Unmarshal(&config)
+// LoadConfig reads the settings of logger-service from flags, the environment and the config file
+func LoadConfig() (cfg EnvConfig, err error) {
+	err = config.Load(&cfg)
 	return
 }
@@ -5,30 +5,40 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"time"
+	"os"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
 	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-)
-
-const (
-	webPort = "80"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"golang.org/x/exp/slog"
 )
 
 var client *mongo.Client
 
 type Config struct {
-	Models data.Models
+	Models    data.Models
+	health    *health.Checker
+	retention *retentionJob
+	writer    *logWriter
 }
 
 func main() {
-	log.Printf("Starting Logging service on port: %s", webPort)
-
 	config, err := LoadConfig()
 	if err != nil {
-		log.Fatal("Error with loading env: ", err)
+		log.Fatal("Error with loading config: ", err)
 	}
+	// logger-service can't ship its own logs, which are written to stdout only, like the output of the log package
+	slog.SetDefault(logging.New("logger-service", logging.Stdout(config.logLevel())))
+
+	// the server drains once the service is asked to stop, before the deferred disconnect from mongo runs
+	ctx, stop := shutdown.Context()
+	defer stop()
+
+	log.Printf("Starting Logging service on port: %s", config.WebPort)
 
 	mongoClient, err := connectToMongo(config.MongoURI, config.Username, config.Password)
 	if err != nil {
@@ -37,30 +47,68 @@ func main() {
 
 	client = mongoClient
 
-	// create a context to disconnect
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-	defer cancel()
-
-	// close connection
+	// close connection, once the server drained, with a timeout of its own
 	defer func() {
-		if err = client.Disconnect(ctx); err != nil {
-			panic(err)
+		ctx, cancel := context.WithTimeout(context.Background(), config.MongoTimeout)
+		defer cancel()
+		if err := client.Disconnect(ctx); err != nil {
+			log.Println("error disconnecting mongo:", err)
 		}
 	}()
 
+	checker := health.NewChecker()
+	checker.Add("mongo", func(ctx context.Context) error {
+		return client.Ping(ctx, readpref.Primary())
+	})
+
+	models := data.New(client)
 	app := Config{
-		Models: data.New(client),
+		Models: models,
+		health: checker,
+		writer: newLogWriter(&models.LogEntry, config.WriteBatchSize, config.WriteQueueSize, config.WriteFlushInterval,
+			config.WriteTimeout),
+		retention: &retentionJob{
+			store:      &models.LogEntry,
+			retentions: config.retentions(),
+			interval:   config.RetentionInterval,
+			archiveDir: config.LogArchiveDir,
+		},
+	}
+
+	indexCtx, indexCancel := context.WithTimeout(context.Background(), config.MongoTimeout)
+	defer indexCancel()
+	if err = data.EnsureIndexes(indexCtx); err != nil {
+		log.Panic(err)
 	}
+	if err = data.EnsureRetention(indexCtx, app.retention.retentions...); err != nil {
+		log.Panic(err)
+	}
+	if config.LogArchiveDir != "" {
+		if err = os.MkdirAll(config.LogArchiveDir, 0o750); err != nil {
+			log.Panic(err)
+		}
+	}
+
+	// the retention job stops with the server, before the deferred disconnect from mongo runs
+	retentionStopped := make(chan struct{})
+	go func() {
+		app.retention.run(ctx)
+		close(retentionStopped)
+	}()
+	defer func() { <-retentionStopped }()
+	// the logs queued by the last requests are stored once the server drained
+	defer app.writer.Close()
 
 	server := &http.Server{
-		Addr:    fmt.Sprintf(":%s", webPort),
+		Addr:    fmt.Sprintf(":%s", config.WebPort),
 		Handler: app.routes(),
 	}
 
-	err = server.ListenAndServe()
+	err = shutdown.ServeHTTP(ctx, server, config.ShutdownTimeout)
 	if err != nil {
-		log.Panic(err)
+		log.Println("HTTP server stopped:", err)
 	}
+	log.Println("Logging service stopped")
 }
 
 func connectToMongo(mongoURI, username, password string) (*mongo.Client, error) {
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// queuedLogs is the number of logs waiting in the writer to be stored
+var queuedLogs = promauto.NewGauge(prometheus.GaugeOpts{
+	Name: "logger_queued_logs",
+	Help: "Logs waiting to be stored in MongoDB.",
+})
+
+// insertFailures counts the logs that couldn't be stored, by the kind of log
+var insertFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "logger_insert_failures_total",
+	Help: "Logs that could not be stored in MongoDB, by kind of log.",
+}, []string{"kind"})
+
+// expiredLogs counts the logs the retention job deleted, by the kind of log. The logs the TTL indexes expire
+// aren't counted.
+var expiredLogs = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "logger_expired_logs_total",
+	Help: "Logs deleted by the retention job ahead of the TTL index of their collection, by kind of log.",
+}, []string{"kind"})
+
+// archivedLogs counts the logs written to archive files, by the kind of log
+var archivedLogs = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "logger_archived_logs_total",
+	Help: "Logs archived to compressed NDJSON files before expiring, by kind of log.",
+}, []string{"kind"})
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+)
+
+// logQuery reads the filters of ReadLogs from the query parameters of the request:
+//
+//	kind        error or request, repeated or comma separated
+//	service     service that logged, such as account-service
+//	name        name of the log, such as account-getAccount
+//	level       level of the log, such as error
+//	status      status code, such as 503, a class such as 5xx or a range such as 400-499
+//	from, to    RFC 3339 times the logs were stored between, from included and to excluded
+//	request_id  id of the request the logs are about
+//	q           words searched for in the name, message and error of the logs
+//	cursor      next_cursor of the previous page
+//	limit       number of logs of a page, at most 500
+func logQuery(r *http.Request) (data.LogQuery, error) {
+	values := r.URL.Query()
+	query := data.LogQuery{
+		Service:   values.Get("service"),
+		Name:      values.Get("name"),
+		Level:     values.Get("level"),
+		RequestID: values.Get("request_id"),
+		Text:      values.Get("q"),
+		Cursor:    values.Get("cursor"),
+	}
+
+	for _, kinds := range values["kind"] {
+		for _, kind := range strings.Split(kinds, ",") {
+			if kind != "error" && kind != "request" {
+				return data.LogQuery{}, fmt.Errorf("invalid kind %q", kind)
+			}
+			query.Kinds = append(query.Kinds, kind)
+		}
+	}
+
+	if query.RequestID != "" && !requestid.Valid(query.RequestID) {
+		return data.LogQuery{}, fmt.Errorf("invalid request id")
+	}
+
+	var err error
+	if status := values.Get("status"); status != "" {
+		query.StatusFrom, query.StatusTo, err = statusRange(status)
+		if err != nil {
+			return data.LogQuery{}, err
+		}
+	}
+
+	for name, t := range map[string]*time.Time{"from": &query.From, "to": &query.To} {
+		value := values.Get(name)
+		if value == "" {
+			continue
+		}
+		*t, err = time.Parse(time.RFC3339, value)
+		if err != nil {
+			return data.LogQuery{}, fmt.Errorf("invalid %s: must be an RFC 3339 time", name)
+		}
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return data.LogQuery{}, fmt.Errorf("from must be before to")
+	}
+
+	if limit := values.Get("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit < 1 || query.Limit > data.MaxQueryLimit {
+			return data.LogQuery{}, fmt.Errorf("invalid limit: must be between 1 and %d", data.MaxQueryLimit)
+		}
+	}
+
+	return query, nil
+}
+
+// statusRange returns the status codes a status parameter selects, both included
+func statusRange(status string) (int, int, error) {
+	invalid := fmt.Errorf("invalid status %q: must be a code, a class such as 5xx or a range such as 400-499", status)
+
+	if len(status) == 3 && strings.HasSuffix(strings.ToLower(status), "xx") {
+		class, err := strconv.Atoi(status[:1])
+		if err != nil || class < 1 || class > 5 {
+			return 0, 0, invalid
+		}
+		return class * 100, class*100 + 99, nil
+	}
+
+	from, to, isRange := strings.Cut(status, "-")
+	if !isRange {
+		to = from
+	}
+	fromCode, err := strconv.Atoi(from)
+	if err != nil || fromCode < 100 || fromCode > 599 {
+		return 0, 0, invalid
+	}
+	toCode, err := strconv.Atoi(to)
+	if err != nil || toCode < fromCode || toCode > 599 {
+		return 0, 0, invalid
+	}
+	return fromCode, toCode, nil
+}
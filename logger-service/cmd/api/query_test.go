@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatusRange(t *testing.T) {
+	testCases := []struct {
+		status   string
+		from, to int
+		err      bool
+	}{
+		{status: "503", from: 503, to: 503},
+		{status: "5xx", from: 500, to: 599},
+		{status: "4XX", from: 400, to: 499},
+		{status: "1xx", from: 100, to: 199},
+		{status: "400-499", from: 400, to: 499},
+		{status: "404-404", from: 404, to: 404},
+		{status: "6xx", err: true},
+		{status: "0xx", err: true},
+		{status: "axx", err: true},
+		{status: "99", err: true},
+		{status: "600", err: true},
+		{status: "499-400", err: true},
+		{status: "400-600", err: true},
+		{status: "400-", err: true},
+		{status: "-499", err: true},
+		{status: "ok", err: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.status, func(t *testing.T) {
+			from, to, err := statusRange(tc.status)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.from, from)
+			require.Equal(t, tc.to, to)
+		})
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+	from := time.Date(2023, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2023, 3, 2, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name  string
+		query string
+		want  data.LogQuery
+		err   bool
+	}{
+		{name: "Empty", query: ""},
+		{
+			name:  "Filters",
+			query: "kind=error,request&service=account-service&level=error&status=5xx&q=timeout&limit=10",
+			want: data.LogQuery{
+				Kinds:      []string{"error", "request"},
+				Service:    "account-service",
+				Level:      "error",
+				StatusFrom: 500,
+				StatusTo:   599,
+				Text:       "timeout",
+				Limit:      10,
+			},
+		},
+		{name: "RepeatedKind", query: "kind=error&kind=request", want: data.LogQuery{Kinds: []string{"error", "request"}}},
+		{name: "FromTo", query: "from=2023-03-01T00:00:00Z&to=2023-03-02T00:00:00Z", want: data.LogQuery{From: from, To: to}},
+		{name: "OnlyFrom", query: "from=2023-03-01T00:00:00Z", want: data.LogQuery{From: from}},
+		{name: "FromAfterTo", query: "from=2023-03-02T00:00:00Z&to=2023-03-01T00:00:00Z", err: true},
+		{name: "FromEqualsTo", query: "from=2023-03-01T00:00:00Z&to=2023-03-01T00:00:00Z", err: true},
+		{name: "InvalidTime", query: "from=yesterday", err: true},
+		{name: "InvalidKind", query: "kind=debug", err: true},
+		{name: "InvalidStatus", query: "status=5xx-6xx", err: true},
+		{name: "InvalidRequestID", query: "request_id=%20", err: true},
+		{name: "LimitTooLarge", query: "limit=501", err: true},
+		{name: "LimitZero", query: "limit=0", err: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/logs?"+tc.query, nil)
+			query, err := logQuery(r)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, query)
+		})
+	}
+}
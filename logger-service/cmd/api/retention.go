@@ -0,0 +1,236 @@
+package main
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// archiveAhead is the number of runs of the retention job ahead of their expiry logs are archived at, so that a
+// failed run can be made up for by the next one before the logs expire
+const archiveAhead = 2
+
+// retentionStore expires and exports logs and keeps the progress of their archive, as data.LogEntry does in mongo
+type retentionStore interface {
+	Expire(ctx context.Context, r data.Retention, now time.Time) (int64, error)
+	Export(ctx context.Context, kind string, from, to time.Time, fn func(bson.Raw) error) (int, error)
+	ArchiveState(ctx context.Context, kind string) (data.ArchiveState, error)
+	SaveArchiveState(ctx context.Context, state data.ArchiveState) error
+}
+
+// retentionJob deletes the logs kept for less than the TTL index of their collection and, with an archive
+// directory, archives logs to gzip compressed NDJSON files before they expire. The files hold the documents as
+// mongo extended JSON, so that they can be restored with mongoimport.
+type retentionJob struct {
+	store      retentionStore
+	retentions []data.Retention
+	interval   time.Duration
+	// archiveDir is the directory of the archives, archiving is disabled if it is empty
+	archiveDir string
+
+	mu        sync.Mutex
+	lastRun   time.Time
+	lastError string
+}
+
+// run runs the job every interval until ctx is done. A run that already started is waited for.
+func (job *retentionJob) run(ctx context.Context) {
+	ticker := time.NewTicker(job.interval)
+	defer ticker.Stop()
+	for {
+		job.runOnce(ctx)
+
+		select {
+		case <-ctx.Done():
+			log.Println("stopped retention job")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runOnce archives and expires the logs of every kind, and records the outcome for the status of the job
+func (job *retentionJob) runOnce(ctx context.Context) {
+	now := time.Now()
+	var failed error
+	for _, r := range job.retentions {
+		if job.archiveDir != "" {
+			if err := job.archive(ctx, r, now); err != nil {
+				log.Printf("error archiving %s logs: %s", r.Kind, err)
+				failed = err
+				// logs are only expired once they are archived
+				continue
+			}
+		}
+
+		deleted, err := job.store.Expire(ctx, r, now)
+		if err != nil {
+			log.Printf("error expiring %s logs: %s", r.Kind, err)
+			failed = err
+		}
+		expiredLogs.WithLabelValues(r.Kind).Add(float64(deleted))
+	}
+
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	job.lastRun = now
+	job.lastError = ""
+	if failed != nil {
+		job.lastError = failed.Error()
+	}
+}
+
+// archive writes the logs of a kind that expire before the run archiveAhead runs later, and weren't archived yet,
+// to a new file of the archive directory
+func (job *retentionJob) archive(ctx context.Context, r data.Retention, now time.Time) error {
+	state, err := job.store.ArchiveState(ctx, r.Kind)
+	if err != nil {
+		return err
+	}
+
+	until := now.Add(-r.Shortest() + archiveAhead*job.interval).UTC().Truncate(time.Second)
+	if !until.After(state.ArchivedUntil) {
+		return nil
+	}
+
+	name := fmt.Sprintf("%s-logs-%s-%s.ndjson.gz", r.Kind,
+		state.ArchivedUntil.UTC().Format("20060102T150405Z"), until.Format("20060102T150405Z"))
+	count, err := job.writeArchive(ctx, filepath.Join(job.archiveDir, name), r.Kind, state.ArchivedUntil, until)
+	if err != nil {
+		return err
+	}
+	archivedLogs.WithLabelValues(r.Kind).Add(float64(count))
+
+	state.ArchivedUntil = until
+	state.Total += int64(count)
+	if count > 0 {
+		state.LastFile = name
+		state.LastCount = count
+	}
+	return job.store.SaveArchiveState(ctx, state)
+}
+
+// writeArchive writes the logs of a kind stored from from to until to the file at path, unless there are none.
+// The file is written under a temporary name first, so that the archive directory only holds complete files.
+func (job *retentionJob) writeArchive(ctx context.Context, path, kind string, from, until time.Time) (int, error) {
+	tmp, err := os.CreateTemp(job.archiveDir, ".archive-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	zw := gzip.NewWriter(tmp)
+	count, err := job.store.Export(ctx, kind, from, until, func(doc bson.Raw) error {
+		line, err := bson.MarshalExtJSON(doc, false, false)
+		if err != nil {
+			return err
+		}
+		_, err = zw.Write(append(line, '\n'))
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	if err = zw.Close(); err != nil {
+		return 0, err
+	}
+	if err = tmp.Sync(); err != nil {
+		return 0, err
+	}
+	if err = tmp.Close(); err != nil {
+		return 0, err
+	}
+	return count, os.Rename(tmp.Name(), path)
+}
+
+type retentionSettings struct {
+	Kind       string            `json:"kind"`
+	Collection string            `json:"collection"`
+	Default    string            `json:"default"`
+	Levels     map[string]string `json:"levels,omitempty"`
+	// TTL is the expiry of the TTL index of the collection
+	TTL string `json:"ttl"`
+}
+
+type archiveStatus struct {
+	Enabled bool                `json:"enabled"`
+	Dir     string              `json:"dir,omitempty"`
+	Kinds   []data.ArchiveState `json:"kinds,omitempty"`
+}
+
+type retentionStatus struct {
+	Retention []retentionSettings `json:"retention"`
+	// Interval is the time between the runs of the retention job
+	Interval  string        `json:"interval"`
+	LastRun   *time.Time    `json:"last_run,omitempty"`
+	LastError string        `json:"last_error,omitempty"`
+	Archive   archiveStatus `json:"archive"`
+}
+
+// RetentionStatus returns the retention of the logs and the status of their archive
+func (app *Config) RetentionStatus(w http.ResponseWriter, r *http.Request) {
+	job := app.retention
+	status := retentionStatus{
+		Interval: job.interval.String(),
+		Archive: archiveStatus{
+			Enabled: job.archiveDir != "",
+			Dir:     job.archiveDir,
+		},
+	}
+
+	for _, retention := range job.retentions {
+		settings := retentionSettings{
+			Kind:       retention.Kind,
+			Collection: retention.Collection(),
+			Default:    retention.Default.String(),
+			TTL:        retention.Longest().String(),
+		}
+		if len(retention.Levels) > 0 {
+			settings.Levels = map[string]string{}
+			for level, d := range retention.Levels {
+				settings.Levels[level] = d.String()
+			}
+		}
+		status.Retention = append(status.Retention, settings)
+
+		if !status.Archive.Enabled {
+			continue
+		}
+		state, err := job.store.ArchiveState(r.Context(), retention.Kind)
+		if err != nil {
+			app.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		status.Archive.Kinds = append(status.Archive.Kinds, state)
+	}
+
+	job.mu.Lock()
+	if !job.lastRun.IsZero() {
+		lastRun := job.lastRun
+		status.LastRun = &lastRun
+	}
+	status.LastError = job.lastError
+	job.mu.Unlock()
+
+	resp := jsonResponse{
+		Error:   false,
+		Message: "success",
+		Data:    status,
+	}
+
+	app.writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fakeRetentionStore exports the logs it holds and keeps the archive states instead of mongo
+type fakeRetentionStore struct {
+	logs      map[string][]bson.Raw
+	states    map[string]data.ArchiveState
+	exportErr error
+
+	exports []exportCall
+	expired []string
+}
+
+// exportCall is the window of logs an archive exported
+type exportCall struct {
+	kind     string
+	from, to time.Time
+}
+
+func (f *fakeRetentionStore) Expire(_ context.Context, r data.Retention, _ time.Time) (int64, error) {
+	f.expired = append(f.expired, r.Kind)
+	return 0, nil
+}
+
+func (f *fakeRetentionStore) Export(_ context.Context, kind string, from, to time.Time, fn func(bson.Raw) error) (int, error) {
+	f.exports = append(f.exports, exportCall{kind: kind, from: from, to: to})
+	if f.exportErr != nil {
+		return 0, f.exportErr
+	}
+	for _, doc := range f.logs[kind] {
+		if err := fn(doc); err != nil {
+			return 0, err
+		}
+	}
+	return len(f.logs[kind]), nil
+}
+
+func (f *fakeRetentionStore) ArchiveState(_ context.Context, kind string) (data.ArchiveState, error) {
+	if state, ok := f.states[kind]; ok {
+		return state, nil
+	}
+	return data.ArchiveState{Kind: kind}, nil
+}
+
+func (f *fakeRetentionStore) SaveArchiveState(_ context.Context, state data.ArchiveState) error {
+	if f.states == nil {
+		f.states = map[string]data.ArchiveState{}
+	}
+	f.states[state.Kind] = state
+	return nil
+}
+
+func TestParseLevelRetention(t *testing.T) {
+	testCases := []struct {
+		name   string
+		value  string
+		levels map[string]time.Duration
+		err    bool
+	}{
+		{name: "Empty", value: "", levels: map[string]time.Duration{}},
+		{name: "Single", value: "debug=24h", levels: map[string]time.Duration{"DEBUG": 24 * time.Hour}},
+		{
+			name:   "Several",
+			value:  "debug=24h, error=2160h,WARN=72h",
+			levels: map[string]time.Duration{"DEBUG": 24 * time.Hour, "ERROR": 2160 * time.Hour, "WARN": 72 * time.Hour},
+		},
+		{name: "MissingDuration", value: "debug", err: true},
+		{name: "UnknownLevel", value: "loud=24h", err: true},
+		{name: "InvalidDuration", value: "debug=a day", err: true},
+		{name: "ZeroDuration", value: "debug=0s", err: true},
+		{name: "NegativeDuration", value: "debug=-1h", err: true},
+		{name: "TrailingComma", value: "debug=24h,", err: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			levels, err := parseLevelRetention(tc.value)
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.levels, levels)
+		})
+	}
+}
+
+func TestEnvConfigValidate(t *testing.T) {
+	valid := EnvConfig{
+		LogLevel:            "info",
+		WriteQueueSize:      maxBatchLogs,
+		ErrorLogRetention:   720 * time.Hour,
+		RequestLogRetention: 168 * time.Hour,
+		RetentionInterval:   time.Hour,
+	}
+
+	testCases := []struct {
+		name   string
+		modify func(c *EnvConfig)
+		err    bool
+	}{
+		{name: "Valid", modify: func(c *EnvConfig) {}},
+		{name: "LogLevel", modify: func(c *EnvConfig) { c.LogLevel = "loud" }, err: true},
+		{name: "QueueSmallerThanBatch", modify: func(c *EnvConfig) { c.WriteQueueSize = maxBatchLogs - 1 }, err: true},
+		{name: "ErrorLevelRetention", modify: func(c *EnvConfig) { c.ErrorLogLevelRetention = "debug" }, err: true},
+		{name: "RequestLevelRetention", modify: func(c *EnvConfig) { c.RequestLogLevelRetention = "info=0s" }, err: true},
+		{
+			// without an archive, logs may be kept for less than the runs of the job ahead
+			name:   "ShortRetentionWithoutArchive",
+			modify: func(c *EnvConfig) { c.ErrorLogLevelRetention = "debug=1h" },
+		},
+		{
+			name: "ArchiveLongerThanAhead",
+			modify: func(c *EnvConfig) {
+				c.LogArchiveDir = "/archive"
+				c.ErrorLogLevelRetention = "debug=3h"
+			},
+		},
+		{
+			name: "ArchiveLevelEqualToAhead",
+			modify: func(c *EnvConfig) {
+				c.LogArchiveDir = "/archive"
+				c.ErrorLogLevelRetention = "debug=2h"
+			},
+			err: true,
+		},
+		{
+			name: "ArchiveDefaultShorterThanAhead",
+			modify: func(c *EnvConfig) {
+				c.LogArchiveDir = "/archive"
+				c.RequestLogRetention = 90 * time.Minute
+			},
+			err: true,
+		},
+		{
+			name: "ArchiveLongInterval",
+			modify: func(c *EnvConfig) {
+				c.LogArchiveDir = "/archive"
+				c.RetentionInterval = 84 * time.Hour
+			},
+			err: true,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			c := valid
+			tc.modify(&c)
+			err := c.Validate()
+			if tc.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// readArchive reads the documents of a gzip compressed NDJSON archive
+func readArchive(t *testing.T, path string) []bson.D {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	zr, err := gzip.NewReader(file)
+	require.NoError(t, err)
+
+	docs := []bson.D{}
+	scanner := bufio.NewScanner(zr)
+	for scanner.Scan() {
+		var doc bson.D
+		require.NoError(t, bson.UnmarshalExtJSON(scanner.Bytes(), false, &doc))
+		docs = append(docs, doc)
+	}
+	require.NoError(t, scanner.Err())
+	return docs
+}
+
+func TestWriteArchive(t *testing.T) {
+	createdAt := time.Date(2023, 3, 14, 12, 0, 0, 0, time.UTC)
+	docs := []bson.D{
+		{{Key: "name", Value: "first"}, {Key: "created_at", Value: createdAt}},
+		{{Key: "name", Value: "second"}, {Key: "data", Value: bson.D{{Key: "level", Value: "ERROR"}}}, {Key: "created_at", Value: createdAt}},
+	}
+	store := &fakeRetentionStore{logs: map[string][]bson.Raw{}}
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		require.NoError(t, err)
+		store.logs["error"] = append(store.logs["error"], raw)
+	}
+
+	dir := t.TempDir()
+	job := &retentionJob{store: store, archiveDir: dir}
+	from := createdAt.Add(-time.Hour)
+	until := createdAt.Add(time.Hour)
+	path := filepath.Join(dir, "error.ndjson.gz")
+
+	count, err := job.writeArchive(context.Background(), path, "error", from, until)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.Equal(t, []exportCall{{kind: "error", from: from, to: until}}, store.exports)
+
+	archived := readArchive(t, path)
+	require.Len(t, archived, len(docs))
+	for i := range docs {
+		require.Equal(t, docs[i][0], archived[i][0])
+	}
+
+	// no file is written without logs, and the temporary file is removed either way
+	count, err = job.writeArchive(context.Background(), filepath.Join(dir, "request.ndjson.gz"), "request", from, until)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, "error.ndjson.gz", entries[0].Name())
+}
+
+func TestArchiveWindow(t *testing.T) {
+	now := time.Date(2023, 3, 14, 12, 30, 15, 500, time.UTC)
+	retention := data.Retention{
+		Kind:    "error",
+		Default: 720 * time.Hour,
+		Levels:  map[string]time.Duration{"DEBUG": 24 * time.Hour},
+	}
+	doc, err := bson.Marshal(bson.D{{Key: "name", Value: "log"}})
+	require.NoError(t, err)
+	store := &fakeRetentionStore{logs: map[string][]bson.Raw{"error": {doc}}}
+	dir := t.TempDir()
+	job := &retentionJob{store: store, retentions: []data.Retention{retention}, interval: time.Hour, archiveDir: dir}
+
+	// the logs expiring within archiveAhead runs, after the shortest retention, are archived
+	require.NoError(t, job.archive(context.Background(), retention, now))
+	until := time.Date(2023, 3, 13, 14, 30, 15, 0, time.UTC)
+	require.Equal(t, []exportCall{{kind: "error", from: time.Time{}, to: until}}, store.exports)
+
+	name := "error-logs-00010101T000000Z-20230313T143015Z.ndjson.gz"
+	require.FileExists(t, filepath.Join(dir, name))
+	state := store.states["error"]
+	require.Equal(t, until, state.ArchivedUntil)
+	require.Equal(t, name, state.LastFile)
+	require.Equal(t, 1, state.LastCount)
+	require.EqualValues(t, 1, state.Total)
+
+	// the next run within the same second has nothing left to archive
+	require.NoError(t, job.archive(context.Background(), retention, now.Add(time.Millisecond)))
+	require.Len(t, store.exports, 1)
+
+	// the next window starts where the last one ended, and an empty window keeps the last file
+	store.logs["error"] = nil
+	next := now.Add(time.Hour)
+	require.NoError(t, job.archive(context.Background(), retention, next))
+	require.Equal(t, exportCall{kind: "error", from: until, to: until.Add(time.Hour)}, store.exports[1])
+	state = store.states["error"]
+	require.Equal(t, until.Add(time.Hour), state.ArchivedUntil)
+	require.Equal(t, name, state.LastFile)
+	require.EqualValues(t, 1, state.Total)
+}
+
+func TestRunOnceArchivesBeforeExpiring(t *testing.T) {
+	store := &fakeRetentionStore{exportErr: errors.New("mongo is down")}
+	job := &retentionJob{
+		store:      store,
+		retentions: []data.Retention{{Kind: "error", Default: 720 * time.Hour}, {Kind: "request", Default: 168 * time.Hour}},
+		interval:   time.Hour,
+		archiveDir: t.TempDir(),
+	}
+
+	// logs that couldn't be archived aren't expired
+	job.runOnce(context.Background())
+	require.Empty(t, store.expired)
+	require.Equal(t, "mongo is down", job.lastError)
+	require.False(t, job.lastRun.IsZero())
+
+	store.exportErr = nil
+	job.runOnce(context.Background())
+	require.Equal(t, []string{"error", "request"}, store.expired)
+	require.Empty(t, job.lastError)
+}
+
+func TestRetentionStatus(t *testing.T) {
+	archivedUntil := time.Date(2023, 3, 13, 14, 30, 15, 0, time.UTC)
+	store := &fakeRetentionStore{states: map[string]data.ArchiveState{
+		"error": {Kind: "error", ArchivedUntil: archivedUntil, LastFile: "error-logs.ndjson.gz", LastCount: 3, Total: 10},
+	}}
+	lastRun := time.Date(2023, 3, 14, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name       string
+		archiveDir string
+		archive    archiveStatus
+	}{
+		{name: "NoArchive", archive: archiveStatus{}},
+		{
+			name:       "Archive",
+			archiveDir: "/archive",
+			archive: archiveStatus{Enabled: true, Dir: "/archive", Kinds: []data.ArchiveState{
+				{Kind: "error", ArchivedUntil: archivedUntil, LastFile: "error-logs.ndjson.gz", LastCount: 3, Total: 10},
+				{Kind: "request"},
+			}},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app := Config{retention: &retentionJob{
+				store: store,
+				retentions: []data.Retention{
+					{Kind: "error", Default: 720 * time.Hour, Levels: map[string]time.Duration{"ERROR": 2160 * time.Hour}},
+					{Kind: "request", Default: 168 * time.Hour},
+				},
+				interval:   time.Hour,
+				archiveDir: tc.archiveDir,
+				lastRun:    lastRun,
+				lastError:  "mongo is down",
+			}}
+
+			r := httptest.NewRequest(http.MethodGet, "/admin/retention", nil)
+			w := httptest.NewRecorder()
+			app.RetentionStatus(w, r)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Data retentionStatus `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, []retentionSettings{
+				{Kind: "error", Collection: "error-logs", Default: "720h0m0s", Levels: map[string]string{"ERROR": "2160h0m0s"}, TTL: "2160h0m0s"},
+				{Kind: "request", Collection: "request-logs", Default: "168h0m0s", TTL: "168h0m0s"},
+			}, resp.Data.Retention)
+			require.Equal(t, "1h0m0s", resp.Data.Interval)
+			require.NotNil(t, resp.Data.LastRun)
+			require.True(t, lastRun.Equal(*resp.Data.LastRun))
+			require.Equal(t, "mongo is down", resp.Data.LastError)
+			require.Equal(t, tc.archive, resp.Data.Archive)
+		})
+	}
+}
@@ -3,16 +3,30 @@ package main
 import (
 	"net/http"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
 	"github.com/go-chi/chi/v5"
 )
 
 func (app *Config) routes() http.Handler {
 	mux := chi.NewRouter()
+	mux.Use(metrics.Middleware(routePattern))
 
 	mux.Post("/logs/create-error", app.WriteErrorLog)
 	mux.Post("/logs/create-request", app.WriteRequestLog)
+	mux.Post("/logs/batch", app.WriteLogs)
 	mux.Get("/logs", app.ReadLogs)
+	mux.Get("/logs/requests/{request_id}", app.ReadRequestLogs)
 	mux.Get("/logs/{id}", app.ReadOne)
+	mux.Get("/admin/retention", app.RetentionStatus)
+	mux.Method(http.MethodGet, "/metrics", metrics.Handler())
+	mux.Method(http.MethodGet, "/healthz", health.LiveHandler())
+	mux.Method(http.MethodGet, "/readyz", app.health.ReadyHandler())
 
 	return mux
 }
+
+// routePattern returns the pattern of the route a request matched, once chi routed it
+func routePattern(r *http.Request) string {
+	return chi.RouteContext(r.Context()).RoutePattern()
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	// errWriterFull is returned for logs that don't fit into the queue of the writer
+	errWriterFull = errors.New("too many logs waiting to be stored, try again later")
+	// errWriteTimeout is returned for logs that weren't stored in time, which are then skipped
+	errWriteTimeout = errors.New("storing logs took too long, try again later")
+	errWriterClosed = errors.New("logger-service is stopping")
+)
+
+// States of a pendingWrite, which the writer and the request waiting for it change under the lock of the writer
+const (
+	// writeQueued writes wait to be inserted
+	writeQueued = iota
+	// writeInserting writes were taken by a flush, whose insert is bounded by the timeout of the writer
+	writeInserting
+	// writeCancelled writes timed out before a flush took them, and are skipped
+	writeCancelled
+)
+
+// logInserter stores logs of a kind at once, as data.LogEntry does in mongo
+type logInserter interface {
+	InsertMany(ctx context.Context, kind string, entries []data.LogEntry) error
+}
+
+// writeResult tells how the logs of a write fared. Failed are the indexes of the logs that weren't stored, if
+// err is set.
+type writeResult struct {
+	failed []int
+	err    error
+}
+
+// pendingWrite are the logs of a request to the writer, which is told through done how they fared
+type pendingWrite struct {
+	entries []data.LogEntry
+	state   int
+	done    chan writeResult
+}
+
+// logWriter stores the logs of all requests in batches, which are inserted once they reach batchSize logs or
+// every interval. The requests wait for their logs to be stored, so that a log is only acknowledged once it is,
+// and are told which of their logs weren't stored, so that only those are sent again.
+type logWriter struct {
+	inserter  logInserter
+	batchSize int
+	interval  time.Duration
+	// timeout bounds the insert of a batch, and the wait of a request for its logs to be taken by a flush
+	timeout time.Duration
+	// queueSize is the number of logs waiting to be stored, beyond which requests are turned away
+	queueSize int
+
+	mu      sync.Mutex
+	queued  int
+	closed  bool
+	writes  chan *pendingWrite
+	stopped chan struct{}
+}
+
+// newLogWriter creates a logWriter, which starts storing logs right away
+func newLogWriter(inserter logInserter, batchSize, queueSize int, interval, timeout time.Duration) *logWriter {
+	w := &logWriter{
+		inserter:  inserter,
+		batchSize: batchSize,
+		interval:  interval,
+		timeout:   timeout,
+		queueSize: queueSize,
+		// every write holds at least one log, and is counted until a flush takes or skips it, so the queue of logs
+		// bounds the writes
+		writes:  make(chan *pendingWrite, queueSize),
+		stopped: make(chan struct{}),
+	}
+
+	go w.run()
+	return w
+}
+
+// Write queues entries, whose Kind tells their collection, and waits until they are stored. It fails right away
+// with errWriterFull if the queue is full. Entries that no flush took within timeout are skipped and fail with
+// errWriteTimeout, while entries a flush took are waited for, so that the result tells which entries were stored.
+func (w *logWriter) Write(ctx context.Context, entries []data.LogEntry) writeResult {
+	if len(entries) == 0 {
+		return writeResult{}
+	}
+	write := &pendingWrite{entries: entries, state: writeQueued, done: make(chan writeResult, 1)}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return writeResult{failed: allIndexes(len(entries)), err: errWriterClosed}
+	}
+	if w.queued+len(entries) > w.queueSize {
+		w.mu.Unlock()
+		return writeResult{failed: allIndexes(len(entries)), err: errWriterFull}
+	}
+	w.queued += len(entries)
+	queuedLogs.Add(float64(len(entries)))
+	// the queue of logs has room, so the send doesn't block
+	w.writes <- write
+	w.mu.Unlock()
+
+	timer := time.NewTimer(w.timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case result := <-write.done:
+		return result
+	case <-timer.C:
+		err = errWriteTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	w.mu.Lock()
+	if write.state == writeQueued {
+		// the flush that comes across the write skips it
+		write.state = writeCancelled
+		w.mu.Unlock()
+		return writeResult{failed: allIndexes(len(entries)), err: err}
+	}
+	w.mu.Unlock()
+
+	return <-write.done
+}
+
+// Close stores the queued logs and stops the writer
+func (w *logWriter) Close() {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.writes)
+	}
+	w.mu.Unlock()
+	<-w.stopped
+}
+
+func (w *logWriter) run() {
+	defer close(w.stopped)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	batch := []*pendingWrite{}
+	size := 0
+	flush := func() {
+		if len(batch) > 0 {
+			w.flush(batch)
+			batch = []*pendingWrite{}
+			size = 0
+		}
+	}
+
+	for {
+		select {
+		case write, ok := <-w.writes:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, write)
+			size += len(write.entries)
+			if size >= w.batchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// flush inserts the logs of the writes of batch that didn't time out, one insert per kind, and tells every write
+// which of its logs weren't stored
+func (w *logWriter) flush(batch []*pendingWrite) {
+	taken := make([]*pendingWrite, 0, len(batch))
+	w.mu.Lock()
+	for _, write := range batch {
+		if write.state == writeCancelled {
+			w.queued -= len(write.entries)
+			queuedLogs.Sub(float64(len(write.entries)))
+			continue
+		}
+		write.state = writeInserting
+		taken = append(taken, write)
+	}
+	w.mu.Unlock()
+
+	// owner is the write a log to insert belongs to, and the index of the log in it
+	type owner struct{ write, index int }
+	entries := map[string][]data.LogEntry{}
+	owners := map[string][]owner{}
+	for i, write := range taken {
+		for j, entry := range write.entries {
+			entries[entry.Kind] = append(entries[entry.Kind], entry)
+			owners[entry.Kind] = append(owners[entry.Kind], owner{write: i, index: j})
+		}
+	}
+
+	results := make([]writeResult, len(taken))
+	fail := func(o owner, err error) {
+		results[o.write].failed = append(results[o.write].failed, o.index)
+		results[o.write].err = err
+	}
+	for kind := range entries {
+		ctx, cancel := context.WithTimeout(context.Background(), w.timeout)
+		err := w.inserter.InsertMany(ctx, kind, entries[kind])
+		cancel()
+		if err == nil {
+			continue
+		}
+
+		var bulk mongo.BulkWriteException
+		if errors.As(err, &bulk) && bulk.WriteConcernError == nil && len(bulk.WriteErrors) > 0 {
+			// the other logs of the kind were stored
+			for _, writeErr := range bulk.WriteErrors {
+				fail(owners[kind][writeErr.Index], err)
+			}
+			insertFailures.WithLabelValues(kind).Add(float64(len(bulk.WriteErrors)))
+			continue
+		}
+		// mongo didn't tell which logs were stored, as when the insert timed out, so all of them are sent again
+		for _, o := range owners[kind] {
+			fail(o, err)
+		}
+		insertFailures.WithLabelValues(kind).Add(float64(len(entries[kind])))
+	}
+
+	// the kinds are inserted in no particular order, while the indexes are reported in the order of the logs
+	for i := range results {
+		sort.Ints(results[i].failed)
+	}
+
+	w.mu.Lock()
+	for i, write := range taken {
+		w.queued -= len(write.entries)
+		queuedLogs.Sub(float64(len(write.entries)))
+		write.done <- results[i]
+	}
+	w.mu.Unlock()
+
+	for _, result := range results {
+		if result.err != nil {
+			log.Println("error storing logs:", result.err)
+			break
+		}
+	}
+}
+
+// allIndexes returns the indexes of n logs
+func allIndexes(n int) []int {
+	indexes := make([]int, n)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logger-service/data"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// fakeInserter keeps the logs the writer inserts instead of storing them in mongo
+type fakeInserter struct {
+	// insert, if set, is called before the logs are kept, and its error is returned instead of keeping them
+	insert func(ctx context.Context, kind string, entries []data.LogEntry) error
+
+	mu     sync.Mutex
+	stored map[string][]data.LogEntry
+}
+
+func (f *fakeInserter) InsertMany(ctx context.Context, kind string, entries []data.LogEntry) error {
+	if f.insert != nil {
+		if err := f.insert(ctx, kind, entries); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.stored == nil {
+		f.stored = map[string][]data.LogEntry{}
+	}
+	f.stored[kind] = append(f.stored[kind], entries...)
+	return nil
+}
+
+func (f *fakeInserter) storedLogs(kind string) []data.LogEntry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.stored[kind]
+}
+
+// bulkFailure fails the insert of the logs of kind at the given indexes, as mongo does for an unordered insert
+func bulkFailure(kind string, indexes ...int) func(context.Context, string, []data.LogEntry) error {
+	return func(_ context.Context, k string, _ []data.LogEntry) error {
+		if k != kind {
+			return nil
+		}
+		var bulk mongo.BulkWriteException
+		for _, index := range indexes {
+			bulk.WriteErrors = append(bulk.WriteErrors, mongo.BulkWriteError{
+				WriteError: mongo.WriteError{Index: index, Code: 11000, Message: "duplicate key"},
+			})
+		}
+		return bulk
+	}
+}
+
+// logEntries returns a log of every kind, named after its index
+func logEntries(kinds ...string) []data.LogEntry {
+	entries := make([]data.LogEntry, len(kinds))
+	for i, kind := range kinds {
+		entries[i] = data.LogEntry{Name: strings.Repeat("x", i+1), Kind: kind}
+	}
+	return entries
+}
+
+// waitQueued waits until n logs are queued in the writer
+func waitQueued(t *testing.T, w *logWriter, n int) {
+	require.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return w.queued == n
+	}, time.Second, time.Millisecond)
+}
+
+// writeAsync writes entries in the background and returns where the result arrives
+func writeAsync(w *logWriter, entries []data.LogEntry) <-chan writeResult {
+	done := make(chan writeResult, 1)
+	go func() {
+		done <- w.Write(context.Background(), entries)
+	}()
+	return done
+}
+
+func TestLogWriterFlushBySize(t *testing.T) {
+	inserter := &fakeInserter{}
+	// the interval is never reached, so only the size of the batch flushes it
+	w := newLogWriter(inserter, 3, 10, time.Hour, time.Second)
+	defer w.Close()
+
+	first := writeAsync(w, logEntries("error", "request"))
+	waitQueued(t, w, 2)
+
+	result := w.Write(context.Background(), logEntries("error"))
+	require.NoError(t, result.err)
+	require.Empty(t, result.failed)
+	result = <-first
+	require.NoError(t, result.err)
+	require.Empty(t, result.failed)
+
+	// one insert per kind, with the logs of both writes
+	require.Len(t, inserter.storedLogs("error"), 2)
+	require.Len(t, inserter.storedLogs("request"), 1)
+	waitQueued(t, w, 0)
+}
+
+func TestLogWriterFlushByTime(t *testing.T) {
+	inserter := &fakeInserter{}
+	// the batch is never full, so only the interval flushes it
+	w := newLogWriter(inserter, 100, 10, 10*time.Millisecond, time.Second)
+	defer w.Close()
+
+	result := w.Write(context.Background(), logEntries("request"))
+	require.NoError(t, result.err)
+	require.Len(t, inserter.storedLogs("request"), 1)
+}
+
+func TestLogWriterFull(t *testing.T) {
+	inserter := &fakeInserter{}
+	w := newLogWriter(inserter, 100, 2, time.Hour, time.Minute)
+
+	// logs beyond the size of the queue are turned away at once
+	result := w.Write(context.Background(), logEntries("error", "error", "error"))
+	require.ErrorIs(t, result.err, errWriterFull)
+	require.Equal(t, []int{0, 1, 2}, result.failed)
+
+	queued := writeAsync(w, logEntries("error", "request"))
+	waitQueued(t, w, 2)
+
+	result = w.Write(context.Background(), logEntries("request"))
+	require.ErrorIs(t, result.err, errWriterFull)
+	require.Equal(t, []int{0}, result.failed)
+
+	// closing stores the queued logs
+	w.Close()
+	result = <-queued
+	require.NoError(t, result.err)
+	require.Len(t, inserter.storedLogs("error"), 1)
+	require.Len(t, inserter.storedLogs("request"), 1)
+
+	result = w.Write(context.Background(), logEntries("error"))
+	require.ErrorIs(t, result.err, errWriterClosed)
+	require.Equal(t, []int{0}, result.failed)
+}
+
+func TestLogWriterCancelled(t *testing.T) {
+	inserter := &fakeInserter{}
+	w := newLogWriter(inserter, 100, 10, time.Hour, 20*time.Millisecond)
+
+	// no flush takes the queued logs in time, so they are cancelled
+	result := w.Write(context.Background(), logEntries("error", "request"))
+	require.ErrorIs(t, result.err, errWriteTimeout)
+	require.Equal(t, []int{0, 1}, result.failed)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result = w.Write(ctx, logEntries("error"))
+	require.ErrorIs(t, result.err, context.Canceled)
+	require.Equal(t, []int{0}, result.failed)
+
+	// the flush that comes across the cancelled logs skips them
+	w.Close()
+	require.Empty(t, inserter.storedLogs("error"))
+	require.Empty(t, inserter.storedLogs("request"))
+	waitQueued(t, w, 0)
+}
+
+func TestLogWriterInserting(t *testing.T) {
+	release := make(chan struct{})
+	inserter := &fakeInserter{insert: func(context.Context, string, []data.LogEntry) error {
+		<-release
+		return nil
+	}}
+	w := newLogWriter(inserter, 1, 10, time.Hour, 20*time.Millisecond)
+	defer w.Close()
+
+	done := writeAsync(w, logEntries("error"))
+
+	// the flush took the logs, so the write waits for the insert past its timeout rather than cancelling them
+	select {
+	case <-done:
+		t.Fatal("write returned before its logs were inserted")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	result := <-done
+	require.NoError(t, result.err)
+	require.Empty(t, result.failed)
+	require.Len(t, inserter.storedLogs("error"), 1)
+}
+
+func TestLogWriterInsertTimeout(t *testing.T) {
+	inserter := &fakeInserter{insert: func(ctx context.Context, _ string, _ []data.LogEntry) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	w := newLogWriter(inserter, 1, 10, time.Hour, 20*time.Millisecond)
+	defer w.Close()
+
+	// mongo doesn't tell which logs were stored, so all of them fail
+	result := w.Write(context.Background(), logEntries("error"))
+	require.ErrorIs(t, result.err, context.DeadlineExceeded)
+	require.Equal(t, []int{0}, result.failed)
+}
+
+func TestLogWriterPartialFailure(t *testing.T) {
+	// the second error log of the flush is the third log of the first write
+	inserter := &fakeInserter{insert: bulkFailure("error", 1)}
+	w := newLogWriter(inserter, 4, 10, time.Hour, time.Second)
+	defer w.Close()
+
+	first := writeAsync(w, logEntries("error", "request", "error"))
+	waitQueued(t, w, 3)
+	second := w.Write(context.Background(), logEntries("error"))
+
+	result := <-first
+	var bulk mongo.BulkWriteException
+	require.ErrorAs(t, result.err, &bulk)
+	require.Equal(t, []int{2}, result.failed)
+
+	require.NoError(t, second.err)
+	require.Empty(t, second.failed)
+	require.Len(t, inserter.storedLogs("request"), 1)
+}
+
+func TestWriteLogs(t *testing.T) {
+	ndjson := `{"kind":"error","name":"a","data":{"message":"x"}}
+{"kind":"request","name":"b","data":{"status_code":200}}
+`
+	array := `[{"kind":"error","name":"a"},{"kind":"request","name":"b"}]`
+
+	testCases := []struct {
+		name       string
+		body       string
+		queueSize  int
+		insert     func(context.Context, string, []data.LogEntry) error
+		status     int
+		retryAfter string
+		result     batchResult
+	}{
+		{name: "NDJSON", body: ndjson, queueSize: 10, status: http.StatusCreated, result: batchResult{Stored: 2}},
+		{name: "JSONArray", body: array, queueSize: 10, status: http.StatusCreated, result: batchResult{Stored: 2}},
+		{name: "InvalidKind", body: `{"kind":"debug","name":"a"}`, queueSize: 10, status: http.StatusBadRequest},
+		{name: "Empty", body: "\n", queueSize: 10, status: http.StatusBadRequest},
+		{name: "InvalidLine", body: "{\"kind\":\"error\"}\n{", queueSize: 10, status: http.StatusBadRequest},
+		{
+			name:       "Full",
+			body:       array,
+			queueSize:  1,
+			status:     http.StatusTooManyRequests,
+			retryAfter: "1",
+			result:     batchResult{Failed: []int{0, 1}},
+		},
+		{
+			name:      "Timeout",
+			body:      ndjson,
+			queueSize: 10,
+			insert: func(ctx context.Context, _ string, _ []data.LogEntry) error {
+				<-ctx.Done()
+				return ctx.Err()
+			},
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "1",
+			result:     batchResult{Failed: []int{0, 1}},
+		},
+		{
+			name:       "PartialFailure",
+			body:       ndjson,
+			queueSize:  10,
+			insert:     bulkFailure("request", 0),
+			status:     http.StatusServiceUnavailable,
+			retryAfter: "1",
+			result:     batchResult{Stored: 1, Failed: []int{1}},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			app := Config{writer: newLogWriter(&fakeInserter{insert: tc.insert}, 100, tc.queueSize, 5*time.Millisecond, 50*time.Millisecond)}
+			defer app.writer.Close()
+
+			r := httptest.NewRequest(http.MethodPost, "/logs/batch", strings.NewReader(tc.body))
+			w := httptest.NewRecorder()
+			app.WriteLogs(w, r)
+
+			require.Equal(t, tc.status, w.Code)
+			require.Equal(t, tc.retryAfter, w.Header().Get("Retry-After"))
+			if tc.status == http.StatusBadRequest {
+				return
+			}
+
+			var resp struct {
+				Error bool        `json:"error"`
+				Data  batchResult `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			require.Equal(t, tc.status != http.StatusCreated, resp.Error)
+			require.Equal(t, tc.result, resp.Data)
+		})
+	}
+}
@@ -2,7 +2,9 @@ package data
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"sort"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -21,9 +23,66 @@ type LogEntry struct {
 	ID        string    `bson:"_id,omitempty" json:"id,omitempty"`
 	Name      string    `bson:"name" json:"name"`
 	Data      any       `bson:"data" json:"data"`
+	RequestID string    `bson:"request_id,omitempty" json:"request_id,omitempty"`
+	Kind      string    `bson:"-" json:"kind,omitempty"`
 	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 }
 
+const (
+	errorLogs   = "error-logs"
+	requestLogs = "request-logs"
+)
+
+// logKinds are the collections of the logs by their kind, as reported in LogEntry.Kind
+var logKinds = map[string]string{
+	errorLogs:   "error",
+	requestLogs: "request",
+}
+
+// logIndexes are the indexes of both collections. Every filter of a LogQuery is followed by the fields logs are
+// ordered by, and the text index is the one $text searches.
+var logIndexes = []mongo.IndexModel{
+	{
+		Keys:    bson.D{{Key: "request_id", Value: 1}, {Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("request_id_created_at").SetSparse(true),
+	},
+	{
+		Keys:    bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("created_at_id"),
+	},
+	{
+		Keys:    bson.D{{Key: "data.service", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("service_created_at"),
+	},
+	{
+		Keys:    bson.D{{Key: "name", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("name_created_at"),
+	},
+	{
+		Keys:    bson.D{{Key: "data.level", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("level_created_at"),
+	},
+	{
+		Keys:    bson.D{{Key: "data.status_code", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("status_code_created_at"),
+	},
+	{
+		Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "data.message", Value: "text"}, {Key: "data.error", Value: "text"}},
+		Options: options.Index().SetName("text"),
+	},
+}
+
+// EnsureIndexes creates the indexes the queries of the logs rely on, unless they exist
+func EnsureIndexes(ctx context.Context) error {
+	for collection := range logKinds {
+		_, err := client.Database("logs").Collection(collection).Indexes().CreateMany(ctx, logIndexes)
+		if err != nil {
+			return fmt.Errorf("cannot create index of %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
 func New(mongo *mongo.Client) Models {
 	client = mongo
 
@@ -32,32 +91,27 @@ func New(mongo *mongo.Client) Models {
 	}
 }
 
-func (l *LogEntry) InsertError(entry LogEntry) error {
-	collection := client.Database("logs").Collection("error-logs")
-
-	_, err := collection.InsertOne(context.TODO(), LogEntry{
-		Name:      entry.Name,
-		Data:      entry.Data,
-		CreatedAt: time.Now(),
-	})
-
-	if err != nil {
-		log.Println("error inserting into logs:", err)
-		return err
+// InsertMany stores logs of a kind at once, stamped with the time they are stored at. The logs are inserted
+// unordered, so that a log that can't be stored doesn't keep the others from being stored, and a
+// mongo.BulkWriteException tells which ones weren't.
+func (l *LogEntry) InsertMany(ctx context.Context, kind string, entries []LogEntry) error {
+	collection := Retention{Kind: kind}.Collection()
+	if collection == "" {
+		return fmt.Errorf("unknown kind of logs %q", kind)
 	}
 
-	return nil
-}
-
-func (l *LogEntry) InsertRequest(entry LogEntry) error {
-	collection := client.Database("logs").Collection("request-logs")
-
-	_, err := collection.InsertOne(context.TODO(), LogEntry{
-		Name:      entry.Name,
-		Data:      entry.Data,
-		CreatedAt: time.Now(),
-	})
+	now := time.Now()
+	docs := make([]any, len(entries))
+	for i, entry := range entries {
+		docs[i] = LogEntry{
+			Name:      entry.Name,
+			Data:      entry.Data,
+			RequestID: entry.RequestID,
+			CreatedAt: now,
+		}
+	}
 
+	_, err := client.Database("logs").Collection(collection).InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
 	if err != nil {
 		log.Println("error inserting into logs:", err)
 		return err
@@ -66,55 +120,65 @@ func (l *LogEntry) InsertRequest(entry LogEntry) error {
 	return nil
 }
 
-func (l *LogEntry) All() ([]*LogEntry, error) {
+// GetOne returns the error or request log of the id
+func (l *LogEntry) GetOne(id string) (*LogEntry, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	collection := client.Database("logs").Collection("logs")
-
-	opts := options.Find()
-	opts.SetSort(bson.D{{"created_at", -1}})
-
-	cursor, err := collection.Find(context.TODO(), bson.D{}, opts)
+	docID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		log.Println("error finding all logs:", err)
 		return nil, err
 	}
-	defer cursor.Close(ctx)
-
-	var logs []*LogEntry
 
-	for cursor.Next(ctx) {
-		var item LogEntry
-
-		err = cursor.Decode(&item)
+	for collection, kind := range logKinds {
+		var entry LogEntry
+		err = client.Database("logs").Collection(collection).FindOne(ctx, bson.M{"_id": docID}).Decode(&entry)
+		if err == mongo.ErrNoDocuments {
+			continue
+		}
 		if err != nil {
-			log.Println("error decoding log into slice:", err)
 			return nil, err
-		} else {
-			logs = append(logs, &item)
 		}
+
+		entry.Kind = kind
+		return &entry, nil
 	}
 
-	return logs, nil
+	return nil, mongo.ErrNoDocuments
 }
 
-func (l *LogEntry) GetOne(id string) (*LogEntry, error) {
+// ByRequest returns the error and request logs of a request, oldest first, so that the path of a request through
+// the services can be followed
+func (l *LogEntry) ByRequest(requestID string) ([]*LogEntry, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
 	defer cancel()
 
-	collection := client.Database("logs").Collection("logs")
+	opts := options.Find()
+	opts.SetSort(bson.D{{Key: "created_at", Value: 1}})
 
-	docID, err := primitive.ObjectIDFromHex(id)
-	if err != nil {
-		return nil, err
-	}
+	logs := []*LogEntry{}
+	for collection, kind := range logKinds {
+		cursor, err := client.Database("logs").Collection(collection).Find(ctx, bson.M{"request_id": requestID}, opts)
+		if err != nil {
+			log.Println("error finding logs of request:", err)
+			return nil, err
+		}
 
-	var entry LogEntry
-	err = collection.FindOne(ctx, bson.M{"_id": docID}).Decode(&entry)
-	if err != nil {
-		return nil, err
+		var entries []*LogEntry
+		err = cursor.All(ctx, &entries)
+		if err != nil {
+			log.Println("error decoding logs of request:", err)
+			return nil, err
+		}
+		for _, entry := range entries {
+			entry.Kind = kind
+		}
+		logs = append(logs, entries...)
 	}
 
-	return &entry, nil
+	sort.SliceStable(logs, func(i, j int) bool {
+		return logs[i].CreatedAt.Before(logs[j].CreatedAt)
+	})
+
+	return logs, nil
 }
@@ -0,0 +1,201 @@
+package data
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Limits of the number of logs of a page
+const (
+	DefaultQueryLimit = 50
+	MaxQueryLimit     = 500
+)
+
+// ErrInvalidCursor is returned for a cursor that wasn't returned by Query
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// LogQuery selects logs. Zero fields don't filter, and the fields of the data of a log are the ones the logging
+// package of the services ships.
+type LogQuery struct {
+	// Kinds are the kinds of logs, error or request, all kinds if empty
+	Kinds []string
+	// Service is the service that logged
+	Service string
+	// Name is the name of the log, such as account-getAccount
+	Name string
+	// Level is the level of the log, such as ERROR
+	Level string
+	// StatusFrom and StatusTo bound the status code of the log, both included
+	StatusFrom, StatusTo int
+	// From and To bound the time the log was stored at, From included and To excluded
+	From, To time.Time
+	// RequestID is the id of the request the log is about
+	RequestID string
+	// Text is searched for in the name, message and error of the log
+	Text string
+	// Cursor continues a previous query after the last log it returned
+	Cursor string
+	// Limit is the number of logs of a page
+	Limit int
+}
+
+// filter returns the filter of the fields of q, without its cursor
+func (q LogQuery) filter() bson.D {
+	filter := bson.D{}
+	if q.Service != "" {
+		filter = append(filter, bson.E{Key: "data.service", Value: q.Service})
+	}
+	if q.Name != "" {
+		filter = append(filter, bson.E{Key: "name", Value: q.Name})
+	}
+	if q.Level != "" {
+		filter = append(filter, bson.E{Key: "data.level", Value: strings.ToUpper(q.Level)})
+	}
+	if q.StatusFrom != 0 || q.StatusTo != 0 {
+		status := bson.D{}
+		if q.StatusFrom != 0 {
+			status = append(status, bson.E{Key: "$gte", Value: q.StatusFrom})
+		}
+		if q.StatusTo != 0 {
+			status = append(status, bson.E{Key: "$lte", Value: q.StatusTo})
+		}
+		filter = append(filter, bson.E{Key: "data.status_code", Value: status})
+	}
+	if !q.From.IsZero() || !q.To.IsZero() {
+		createdAt := bson.D{}
+		if !q.From.IsZero() {
+			createdAt = append(createdAt, bson.E{Key: "$gte", Value: q.From})
+		}
+		if !q.To.IsZero() {
+			createdAt = append(createdAt, bson.E{Key: "$lt", Value: q.To})
+		}
+		filter = append(filter, bson.E{Key: "created_at", Value: createdAt})
+	}
+	if q.RequestID != "" {
+		filter = append(filter, bson.E{Key: "request_id", Value: q.RequestID})
+	}
+	if q.Text != "" {
+		filter = append(filter, bson.E{Key: "$text", Value: bson.D{{Key: "$search", Value: q.Text}}})
+	}
+	return filter
+}
+
+// cursor is the position after the last log of a page. Logs are ordered by the time they were stored at and then
+// by their id, newest first, which orders the logs of both collections the same way.
+type cursor struct {
+	createdAt time.Time
+	id        primitive.ObjectID
+}
+
+func (c cursor) encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", c.createdAt.UnixMilli(), c.id.Hex())))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	var millis int64
+	var hex string
+	if _, err = fmt.Sscanf(strings.Replace(string(raw), ":", " ", 1), "%d %s", &millis, &hex); err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{createdAt: time.UnixMilli(millis).UTC(), id: id}, nil
+}
+
+// filter returns the filter of the logs after c
+func (c cursor) filter() bson.E {
+	return bson.E{Key: "$or", Value: bson.A{
+		bson.D{{Key: "created_at", Value: bson.D{{Key: "$lt", Value: c.createdAt}}}},
+		bson.D{{Key: "created_at", Value: c.createdAt}, {Key: "_id", Value: bson.D{{Key: "$lt", Value: c.id}}}},
+	}}
+}
+
+// Query returns a page of the logs q selects from the error and request logs, newest first, and the cursor of the
+// next page, which is empty after the last one
+func (l *LogEntry) Query(ctx context.Context, q LogQuery) ([]*LogEntry, string, error) {
+	if q.Limit <= 0 {
+		q.Limit = DefaultQueryLimit
+	}
+	if q.Limit > MaxQueryLimit {
+		q.Limit = MaxQueryLimit
+	}
+
+	filter := q.filter()
+	if q.Cursor != "" {
+		after, err := decodeCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter = append(filter, after.filter())
+	}
+
+	// every collection returns a page of its own, one more than needed to tell whether there is a next one
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(q.Limit + 1))
+
+	logs := []*LogEntry{}
+	for collection, kind := range logKinds {
+		if len(q.Kinds) > 0 && !contains(q.Kinds, kind) {
+			continue
+		}
+
+		found, err := client.Database("logs").Collection(collection).Find(ctx, filter, opts)
+		if err != nil {
+			log.Println("error querying logs:", err)
+			return nil, "", err
+		}
+		var entries []*LogEntry
+		if err = found.All(ctx, &entries); err != nil {
+			log.Println("error decoding logs:", err)
+			return nil, "", err
+		}
+		for _, entry := range entries {
+			entry.Kind = kind
+		}
+		logs = append(logs, entries...)
+	}
+
+	sort.Slice(logs, func(i, j int) bool {
+		if !logs[i].CreatedAt.Equal(logs[j].CreatedAt) {
+			return logs[i].CreatedAt.After(logs[j].CreatedAt)
+		}
+		return logs[i].ID > logs[j].ID
+	})
+	if len(logs) <= q.Limit {
+		return logs, "", nil
+	}
+
+	logs = logs[:q.Limit]
+	last := logs[len(logs)-1]
+	id, err := primitive.ObjectIDFromHex(last.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return logs, cursor{createdAt: last.CreatedAt, id: id}.encode(), nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	c := cursor{
+		createdAt: time.Date(2023, 3, 14, 15, 9, 26, 535000000, time.UTC),
+		id:        primitive.NewObjectID(),
+	}
+
+	decoded, err := decodeCursor(c.encode())
+	require.NoError(t, err)
+	require.Equal(t, c, decoded)
+
+	// the time is kept to the millisecond, as mongo stores it
+	c.createdAt = c.createdAt.Add(999 * time.Microsecond)
+	decoded, err = decodeCursor(c.encode())
+	require.NoError(t, err)
+	require.Equal(t, c.createdAt.Truncate(time.Millisecond), decoded.createdAt)
+}
+
+func TestDecodeInvalidCursor(t *testing.T) {
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+
+	testCases := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "Empty", cursor: ""},
+		{name: "NotBase64", cursor: "not a cursor!"},
+		{name: "PaddedBase64", cursor: base64.URLEncoding.EncodeToString([]byte("1:640f8e3e1c2b3a4d5e6f7a8b"))},
+		{name: "NoSeparator", cursor: encode("1678806566535")},
+		{name: "NotMillis", cursor: encode("yesterday:640f8e3e1c2b3a4d5e6f7a8b")},
+		{name: "ShortID", cursor: encode("1678806566535:640f8e")},
+		{name: "NotHexID", cursor: encode("1678806566535:zzzzzzzzzzzzzzzzzzzzzzzz")},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := decodeCursor(tc.cursor)
+			require.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// ttlIndex is the name of the TTL index of both collections
+	ttlIndex = "created_at_ttl"
+	// archiveStates is the collection of the progress of the archive of every kind of logs
+	archiveStates = "archive-states"
+)
+
+// Retention is how long the logs of a kind are kept, by their level
+type Retention struct {
+	// Kind is the kind of logs, error or request
+	Kind string
+	// Default is the retention of the logs of levels that aren't in Levels, and of logs without a level
+	Default time.Duration
+	// Levels are the retentions of levels, such as ERROR, that are kept longer or shorter than Default
+	Levels map[string]time.Duration
+}
+
+// Collection returns the collection of the logs of the kind
+func (r Retention) Collection() string {
+	for collection, kind := range logKinds {
+		if kind == r.Kind {
+			return collection
+		}
+	}
+	return ""
+}
+
+// Longest returns the longest retention of the logs, which the TTL index of their collection expires them after
+func (r Retention) Longest() time.Duration {
+	longest := r.Default
+	for _, retention := range r.Levels {
+		if retention > longest {
+			longest = retention
+		}
+	}
+	return longest
+}
+
+// Shortest returns the shortest retention of the logs
+func (r Retention) Shortest() time.Duration {
+	shortest := r.Default
+	for _, retention := range r.Levels {
+		if retention < shortest {
+			shortest = retention
+		}
+	}
+	return shortest
+}
+
+// EnsureRetention sets the TTL index of the collection of every retention, which expires logs after the longest
+// retention of their kind. A TTL index is a single field index and mongo keeps a single index of a key, so logs
+// kept for less than that are deleted by Expire.
+func EnsureRetention(ctx context.Context, retentions ...Retention) error {
+	for _, r := range retentions {
+		collection := r.Collection()
+		if collection == "" {
+			return fmt.Errorf("unknown kind of logs %q", r.Kind)
+		}
+		seconds := int32(r.Longest().Seconds())
+
+		indexes, err := client.Database("logs").Collection(collection).Indexes().List(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot list indexes of %s: %w", collection, err)
+		}
+		var existing []struct {
+			Name               string `bson:"name"`
+			ExpireAfterSeconds *int32 `bson:"expireAfterSeconds"`
+		}
+		if err = indexes.All(ctx, &existing); err != nil {
+			return fmt.Errorf("cannot decode indexes of %s: %w", collection, err)
+		}
+
+		found := false
+		for _, index := range existing {
+			if index.Name != ttlIndex {
+				continue
+			}
+			found = true
+			if index.ExpireAfterSeconds != nil && *index.ExpireAfterSeconds == seconds {
+				break
+			}
+			// the retention changed since the index was created
+			err = client.Database("logs").RunCommand(ctx, bson.D{
+				{Key: "collMod", Value: collection},
+				{Key: "index", Value: bson.D{{Key: "name", Value: ttlIndex}, {Key: "expireAfterSeconds", Value: seconds}}},
+			}).Err()
+			if err != nil {
+				return fmt.Errorf("cannot update TTL index of %s: %w", collection, err)
+			}
+		}
+		if found {
+			continue
+		}
+
+		_, err = client.Database("logs").Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName(ttlIndex).SetExpireAfterSeconds(seconds),
+		})
+		if err != nil {
+			return fmt.Errorf("cannot create TTL index of %s: %w", collection, err)
+		}
+	}
+	return nil
+}
+
+// Expire deletes the logs that are kept for less than the TTL index of their collection once their retention
+// passed, and returns the number of logs deleted
+func (l *LogEntry) Expire(ctx context.Context, r Retention, now time.Time) (int64, error) {
+	collection := client.Database("logs").Collection(r.Collection())
+
+	var deleted int64
+	for _, filter := range r.expireFilters(now) {
+		result, err := collection.DeleteMany(ctx, filter)
+		if err != nil {
+			log.Println("error expiring logs:", err)
+			return deleted, err
+		}
+		deleted += result.DeletedCount
+	}
+	return deleted, nil
+}
+
+// expireFilters returns the filters of the logs whose retention passed at now, one per level kept for less than
+// the longest retention and one for the other levels if Default is shorter. The logs kept for the longest retention
+// are left to the TTL index.
+func (r Retention) expireFilters(now time.Time) []bson.D {
+	longest := r.Longest()
+
+	levels := make([]string, 0, len(r.Levels))
+	for level := range r.Levels {
+		levels = append(levels, level)
+	}
+	sort.Strings(levels)
+
+	filters := []bson.D{}
+	overridden := bson.A{}
+	for _, level := range levels {
+		overridden = append(overridden, level)
+		if r.Levels[level] < longest {
+			filters = append(filters, bson.D{
+				{Key: "data.level", Value: level},
+				{Key: "created_at", Value: bson.D{{Key: "$lt", Value: now.Add(-r.Levels[level])}}},
+			})
+		}
+	}
+	if r.Default < longest {
+		filters = append(filters, bson.D{
+			{Key: "data.level", Value: bson.D{{Key: "$nin", Value: overridden}}},
+			{Key: "created_at", Value: bson.D{{Key: "$lt", Value: now.Add(-r.Default)}}},
+		})
+	}
+	return filters
+}
+
+// Export calls fn with every log of a kind stored from from, included, to to, excluded, oldest first. The logs
+// are passed as the documents stored, so that they can be archived and restored as they are.
+func (l *LogEntry) Export(ctx context.Context, kind string, from, to time.Time, fn func(bson.Raw) error) (int, error) {
+	collection := Retention{Kind: kind}.Collection()
+	if collection == "" {
+		return 0, fmt.Errorf("unknown kind of logs %q", kind)
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	filter := bson.D{{Key: "created_at", Value: bson.D{{Key: "$gte", Value: from}, {Key: "$lt", Value: to}}}}
+	cursor, err := client.Database("logs").Collection(collection).Find(ctx, filter, opts)
+	if err != nil {
+		log.Println("error exporting logs:", err)
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	for cursor.Next(ctx) {
+		if err = fn(cursor.Current); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, cursor.Err()
+}
+
+// ArchiveState is the progress of the archive of a kind of logs
+type ArchiveState struct {
+	Kind string `bson:"_id" json:"kind"`
+	// ArchivedUntil is the time until which the logs were archived, the logs stored since are archived next
+	ArchivedUntil time.Time `bson:"archived_until" json:"archived_until"`
+	// LastFile is the file the last archived logs were written to
+	LastFile string `bson:"last_file" json:"last_file"`
+	// LastCount is the number of logs written to LastFile
+	LastCount int `bson:"last_count" json:"last_count"`
+	// Total is the number of logs of the kind archived so far
+	Total     int64     `bson:"total" json:"total"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// ArchiveState returns the progress of the archive of a kind of logs, which is zero before the first archive
+func (l *LogEntry) ArchiveState(ctx context.Context, kind string) (ArchiveState, error) {
+	var state ArchiveState
+	err := client.Database("logs").Collection(archiveStates).FindOne(ctx, bson.M{"_id": kind}).Decode(&state)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ArchiveState{Kind: kind}, nil
+	}
+	return state, err
+}
+
+// SaveArchiveState stores the progress of the archive of a kind of logs
+func (l *LogEntry) SaveArchiveState(ctx context.Context, state ArchiveState) error {
+	state.UpdatedAt = time.Now()
+	_, err := client.Database("logs").Collection(archiveStates).ReplaceOne(ctx, bson.M{"_id": state.Kind}, state,
+		options.Replace().SetUpsert(true))
+	return err
+}
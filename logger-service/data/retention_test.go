@@ -0,0 +1,94 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestRetentionBounds(t *testing.T) {
+	testCases := []struct {
+		name              string
+		retention         Retention
+		longest, shortest time.Duration
+	}{
+		{name: "Default", retention: Retention{Default: 24 * time.Hour}, longest: 24 * time.Hour, shortest: 24 * time.Hour},
+		{
+			name:      "Levels",
+			retention: Retention{Default: 24 * time.Hour, Levels: map[string]time.Duration{"DEBUG": time.Hour, "ERROR": 72 * time.Hour}},
+			longest:   72 * time.Hour,
+			shortest:  time.Hour,
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.longest, tc.retention.Longest())
+			require.Equal(t, tc.shortest, tc.retention.Shortest())
+		})
+	}
+}
+
+func TestRetentionCollection(t *testing.T) {
+	require.Equal(t, errorLogs, Retention{Kind: "error"}.Collection())
+	require.Equal(t, requestLogs, Retention{Kind: "request"}.Collection())
+	require.Empty(t, Retention{Kind: "debug"}.Collection())
+}
+
+func TestExpireFilters(t *testing.T) {
+	now := time.Date(2023, 3, 14, 12, 0, 0, 0, time.UTC)
+	before := func(d time.Duration) bson.D {
+		return bson.D{{Key: "$lt", Value: now.Add(-d)}}
+	}
+
+	testCases := []struct {
+		name      string
+		retention Retention
+		filters   []bson.D
+	}{
+		{
+			// the TTL index expires every log
+			name:      "DefaultOnly",
+			retention: Retention{Kind: "request", Default: 168 * time.Hour},
+			filters:   []bson.D{},
+		},
+		{
+			name: "ShorterLevels",
+			retention: Retention{Kind: "error", Default: 720 * time.Hour, Levels: map[string]time.Duration{
+				"WARN":  72 * time.Hour,
+				"DEBUG": 24 * time.Hour,
+			}},
+			filters: []bson.D{
+				{{Key: "data.level", Value: "DEBUG"}, {Key: "created_at", Value: before(24 * time.Hour)}},
+				{{Key: "data.level", Value: "WARN"}, {Key: "created_at", Value: before(72 * time.Hour)}},
+			},
+		},
+		{
+			// the longest level is left to the TTL index, the other levels expire after the default
+			name: "LongerLevel",
+			retention: Retention{Kind: "error", Default: 720 * time.Hour, Levels: map[string]time.Duration{
+				"ERROR": 2160 * time.Hour,
+				"DEBUG": 24 * time.Hour,
+			}},
+			filters: []bson.D{
+				{{Key: "data.level", Value: "DEBUG"}, {Key: "created_at", Value: before(24 * time.Hour)}},
+				{
+					{Key: "data.level", Value: bson.D{{Key: "$nin", Value: bson.A{"DEBUG", "ERROR"}}}},
+					{Key: "created_at", Value: before(720 * time.Hour)},
+				},
+			},
+		},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.filters, tc.retention.expireFilters(now))
+		})
+	}
+}
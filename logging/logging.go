@@ -0,0 +1,215 @@
+// Package logging is the structured, leveled logger of the dummy bank services. A logger writes records through any
+// number of handlers, such as JSON on stdout and Ship, which passes them on to logger-service through a
+// logship.Shipper. Every record carries the name of the service, the id of its request and the fields of its context,
+// and secrets such as passwords, tokens and authorization headers are redacted before any handler sees them.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"golang.org/x/exp/slog"
+)
+
+// Keys of the fields every service logs the same way
+const (
+	KeyService    = "service"
+	KeyRoute      = "route"
+	KeyRequestID  = "request_id"
+	KeyUserID     = "user_id"
+	KeyAccountID  = "account_id"
+	KeyStatusCode = "status_code"
+	KeyError      = "error"
+)
+
+// Route is the field of the route a request matched, such as /accounts/:account_id
+func Route(route string) slog.Attr {
+	return slog.String(KeyRoute, route)
+}
+
+// RequestID is the field of the id of a request, for records logged without the context of the request
+func RequestID(id string) slog.Attr {
+	return slog.String(KeyRequestID, id)
+}
+
+// UserID is the field of the user a record is about
+func UserID(id string) slog.Attr {
+	return slog.String(KeyUserID, id)
+}
+
+// AccountID is the field of the account a record is about
+func AccountID(id string) slog.Attr {
+	return slog.String(KeyAccountID, id)
+}
+
+// StatusCode is the field of the HTTP status a request was answered with
+func StatusCode(code int) slog.Attr {
+	return slog.Int(KeyStatusCode, code)
+}
+
+// Err is the field of an error
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.String(KeyError, "")
+	}
+	return slog.String(KeyError, err.Error())
+}
+
+// ParseLevel parses the name of a level: debug, info, warn or error
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q, expected debug, info, warn or error", name)
+	}
+	return level, nil
+}
+
+// JSON writes records at level and above to w as JSON, one per line
+func JSON(w io.Writer, level slog.Leveler) slog.Handler {
+	return slog.HandlerOptions{Level: level}.NewJSONHandler(w)
+}
+
+// Stdout writes records at level and above to stdout as JSON, where docker collects them
+func Stdout(level slog.Leveler) slog.Handler {
+	return JSON(os.Stdout, level)
+}
+
+// New creates the logger of service, which writes its records through handlers
+func New(service string, handlers ...slog.Handler) *slog.Logger {
+	var h slog.Handler = &handler{handlers: handlers}
+	return slog.New(h.WithAttrs([]slog.Attr{slog.String(KeyService, service)}))
+}
+
+// fields are the fields of a context, which AddFields may add to while the request of the context is served
+type fields struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+func (f *fields) get() []slog.Attr {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.attrs
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx whose records carry attrs, besides the fields ctx already carries
+func NewContext(ctx context.Context, attrs ...slog.Attr) context.Context {
+	f := &fields{}
+	if parent, ok := ctx.Value(contextKey{}).(*fields); ok {
+		f.attrs = append(f.attrs, parent.get()...)
+	}
+	f.attrs = append(f.attrs, attrs...)
+	return context.WithValue(ctx, contextKey{}, f)
+}
+
+// AddFields adds attrs to the fields of ctx, so that they show up in the records of every handler that shares the
+// context created by NewContext, such as the access log of a request whose user only the authentication further down
+// knows. It does nothing for a context that wasn't created by NewContext.
+func AddFields(ctx context.Context, attrs ...slog.Attr) {
+	f, ok := ctx.Value(contextKey{}).(*fields)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// a copy, so that the attrs already handed out by get don't change
+	f.attrs = append(append([]slog.Attr(nil), f.attrs...), attrs...)
+}
+
+// handler adds the request id and the fields of the context to records, redacts them and passes them on to every
+// handler that is enabled for their level. It keeps the groups of the logger itself, so that the fields of the
+// context stay at the top of the record rather than in the group of the logger.
+type handler struct {
+	handlers []slog.Handler
+	groups   []group
+}
+
+// group is a group of the logger, with the attrs added to the logger while it was the innermost one
+type group struct {
+	name  string
+	attrs []slog.Attr
+}
+
+func (h *handler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, next := range h.handlers {
+		if next.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) {
+		attrs = append(attrs, redact(a))
+	})
+	for i := len(h.groups) - 1; i >= 0; i-- {
+		members := append(append([]slog.Attr(nil), h.groups[i].attrs...), attrs...)
+		attrs = []slog.Attr{slog.Group(h.groups[i].name, members...)}
+	}
+
+	if f, ok := ctx.Value(contextKey{}).(*fields); ok {
+		for _, a := range f.get() {
+			attrs = append(attrs, redact(a))
+		}
+	}
+	hasRequestID := false
+	for _, a := range attrs {
+		hasRequestID = hasRequestID || a.Key == KeyRequestID
+	}
+	if id := requestid.FromContext(ctx); id != "" && !hasRequestID {
+		attrs = append(attrs, RequestID(id))
+	}
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	record.AddAttrs(attrs...)
+
+	var errs []string
+	for _, next := range h.handlers {
+		if !next.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := next.Handle(ctx, record.Clone()); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("cannot write log: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redact(a)
+	}
+
+	if len(h.groups) == 0 {
+		handlers := make([]slog.Handler, len(h.handlers))
+		for i, next := range h.handlers {
+			handlers[i] = next.WithAttrs(redacted)
+		}
+		return &handler{handlers: handlers}
+	}
+
+	groups := append([]group(nil), h.groups...)
+	last := &groups[len(groups)-1]
+	last.attrs = append(append([]slog.Attr(nil), last.attrs...), redacted...)
+	return &handler{handlers: h.handlers, groups: groups}
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return &handler{handlers: h.handlers, groups: append(append([]group(nil), h.groups...), group{name: name})}
+}
@@ -0,0 +1,144 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+// lines decodes the JSON records written to buf
+func lines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	records := []map[string]any{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var record map[string]any
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New("account-service", JSON(&buf, slog.LevelInfo))
+
+	ctx := requestid.NewContext(context.Background(), "req-1")
+	ctx = NewContext(ctx, Route("/accounts/:account_id"), AccountID("acc-1"))
+	AddFields(ctx, UserID("user-1"))
+
+	logger.DebugCtx(ctx, "not written")
+	logger.ErrorCtx(ctx, "account-getAccount", StatusCode(http.StatusInternalServerError), Err(errors.New("db is down")))
+
+	records := lines(t, &buf)
+	require.Len(t, records, 1)
+	record := records[0]
+	require.Equal(t, "ERROR", record["level"])
+	require.Equal(t, "account-getAccount", record["msg"])
+	require.Equal(t, "account-service", record[KeyService])
+	require.Equal(t, "req-1", record[KeyRequestID])
+	require.Equal(t, "/accounts/:account_id", record[KeyRoute])
+	require.Equal(t, "acc-1", record[KeyAccountID])
+	require.Equal(t, "user-1", record[KeyUserID])
+	require.Equal(t, float64(500), record[KeyStatusCode])
+	require.Equal(t, "db is down", record[KeyError])
+}
+
+func TestRedact(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New("user-service", JSON(&buf, slog.LevelInfo))
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer abc")
+	header.Set("Accept", "application/json")
+	login := struct {
+		Username    string `json:"username"`
+		AccessToken string `json:"access_token"`
+	}{Username: "alice", AccessToken: "abc"}
+
+	logger.With("api_key", "key").Info("login",
+		"password", "hunter2",
+		"header", header,
+		"response", login,
+		"note", "bearer abc",
+		slog.Group("client", slog.String("client_secret", "s3cret"), slog.String("name", "app")),
+		"duration", time.Second,
+	)
+
+	record := lines(t, &buf)[0]
+	require.Equal(t, Redacted, record["api_key"])
+	require.Equal(t, Redacted, record["password"])
+	require.Equal(t, map[string]any{"Authorization": Redacted, "Accept": []any{"application/json"}}, record["header"])
+	require.Equal(t, map[string]any{"username": "alice", "access_token": Redacted}, record["response"])
+	require.Equal(t, Redacted, record["note"])
+	require.Equal(t, map[string]any{"client_secret": Redacted, "name": "app"}, record["client"])
+	require.Equal(t, float64(time.Second), record["duration"])
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warn")
+	require.NoError(t, err)
+	require.Equal(t, slog.LevelWarn, level)
+
+	_, err = ParseLevel("loud")
+	require.Error(t, err)
+}
+
+// sink records the entries shipped to it
+type sink struct {
+	entries chan logship.Entry
+}
+
+func (s sink) Ship(_ context.Context, batch []logship.Entry) (int, error) {
+	for _, entry := range batch {
+		s.entries <- entry
+	}
+	return len(batch), nil
+}
+
+func TestShip(t *testing.T) {
+	s := sink{entries: make(chan logship.Entry, 10)}
+	shipper := logship.New(s, logship.Config{Service: "gateway", FlushInterval: time.Hour})
+
+	var buf bytes.Buffer
+	logger := New("gateway", JSON(&buf, slog.LevelDebug), Ship(shipper, slog.LevelWarn))
+
+	ctx := NewContext(requestid.NewContext(context.Background(), "req-1"), UserID("user-1"))
+	logger.InfoCtx(ctx, "not shipped")
+	logger.InfoCtx(ctx, "/api/v1/accounts", RequestLog, StatusCode(http.StatusOK))
+	logger.WithGroup("upstream").WarnCtx(ctx, "account-service is slow", "took", 1500*time.Millisecond, "password", "x")
+	shipper.Close(context.Background())
+	close(s.entries)
+
+	entries := []logship.Entry{}
+	for entry := range s.entries {
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 2)
+	require.Len(t, lines(t, &buf), 3)
+
+	var payload Payload
+	require.Equal(t, logship.KindRequest, entries[0].Kind)
+	require.Equal(t, "req-1", entries[0].RequestID)
+	require.NoError(t, json.Unmarshal(entries[0].Data, &payload))
+	require.Equal(t, "gateway", payload.Service)
+	require.Equal(t, "INFO", payload.Level)
+	require.Equal(t, "user-1", payload.UserID)
+	require.Equal(t, http.StatusOK, payload.StatusCode)
+	require.Empty(t, payload.Fields)
+
+	payload = Payload{}
+	require.Equal(t, logship.KindError, entries[1].Kind)
+	require.Equal(t, "account-service is slow", entries[1].Name)
+	require.NoError(t, json.Unmarshal(entries[1].Data, &payload))
+	require.Equal(t, "WARN", payload.Level)
+	require.Equal(t, map[string]any{"upstream": map[string]any{"took": "1.5s", "password": Redacted}}, payload.Fields)
+}
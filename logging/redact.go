@@ -0,0 +1,114 @@
+package logging
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"golang.org/x/exp/slog"
+)
+
+// Redacted replaces the values of secrets
+const Redacted = "[REDACTED]"
+
+// secretKeys are parts of the keys of secrets, compared in lower case with dashes as underscores
+var secretKeys = []string{"password", "passwd", "token", "secret", "authorization", "api_key", "apikey", "cookie", "recovery_code"}
+
+// isSecret reports whether the value of key is a secret, such as password, access_token, Authorization or
+// X-API-Key
+func isSecret(key string) bool {
+	key = strings.ReplaceAll(strings.ToLower(key), "-", "_")
+	if key == "otp" || key == "code" {
+		return true
+	}
+	for _, secret := range secretKeys {
+		if strings.Contains(key, secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBearer reports whether s is the value of a bearer authorization, which is a secret whatever its key
+func isBearer(s string) bool {
+	return len(s) > 7 && strings.EqualFold(s[:7], "bearer ")
+}
+
+// redact replaces the secrets of a, including the ones in its groups and in the maps and structs of its value
+func redact(a slog.Attr) slog.Attr {
+	if isSecret(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		if isBearer(v.String()) {
+			return slog.String(a.Key, Redacted)
+		}
+	case slog.KindGroup:
+		group := v.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, member := range group {
+			redacted[i] = redact(member)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		return slog.Any(a.Key, redactValue(v.Any()))
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
+
+// redactValue redacts maps, structs and slices by their JSON encoding, so that the keys of secrets are the ones that
+// end up in the log. Other values, such as errors, are kept as they are.
+func redactValue(value any) any {
+	if value == nil {
+		return nil
+	}
+	if _, ok := value.(error); ok {
+		return value
+	}
+	if _, ok := value.(json.RawMessage); !ok {
+		t := reflect.TypeOf(value)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Map, reflect.Struct, reflect.Slice, reflect.Array:
+		default:
+			return value
+		}
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var decoded any
+	if err = json.Unmarshal(encoded, &decoded); err != nil {
+		return value
+	}
+	return redactJSON(decoded)
+}
+
+func redactJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if isSecret(key) {
+				v[key] = Redacted
+			} else {
+				v[key] = redactJSON(member)
+			}
+		}
+	case []any:
+		for i, member := range v {
+			v[i] = redactJSON(member)
+		}
+	case string:
+		if isBearer(v) {
+			return Redacted
+		}
+	}
+	return value
+}
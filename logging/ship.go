@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"context"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"golang.org/x/exp/slog"
+)
+
+// KeyKind is the key of RequestLog
+const KeyKind = "log_kind"
+
+// RequestLog marks the access log of a request, which Ship passes on as a request log whatever its level
+var RequestLog = slog.String(KeyKind, logship.KindRequest)
+
+// Payload is the data of a log shipped to logger-service
+type Payload struct {
+	Time       time.Time      `json:"time"`
+	Level      string         `json:"level"`
+	Service    string         `json:"service"`
+	Message    string         `json:"message"`
+	Route      string         `json:"route,omitempty"`
+	UserID     string         `json:"user_id,omitempty"`
+	AccountID  string         `json:"account_id,omitempty"`
+	StatusCode int            `json:"status_code,omitempty"`
+	Error      string         `json:"error,omitempty"`
+	Fields     map[string]any `json:"fields,omitempty"`
+}
+
+// shipHandler passes records on to logger-service through a shipper
+type shipHandler struct {
+	shipper *logship.Shipper
+	level   slog.Leveler
+	attrs   []slog.Attr
+	groups  []string
+}
+
+// Ship passes records at level and above on to logger-service through shipper, as error logs named by their message.
+// Records marked with RequestLog are passed on as request logs from LevelInfo on.
+func Ship(shipper *logship.Shipper, level slog.Leveler) slog.Handler {
+	return &shipHandler{shipper: shipper, level: level}
+}
+
+func (h *shipHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level() || level >= slog.LevelInfo
+}
+
+func (h *shipHandler) Handle(_ context.Context, r slog.Record) error {
+	kind := logship.KindError
+	payload := Payload{Time: r.Time, Level: r.Level.String(), Message: r.Message}
+	requestID := ""
+
+	set := func(a slog.Attr, fields map[string]any) {
+		v := a.Value.Resolve()
+		switch a.Key {
+		case KeyKind:
+			kind = v.String()
+		case KeyService:
+			payload.Service = v.String()
+		case KeyRoute:
+			payload.Route = v.String()
+		case KeyUserID:
+			payload.UserID = v.String()
+		case KeyAccountID:
+			payload.AccountID = v.String()
+		case KeyStatusCode:
+			payload.StatusCode = int(v.Int64())
+		case KeyError:
+			payload.Error = v.String()
+		case KeyRequestID:
+			requestID = v.String()
+		default:
+			fields[a.Key] = value(v)
+		}
+	}
+
+	// the fields of the groups are nested under their names, while the well-known fields are only looked for at the
+	// top, where this package puts them
+	fields := map[string]any{}
+	add := func(a slog.Attr, groups []string) {
+		if len(groups) == 0 {
+			set(a, fields)
+			return
+		}
+		group := fields
+		for _, name := range groups {
+			nested, ok := group[name].(map[string]any)
+			if !ok {
+				nested = map[string]any{}
+				group[name] = nested
+			}
+			group = nested
+		}
+		group[a.Key] = value(a.Value.Resolve())
+	}
+	for _, a := range h.attrs {
+		add(a, nil)
+	}
+	r.Attrs(func(a slog.Attr) {
+		add(a, h.groups)
+	})
+
+	if kind != logship.KindRequest && r.Level < h.level.Level() {
+		return nil
+	}
+	if len(fields) > 0 {
+		payload.Fields = fields
+	}
+	if kind == logship.KindRequest {
+		h.shipper.Request(requestID, r.Message, payload)
+	} else {
+		h.shipper.Error(requestID, r.Message, payload)
+	}
+	return nil
+}
+
+func (h *shipHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	if len(h.groups) == 0 {
+		clone.attrs = append(append([]slog.Attr(nil), h.attrs...), attrs...)
+		return &clone
+	}
+
+	// attrs of a group are kept as a group attr, so that they end up nested under its name
+	group := slog.Group(h.groups[len(h.groups)-1], attrs...)
+	for i := len(h.groups) - 2; i >= 0; i-- {
+		group = slog.Group(h.groups[i], group)
+	}
+	clone.attrs = append(append([]slog.Attr(nil), h.attrs...), group)
+	clone.groups = h.groups
+	return &clone
+}
+
+func (h *shipHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.groups = append(append([]string(nil), h.groups...), name)
+	return &clone
+}
+
+// value converts v into a value that encodes as JSON, with durations written like 1.5s
+func value(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindGroup:
+		group := map[string]any{}
+		for _, a := range v.Group() {
+			group[a.Key] = value(a.Value.Resolve())
+		}
+		return group
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.Any()
+}
@@ -0,0 +1,119 @@
+package logship
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// httpTimeout bounds a request of the HTTPSink made without a client of its own
+const httpTimeout = 5 * time.Second
+
+type httpSink struct {
+	client  *http.Client
+	baseURL string
+}
+
+// HTTPSink ships entries to the logger-service at baseURL, one request per batch. A nil client is replaced by one
+// with a timeout of 5 seconds.
+func HTTPSink(client *http.Client, baseURL string) Sink {
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+	return httpSink{client: client, baseURL: baseURL}
+}
+
+// batchResponse is the response of logger-service to a batch. Failed are the indexes of the entries it didn't
+// store, when it stored some entries of the batch but not all.
+type batchResponse struct {
+	Message string `json:"message"`
+	Data    struct {
+		Stored int   `json:"stored"`
+		Failed []int `json:"failed"`
+	} `json:"data"`
+}
+
+// Ship posts batch to the batch endpoint of logger-service. logger-service answers with 429 or 503 while it can't
+// keep up, which the Shipper backs off from, and tells which entries it stored if it stored some of them, which are
+// moved to the start of batch so that only the others are shipped again.
+func (h httpSink) Ship(ctx context.Context, batch []Entry) (int, error) {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return 0, err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, h.baseURL+"/logs/batch", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := h.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	body, err = io.ReadAll(response.Body)
+	if err != nil && response.StatusCode != http.StatusCreated {
+		return 0, err
+	}
+
+	n := 0
+	if response.StatusCode != http.StatusCreated {
+		var result batchResponse
+		if json.Unmarshal(body, &result) == nil {
+			n = storedFirst(batch, result.Data.Stored, result.Data.Failed)
+		}
+	}
+
+	switch {
+	case response.StatusCode == http.StatusCreated:
+		return len(batch), nil
+	case response.StatusCode == http.StatusBadRequest:
+		// logger-service won't take the batch however often it is sent
+		var result batchResponse
+		_ = json.Unmarshal(body, &result)
+		return 0, &RejectedError{Err: fmt.Errorf("logger-service responded with status %d: %s", response.StatusCode,
+			result.Message)}
+	case response.Header.Get("Retry-After") != "":
+		return n, fmt.Errorf("logger-service responded with status %d, retry after %ss", response.StatusCode,
+			response.Header.Get("Retry-After"))
+	default:
+		return n, fmt.Errorf("logger-service responded with status %d", response.StatusCode)
+	}
+}
+
+// storedFirst moves the stored entries of batch to its start, keeping the order of the stored and of the failed
+// entries, and returns how many were stored. Nothing is taken as stored unless stored and failed add up to the batch.
+func storedFirst(batch []Entry, stored int, failed []int) int {
+	if stored <= 0 || stored+len(failed) != len(batch) {
+		return 0
+	}
+	isFailed := make(map[int]bool, len(failed))
+	for _, i := range failed {
+		if i < 0 || i >= len(batch) {
+			return 0
+		}
+		isFailed[i] = true
+	}
+	if len(isFailed) != len(failed) {
+		return 0
+	}
+
+	reordered := make([]Entry, 0, len(batch))
+	for i, entry := range batch {
+		if !isFailed[i] {
+			reordered = append(reordered, entry)
+		}
+	}
+	for i, entry := range batch {
+		if isFailed[i] {
+			reordered = append(reordered, entry)
+		}
+	}
+	copy(batch, reordered)
+	return stored
+}
@@ -0,0 +1,345 @@
+// Package logship ships the error and request logs of a dummy bank service to logger-service without holding up the
+// requests they are about. Entries are buffered in a bounded queue and shipped in batches by a single goroutine,
+// through a Sink such as HTTPSink. Batches that can't be shipped after a few retries, and entries that don't fit
+// into the queue, are spilled to a file and shipped again once the sink recovers. Entries are only dropped when
+// neither the queue nor the spill file has room, or when the sink rejects them, which the log_entries_dropped_total
+// counter records.
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Kinds of entries, which logger-service keeps apart
+const (
+	KindError   = "error"
+	KindRequest = "request"
+)
+
+// Entry is a log on its way to logger-service. Data is encoded when the entry is created, so that it can't change
+// while the entry is queued.
+type Entry struct {
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name"`
+	Data      json.RawMessage `json:"data"`
+	RequestID string          `json:"request_id,omitempty"`
+}
+
+// Sink ships a batch of entries. It reports how many entries from the start of the batch it shipped before it
+// failed, so that those aren't shipped twice. A sink that shipped entries from further back moves them to the start
+// of the batch.
+type Sink interface {
+	Ship(ctx context.Context, batch []Entry) (int, error)
+}
+
+// RejectedError is returned by a Sink for entries the receiver won't take however often they are shipped, which are
+// dropped rather than retried or spilled
+type RejectedError struct {
+	Err error
+}
+
+func (e *RejectedError) Error() string {
+	return "logs rejected: " + e.Err.Error()
+}
+
+func (e *RejectedError) Unwrap() error {
+	return e.Err
+}
+
+// Defaults of the zero fields of Config
+const (
+	DefaultQueueSize     = 1000
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+	DefaultRetries       = 3
+	DefaultBackoff       = 200 * time.Millisecond
+	DefaultSpillMaxBytes = 64 << 20
+)
+
+// replayPause is the number of flush intervals replays of the spill file pause for after the sink failed
+const replayPause = 30
+
+// Config are the settings of a Shipper. Zero fields take the defaults, and an empty SpillDir turns spilling off.
+type Config struct {
+	// Service is the name of the service, by which the metrics of the shipper are labelled
+	Service string
+	// QueueSize is the number of entries waiting to be shipped, beyond which entries are spilled
+	QueueSize int
+	// BatchSize is the number of entries shipped at once
+	BatchSize int
+	// FlushInterval is how long an entry waits at most for its batch to fill up
+	FlushInterval time.Duration
+	// Retries is the number of retries of a batch that failed, before it is spilled. A negative number turns retries
+	// off.
+	Retries int
+	// Backoff is the wait before the first retry, doubled for every further retry
+	Backoff time.Duration
+	// SpillDir is the directory of the spill file
+	SpillDir string
+	// SpillMaxBytes is the size the spill file may grow to
+	SpillMaxBytes int64
+}
+
+func (c Config) withDefaults() Config {
+	if c.QueueSize <= 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = DefaultBatchSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	if c.Retries < 0 {
+		c.Retries = 0
+	} else if c.Retries == 0 {
+		c.Retries = DefaultRetries
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = DefaultBackoff
+	}
+	if c.SpillMaxBytes <= 0 {
+		c.SpillMaxBytes = DefaultSpillMaxBytes
+	}
+	return c
+}
+
+// Stats counts what became of the entries of a Shipper
+type Stats struct {
+	Shipped int64
+	Spilled int64
+	Dropped int64
+}
+
+// Shipper buffers entries and ships them through its sink in the background, until it is closed
+type Shipper struct {
+	sink    Sink
+	config  Config
+	spill   *spill
+	queue   chan Entry
+	closing atomic.Bool
+	stop    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	// closeCtx bounds the shipping of the last batches, once Close was called
+	closeCtx context.Context
+	// nextReplay is when the spilled entries are shipped again, which only run touches
+	nextReplay time.Time
+
+	shipped, spilled, dropped atomic.Int64
+}
+
+// New creates a Shipper, which starts shipping entries through sink right away
+func New(sink Sink, config Config) *Shipper {
+	config = config.withDefaults()
+	s := &Shipper{
+		sink:     sink,
+		config:   config,
+		queue:    make(chan Entry, config.QueueSize),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+		closeCtx: context.Background(),
+	}
+	if config.SpillDir != "" {
+		s.spill = newSpill(config.SpillDir, config.SpillMaxBytes)
+	}
+
+	go s.run()
+	return s
+}
+
+// Error queues an error log of the request requestID, which may be empty
+func (s *Shipper) Error(requestID, name string, data any) {
+	s.send(KindError, requestID, name, data)
+}
+
+// Request queues the log of the response to the request requestID
+func (s *Shipper) Request(requestID, name string, data any) {
+	s.send(KindRequest, requestID, name, data)
+}
+
+func (s *Shipper) send(kind, requestID, name string, data any) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Println("logship: cannot encode log:", err)
+		s.drop(dropEncode, 1)
+		return
+	}
+	s.Send(Entry{Kind: kind, Name: name, Data: encoded, RequestID: requestID})
+}
+
+// Send queues entry without waiting. An entry that doesn't fit into the queue, or comes after Close, is spilled.
+func (s *Shipper) Send(entry Entry) {
+	if !s.closing.Load() {
+		select {
+		case s.queue <- entry:
+			queued.WithLabelValues(s.config.Service).Inc()
+			return
+		default:
+		}
+	}
+	s.spillOrDrop([]Entry{entry}, dropOverflow)
+}
+
+// Stats returns what became of the entries so far
+func (s *Shipper) Stats() Stats {
+	return Stats{Shipped: s.shipped.Load(), Spilled: s.spilled.Load(), Dropped: s.dropped.Load()}
+}
+
+// Close ships the queued entries and stops the shipper. Entries that can't be shipped before ctx is done are spilled.
+func (s *Shipper) Close(ctx context.Context) {
+	s.once.Do(func() {
+		s.closeCtx = ctx
+		s.closing.Store(true)
+		close(s.stop)
+	})
+	<-s.stopped
+
+	// entries sent while Close was called
+	for len(s.queue) > 0 {
+		queued.WithLabelValues(s.config.Service).Dec()
+		s.spillOrDrop([]Entry{<-s.queue}, dropOverflow)
+	}
+}
+
+func (s *Shipper) run() {
+	defer close(s.stopped)
+
+	ticker := time.NewTicker(s.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]Entry, 0, s.config.BatchSize)
+	flush := func(ctx context.Context) {
+		if len(batch) > 0 {
+			s.ship(ctx, batch)
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case entry := <-s.queue:
+			queued.WithLabelValues(s.config.Service).Dec()
+			batch = append(batch, entry)
+			if len(batch) == s.config.BatchSize {
+				flush(context.Background())
+			}
+		case <-ticker.C:
+			flush(context.Background())
+			if s.spill != nil && !time.Now().Before(s.nextReplay) {
+				s.replay(context.Background())
+			}
+		case <-s.stop:
+			// run is the only receiver, so the entries in the queue can be taken without blocking
+			for len(s.queue) > 0 {
+				entry := <-s.queue
+				queued.WithLabelValues(s.config.Service).Dec()
+				batch = append(batch, entry)
+				if len(batch) == s.config.BatchSize {
+					flush(s.closeCtx)
+				}
+			}
+			flush(s.closeCtx)
+			return
+		}
+	}
+}
+
+// ship ships batch, retrying what is left of it with backoff. What can't be shipped is spilled.
+func (s *Shipper) ship(ctx context.Context, batch []Entry) {
+	backoff := s.config.Backoff
+	for attempt := 0; ; attempt++ {
+		n, err := s.sink.Ship(ctx, batch)
+		s.count(&s.shipped, shippedEntries, n)
+		batch = batch[n:]
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			log.Printf("logship: %d logs rejected: %v", len(batch), rejected.Err)
+			s.drop(dropRejected, len(batch))
+		}
+		if err == nil || rejected != nil || len(batch) == 0 {
+			// the sink recovered, so the spilled entries can follow
+			s.nextReplay = time.Time{}
+			return
+		}
+
+		if attempt == s.config.Retries || !wait(ctx, backoff) {
+			log.Printf("logship: cannot ship %d logs: %v", len(batch), err)
+			s.spillOrDrop(batch, dropShip)
+			s.nextReplay = time.Now().Add(replayPause * s.config.FlushInterval)
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// wait waits for d, unless ctx is done first
+func wait(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// replay ships the spilled entries again, as long as the sink takes them. After a failure, replays pause for
+// replayPause flush intervals, unless a batch gets through before.
+func (s *Shipper) replay(ctx context.Context) {
+	dropped, err := s.spill.replay(s.config.BatchSize, func(batch []Entry) (int, error) {
+		n, err := s.sink.Ship(ctx, batch)
+		s.count(&s.shipped, shippedEntries, n)
+		var rejected *RejectedError
+		if errors.As(err, &rejected) {
+			log.Printf("logship: %d spilled logs rejected: %v", len(batch)-n, rejected.Err)
+			s.drop(dropRejected, len(batch)-n)
+			return len(batch), nil
+		}
+		return n, err
+	})
+	s.drop(dropSpillFull, dropped)
+	if err != nil {
+		log.Println("logship: cannot replay spilled logs:", err)
+		s.nextReplay = time.Now().Add(replayPause * s.config.FlushInterval)
+	}
+}
+
+func (s *Shipper) spillOrDrop(entries []Entry, reason string) {
+	if s.spill != nil {
+		n, err := s.spill.write(entries)
+		s.count(&s.spilled, spilledEntries, n)
+		entries = entries[n:]
+		if err != nil {
+			log.Println("logship: cannot spill logs:", err)
+		} else {
+			reason = dropSpillFull
+		}
+	}
+	s.drop(reason, len(entries))
+}
+
+func (s *Shipper) drop(reason string, n int) {
+	if n == 0 {
+		return
+	}
+	s.dropped.Add(int64(n))
+	droppedEntries.WithLabelValues(s.config.Service, reason).Add(float64(n))
+}
+
+func (s *Shipper) count(total *atomic.Int64, counter counterVec, n int) {
+	if n == 0 {
+		return
+	}
+	total.Add(int64(n))
+	counter.WithLabelValues(s.config.Service).Add(float64(n))
+}
@@ -0,0 +1,208 @@
+package logship
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink records the entries it ships, and fails while failing is set
+type fakeSink struct {
+	mu      sync.Mutex
+	entries []Entry
+	failing bool
+	// rejected are the names of the entries the sink rejects
+	rejected map[string]bool
+	block    chan struct{}
+}
+
+func (f *fakeSink) Ship(ctx context.Context, batch []Entry) (int, error) {
+	if f.block != nil {
+		<-f.block
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failing {
+		return 0, errors.New("logger-service is down")
+	}
+	for _, entry := range batch {
+		if f.rejected[entry.Name] {
+			return 0, &RejectedError{Err: errors.New("invalid log")}
+		}
+	}
+	f.entries = append(f.entries, batch...)
+	return len(batch), nil
+}
+
+func (f *fakeSink) setFailing(failing bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failing = failing
+}
+
+func (f *fakeSink) shipped() []Entry {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Entry(nil), f.entries...)
+}
+
+func TestShipper(t *testing.T) {
+	sink := &fakeSink{}
+	shipper := New(sink, Config{Service: "test", BatchSize: 2, FlushInterval: time.Hour})
+
+	shipper.Request("req-1", "getAccount", map[string]any{"status_code": 200})
+	shipper.Error("req-1", "getAccount", map[string]any{"status_code": 404})
+	shipper.Error("", "cron", "daily report failed")
+	shipper.Close(context.Background())
+
+	entries := sink.shipped()
+	require.Len(t, entries, 3)
+	require.Equal(t, Entry{Kind: KindRequest, Name: "getAccount", Data: json.RawMessage(`{"status_code":200}`), RequestID: "req-1"}, entries[0])
+	require.Equal(t, KindError, entries[1].Kind)
+	require.Equal(t, json.RawMessage(`"daily report failed"`), entries[2].Data)
+	require.Equal(t, Stats{Shipped: 3}, shipper.Stats())
+}
+
+func TestShipperSpillsAndReplays(t *testing.T) {
+	dir := t.TempDir()
+	sink := &fakeSink{failing: true}
+	shipper := New(sink, Config{Service: "test", BatchSize: 1, FlushInterval: 5 * time.Millisecond, Retries: -1, SpillDir: dir})
+
+	shipper.Error("", "first", 1)
+	shipper.Error("", "second", 2)
+	require.Eventually(t, func() bool { return shipper.Stats().Spilled == 2 }, time.Second, time.Millisecond)
+	require.FileExists(t, filepath.Join(dir, "logs.ndjson"))
+
+	sink.setFailing(false)
+	shipper.Error("", "third", 3)
+	require.Eventually(t, func() bool { return len(sink.shipped()) == 3 }, 2*time.Second, 5*time.Millisecond)
+	shipper.Close(context.Background())
+
+	names := []string{}
+	for _, entry := range sink.shipped() {
+		names = append(names, entry.Name)
+	}
+	require.ElementsMatch(t, []string{"first", "second", "third"}, names)
+	require.Equal(t, Stats{Shipped: 3, Spilled: 2}, shipper.Stats())
+	require.NoFileExists(t, filepath.Join(dir, "logs.ndjson"))
+	require.NoFileExists(t, filepath.Join(dir, "logs.replay.ndjson"))
+}
+
+func TestShipperDropsRejected(t *testing.T) {
+	dir := t.TempDir()
+	sink := &fakeSink{rejected: map[string]bool{"invalid": true}}
+	shipper := New(sink, Config{Service: "test", BatchSize: 1, FlushInterval: time.Hour, SpillDir: dir})
+
+	shipper.Error("", "invalid", 1)
+	shipper.Error("", "valid", 2)
+	shipper.Close(context.Background())
+
+	require.Len(t, sink.shipped(), 1)
+	require.Equal(t, Stats{Shipped: 1, Dropped: 1}, shipper.Stats())
+	require.NoFileExists(t, filepath.Join(dir, "logs.ndjson"))
+}
+
+func TestShipperDropsOnOverflow(t *testing.T) {
+	sink := &fakeSink{block: make(chan struct{})}
+	shipper := New(sink, Config{Service: "test", QueueSize: 1, BatchSize: 1, FlushInterval: time.Hour})
+
+	// the first entry is being shipped, the second waits in the queue and the third has no room
+	shipper.Error("", "first", 1)
+	require.Eventually(t, func() bool { return len(shipper.queue) == 0 }, time.Second, time.Millisecond)
+	shipper.Error("", "second", 2)
+	shipper.Error("", "third", 3)
+	require.Equal(t, int64(1), shipper.Stats().Dropped)
+
+	close(sink.block)
+	shipper.Close(context.Background())
+	require.Equal(t, Stats{Shipped: 2, Dropped: 1}, shipper.Stats())
+}
+
+func TestSpillLimit(t *testing.T) {
+	dir := t.TempDir()
+	sink := &fakeSink{failing: true}
+	shipper := New(sink, Config{Service: "test", BatchSize: 2, FlushInterval: time.Hour, Retries: -1, SpillDir: dir, SpillMaxBytes: 80})
+
+	shipper.Error("", "first", 1)
+	shipper.Error("", "second", 2)
+	shipper.Close(context.Background())
+
+	require.Equal(t, Stats{Spilled: 1, Dropped: 1}, shipper.Stats())
+	info, err := os.Stat(filepath.Join(dir, "logs.ndjson"))
+	require.NoError(t, err)
+	require.LessOrEqual(t, info.Size(), int64(80))
+}
+
+func TestHTTPSink(t *testing.T) {
+	var mu sync.Mutex
+	batches := [][]Entry{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/logs/batch", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		var batch []Entry
+		require.NoError(t, json.Unmarshal(body, &batch))
+
+		mu.Lock()
+		batches = append(batches, batch)
+		mu.Unlock()
+
+		switch batch[0].Name {
+		case "busy":
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "invalid":
+			w.WriteHeader(http.StatusBadRequest)
+		case "partial":
+			// the first entry of the batch failed, the second was stored
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"error":true,"message":"insert failed","data":{"stored":1,"failed":[0]}}`))
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+
+	sink := HTTPSink(server.Client(), server.URL)
+	n, err := sink.Ship(context.Background(), []Entry{
+		{Kind: KindRequest, Name: "getAccount", Data: json.RawMessage(`{}`), RequestID: "req-1"},
+		{Kind: KindError, Name: "getAccount", Data: json.RawMessage(`{}`)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	n, err = sink.Ship(context.Background(), []Entry{{Kind: KindError, Name: "invalid", Data: json.RawMessage(`{}`)}})
+	var rejected *RejectedError
+	require.ErrorAs(t, err, &rejected)
+	require.Equal(t, 0, n)
+
+	n, err = sink.Ship(context.Background(), []Entry{{Kind: KindError, Name: "busy", Data: json.RawMessage(`{}`)}})
+	require.Error(t, err)
+	require.Equal(t, 0, n)
+
+	batch := []Entry{
+		{Kind: KindError, Name: "partial", Data: json.RawMessage(`{}`)},
+		{Kind: KindError, Name: "stored", Data: json.RawMessage(`{}`)},
+	}
+	n, err = sink.Ship(context.Background(), batch)
+	require.Error(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, "stored", batch[0].Name)
+	require.Equal(t, "partial", batch[1].Name)
+
+	require.Len(t, batches, 4)
+	require.Equal(t, KindRequest, batches[0][0].Kind)
+	require.Equal(t, "req-1", batches[0][0].RequestID)
+	require.Equal(t, KindError, batches[0][1].Kind)
+}
@@ -0,0 +1,44 @@
+package logship
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Reasons for dropping entries
+const (
+	// dropEncode is for data that can't be encoded as JSON
+	dropEncode = "encode"
+	// dropOverflow is for entries that didn't fit into the queue, with spilling turned off or failing
+	dropOverflow = "overflow"
+	// dropShip is for entries that couldn't be shipped, with spilling turned off or failing
+	dropShip = "ship"
+	// dropSpillFull is for entries that didn't fit into the spill file
+	dropSpillFull = "spill_full"
+	// dropRejected is for entries the sink won't take however often they are shipped
+	dropRejected = "rejected"
+)
+
+type counterVec = *prometheus.CounterVec
+
+var (
+	queued = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "log_entries_queued",
+		Help: "Log entries waiting to be shipped to logger-service, by service.",
+	}, []string{"service"})
+
+	shippedEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_entries_shipped_total",
+		Help: "Log entries shipped to logger-service, by service.",
+	}, []string{"service"})
+
+	spilledEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_entries_spilled_total",
+		Help: "Log entries written to the spill file to be shipped later, by service.",
+	}, []string{"service"})
+
+	droppedEntries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "log_entries_dropped_total",
+		Help: "Log entries lost before they reached logger-service, by service and reason.",
+	}, []string{"service", "reason"})
+)
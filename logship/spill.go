@@ -0,0 +1,149 @@
+package logship
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxSpillLine is the size of the largest entry read back from the spill file
+const maxSpillLine = 16 << 20
+
+// spill keeps entries that couldn't be shipped in a file of newline delimited JSON. A replay moves the file aside,
+// so that entries spilled meanwhile go to a new one.
+type spill struct {
+	mu         sync.Mutex
+	dir        string
+	path       string
+	replayPath string
+	maxBytes   int64
+}
+
+func newSpill(dir string, maxBytes int64) *spill {
+	return &spill{
+		dir:        dir,
+		path:       filepath.Join(dir, "logs.ndjson"),
+		replayPath: filepath.Join(dir, "logs.replay.ndjson"),
+		maxBytes:   maxBytes,
+	}
+}
+
+// write appends as many of entries as fit under the size limit, and returns how many it wrote
+func (sp *spill) write(entries []Entry) (int, error) {
+	if len(entries) == 0 {
+		return 0, nil
+	}
+
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+
+	if err := os.MkdirAll(sp.dir, 0o755); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(sp.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	n := 0
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return 0, err
+		}
+		if info.Size()+int64(buf.Len()+len(line)+1) > sp.maxBytes {
+			break
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		n++
+	}
+
+	if _, err = file.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// replay hands the spilled entries to ship in batches of batchSize, until ship fails. What ship didn't take is
+// written back, and the number of entries that no longer fit is returned. A replay interrupted by a crash is
+// picked up again by the next one.
+func (sp *spill) replay(batchSize int, ship func(batch []Entry) (int, error)) (dropped int, err error) {
+	sp.mu.Lock()
+	if _, err = os.Stat(sp.replayPath); errors.Is(err, fs.ErrNotExist) {
+		err = os.Rename(sp.path, sp.replayPath)
+	}
+	sp.mu.Unlock()
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	file, err := os.Open(sp.replayPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var batch, rest []Entry
+	var shipErr error
+	flush := func() {
+		if shipErr != nil {
+			rest = append(rest, batch...)
+		} else {
+			var n int
+			n, shipErr = ship(batch)
+			if shipErr != nil {
+				rest = append(rest, batch[n:]...)
+			}
+		}
+		batch = batch[:0]
+	}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64<<10), maxSpillLine)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			// a line cut off by a crash
+			dropped++
+			continue
+		}
+		batch = append(batch, entry)
+		if len(batch) == batchSize {
+			flush()
+		}
+	}
+	if len(batch) > 0 {
+		flush()
+	}
+	err = scanner.Err()
+	file.Close()
+	if err != nil {
+		return dropped, err
+	}
+
+	n, err := sp.write(rest)
+	if err != nil {
+		// the replay file is kept, to be shipped again once the spill file can be written
+		return dropped, err
+	}
+	dropped += len(rest) - n
+	if err = os.Remove(sp.replayPath); err != nil {
+		return dropped, err
+	}
+	return dropped, shipErr
+}
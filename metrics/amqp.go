@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	amqpPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "amqp_messages_published_total",
+		Help: "AMQP messages published, by exchange, routing key and result.",
+	}, []string{"exchange", "routing_key", "result"})
+
+	amqpConsumed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "amqp_messages_consumed_total",
+		Help: "AMQP messages consumed, by exchange, routing key and whether handling them succeeded.",
+	}, []string{"exchange", "routing_key", "result"})
+)
+
+// ObservePublish counts a message published to exchange with routingKey, which failed when err isn't nil
+func ObservePublish(exchange, routingKey string, err error) {
+	amqpPublished.WithLabelValues(exchange, routingKey, result(err)).Inc()
+}
+
+// ObserveConsume counts a message consumed from exchange with routingKey, whose handling failed when err isn't nil
+func ObserveConsume(exchange, routingKey string, err error) {
+	amqpConsumed.WithLabelValues(exchange, routingKey, result(err)).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "success"
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMiddleware(t *testing.T) {
+	handler := Middleware(func(r *http.Request) string {
+		if strings.HasPrefix(r.URL.Path, "/accounts/") {
+			return "/accounts/{id}"
+		}
+		return ""
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/accounts/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+		w.Write([]byte("{}"))
+	}))
+
+	for _, path := range []string{"/accounts/1", "/accounts/2", "/accounts/missing", "/wp-login.php"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	require.Equal(t, 2.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/accounts/{id}", "200")))
+	require.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, "/accounts/{id}", "404")))
+	require.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues(http.MethodGet, unmatchedRoute, "200")))
+}
+
+func TestAMQP(t *testing.T) {
+	ObservePublish("logs_topic", "log.ERROR", nil)
+	ObservePublish("logs_topic", "log.ERROR", errors.New("channel closed"))
+	ObserveConsume("logs_topic", "log.ERROR", nil)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(amqpPublished.WithLabelValues("logs_topic", "log.ERROR", "success")))
+	require.Equal(t, 1.0, testutil.ToFloat64(amqpPublished.WithLabelValues("logs_topic", "log.ERROR", "error")))
+	require.Equal(t, 1.0, testutil.ToFloat64(amqpConsumed.WithLabelValues("logs_topic", "log.ERROR", "success")))
+}
+
+func TestHandler(t *testing.T) {
+	ObserveHTTP(http.MethodPost, "/transactions/create", http.StatusCreated, 0)
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), `http_requests_total{method="POST",route="/transactions/create",status="201"} 1`)
+	require.Contains(t, string(body), "go_goroutines")
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"fmt"
 	"log"
+	"net/http"
 	"time"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
 	db "github.com/bugrakocabay/dummy-bank-microservice/report-service/db/sqlc"
 	"github.com/gin-gonic/gin"
 )
@@ -14,10 +16,7 @@ func (server *Server) getDailyReport(ctx *gin.Context) {
 
 	report, err := server.store.GetDailyTransactionReport(ctx, currentTime)
 	if err != nil {
-		server.sendErrorLog("getDailyReport", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("error fetching transactions: %v", err),
-		})
+		server.logger.ErrorCtx(ctx, "getDailyReport", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("error fetching transactions: %w", err)))
 		return
 	}
 
@@ -31,10 +30,7 @@ func (server *Server) getDailyReport(ctx *gin.Context) {
 
 	err = server.store.SaveDailyTransactionReport(ctx, resp)
 	if err != nil {
-		server.sendErrorLog("getDailyReport", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("error saving transactions: %v", err),
-		})
+		server.logger.ErrorCtx(ctx, "getDailyReport", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("error saving transactions: %w", err)))
 		return
 	}
 
@@ -1,10 +1,11 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
-	"github.com/gin-gonic/gin"
+	"fmt"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	"io"
 	"log"
 	"net/http"
@@ -73,60 +74,30 @@ func (server *Server) errorJSON(w http.ResponseWriter, err error, status ...int)
 	return server.writeJSON(w, statusCode, payload)
 }
 
-type JSONPayload struct {
-	Name string `json:"name"`
-	Data any    `json:"data"`
-}
-
-type Log struct {
-	Message    any `json:"message"`
-	StatusCode int `json:"status_code"`
-}
-
-func (server *Server) sendErrorLog(name string, payload Log) {
-	arg := JSONPayload{
-		Name: name,
-		Data: payload,
-	}
-	jsonData, err := json.Marshal(arg)
-	if err != nil {
-		log.Println("sendErrorLog error: cant marshal json:", err)
-		return
-	}
-	request, err := http.NewRequest(http.MethodPost, "http://logger-service/logs/create-error", bytes.NewBuffer(jsonData))
-
-	client := &http.Client{}
-	response, err := client.Do(request)
-	if err != nil {
-		log.Println("sendErrorLog error: cant send response:", err)
-		return
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusCreated {
-		log.Println("sendErrorLog error: calling auth service:", err)
-		return
-	}
-
-	log.Println("error logged successfully")
-	return
-}
-
-// runDailyCron runs a cron that runs every day at 00:00
-func (server *Server) runDailyCron(ctx *gin.Context) {
+// runDailyCron runs a cron that runs every day at 00:00, until ctx is done. A run that already started is waited for.
+func (server *Server) runDailyCron(ctx context.Context) {
 	var lock sync.Mutex
+	var running sync.WaitGroup
+	defer running.Wait()
+
 	now := time.Now()
 	next := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
 	timer := time.NewTimer(next.Sub(now))
 	defer timer.Stop()
 	for {
-		/* run forever */
+		/* run until the service stops */
 		select {
+		case <-ctx.Done():
+			log.Println("stopped daily cron")
+			return
 		case <-timer.C:
+			running.Add(1)
 			go func() {
+				defer running.Done()
 				lock.Lock()
 				defer lock.Unlock()
-				_, err := http.Get("http://report-service/reports/daily-report")
+				client := &http.Client{Transport: tracing.Transport(nil)}
+				_, err := client.Get(fmt.Sprintf("%s/reports/daily-report", server.config.ReportServiceURL))
 				if err != nil {
 					log.Println("error daily report request: ", err)
 				}
@@ -1,21 +1,76 @@
 package main
 
 import (
-	"github.com/spf13/viper"
+	"fmt"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/config"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+	"golang.org/x/exp/slog"
 )
 
 type Config struct {
-	AccountDbConnString string `mapstructure:"ACCOUNT_DB_CONN_STRING"`
+	AccountDbConnString string        `mapstructure:"ACCOUNT_DB_CONN_STRING" validate:"required" usage:"postgres connection string of the accounts database"`
+	WebPort             string        `mapstructure:"WEB_PORT" default:"80" validate:"port" usage:"port of the HTTP api"`
+	ShutdownTimeout     time.Duration `mapstructure:"SHUTDOWN_TIMEOUT" default:"10s" validate:"positive" usage:"time the requests in flight may take to finish once the service is asked to stop"`
+	ReportServiceURL    string        `mapstructure:"REPORT_SERVICE_URL" default:"http://report-service" validate:"url" usage:"base URL the daily cron requests the daily report from"`
+	LoggerServiceURL    string        `mapstructure:"LOGGER_SERVICE_URL" default:"http://logger-service" validate:"url" usage:"base URL of logger-service"`
+	LogQueueSize        int           `mapstructure:"LOG_QUEUE_SIZE" default:"1000" validate:"positive" usage:"logs waiting to be shipped to logger-service, beyond which they are spilled"`
+	LogBatchSize        int           `mapstructure:"LOG_BATCH_SIZE" default:"100" validate:"positive" usage:"logs shipped to logger-service at once"`
+	LogFlushInterval    time.Duration `mapstructure:"LOG_FLUSH_INTERVAL" default:"1s" validate:"positive" usage:"longest time a log waits for its batch to fill up"`
+	LogSpillDir         string        `mapstructure:"LOG_SPILL_DIR" default:"./app/log-spill" usage:"directory logs that can't be shipped are kept in until logger-service is back, empty to drop them"`
+	LogLevel            string        `mapstructure:"LOG_LEVEL" default:"info" usage:"level from which logs are written to stdout: debug, info, warn or error"`
+	LogShipLevel        string        `mapstructure:"LOG_SHIP_LEVEL" default:"warn" usage:"level from which logs are shipped to logger-service, besides the request logs"`
+	TraceExporter       string        `mapstructure:"TRACE_EXPORTER" default:"none" usage:"where traces are exported to: none, otlp, stdout or file"`
+	TraceEndpoint       string        `mapstructure:"TRACE_OTLP_ENDPOINT" default:"jaeger:4317" usage:"host:port of the OTLP receiver traces are sent to by the otlp exporter"`
+	TraceFile           string        `mapstructure:"TRACE_FILE" default:"traces.json" usage:"file the file exporter appends traces to"`
+	TraceSampleRatio    float64       `mapstructure:"TRACE_SAMPLE_RATIO" default:"1" usage:"share of new traces that are recorded, between 0 and 1"`
+}
+
+// logLevels returns the levels from which logs are written to stdout and shipped to logger-service
+func (c Config) logLevels() (stdout, ship slog.Level) {
+	// Validate rejected levels that can't be parsed, which leaves unset ones at info
+	stdout, _ = logging.ParseLevel(c.LogLevel)
+	ship, _ = logging.ParseLevel(c.LogShipLevel)
+	return stdout, ship
+}
+
+// logShipConfig returns the settings of the shipper of the logs to logger-service
+func (c Config) logShipConfig() logship.Config {
+	return logship.Config{
+		Service:       "report-service",
+		QueueSize:     c.LogQueueSize,
+		BatchSize:     c.LogBatchSize,
+		FlushInterval: c.LogFlushInterval,
+		SpillDir:      c.LogSpillDir,
+	}
 }
 
-func LoadConfig() (config Config, err error) {
-	viper.SetConfigFile("./app/config.json")
+// traceConfig returns the settings of the trace exporter
+func (c Config) traceConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.TraceExporter,
+		Endpoint:    c.TraceEndpoint,
+		File:        c.TraceFile,
+		SampleRatio: c.TraceSampleRatio,
+	}
+}
 
-	err = viper.ReadInConfig()
-	if err != nil {
-		return
+// Validate checks the log levels and the settings of the trace exporter
+func (c Config) Validate() error {
+	if _, err := logging.ParseLevel(c.LogLevel); err != nil {
+		return fmt.Errorf("LOG_LEVEL: %w", err)
 	}
+	if _, err := logging.ParseLevel(c.LogShipLevel); err != nil {
+		return fmt.Errorf("LOG_SHIP_LEVEL: %w", err)
+	}
+	return c.traceConfig().Validate()
+}
 
-	err = viper.Unmarshal(&config)
+// LoadConfig reads the settings of report-service from flags, the environment and the config file
+func LoadConfig() (cfg Config, err error) {
+	err = config.Load(&cfg)
 	return
 }
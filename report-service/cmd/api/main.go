@@ -1,33 +1,63 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
 	db "github.com/bugrakocabay/dummy-bank-microservice/report-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	_ "github.com/lib/pq"
+	"golang.org/x/exp/slog"
 	"log"
 )
 
-const webPort = "80"
-
 func main() {
-	log.Printf("Starting Report service on port: %s", webPort)
-
 	config, err := LoadConfig()
 	if err != nil {
-		log.Fatal("Error with loading env: ", err)
+		log.Fatal("Error with loading config: ", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "report-service", config.traceConfig())
+	if err != nil {
+		log.Fatal("Error with setting up tracing: ", err)
 	}
+	defer shutdownTracing(context.Background())
+
+	// the server drains once the service is asked to stop, before the deferred close of the connection runs
+	ctx, stop := shutdown.Context()
+	defer stop()
+
+	log.Printf("Starting Report service on port: %s", config.WebPort)
 
 	conn, err := sql.Open("postgres", config.AccountDbConnString)
 	if err != nil {
 		log.Fatal("Cannot connect to DB:", err)
 	}
+	defer conn.Close()
+	metrics.RegisterDB(conn, "accounts")
 
 	store := db.NewStore(conn)
-	server := NewServer(store)
+	server := NewServer(config, store)
+	// the logs of the last requests are shipped once the servers drained
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		server.logs.Close(ctx)
+	}()
+	// the output of the log package, such as the one of the code that doesn't log through the logger yet, is written
+	// as JSON records too
+	slog.SetDefault(server.logger)
+
+	checker := health.NewChecker()
+	checker.Add("postgres", health.DB(conn))
+	server.ServeHealth(checker)
 
-	address := fmt.Sprintf(":%s", webPort)
-	if err = server.Start(address); err != nil {
-		log.Fatal("Server start failed:", err)
+	address := fmt.Sprintf(":%s", config.WebPort)
+	if err = server.Start(ctx, address); err != nil {
+		log.Println("HTTP server stopped:", err)
 	}
+	log.Println("Report service stopped")
 }
@@ -1,22 +1,42 @@
 package main
 
 import (
+	"context"
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
 	db "github.com/bugrakocabay/dummy-bank-microservice/report-service/db/sqlc"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
 )
 
 type Server struct {
+	config Config
 	store  db.Store
 	router *gin.Engine
+	logs   *logship.Shipper
+	logger *slog.Logger
 }
 
-func NewServer(store db.Store) *Server {
+func NewServer(config Config, store db.Store) *Server {
 	server := &Server{
-		store: store,
+		config: config,
+		store:  store,
+		logs:   logship.New(logship.HTTPSink(nil, config.LoggerServiceURL), config.logShipConfig()),
 	}
-	ctx := gin.Context{}
-	go server.runDailyCron(&ctx)
+	stdoutLevel, shipLevel := config.logLevels()
+	server.logger = logging.New("report-service", logging.Stdout(stdoutLevel), logging.Ship(server.logs, shipLevel))
 	router := gin.Default()
+	// handlers pass their *gin.Context on as a context.Context, which has to reach the values of the request context
+	router.ContextWithFallback = true
+	router.Use(traceRequest, observeRequest, requestID, logFields)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	router.GET("/reports/daily-report", server.getDailyReport)
 
@@ -24,10 +44,56 @@ func NewServer(store db.Store) *Server {
 	return server
 }
 
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// ServeHealth serves the liveness of the service at /healthz and the checks of checker at /readyz
+func (server *Server) ServeHealth(checker *health.Checker) {
+	server.router.GET("/healthz", gin.WrapH(health.LiveHandler()))
+	server.router.GET("/readyz", gin.WrapH(checker.ReadyHandler()))
+}
+
+// Start serves the HTTP api on address and runs the daily cron until ctx is done, then lets the requests in flight
+// and a running cron finish
+func (server *Server) Start(ctx context.Context, address string) error {
+	cronStopped := make(chan struct{})
+	go func() {
+		server.runDailyCron(ctx)
+		close(cronStopped)
+	}()
+	defer func() { <-cronStopped }()
+
+	srv := &http.Server{Addr: address, Handler: server.router}
+	return shutdown.ServeHTTP(ctx, srv, server.config.ShutdownTimeout)
 }
 
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// traceRequest starts the span of the request, which continues the trace of the caller
+func traceRequest(ctx *gin.Context) {
+	spanCtx, span := tracing.StartHTTPServer(ctx.Request, "report-service", ctx.FullPath())
+	ctx.Request = ctx.Request.WithContext(spanCtx)
+	ctx.Next()
+	tracing.EndHTTPServer(span, ctx.Writer.Status())
+}
+
+// observeRequest counts the request and the time taken to serve it, by the route it matched
+func observeRequest(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+	metrics.ObserveHTTP(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+}
+
+// requestID puts the id of the X-Request-ID header, or a new one, into the context of the request and the header of
+// its response, so that the logs of the request can be matched with the ones of the other services
+func requestID(ctx *gin.Context) {
+	id := requestid.FromRequest(ctx.Request)
+	ctx.Request = ctx.Request.WithContext(requestid.NewContext(ctx.Request.Context(), id))
+	ctx.Header(requestid.Header, id)
+	ctx.Next()
+}
+
+// logFields puts the route of the request into the context of its logs
+func logFields(ctx *gin.Context) {
+	ctx.Request = ctx.Request.WithContext(logging.NewContext(ctx.Request.Context(), logging.Route(ctx.FullPath())))
+	ctx.Next()
+}
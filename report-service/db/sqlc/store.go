@@ -2,6 +2,8 @@ package db
 
 import (
 	"database/sql"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 )
 
 // Store provides all functions to execute db queries and transactions
@@ -17,6 +19,6 @@ type SQLStore struct {
 func NewStore(db *sql.DB) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(tracing.WrapDB(db, "postgresql")),
 	}
 }
@@ -0,0 +1,102 @@
+// Package requestid carries the id of a client request through the dummy bank services, so that the logs of every
+// service a request passed through can be matched. The gateway accepts the id from the X-Request-ID header of the
+// client, or generates one, and every service passes it on in the header of HTTP calls, the metadata of gRPC calls
+// and the headers of AMQP messages.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	// Header is the HTTP header of the id
+	Header = "X-Request-ID"
+	// Key is the key of the id in gRPC metadata and in the headers of AMQP messages
+	Key = "x-request-id"
+
+	// maxLength is the length of the longest id that is accepted from a client
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// New generates a random id
+func New() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// Valid reports whether id may be accepted from a client: up to 128 letters, digits and the characters -_.:, so
+// that an id can't break the logs it ends up in
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying id
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the id ctx carries, or an empty string
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// FromRequest returns the id of the X-Request-ID header of r, or a new one when the header is missing or invalid
+func FromRequest(r *http.Request) string {
+	if id := r.Header.Get(Header); Valid(id) {
+		return id
+	}
+	return New()
+}
+
+// Handler puts the id of every request into its context and the X-Request-ID header of its response
+func Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := FromRequest(r)
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), id)))
+	})
+}
+
+// Transport sets the X-Request-ID header of the requests of an http.Client to the id of their context, sending them
+// with base
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return transport{base: base}
+}
+
+type transport struct {
+	base http.RoundTripper
+}
+
+func (t transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	id := FromContext(req.Context())
+	if id == "" || req.Header.Get(Header) != "" {
+		return t.base.RoundTrip(req)
+	}
+
+	// a RoundTripper must not modify the request it was given
+	req = req.Clone(req.Context())
+	req.Header.Set(Header, id)
+	return t.base.RoundTrip(req)
+}
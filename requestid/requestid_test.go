@@ -0,0 +1,90 @@
+package requestid
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestValid(t *testing.T) {
+	require.True(t, Valid(New()))
+	require.True(t, Valid("client-42_retry.1:a"))
+	require.False(t, Valid(""))
+	require.False(t, Valid("id with spaces"))
+	require.False(t, Valid("id\nINFO forged log line"))
+	require.False(t, Valid(strings.Repeat("a", maxLength+1)))
+}
+
+func TestHandlerAndTransport(t *testing.T) {
+	var received string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(Header)
+	}))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: Transport(nil)}
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL, nil)
+		require.NoError(t, err)
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}))
+
+	// a valid id of the client is kept
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(Header, "client-id-1")
+	handler.ServeHTTP(w, r)
+	require.Equal(t, "client-id-1", w.Header().Get(Header))
+	require.Equal(t, "client-id-1", received)
+
+	// an invalid one is replaced
+	w = httptest.NewRecorder()
+	r = httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(Header, "bad id")
+	handler.ServeHTTP(w, r)
+	id := w.Header().Get(Header)
+	require.NotEqual(t, "bad id", id)
+	require.True(t, Valid(id))
+	require.Equal(t, id, received)
+}
+
+func TestGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var received string
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryServerInterceptor(),
+		func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+			received = FromContext(ctx)
+			return handler(ctx, req)
+		}))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = healthpb.NewHealthClient(conn).Check(NewContext(context.Background(), "client-id-1"), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, "client-id-1", received)
+
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.True(t, Valid(received))
+	require.NotEqual(t, "client-id-1", received)
+}
@@ -1,3 +1,7 @@
 module github.com/dummy-bank-scripts
 
 go 1.19
+
+require github.com/bugrakocabay/dummy-bank-microservice/client v0.0.0
+
+replace github.com/bugrakocabay/dummy-bank-microservice/client => ../client
@@ -18,13 +18,21 @@ func main() {
 		}
 		fmt.Printf("Created user: %d\n", i+1)
 	}*/
+	// accountIDs collects the accounts created so far, which transfers pick their receiver from
+	var accountIDs []string
+	var mu sync.Mutex
+
 	createUserWithTransaction := func(wg *sync.WaitGroup, id int) {
 		defer wg.Done()
 		email := requests.CreateUser()
 		accessToken := requests.Login(email)
-		accountIDs := requests.GetAllAccounts(accessToken)
 		myAccountID := requests.CreateAccount(accessToken)
+
+		mu.Lock()
+		accountIDs = append(accountIDs, myAccountID)
 		randomAccountID := accountIDs[requests.RandomInt(0, int64(len(accountIDs)-1))]
+		mu.Unlock()
+
 		err := requests.AddBalance(accessToken, myAccountID)
 		if err != nil {
 			panic(err)
@@ -0,0 +1,7 @@
+package requests
+
+import "github.com/bugrakocabay/dummy-bank-microservice/client"
+
+const gatewayURL = "http://localhost:8080"
+
+var api = client.NewAPI(gatewayURL)
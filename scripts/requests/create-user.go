@@ -1,73 +1,29 @@
 package requests
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
 	"fmt"
 	"log"
 	"math/rand"
-	"net/http"
 	"strings"
 	"time"
-)
-
-type CreateUserPayload struct {
-	Action string         `json:"action"`
-	Create CreateUserData `json:"create"`
-}
-
-type CreateUserData struct {
-	Firstname string `json:"firstname"`
-	Lastname  string `json:"lastname"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-}
-
-type GetUserResponse struct {
-	Error   bool     `json:"error"`
-	Message string   `json:"message"`
-	Data    UserData `json:"data"`
-}
 
-type UserData struct {
-	CreatedAt string `json:"created_at"`
-	FirstName string `json:"firstname"`
-	LastName  string `json:"lastname"`
-	Email     string `json:"email"`
-	UserID    string `json:"user_id"`
-}
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+)
 
 func CreateUser() string {
 	randomMail := fmt.Sprintf("%s%d@mail.com", RandomString(int(RandomInt(3, 7))), RandomInt(100, 1000))
-	requestBody := CreateUserPayload{
-		Action: "create",
-		Create: CreateUserData{
-			Firstname: RandomString(5),
-			Lastname:  RandomString(6),
-			Email:     randomMail,
-			Password:  "qwerty",
-		},
-	}
-
-	jsonData, _ := json.Marshal(requestBody)
-	request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/handle/users", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Fatalf("request err: %v", err)
-	}
-
-	client := &http.Client{}
-	response, err := client.Do(request)
+	user, err := api.CreateUser(context.Background(), client.CreateUserRequest{
+		Firstname: RandomString(5),
+		Lastname:  RandomString(6),
+		Email:     randomMail,
+		Password:  "qwerty",
+	})
 	if err != nil {
-		log.Fatalf("response err: %v", err)
-	}
-	defer response.Body.Close()
-
-	var resp GetUserResponse
-	if err = json.NewDecoder(response.Body).Decode(&resp); err != nil {
-		log.Fatalf("failed to decode response body: %v", err)
+		log.Fatalf("create user err: %v", err)
 	}
 
-	return resp.Data.Email
+	return user.Email
 }
 
 const alphabet = "abcdefghijklmnopqrstuvwxyz"
@@ -1,57 +1,22 @@
 package requests
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
+	"context"
 	"log"
-	"net/http"
-)
-
-type CreateTransactionPayload struct {
-	Action string                `json:"action"`
-	Create CreateTransactionData `json:"create"`
-}
 
-type CreateTransactionData struct {
-	FromAccountID     string `json:"from_account_id"`
-	ToAccountID       string `json:"to_account_id"`
-	TransactionAmount int32  `json:"transaction_amount"`
-}
-
-type CreateTransactionResponse struct {
-	Error   bool   `json:"error"`
-	Message string `json:"message"`
-	Data    any    `json:"data"`
-}
+	"github.com/bugrakocabay/dummy-bank-microservice/client"
+)
 
 func CreateTransaction(accessToken, fromAccountID, toAccountID string) error {
-	requestBody := CreateTransactionPayload{
-		Action: "create",
-		Create: CreateTransactionData{
-			FromAccountID:     fromAccountID,
-			ToAccountID:       toAccountID,
-			TransactionAmount: int32(RandomInt(1, 999)),
-		},
-	}
-
-	jsonData, _ := json.Marshal(requestBody)
-	request, err := http.NewRequest(http.MethodPost, "http://localhost:8080/handle/transactions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Fatalf("request err: %v", err)
-		return err
-	}
-
-	token := fmt.Sprintf("Bearer %s", accessToken)
-	request.Header.Set("Authorization", token)
-
-	client := &http.Client{}
-	response, err := client.Do(request)
+	_, err := api.CreateTransaction(context.Background(), accessToken, client.CreateTransactionRequest{
+		FromAccountID:     fromAccountID,
+		ToAccountID:       toAccountID,
+		TransactionAmount: float64(RandomInt(1, 999)),
+	})
 	if err != nil {
-		log.Fatalf("response err: %v", err)
+		log.Printf("create transaction err: %v", err)
 		return err
 	}
-	defer response.Body.Close()
 
 	return nil
 }
@@ -0,0 +1,89 @@
+// Package shutdown stops the servers of a dummy bank service gracefully. A service serves until the context of
+// Context is cancelled by SIGINT or SIGTERM, then stops accepting connections and lets the requests in flight finish
+// within its SHUTDOWN_TIMEOUT, before it closes its database and broker connections.
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Context returns a context that is cancelled once the service is asked to stop, by SIGINT or by the SIGTERM docker
+// sends. Calling stop restores the default behaviour, so that a second signal kills the service right away.
+func Context() (ctx context.Context, stop context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+// ServeHTTP serves srv until ctx is done, then closes its listeners and waits up to timeout for the requests in
+// flight. Connections still open after timeout are closed and reported with the error.
+func ServeHTTP(ctx context.Context, srv *http.Server, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		_ = srv.Close()
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// ServeGRPC serves srv on listener until ctx is done, then stops it from accepting connections and waits up to
+// timeout for the calls in flight, before it cancels the remaining ones
+func ServeGRPC(ctx context.Context, srv *grpc.Server, listener net.Listener, timeout time.Duration) error {
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(listener)
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-stopped:
+	case <-timer.C:
+		srv.Stop()
+		<-stopped
+		err = context.DeadlineExceeded
+	}
+
+	// Serve reports a server stopped before it got to serve, which isn't an error once the service stops
+	if serveErr := <-errs; serveErr != nil && !errors.Is(serveErr, grpc.ErrServerStopped) {
+		return serveErr
+	}
+	return err
+}
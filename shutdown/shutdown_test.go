@@ -0,0 +1,112 @@
+package shutdown
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// freeAddress returns an address nothing listens on, for servers that listen by themselves
+func freeAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// get requests address once the server listens on it
+func get(address string) (*http.Response, error) {
+	for i := 0; ; i++ {
+		resp, err := http.Get("http://" + address)
+		if err == nil || i == 100 {
+			return resp, err
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestServeHTTPDrains(t *testing.T) {
+	started := make(chan struct{})
+	address := freeAddress(t)
+	srv := &http.Server{Addr: address, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeHTTP(ctx, srv, time.Second)
+	}()
+
+	responses := make(chan string, 1)
+	go func() {
+		resp, err := get(address)
+		if err != nil {
+			responses <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		responses <- string(body)
+	}()
+
+	<-started
+	cancel()
+
+	require.Equal(t, "done", <-responses)
+	require.NoError(t, <-served)
+
+	_, err := http.Get("http://" + address)
+	require.Error(t, err)
+}
+
+func TestServeHTTPTimeout(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+
+	address := freeAddress(t)
+	srv := &http.Server{Addr: address, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeHTTP(ctx, srv, 50*time.Millisecond)
+	}()
+
+	go get(address)
+
+	<-started
+	cancel()
+	require.ErrorIs(t, <-served, context.DeadlineExceeded)
+}
+
+func TestServeGRPC(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- ServeGRPC(ctx, grpc.NewServer(), listener, time.Second)
+	}()
+
+	cancel()
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server didn't stop")
+	}
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// messagingSystem is the message broker of the services
+const messagingSystem = "rabbitmq"
+
+// headerCarrier carries the trace context in the headers of an AMQP message. amqp.Table is a map[string]interface{},
+// so the headers of a message can be passed as they are.
+type headerCarrier map[string]any
+
+func (c headerCarrier) Get(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c[key] = value
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// StartPublish starts the span of publishing a message to exchange with routingKey, and adds the trace context to
+// headers, which must not be nil
+func StartPublish(ctx context.Context, headers map[string]any, exchange, routingKey string) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, fmt.Sprintf("%s publish", exchange),
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationPublish,
+			semconv.MessagingDestinationName(exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, headerCarrier(headers))
+	return ctx, span
+}
+
+// StartConsume starts the span of processing a message consumed from exchange, which continues the trace whose
+// context the publisher added to headers
+func StartConsume(ctx context.Context, headers map[string]any, exchange, routingKey string) (context.Context, trace.Span) {
+	if headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier(headers))
+	}
+	return Tracer().Start(ctx, fmt.Sprintf("%s process", exchange),
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystem(messagingSystem),
+			semconv.MessagingOperationProcess,
+			semconv.MessagingSourceName(exchange),
+			semconv.MessagingRabbitmqDestinationRoutingKey(routingKey),
+		),
+	)
+}
@@ -0,0 +1,64 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/semconv/v1.17.0/httpconv"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Handler starts a span for every request to h, which continues the trace of the caller. Spans are named after the
+// method and path of the request, a router can rename them after the route the request matched.
+func Handler(h http.Handler, service string) http.Handler {
+	return otelhttp.NewHandler(h, service, otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+		return r.Method + " " + r.URL.Path
+	}))
+}
+
+// Transport starts a span for every request sent with base, or with http.DefaultTransport when base is nil, and
+// passes the trace context on in the request headers
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
+
+// StartHTTPServer starts the span of an incoming request for routers that otelhttp doesn't wrap. The span continues
+// the trace of the caller and is named after the route the request matched, such as /accounts/:account_id.
+func StartHTTPServer(r *http.Request, service, route string) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+	name := r.Method + " " + route
+	if route == "" {
+		name = r.Method + " " + r.URL.Path
+	}
+	attributes := httpconv.ServerRequest(service, r)
+	if route != "" {
+		attributes = append(attributes, semconv.HTTPRoute(route))
+	}
+
+	return Tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+}
+
+// EndHTTPServer records the status the request was answered with and ends its span. Only server errors mark the span
+// as failed.
+func EndHTTPServer(span trace.Span, status int) {
+	span.SetAttributes(semconv.HTTPStatusCode(status))
+	code, description := httpconv.ServerStatus(status)
+	span.SetStatus(code, description)
+	span.End()
+}
+
+// SetRoute names the span Handler started for r after the route r matched, for routers that only find the route after
+// the span was started
+func SetRoute(r *http.Request, route string) {
+	span := trace.SpanFromContext(r.Context())
+	span.SetName(r.Method + " " + route)
+	span.SetAttributes(semconv.HTTPRoute(route))
+}
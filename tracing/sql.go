@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// DBTX is the database handle of the queries sqlc generates, which *sql.DB and *sql.Tx implement
+type DBTX interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+	QueryRowContext(context.Context, string, ...interface{}) *sql.Row
+}
+
+// WrapDB returns a DBTX that starts a span for every statement run on db. system is the database system of db, such
+// as postgresql. Spans are named after the sqlc query, taken from the "-- name:" comment sqlc puts in front of it.
+func WrapDB(db DBTX, system string) DBTX {
+	return &tracedDB{db: db, system: system}
+}
+
+type tracedDB struct {
+	db     DBTX
+	system string
+}
+
+func (t *tracedDB) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := queryName(query)
+	return Tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(t.system),
+			semconv.DBOperation(operation),
+			semconv.DBStatement(query),
+		),
+	)
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := t.start(ctx, query)
+	result, err := t.db.ExecContext(ctx, query, args...)
+	End(span, err)
+	return result, err
+}
+
+func (t *tracedDB) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	ctx, span := t.start(ctx, query)
+	stmt, err := t.db.PrepareContext(ctx, query)
+	End(span, err)
+	return stmt, err
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := t.start(ctx, query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	End(span, err)
+	return rows, err
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := t.start(ctx, query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	End(span, row.Err())
+	return row
+}
+
+// queryName returns the name sqlc gave query, or the first word of a statement written by hand
+func queryName(query string) string {
+	query = strings.TrimSpace(query)
+	if strings.HasPrefix(query, "-- name:") {
+		if fields := strings.Fields(strings.TrimPrefix(query, "-- name:")); len(fields) > 0 {
+			return fields[0]
+		}
+	}
+	if fields := strings.Fields(query); len(fields) > 0 {
+		return strings.ToUpper(fields[0])
+	}
+	return "query"
+}
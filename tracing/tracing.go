@@ -0,0 +1,142 @@
+// Package tracing records distributed traces of the dummy bank services with OpenTelemetry. Every service sets up an
+// exporter with Setup, and the helpers of the package start spans for incoming and outgoing HTTP requests, gRPC
+// calls, AMQP messages and database queries, passing the trace context on to the next service.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the spans started by the package
+const instrumentationName = "github.com/bugrakocabay/dummy-bank-microservice/tracing"
+
+// Exporters where spans can be sent
+const (
+	// ExporterNone records no spans, but the trace context of incoming requests is still passed on
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector, or a backend such as Jaeger, over gRPC
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans to standard output as JSON, for local runs
+	ExporterStdout = "stdout"
+	// ExporterFile appends spans to a file as JSON, for local runs
+	ExporterFile = "file"
+)
+
+// Config selects where the spans of a service are exported to
+type Config struct {
+	// Exporter is one of none, otlp, stdout or file
+	Exporter string
+	// Endpoint is the host:port of the OTLP receiver
+	Endpoint string
+	// File is the path of the file spans are appended to
+	File string
+	// SampleRatio is the share of new traces that are recorded. Traces started by a caller follow its decision.
+	SampleRatio float64
+}
+
+// Validate rejects an unknown exporter, an exporter without its destination and a ratio outside of 0 to 1
+func (c Config) Validate() error {
+	switch c.Exporter {
+	case "", ExporterNone, ExporterStdout:
+	case ExporterOTLP:
+		if c.Endpoint == "" {
+			return fmt.Errorf("the %s exporter needs an endpoint", c.Exporter)
+		}
+	case ExporterFile:
+		if c.File == "" {
+			return fmt.Errorf("the %s exporter needs a file", c.Exporter)
+		}
+	default:
+		return fmt.Errorf("unknown trace exporter %q, must be none, otlp, stdout or file", c.Exporter)
+	}
+
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio %v must be between 0 and 1", c.SampleRatio)
+	}
+	return nil
+}
+
+// Setup installs the tracer provider of service, which exports spans as cfg selects, and the W3C trace context
+// propagator. The returned function flushes the spans that weren't exported yet and has to be called before the
+// service exits.
+func Setup(ctx context.Context, service string, cfg Config) (func(context.Context) error, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var closeFile func() error
+	switch cfg.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		otlp, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpoint(cfg.Endpoint), otlptracegrpc.WithInsecure())
+		if err != nil {
+			return nil, fmt.Errorf("cannot create OTLP exporter: %w", err)
+		}
+		exporter = otlp
+	case ExporterStdout:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+		if err != nil {
+			return nil, err
+		}
+		exporter = stdout
+	case ExporterFile:
+		file, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("cannot open trace file: %w", err)
+		}
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(file))
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		exporter = stdout
+		closeFile = file.Close
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(service))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closeFile != nil {
+			if closeErr := closeFile(); err == nil {
+				err = closeErr
+			}
+		}
+		return err
+	}, nil
+}
+
+// Tracer returns the tracer of the package, for spans of operations the helpers don't cover
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// End records err on span, unless it is nil, and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,112 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var recorder = tracetest.NewSpanRecorder()
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	os.Exit(m.Run())
+}
+
+// spansOf returns the ended spans of the trace with id
+func spansOf(id trace.TraceID) map[string]sdktrace.ReadOnlySpan {
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if span.SpanContext().TraceID() == id {
+			spans[span.Name()] = span
+		}
+	}
+	return spans
+}
+
+func TestConfigValidate(t *testing.T) {
+	require.NoError(t, Config{Exporter: ExporterNone}.Validate())
+	require.NoError(t, Config{Exporter: ExporterOTLP, Endpoint: "jaeger:4317", SampleRatio: 1}.Validate())
+	require.NoError(t, Config{Exporter: ExporterStdout, SampleRatio: 0.5}.Validate())
+	require.Error(t, Config{Exporter: ExporterOTLP}.Validate())
+	require.Error(t, Config{Exporter: ExporterFile}.Validate())
+	require.Error(t, Config{Exporter: "zipkin"}.Validate())
+	require.Error(t, Config{Exporter: ExporterStdout, SampleRatio: 2}.Validate())
+}
+
+func TestHandlerAndTransport(t *testing.T) {
+	var upstreamSpan trace.SpanContext
+	upstream := httptest.NewServer(Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamSpan = trace.SpanContextFromContext(r.Context())
+	}), "upstream"))
+	defer upstream.Close()
+
+	httpClient := &http.Client{Transport: Transport(nil)}
+	handler := Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetRoute(r, "/api/v1/users/{id}")
+		req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, upstream.URL+"/users/1", nil)
+		require.NoError(t, err)
+		resp, err := httpClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}), "gateway")
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil))
+
+	// the upstream continued the trace of the gateway
+	require.True(t, upstreamSpan.IsValid())
+	spans := spansOf(upstreamSpan.TraceID())
+	require.Contains(t, spans, "GET /api/v1/users/{id}")
+	require.Contains(t, spans, "GET /users/1")
+	require.Equal(t, trace.SpanKindServer, spans["GET /users/1"].SpanKind())
+}
+
+func TestHTTPServer(t *testing.T) {
+	parent, span := Tracer().Start(context.Background(), "caller")
+	span.End()
+
+	r := httptest.NewRequest(http.MethodGet, "/accounts/42", nil)
+	otel.GetTextMapPropagator().Inject(parent, propagation.HeaderCarrier(r.Header))
+
+	_, span = StartHTTPServer(r, "account-service", "/accounts/:account_id")
+	EndHTTPServer(span, http.StatusInternalServerError)
+
+	spans := spansOf(span.SpanContext().TraceID())
+	require.Contains(t, spans, "caller")
+	server := spans["GET /accounts/:account_id"]
+	require.NotNil(t, server)
+	require.Equal(t, codes.Error, server.Status().Code)
+	require.Equal(t, span.SpanContext().TraceID(), trace.SpanContextFromContext(parent).TraceID())
+}
+
+func TestAMQP(t *testing.T) {
+	headers := map[string]any{"x-request-id": "client-id-1"}
+	ctx, span := StartPublish(context.Background(), headers, "logs_topic", "log.ERROR")
+	span.End()
+	require.Contains(t, headers, "traceparent")
+
+	_, consumer := StartConsume(context.Background(), headers, "logs_topic", "log.ERROR")
+	consumer.End()
+
+	require.Equal(t, trace.SpanContextFromContext(ctx).TraceID(), consumer.SpanContext().TraceID())
+	spans := spansOf(consumer.SpanContext().TraceID())
+	require.Equal(t, trace.SpanKindProducer, spans["logs_topic publish"].SpanKind())
+	require.Equal(t, trace.SpanKindConsumer, spans["logs_topic process"].SpanKind())
+}
+
+func TestQueryName(t *testing.T) {
+	require.Equal(t, "GetAccount", queryName("-- name: GetAccount :one\nSELECT * FROM accounts WHERE account_id = $1"))
+	require.Equal(t, "SELECT", queryName("  select 1"))
+	require.Equal(t, "query", queryName(""))
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scopeAccountsWrite = "accounts:write"
+
+	authorizationAPIKey = "apikey"
+	apiKeyPrefix        = "dbk_"
+	// apiKeyDisplayLength is the length of the start of a key that is stored in plain text, so that users can tell
+	// their keys apart
+	apiKeyDisplayLength = 12
+)
+
+// apiKeyScopes are the permissions an api key can be limited to
+var apiKeyScopes = map[string]bool{
+	scopeAccountsRead:   true,
+	scopeAccountsWrite:  true,
+	scopeTransfersWrite: true,
+}
+
+var errInvalidAPIKey = errors.New("api key is invalid, revoked or has expired")
+
+type apiKeyResponse struct {
+	KeyID      string     `json:"key_id"`
+	Key        string     `json:"key,omitempty"`
+	Prefix     string     `json:"prefix"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func newAPIKeyResponse(apiKey db.ApiKey) apiKeyResponse {
+	resp := apiKeyResponse{
+		KeyID:      apiKey.KeyID,
+		Prefix:     apiKey.Prefix,
+		Name:       apiKey.Name,
+		Scopes:     apiKey.Scopes,
+		LastUsedIP: apiKey.LastUsedIp.String,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+	if apiKey.ExpiresAt.Valid {
+		resp.ExpiresAt = &apiKey.ExpiresAt.Time
+	}
+	if apiKey.LastUsedAt.Valid {
+		resp.LastUsedAt = &apiKey.LastUsedAt.Time
+	}
+	if apiKey.RevokedAt.Valid {
+		resp.RevokedAt = &apiKey.RevokedAt.Time
+	}
+	return resp
+}
+
+type createAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	ExpiresInDays int      `json:"expires_in_days" binding:"omitempty,min=1,max=365"`
+}
+
+// createAPIKey issues an api key for the logged-in user, meant for batch jobs and other services that call the
+// gateway without a person logging in. The key itself is only returned once, only its hash is stored.
+func (server *Server) createAPIKey(ctx *gin.Context) {
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !apiKeyScopes[scope] {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("unsupported scope: %s", scope)))
+			return
+		}
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-createAPIKey")
+	if !ok {
+		return
+	}
+
+	server.issueAPIKey(ctx, "user-createAPIKey", db.APIKeyOwnerUser, user.UserID, req)
+}
+
+// issueAPIKey stores a new api key of an owner and responds with it
+func (server *Server) issueAPIKey(ctx *gin.Context, name, ownerType, ownerID string, req createAPIKeyRequest) {
+	secret, _, err := generateToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	key := apiKeyPrefix + secret
+
+	arg := db.CreateAPIKeyParams{
+		KeyID:     server.createUUID(),
+		KeyHash:   hashToken(key),
+		Prefix:    key[:apiKeyDisplayLength],
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		arg.ExpiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, req.ExpiresInDays), Valid: true}
+	}
+
+	apiKey, err := server.store.CreateAPIKey(ctx, arg)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newAPIKeyResponse(apiKey)
+	resp.Key = key
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// listAPIKeys returns the api keys of the logged-in user that haven't been revoked
+func (server *Server) listAPIKeys(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.listOwnerAPIKeys(ctx, "user-listAPIKeys", db.APIKeyOwnerUser, authPayload.UserID)
+}
+
+// listOwnerAPIKeys responds with the api keys of an owner that haven't been revoked
+func (server *Server) listOwnerAPIKeys(ctx *gin.Context, name, ownerType, ownerID string) {
+	apiKeys, err := server.store.ListAPIKeys(ctx, db.ListAPIKeysParams{
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]apiKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, newAPIKeyResponse(apiKey))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type revokeAPIKeyRequest struct {
+	KeyID string `uri:"key_id" binding:"required"`
+}
+
+// revokeAPIKey revokes an api key of the logged-in user. It is rejected by the gateway right away.
+func (server *Server) revokeAPIKey(ctx *gin.Context) {
+	var req revokeAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	server.revokeOwnerAPIKey(ctx, "user-revokeAPIKey", db.APIKeyOwnerUser, authPayload.UserID, req.KeyID)
+}
+
+// revokeOwnerAPIKey revokes an api key of an owner and responds with it. Keys of other owners aren't found.
+func (server *Server) revokeOwnerAPIKey(ctx *gin.Context, name, ownerType, ownerID, keyID string) {
+	apiKey, err := server.store.RevokeAPIKey(ctx, db.RevokeAPIKeyParams{
+		KeyID:     keyID,
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newAPIKeyResponse(apiKey)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// apiKeyPayload describes who a request made with an api key is made by, which is either a user or a service account
+type apiKeyPayload struct {
+	KeyID            string     `json:"key_id"`
+	UserID           string     `json:"user_id,omitempty"`
+	ServiceAccountID string     `json:"service_account_id,omitempty"`
+	Scopes           []string   `json:"scopes"`
+	ExpiredAt        *time.Time `json:"expired_at,omitempty"`
+}
+
+type authenticateAPIKeyResponse struct {
+	Status  string        `json:"status"`
+	Payload apiKeyPayload `json:"payload"`
+}
+
+// authenticateAPIKey is used by the gateway to check requests made with an "ApiKey" authorization header. Every
+// successful check is recorded as the last use of the key.
+func (server *Server) authenticateAPIKey(ctx *gin.Context) {
+	fields := strings.Fields(ctx.GetHeader(authorizationHeaderKey))
+	if len(fields) != 2 || strings.ToLower(fields[0]) != authorizationAPIKey {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errors.New("invalid authorization header format")))
+		return
+	}
+
+	apiKey, err := server.useAPIKey(ctx, fields[1], ctx.ClientIP())
+	if err != nil {
+		if err == errInvalidAPIKey {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-authenticateAPIKey", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := authenticateAPIKeyResponse{
+		Status: "success",
+		Payload: apiKeyPayload{
+			KeyID:  apiKey.KeyID,
+			Scopes: apiKey.Scopes,
+		},
+	}
+	if apiKey.OwnerType == db.APIKeyOwnerServiceAccount {
+		resp.Payload.ServiceAccountID = apiKey.OwnerID
+	} else {
+		resp.Payload.UserID = apiKey.OwnerID
+	}
+	if apiKey.ExpiresAt.Valid {
+		resp.Payload.ExpiredAt = &apiKey.ExpiresAt.Time
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// useAPIKey looks up an api key that is neither revoked nor expired and records the call from clientIP as its last
+// use. Keys of deactivated users and of disabled service accounts are rejected with errInvalidAPIKey as well.
+func (server *Server) useAPIKey(ctx context.Context, key, clientIP string) (db.ApiKey, error) {
+	apiKey, err := server.store.UseAPIKey(ctx, db.UseAPIKeyParams{
+		KeyHash:    hashToken(key),
+		LastUsedIp: sql.NullString{String: clientIP, Valid: clientIP != ""},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return db.ApiKey{}, errInvalidAPIKey
+		}
+		return db.ApiKey{}, err
+	}
+
+	switch apiKey.OwnerType {
+	case db.APIKeyOwnerUser:
+		user, err := server.store.GetUser(ctx, apiKey.OwnerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return db.ApiKey{}, errInvalidAPIKey
+			}
+			return db.ApiKey{}, err
+		}
+		if !user.IsActive {
+			return db.ApiKey{}, errInvalidAPIKey
+		}
+	case db.APIKeyOwnerServiceAccount:
+		serviceAccount, err := server.store.GetServiceAccount(ctx, apiKey.OwnerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return db.ApiKey{}, errInvalidAPIKey
+			}
+			return db.ApiKey{}, err
+		}
+		if serviceAccount.DisabledAt.Valid {
+			return db.ApiKey{}, errInvalidAPIKey
+		}
+	default:
+		return db.ApiKey{}, errInvalidAPIKey
+	}
+
+	return apiKey, nil
+}
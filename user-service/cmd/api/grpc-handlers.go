@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/mail"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (server *userServer) CreateUser(ctx context.Context, req *pb.CreateUserRequest) (*pb.User, error) {
+	if req.GetFirstname() == "" || req.GetLastname() == "" || req.GetPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "firstname, lastname and password are required")
+	}
+	if _, err := mail.ParseAddress(req.GetEmail()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, "email is not a valid email address")
+	}
+
+	user, err := server.registerUser(ctx, createUserRequest{
+		Firstname: req.GetFirstname(),
+		Lastname:  req.GetLastname(),
+		Password:  req.GetPassword(),
+		Email:     req.GetEmail(),
+	})
+	if err != nil {
+		if err == errEmailInUse {
+			return nil, status.Error(codes.AlreadyExists, err.Error())
+		}
+		return nil, server.internalError(ctx, "user-grpcCreateUser", err)
+	}
+
+	return convertUser(user), nil
+}
+
+func (server *userServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	if req.GetUserId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required")
+	}
+
+	user, err := server.store.GetUser(ctx, req.GetUserId())
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.NotFound, "user not found")
+		}
+		return nil, server.internalError(ctx, "user-grpcGetUser", err)
+	}
+
+	return convertUser(user), nil
+}
+
+func (server *userServer) Authenticate(ctx context.Context, req *pb.AuthenticateRequest) (*pb.AuthPayload, error) {
+	payload, err := server.tokenMaker.VerifyToken(req.GetAccessToken())
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	user, err := server.store.GetUser(ctx, payload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, status.Error(codes.Unauthenticated, "user not found")
+		}
+		return nil, server.internalError(ctx, "user-grpcAuthenticate", err)
+	}
+	if !user.IsActive {
+		return nil, status.Error(codes.Unauthenticated, errUserInactive.Error())
+	}
+
+	if payload.IsDelegated() {
+		err = server.checkConsent(ctx, payload)
+		if err != nil {
+			if err == errConsentRevoked {
+				return nil, status.Error(codes.Unauthenticated, err.Error())
+			}
+			return nil, server.internalError(ctx, "user-grpcAuthenticate", err)
+		}
+	}
+
+	return &pb.AuthPayload{
+		Id:        payload.ID,
+		UserId:    payload.UserID,
+		ClientId:  payload.ClientID,
+		Scopes:    payload.Scopes,
+		IssuedAt:  timestamppb.New(payload.IssuedAt),
+		ExpiredAt: timestamppb.New(payload.ExpiredAt),
+	}, nil
+}
+
+func (server *userServer) AuthenticateAPIKey(ctx context.Context, req *pb.AuthenticateAPIKeyRequest) (*pb.AuthPayload, error) {
+	apiKey, err := server.useAPIKey(ctx, req.GetApiKey(), req.GetClientIp())
+	if err != nil {
+		if err == errInvalidAPIKey {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return nil, server.internalError(ctx, "user-grpcAuthenticateAPIKey", err)
+	}
+
+	resp := &pb.AuthPayload{
+		KeyId:    apiKey.KeyID,
+		Scopes:   apiKey.Scopes,
+		IssuedAt: timestamppb.New(apiKey.CreatedAt),
+	}
+	if apiKey.OwnerType == db.APIKeyOwnerServiceAccount {
+		resp.ServiceAccountId = apiKey.OwnerID
+	} else {
+		resp.UserId = apiKey.OwnerID
+	}
+	if apiKey.ExpiresAt.Valid {
+		resp.ExpiredAt = timestamppb.New(apiKey.ExpiresAt.Time)
+	}
+	return resp, nil
+}
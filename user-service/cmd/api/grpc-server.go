@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/proto/pb"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// userServer serves the UserService of the internal gRPC api
+type userServer struct {
+	pb.UnimplementedUserServiceServer
+	*Server
+}
+
+// StartGRPC serves the internal gRPC api, which the gateway and account-service use, on address. It shares the
+// store and the token maker with the Gin router. It stops once ctx is done, letting the calls in flight finish.
+func (server *Server) StartGRPC(ctx context.Context, address string) error {
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(tracing.UnaryServerInterceptor(), requestid.UnaryServerInterceptor()))
+	pb.RegisterUserServiceServer(grpcServer, &userServer{Server: server})
+	health.RegisterGRPC(grpcServer)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return fmt.Errorf("cannot listen on %s: %w", address, err)
+	}
+
+	return shutdown.ServeGRPC(ctx, grpcServer, listener, server.config.ShutdownTimeout)
+}
+
+// internalError logs an unexpected error of a gRPC method and returns it with the Internal code
+func (server *Server) internalError(ctx context.Context, name string, err error) error {
+	server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+	return status.Error(codes.Internal, err.Error())
+}
+
+func convertUser(user db.User) *pb.User {
+	return &pb.User{
+		UserId:           user.UserID,
+		Firstname:        user.Firstname,
+		Lastname:         user.Lastname,
+		Email:            user.Email,
+		EmailVerified:    user.EmailVerified,
+		TwoFactorEnabled: user.TotpEnabled,
+		IsActive:         user.IsActive,
+		KycStatus:        user.KycStatus,
+		CreatedAt:        timestamppb.New(user.CreatedAt),
+	}
+}
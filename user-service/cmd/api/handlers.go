@@ -1,15 +1,23 @@
 package main
 
 import (
+	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
 	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
 	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+const (
+	userIDConstraint      = "users_user_id_key"
+	emailConstraint       = "users_email_key"
+	maxCreateUserAttempts = 3
 )
 
 type createUserRequest struct {
@@ -20,20 +28,28 @@ type createUserRequest struct {
 }
 
 type userResponse struct {
-	Firstname string    `json:"firstname"`
-	Lastname  string    `json:"lastname"`
-	UserID    string    `json:"user_id"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
+	Firstname     string    `json:"firstname"`
+	Lastname      string    `json:"lastname"`
+	UserID        string    `json:"user_id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	TwoFactor     bool      `json:"two_factor_enabled"`
+	IsActive      bool      `json:"is_active"`
+	KYCStatus     string    `json:"kyc_status"`
+	CreatedAt     time.Time `json:"created_at"`
 }
 
 func newUserResponse(account db.User) userResponse {
 	return userResponse{
-		Firstname: account.Firstname,
-		Lastname:  account.Lastname,
-		UserID:    account.UserID,
-		CreatedAt: account.CreatedAt,
-		Email:     account.Email,
+		Firstname:     account.Firstname,
+		Lastname:      account.Lastname,
+		UserID:        account.UserID,
+		CreatedAt:     account.CreatedAt,
+		Email:         account.Email,
+		EmailVerified: account.EmailVerified,
+		TwoFactor:     account.TotpEnabled,
+		IsActive:      account.IsActive,
+		KYCStatus:     account.KycStatus,
 	}
 }
 
@@ -44,36 +60,58 @@ func (server *Server) createUser(ctx *gin.Context) {
 		return
 	}
 
-	hashedPassword, err := HashPassword(req.Password)
+	user, err := server.registerUser(ctx, req)
 	if err != nil {
-		server.sendErrorLog("user-createUser", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		if err == errEmailInUse {
+			ctx.JSON(http.StatusConflict, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-createUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// registerUser stores a new user and mails them an email verification token. It is shared by the REST and the gRPC
+// api, the request has to be validated already.
+func (server *Server) registerUser(ctx context.Context, req createUserRequest) (db.User, error) {
+	hashedPassword, err := HashPassword(req.Password)
+	if err != nil {
+		return db.User{}, err
+	}
+
 	payload := db.CreateUserParams{
-		UserID:    server.createUUID(),
 		Firstname: req.Firstname,
 		Lastname:  req.Lastname,
 		Email:     req.Email,
 		Password:  hashedPassword,
 	}
 
-	user, err := server.store.CreateUser(ctx, payload)
+	// user_id is unique, so a generated id that is already taken is replaced by a new one
+	var user db.User
+	for attempt := 0; attempt < maxCreateUserAttempts; attempt++ {
+		payload.UserID = server.createUUID()
+		user, err = server.store.CreateUser(ctx, payload)
+		if pqErr, ok := err.(*pq.Error); !ok || pqErr.Constraint != userIDConstraint {
+			break
+		}
+	}
 	if err != nil {
-		server.sendErrorLog("user-createUser", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
-		return
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == emailConstraint {
+			return db.User{}, errEmailInUse
+		}
+		return db.User{}, err
 	}
 
-	resp := newUserResponse(user)
-	ctx.JSON(http.StatusCreated, resp)
+	err = server.sendVerificationEmail(ctx, user)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-createUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("cannot send verification email: %w", err)))
+	}
+
+	return user, nil
 }
 
 type getUserRequest struct {
@@ -90,13 +128,10 @@ func (server *Server) getUser(ctx *gin.Context) {
 	user, err := server.store.GetUser(ctx, req.UserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
 			return
 		}
-		server.sendErrorLog("user-getUser", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "user-getUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
@@ -115,6 +150,14 @@ type loginUserResponse struct {
 	User        userResponse `json:"user"`
 }
 
+type loginChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// loginUser responds the same way for unknown emails and wrong passwords, so it can't be used to find out which
+// emails are registered. Failed attempts are counted per email and per client ip and lead to temporary lockouts.
 func (server *Server) loginUser(ctx *gin.Context) {
 	var req loginUserRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -122,27 +165,52 @@ func (server *Server) loginUser(ctx *gin.Context) {
 		return
 	}
 
+	lockedUntil, err := server.loginLockedUntil(ctx, emailAttemptKey(req.Email), ipAttemptKey(ctx.ClientIP()))
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-loginUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !lockedUntil.IsZero() {
+		loginLockedResponse(ctx, lockedUntil)
+		return
+	}
+
 	user, err := server.store.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			_ = CheckPassword(dummyPasswordHash, req.Password)
+			server.recordLoginFailure(ctx, req.Email, loginFailureCredentials)
+			ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
 			return
 		}
-		server.sendErrorLog("user-getUser", Log{
-			StatusCode: 500,
-			Message:    fmt.Sprintf("%v", err),
-		})
+		server.logger.ErrorCtx(ctx, "user-loginUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
 	}
 
 	err = CheckPassword(user.Password, req.Password)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		server.recordLoginFailure(ctx, req.Email, loginFailureCredentials)
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
+		return
+	}
+
+	// deactivated users get the same response as a wrong password
+	if !user.IsActive {
+		loginFailures.WithLabelValues(loginFailureInactive).Inc()
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errInvalidCredentials))
 		return
 	}
 
-	accessToken, err := server.tokenMaker.CreateToken(user.UserID, user.Email, time.Hour)
+	if user.TotpEnabled {
+		server.createLoginChallenge(ctx, user)
+		return
+	}
+
+	server.resetLoginFailures(ctx, user.Email)
+
+	accessToken, err := server.tokenMaker.CreateToken(user.UserID, user.Email, server.config.AccessTokenDuration)
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
 		return
@@ -155,42 +223,49 @@ func (server *Server) loginUser(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, resp)
 }
 
+// createLoginChallenge responds with a short-lived challenge token that has to be exchanged together with a second
+// factor at loginTwoFactor
+func (server *Server) createLoginChallenge(ctx *gin.Context, user db.User) {
+	challengeToken, tokenHash, err := generateToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	challenge, err := server.store.CreateUserToken(ctx, db.CreateUserTokenParams{
+		TokenHash: tokenHash,
+		UserID:    user.UserID,
+		Purpose:   db.TokenPurposeLoginChallenge,
+		ExpiresAt: time.Now().Add(loginChallengeTokenDuration),
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-loginUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := loginChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    challengeToken,
+		ExpiresAt:         challenge.ExpiresAt,
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
 type authenticateUserResponse struct {
 	Status  string `json:"status"`
 	Payload any    `json:"payload"`
 }
 
-const (
-	authorizationHeaderKey = "authorization"
-	authorizationBearer    = "bearer"
-)
-
+// authenticateUser is used by the gateway to check the token of a request. Tokens of deactivated users and tokens
+// of oauth clients whose consent was revoked are rejected.
 func (server *Server) authenticateUser(ctx *gin.Context) {
-	authorizationHeader := ctx.GetHeader(authorizationHeaderKey)
-	if len(authorizationHeader) == 0 {
-		err := errors.New("authorization header is not provided")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-		return
-	}
-
-	fields := strings.Fields(authorizationHeader)
-	if len(fields) < 2 {
-		err := errors.New("invalid authorization header format")
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
-		return
-	}
+	payload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
 
-	authorizationType := strings.ToLower(fields[0])
-	if authorizationType != authorizationBearer {
-		err := fmt.Errorf("unsupported authorization type %s", authorizationType)
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+	if _, ok := server.getAuthenticatedUser(ctx, "user-authenticateUser"); !ok {
 		return
 	}
-
-	accessToken := fields[1]
-	payload, err := server.tokenMaker.VerifyToken(accessToken)
-	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusUnauthorized, errorResponse(err))
+	if payload.IsDelegated() && !server.verifyDelegatedToken(ctx, payload) {
 		return
 	}
 
@@ -1,13 +1,11 @@
 package main
 
 import (
-	"bytes"
 	"crypto/rand"
-	"encoding/json"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"golang.org/x/crypto/bcrypt"
-	"log"
-	"net/http"
 )
 
 func (server *Server) createUUID() string {
@@ -26,53 +24,32 @@ func (server *Server) createUUID() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
 }
 
-func HashPassword(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+// generateToken creates a random single-use token and returns it together with the hash that is stored in db
+func generateToken() (string, string, error) {
+	buf := make([]byte, 32)
+	_, err := rand.Read(buf)
 	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %v", err)
+		return "", "", fmt.Errorf("failed to generate token: %v", err)
 	}
-	return string(hashedPassword), nil
-}
 
-func CheckPassword(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-}
-
-type JSONPayload struct {
-	Name string `json:"name"`
-	Data Log    `json:"data"`
+	token := hex.EncodeToString(buf)
+	return token, hashToken(token), nil
 }
 
-type Log struct {
-	Message    any `json:"message"`
-	StatusCode int `json:"status_code"`
+// hashToken hashes a token so that it can be looked up without storing it in plain text
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
 
-func (server *Server) sendErrorLog(name string, payload Log) {
-	arg := JSONPayload{
-		Name: name,
-		Data: payload,
-	}
-	jsonData, err := json.Marshal(arg)
-	if err != nil {
-		log.Println("sendErrorLog error: cant marshal json:", err)
-		return
-	}
-	request, err := http.NewRequest(http.MethodPost, "http://logger-service/logs/create-error", bytes.NewBuffer(jsonData))
-
-	client := &http.Client{}
-	response, err := client.Do(request)
+func HashPassword(password string) (string, error) {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		log.Println("sendErrorLog error: cant send response:", err)
-		return
-	}
-	defer response.Body.Close()
-
-	if response.StatusCode != http.StatusCreated {
-		log.Println("sendErrorLog error: calling auth service:", err)
-		return
+		return "", fmt.Errorf("failed to hash password: %v", err)
 	}
+	return string(hashedPassword), nil
+}
 
-	log.Println("logged successfully")
-	return
+func CheckPassword(hashedPassword, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
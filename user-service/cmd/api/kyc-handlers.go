@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/storage"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// maxKYCDocumentSize is the largest accepted document file. Requests may be slightly larger for the other form fields.
+const maxKYCDocumentSize = 10 << 20
+
+// kycDocumentTypes are the identity documents that can be submitted
+var kycDocumentTypes = map[string]bool{
+	"passport":         true,
+	"id_card":          true,
+	"driving_license":  true,
+	"proof_of_address": true,
+}
+
+// kycContentTypes are the accepted file formats, detected from the content rather than taken from the client
+var kycContentTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+}
+
+var (
+	errNotKYCReviewer       = errors.New("only kyc reviewers can do this")
+	errKYCNotPending        = errors.New("user is not waiting for a kyc review")
+	errKYCNoDocuments       = errors.New("user has not submitted any documents")
+	errKYCOwnReview         = errors.New("reviewers can't review themselves")
+	errKYCDocumentForbidden = errors.New("this is not yours")
+)
+
+type kycDocumentResponse struct {
+	DocumentID   string    `json:"document_id"`
+	DocumentType string    `json:"document_type"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newKYCDocumentResponse(document db.KycDocument) kycDocumentResponse {
+	return kycDocumentResponse{
+		DocumentID:   document.DocumentID,
+		DocumentType: document.DocumentType,
+		FileName:     document.FileName,
+		ContentType:  document.ContentType,
+		SizeBytes:    document.SizeBytes,
+		CreatedAt:    document.CreatedAt,
+	}
+}
+
+type kycStatusResponse struct {
+	UserID          string                `json:"user_id"`
+	Status          string                `json:"status"`
+	RejectionReason string                `json:"rejection_reason,omitempty"`
+	ReviewedAt      *time.Time            `json:"reviewed_at,omitempty"`
+	Documents       []kycDocumentResponse `json:"documents"`
+}
+
+// kycStatus collects the kyc state of a user together with the metadata of the submitted documents
+func (server *Server) kycStatus(ctx *gin.Context, user db.User) (kycStatusResponse, error) {
+	documents, err := server.store.ListKYCDocuments(ctx, user.UserID)
+	if err != nil {
+		return kycStatusResponse{}, err
+	}
+
+	resp := kycStatusResponse{
+		UserID:          user.UserID,
+		Status:          user.KycStatus,
+		RejectionReason: user.KycRejectionReason.String,
+		Documents:       make([]kycDocumentResponse, 0, len(documents)),
+	}
+	if user.KycReviewedAt.Valid {
+		resp.ReviewedAt = &user.KycReviewedAt.Time
+	}
+	for _, document := range documents {
+		resp.Documents = append(resp.Documents, newKYCDocumentResponse(document))
+	}
+
+	return resp, nil
+}
+
+// getKYCStatus returns the kyc status of the logged-in user
+func (server *Server) getKYCStatus(ctx *gin.Context) {
+	user, ok := server.getAuthenticatedUser(ctx, "user-getKYCStatus")
+	if !ok {
+		return
+	}
+
+	resp, err := server.kycStatus(ctx, user)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-getKYCStatus", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type uploadKYCDocumentRequest struct {
+	DocumentType string `form:"document_type" binding:"required"`
+}
+
+// uploadKYCDocument accepts an identity document as multipart form upload. The file goes to the blob store and its
+// metadata to the db, which puts the user into the review queue.
+func (server *Server) uploadKYCDocument(ctx *gin.Context) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxKYCDocumentSize+(1<<20))
+
+	var req uploadKYCDocumentRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if !kycDocumentTypes[req.DocumentType] {
+		ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("unsupported document type: %s", req.DocumentType)))
+		return
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	if fileHeader.Size > maxKYCDocumentSize {
+		ctx.JSON(http.StatusRequestEntityTooLarge, errorResponse(fmt.Errorf("document must not exceed %d bytes", maxKYCDocumentSize)))
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-uploadKYCDocument")
+	if !ok {
+		return
+	}
+	if user.KycStatus == db.KYCStatusVerified {
+		ctx.JSON(http.StatusConflict, errorResponse(db.ErrKYCAlreadyVerified))
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !kycContentTypes[contentType] {
+		ctx.JSON(http.StatusUnsupportedMediaType, errorResponse(fmt.Errorf("unsupported file type: %s", contentType)))
+		return
+	}
+
+	documentID := server.createUUID()
+	storageKey := fmt.Sprintf("kyc/%s/%s", user.UserID, documentID)
+	size, err := server.blobStore.Put(ctx, storageKey, io.MultiReader(bytes.NewReader(head), file))
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-uploadKYCDocument", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("cannot store document: %w", err)))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	document, err := server.store.SubmitKYCDocumentTx(ctx, db.CreateKYCDocumentParams{
+		DocumentID:   documentID,
+		UserID:       user.UserID,
+		DocumentType: req.DocumentType,
+		FileName:     fileHeader.Filename,
+		ContentType:  contentType,
+		SizeBytes:    size,
+		StorageKey:   storageKey,
+	})
+	if err != nil {
+		if delErr := server.blobStore.Delete(ctx, storageKey); delErr != nil {
+			server.logger.ErrorCtx(ctx, "user-uploadKYCDocument", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("cannot delete stored document: %w", delErr)))
+		}
+		if err == db.ErrKYCAlreadyVerified {
+			ctx.JSON(http.StatusConflict, errorResponse(db.ErrKYCAlreadyVerified))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-uploadKYCDocument", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newKYCDocumentResponse(document))
+}
+
+type kycDocumentRequest struct {
+	DocumentID string `uri:"document_id" binding:"required,min=1"`
+}
+
+// downloadKYCDocument streams a document from the blob store. Documents can be read by their owner and by reviewers.
+func (server *Server) downloadKYCDocument(ctx *gin.Context) {
+	var req kycDocumentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-downloadKYCDocument")
+	if !ok {
+		return
+	}
+
+	document, err := server.store.GetKYCDocument(ctx, req.DocumentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-downloadKYCDocument", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if document.UserID != user.UserID && user.Role != db.RoleKYCReviewer {
+		ctx.JSON(http.StatusForbidden, errorResponse(errKYCDocumentForbidden))
+		return
+	}
+
+	content, err := server.blobStore.Get(ctx, document.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrBlobNotFound) {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-downloadKYCDocument", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	defer content.Close()
+
+	ctx.DataFromReader(http.StatusOK, document.SizeBytes, document.ContentType, content, map[string]string{
+		"Content-Disposition": fmt.Sprintf("attachment; filename=%q", document.FileName),
+	})
+}
+
+// getKYCReviewer fetches the logged-in user and makes sure they are allowed to review kyc submissions
+func (server *Server) getKYCReviewer(ctx *gin.Context, name string) (db.User, bool) {
+	reviewer, ok := server.getAuthenticatedUser(ctx, name)
+	if !ok {
+		return db.User{}, false
+	}
+	if reviewer.Role != db.RoleKYCReviewer {
+		ctx.JSON(http.StatusForbidden, errorResponse(errNotKYCReviewer))
+		return db.User{}, false
+	}
+
+	return reviewer, true
+}
+
+type listKYCReviewsRequest struct {
+	PageID   int32 `form:"page_id" binding:"required,min=1"`
+	PageSize int32 `form:"page_size" binding:"required,min=5,max=50"`
+}
+
+// listKYCReviews returns the users waiting for a kyc review, oldest first
+func (server *Server) listKYCReviews(ctx *gin.Context) {
+	var req listKYCReviewsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getKYCReviewer(ctx, "user-listKYCReviews"); !ok {
+		return
+	}
+
+	users, err := server.store.ListKYCReviewQueue(ctx, db.ListKYCReviewQueueParams{
+		Limit:  req.PageSize,
+		Offset: (req.PageID - 1) * req.PageSize,
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-listKYCReviews", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]userResponse, 0, len(users))
+	for _, user := range users {
+		resp = append(resp, newUserResponse(user))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type kycReviewRequest struct {
+	UserID string `uri:"user_id" binding:"required,min=1"`
+}
+
+// getReviewedUser fetches the user addressed by a review request. Reviewers can't act on their own submission.
+func (server *Server) getReviewedUser(ctx *gin.Context, name string, reviewer db.User) (db.User, bool) {
+	var req kycReviewRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return db.User{}, false
+	}
+	if req.UserID == reviewer.UserID {
+		ctx.JSON(http.StatusForbidden, errorResponse(errKYCOwnReview))
+		return db.User{}, false
+	}
+
+	user, err := server.store.GetUser(ctx, req.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.User{}, false
+		}
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+
+	return user, true
+}
+
+// getKYCReview returns the kyc status and documents of a user for a reviewer
+func (server *Server) getKYCReview(ctx *gin.Context) {
+	reviewer, ok := server.getKYCReviewer(ctx, "user-getKYCReview")
+	if !ok {
+		return
+	}
+	user, ok := server.getReviewedUser(ctx, "user-getKYCReview", reviewer)
+	if !ok {
+		return
+	}
+
+	resp, err := server.kycStatus(ctx, user)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-getKYCReview", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) approveKYC(ctx *gin.Context) {
+	server.reviewKYC(ctx, "user-approveKYC", db.KYCStatusVerified, "")
+}
+
+type rejectKYCRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (server *Server) rejectKYC(ctx *gin.Context) {
+	var req rejectKYCRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	server.reviewKYC(ctx, "user-rejectKYC", db.KYCStatusRejected, req.Reason)
+}
+
+// reviewKYC closes the pending kyc review of a user with the given status. Only users that have submitted documents
+// can be reviewed.
+func (server *Server) reviewKYC(ctx *gin.Context, name, status, reason string) {
+	reviewer, ok := server.getKYCReviewer(ctx, name)
+	if !ok {
+		return
+	}
+	user, ok := server.getReviewedUser(ctx, name, reviewer)
+	if !ok {
+		return
+	}
+
+	documents, err := server.store.ListKYCDocuments(ctx, user.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if len(documents) == 0 {
+		ctx.JSON(http.StatusConflict, errorResponse(errKYCNoDocuments))
+		return
+	}
+
+	user, err = server.store.ReviewUserKYC(ctx, db.ReviewUserKYCParams{
+		UserID:          user.UserID,
+		KycStatus:       status,
+		RejectionReason: sql.NullString{String: reason, Valid: reason != ""},
+		ReviewedBy:      sql.NullString{String: reviewer.UserID, Valid: true},
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusConflict, errorResponse(errKYCNotPending))
+			return
+		}
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp, err := server.kycStatus(ctx, user)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
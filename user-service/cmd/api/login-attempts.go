@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/exp/slog"
+)
+
+const (
+	// loginAttemptWindow is the time after which failed attempts of a key are forgotten
+	loginAttemptWindow = 15 * time.Minute
+	loginLockDuration  = 15 * time.Minute
+
+	maxFailedLoginsPerEmail = 5
+	maxFailedLoginsPerIP    = 20
+
+	// failed attempts of an email before responses start being delayed, and the upper bound of that delay
+	loginDelayAfter = 2
+	maxLoginDelay   = 8 * time.Second
+)
+
+var (
+	errInvalidCredentials = errors.New("invalid email or password")
+	errLoginLocked        = errors.New("too many failed login attempts, try again later")
+)
+
+// dummyPasswordHash is compared against when the email doesn't exist, so that both cases take the same time
+var dummyPasswordHash, _ = HashPassword("dummy-password-for-timing")
+
+func emailAttemptKey(email string) string {
+	return fmt.Sprintf("email:%s", strings.ToLower(email))
+}
+
+func ipAttemptKey(ip string) string {
+	return fmt.Sprintf("ip:%s", ip)
+}
+
+// loginLockedUntil returns the latest active lock of the given attempt keys
+func (server *Server) loginLockedUntil(ctx *gin.Context, keys ...string) (time.Time, error) {
+	var lockedUntil time.Time
+	for _, key := range keys {
+		attempt, err := server.store.GetLoginAttempt(ctx, key)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				continue
+			}
+			return time.Time{}, err
+		}
+
+		if attempt.LockedUntil.Valid && attempt.LockedUntil.Time.After(lockedUntil) {
+			lockedUntil = attempt.LockedUntil.Time
+		}
+	}
+
+	if time.Now().After(lockedUntil) {
+		return time.Time{}, nil
+	}
+	return lockedUntil, nil
+}
+
+// recordLoginFailure counts a failed login for the email and the client ip, locks the keys that reached their
+// limit and delays the response progressively. reason labels the failure in the login_failures_total metric.
+func (server *Server) recordLoginFailure(ctx *gin.Context, email, reason string) {
+	loginFailures.WithLabelValues(reason).Inc()
+
+	ip := ctx.ClientIP()
+	limits := map[string]int32{
+		emailAttemptKey(email): maxFailedLoginsPerEmail,
+		ipAttemptKey(ip):       maxFailedLoginsPerIP,
+	}
+
+	var emailFailures int32
+	for key, limit := range limits {
+		attempt, err := server.store.RecordFailedLoginAttempt(ctx, db.RecordFailedLoginAttemptParams{
+			Key:         key,
+			WindowStart: time.Now().Add(-loginAttemptWindow),
+		})
+		if err != nil {
+			server.logger.ErrorCtx(ctx, "user-recordLoginFailure", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+			continue
+		}
+		if key == emailAttemptKey(email) {
+			emailFailures = attempt.FailedCount
+		}
+
+		if attempt.FailedCount < limit {
+			continue
+		}
+
+		_, err = server.store.LockLoginAttempt(ctx, db.LockLoginAttemptParams{
+			Key:         key,
+			LockedUntil: sql.NullTime{Time: time.Now().Add(loginLockDuration), Valid: true},
+		})
+		if err != nil {
+			server.logger.ErrorCtx(ctx, "user-recordLoginFailure", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+			continue
+		}
+
+		server.logger.WarnCtx(ctx, "user-loginLockout", logging.StatusCode(http.StatusTooManyRequests),
+			slog.Duration("locked_for", loginLockDuration), slog.Int("failed_attempts", int(attempt.FailedCount)),
+			slog.String("key", key), slog.String("ip", ip))
+	}
+
+	if delay := loginDelay(emailFailures); delay > 0 {
+		server.sleep(delay)
+	}
+}
+
+// loginDelay returns how long the response to a failed login is delayed after the given failures of an email.
+// The delay doubles with every failure past loginDelayAfter, up to maxLoginDelay.
+func loginDelay(emailFailures int32) time.Duration {
+	if emailFailures <= loginDelayAfter {
+		return 0
+	}
+
+	// the bound is applied before the conversion, which overflows after many failures
+	seconds := math.Pow(2, float64(emailFailures-loginDelayAfter-1))
+	if seconds > maxLoginDelay.Seconds() {
+		return maxLoginDelay
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// resetLoginFailures forgets failed attempts of the email after a successful login. Failures of the client ip
+// are kept until they expire, so logging into an own account can't be used to reset them.
+func (server *Server) resetLoginFailures(ctx *gin.Context, email string) {
+	err := server.store.DeleteLoginAttempt(ctx, emailAttemptKey(email))
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-resetLoginFailures", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+	}
+}
+
+// loginLockedResponse responds with 429 and a Retry-After header
+func loginLockedResponse(ctx *gin.Context, lockedUntil time.Time) {
+	loginFailures.WithLabelValues(loginFailureLocked).Inc()
+	retryAfter := int(math.Ceil(time.Until(lockedUntil).Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.JSON(http.StatusTooManyRequests, errorResponse(errLoginLocked))
+}
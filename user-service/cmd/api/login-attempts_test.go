@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+// fakeLoginStore keeps users and login attempts in memory, the other queries of the store aren't faked
+type fakeLoginStore struct {
+	db.Store
+	users    map[string]db.User
+	attempts map[string]db.LoginAttempt
+}
+
+func (s *fakeLoginStore) GetUserByEmail(_ context.Context, email string) (db.User, error) {
+	user, ok := s.users[email]
+	if !ok {
+		return db.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (s *fakeLoginStore) GetLoginAttempt(_ context.Context, key string) (db.LoginAttempt, error) {
+	attempt, ok := s.attempts[key]
+	if !ok {
+		return db.LoginAttempt{}, sql.ErrNoRows
+	}
+	return attempt, nil
+}
+
+func (s *fakeLoginStore) RecordFailedLoginAttempt(_ context.Context, arg db.RecordFailedLoginAttemptParams) (db.LoginAttempt, error) {
+	attempt, ok := s.attempts[arg.Key]
+	if !ok || attempt.LastFailedAt.Before(arg.WindowStart) {
+		attempt.Key = arg.Key
+		attempt.FailedCount = 0
+	}
+	attempt.FailedCount++
+	attempt.LastFailedAt = time.Now()
+	s.attempts[arg.Key] = attempt
+	return attempt, nil
+}
+
+func (s *fakeLoginStore) LockLoginAttempt(_ context.Context, arg db.LockLoginAttemptParams) (db.LoginAttempt, error) {
+	attempt, ok := s.attempts[arg.Key]
+	if !ok {
+		return db.LoginAttempt{}, sql.ErrNoRows
+	}
+	attempt.LockedUntil = arg.LockedUntil
+	s.attempts[arg.Key] = attempt
+	return attempt, nil
+}
+
+func (s *fakeLoginStore) DeleteLoginAttempt(_ context.Context, key string) error {
+	delete(s.attempts, key)
+	return nil
+}
+
+// elapse moves the login attempts d into the past
+func (s *fakeLoginStore) elapse(d time.Duration) {
+	for key, attempt := range s.attempts {
+		attempt.LastFailedAt = attempt.LastFailedAt.Add(-d)
+		if attempt.LockedUntil.Valid {
+			attempt.LockedUntil.Time = attempt.LockedUntil.Time.Add(-d)
+		}
+		s.attempts[key] = attempt
+	}
+}
+
+const (
+	loginEmail    = "alice@example.com"
+	loginPassword = "correct-password"
+)
+
+// newLoginServer returns a server whose store knows alice and bob, who was deactivated. The delays of failed
+// logins are recorded instead of slept.
+func newLoginServer(t *testing.T) (*Server, *fakeLoginStore, *[]time.Duration) {
+	gin.SetMode(gin.TestMode)
+
+	hashedPassword, err := HashPassword(loginPassword)
+	require.NoError(t, err)
+	tokenMaker, err := token.NewPasetoMaker(strings.Repeat("k", 32))
+	require.NoError(t, err)
+
+	store := &fakeLoginStore{
+		users: map[string]db.User{
+			loginEmail:        {UserID: "alice", Email: loginEmail, Password: hashedPassword, IsActive: true},
+			"bob@example.com": {UserID: "bob", Email: "bob@example.com", Password: hashedPassword},
+		},
+		attempts: map[string]db.LoginAttempt{},
+	}
+	delays := &[]time.Duration{}
+
+	server := &Server{
+		config:     EnvConfig{AccessTokenDuration: time.Minute},
+		store:      store,
+		tokenMaker: tokenMaker,
+		logger:     slog.New(slog.HandlerOptions{}.NewJSONHandler(io.Discard)),
+		sleep: func(d time.Duration) {
+			*delays = append(*delays, d)
+		},
+	}
+	server.router = gin.New()
+	server.router.POST("/users/login", server.loginUser)
+	return server, store, delays
+}
+
+// login sends a login request from ip
+func login(server *Server, ip, email, password string) *httptest.ResponseRecorder {
+	body := fmt.Sprintf(`{"email":%q,"password":%q}`, email, password)
+	r := httptest.NewRequest(http.MethodPost, "/users/login", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	r.RemoteAddr = ip + ":1234"
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, r)
+	return w
+}
+
+func TestLoginDelay(t *testing.T) {
+	testCases := []struct {
+		failures int32
+		delay    time.Duration
+	}{
+		{failures: 1, delay: 0},
+		{failures: loginDelayAfter, delay: 0},
+		{failures: loginDelayAfter + 1, delay: time.Second},
+		{failures: loginDelayAfter + 2, delay: 2 * time.Second},
+		{failures: loginDelayAfter + 3, delay: 4 * time.Second},
+		{failures: loginDelayAfter + 4, delay: maxLoginDelay},
+		{failures: 100, delay: maxLoginDelay},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(fmt.Sprint(tc.failures), func(t *testing.T) {
+			require.Equal(t, tc.delay, loginDelay(tc.failures))
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	server, store, delays := newLoginServer(t)
+
+	for i := 1; i <= maxFailedLoginsPerEmail; i++ {
+		w := login(server, "192.0.2.1", loginEmail, "wrong-password")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Equal(t, int32(i), store.attempts[emailAttemptKey(loginEmail)].FailedCount)
+	}
+	require.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}, *delays)
+
+	// the email is locked, even with the right password and from another ip
+	w := login(server, "192.0.2.2", loginEmail, loginPassword)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.Equal(t, fmt.Sprint(loginLockDuration.Seconds()), w.Header().Get("Retry-After"))
+	require.Equal(t, int32(maxFailedLoginsPerEmail), store.attempts[emailAttemptKey(loginEmail)].FailedCount)
+
+	// the lock ends, and a successful login forgets the failures of the email
+	store.elapse(loginLockDuration + time.Second)
+	w = login(server, "192.0.2.1", loginEmail, loginPassword)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NotContains(t, store.attempts, emailAttemptKey(loginEmail))
+	require.Equal(t, int32(maxFailedLoginsPerEmail), store.attempts[ipAttemptKey("192.0.2.1")].FailedCount)
+}
+
+func TestLoginFailuresExpire(t *testing.T) {
+	server, store, delays := newLoginServer(t)
+
+	for i := 0; i < maxFailedLoginsPerEmail-1; i++ {
+		login(server, "192.0.2.1", loginEmail, "wrong-password")
+	}
+
+	// failures older than the window are forgotten, so the next one starts over instead of locking the email
+	store.elapse(loginAttemptWindow + time.Second)
+	*delays = nil
+	w := login(server, "192.0.2.1", loginEmail, "wrong-password")
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Equal(t, int32(1), store.attempts[emailAttemptKey(loginEmail)].FailedCount)
+	require.False(t, store.attempts[emailAttemptKey(loginEmail)].LockedUntil.Valid)
+	require.Empty(t, *delays)
+}
+
+func TestLoginIPLockout(t *testing.T) {
+	server, store, _ := newLoginServer(t)
+
+	// every email fails once, so only the limit of the ip is reached
+	for i := 0; i < maxFailedLoginsPerIP; i++ {
+		w := login(server, "192.0.2.1", fmt.Sprintf("user%d@example.com", i), "wrong-password")
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+	require.True(t, store.attempts[ipAttemptKey("192.0.2.1")].LockedUntil.Valid)
+
+	w := login(server, "192.0.2.1", loginEmail, loginPassword)
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	// other ips can still log in
+	w = login(server, "192.0.2.2", loginEmail, loginPassword)
+	require.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestLoginInvalidCredentials(t *testing.T) {
+	testCases := []struct {
+		name     string
+		email    string
+		password string
+		// failed says whether the attempt counts as a failure of the email
+		failed bool
+	}{
+		{name: "WrongPassword", email: loginEmail, password: "wrong-password", failed: true},
+		{name: "UnknownEmail", email: "mallory@example.com", password: loginPassword, failed: true},
+		{name: "Deactivated", email: "bob@example.com", password: loginPassword},
+	}
+
+	// every case gets the response of a wrong password, so that none tells whether the email exists
+	server, _, _ := newLoginServer(t)
+	expected := login(server, "192.0.2.1", loginEmail, "wrong-password")
+	require.Equal(t, http.StatusUnauthorized, expected.Code)
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			server, store, _ := newLoginServer(t)
+			w := login(server, "192.0.2.1", tc.email, tc.password)
+			require.Equal(t, expected.Code, w.Code)
+			require.Equal(t, expected.Body.String(), w.Body.String())
+			require.JSONEq(t, fmt.Sprintf(`{"error":%q}`, errInvalidCredentials), w.Body.String())
+
+			if tc.failed {
+				require.Equal(t, int32(1), store.attempts[emailAttemptKey(tc.email)].FailedCount)
+			} else {
+				require.NotContains(t, store.attempts, emailAttemptKey(tc.email))
+			}
+		})
+	}
+}
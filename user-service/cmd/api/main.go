@@ -1,37 +1,79 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
 	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
 	_ "github.com/lib/pq"
+	"golang.org/x/exp/slog"
 )
 
-const webPort = "80"
-
 func main() {
-	log.Printf("Starting User service on port: %s", webPort)
-
 	config, err := LoadConfig()
 	if err != nil {
-		log.Fatal("Error with loading env: ", err)
+		log.Fatal("Error with loading config: ", err)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), "user-service", config.traceConfig())
+	if err != nil {
+		log.Fatal("Error with setting up tracing: ", err)
 	}
+	defer shutdownTracing(context.Background())
+
+	// the servers drain once the service is asked to stop, before the deferred closes of the connections run
+	ctx, stop := shutdown.Context()
+	defer stop()
+
+	log.Printf("Starting User service on port: %s", config.WebPort)
 
 	conn, err := sql.Open("postgres", config.UserDbConnString)
 	if err != nil {
 		log.Fatal("Cannot connect to DB:", err)
 	}
+	defer conn.Close()
+	metrics.RegisterDB(conn, "users")
 
 	store := db.NewStore(conn)
-	server, err := NewServer(store)
+	server, err := NewServer(config, store)
 	if err != nil {
 		log.Fatal("cannot create server", err)
 	}
+	// the logs of the last requests are shipped once the servers drained
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		defer cancel()
+		server.logs.Close(ctx)
+	}()
+	// the output of the log package, such as the one of the code that doesn't log through the logger yet, is written
+	// as JSON records too
+	slog.SetDefault(server.logger)
+
+	checker := health.NewChecker()
+	checker.Add("postgres", health.DB(conn))
+	server.ServeHealth(checker)
 
-	address := fmt.Sprintf(":%s", webPort)
-	if err = server.Start(address); err != nil {
-		log.Fatal("Server start failed:", err)
+	grpcStopped := make(chan error, 1)
+	go func() {
+		log.Printf("Starting User gRPC service on port: %s", config.GRPCPort)
+		grpcStopped <- server.StartGRPC(ctx, fmt.Sprintf(":%s", config.GRPCPort))
+		// the service can't do without either api, so the HTTP api stops as well
+		stop()
+	}()
+
+	address := fmt.Sprintf(":%s", config.WebPort)
+	if err = server.Start(ctx, address); err != nil {
+		log.Println("HTTP server stopped:", err)
+	}
+	stop()
+	if err = <-grpcStopped; err != nil {
+		log.Println("gRPC server stopped:", err)
 	}
+	log.Println("User service stopped")
 }
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// reasons a login is rejected for, which label loginFailures
+const (
+	loginFailureCredentials  = "invalid_credentials"
+	loginFailureSecondFactor = "invalid_second_factor"
+	loginFailureInactive     = "inactive"
+	loginFailureLocked       = "locked"
+)
+
+// loginFailures counts the rejected logins, by the reason they were rejected for
+var loginFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "login_failures_total",
+	Help: "Rejected login attempts, by reason.",
+}, []string{"reason"})
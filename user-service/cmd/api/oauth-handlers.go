@@ -0,0 +1,648 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	scopeAccountsRead   = "accounts:read"
+	scopeTransfersWrite = "transfers:write"
+
+	authorizationCodeDuration = 5 * time.Minute
+	delegatedTokenDuration    = time.Hour
+
+	codeChallengeMethodS256 = "S256"
+	grantTypeAuthCode       = "authorization_code"
+)
+
+// supportedScopes are the scopes OAuth clients can ask users for
+var supportedScopes = map[string]bool{
+	scopeAccountsRead:   true,
+	scopeTransfersWrite: true,
+}
+
+// error codes of RFC 6749, sent in the "error" field of OAuth responses
+const (
+	oauthInvalidRequest       = "invalid_request"
+	oauthInvalidClient        = "invalid_client"
+	oauthInvalidGrant         = "invalid_grant"
+	oauthInvalidScope         = "invalid_scope"
+	oauthUnsupportedGrantType = "unsupported_grant_type"
+	oauthUnsupportedResponse  = "unsupported_response_type"
+	oauthAccessDenied         = "access_denied"
+	oauthServerError          = "server_error"
+)
+
+var (
+	errUnknownClient       = errors.New("client is unknown")
+	errInvalidRedirectURI  = errors.New("redirect_uri is not registered for the client")
+	errInvalidCodeVerifier = errors.New("code_verifier does not match the code_challenge")
+	errInvalidAuthCode     = errors.New("authorization code is invalid or has expired")
+	errConsentRevoked      = errors.New("consent has been revoked")
+)
+
+// oauthErrorResponse is the error body of RFC 6749, which keeps the "error" field of errorResponse
+func oauthErrorResponse(code string, err error) gin.H {
+	return gin.H{"error": code, "error_description": err.Error()}
+}
+
+// parseScopes splits a space separated scope parameter. Every scope has to be supported and registered for the client.
+func parseScopes(scope string, client db.OauthClient) ([]string, error) {
+	registered := make(map[string]bool, len(client.Scopes))
+	for _, s := range client.Scopes {
+		registered[s] = true
+	}
+
+	var scopes []string
+	seen := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		if !supportedScopes[s] || !registered[s] {
+			return nil, fmt.Errorf("scope %q is not allowed for the client", s)
+		}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	return scopes, nil
+}
+
+// containsScopes reports whether all scopes are part of granted
+func containsScopes(granted, scopes []string) bool {
+	for _, scope := range scopes {
+		found := false
+		for _, g := range granted {
+			if g == scope {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// validateRedirectURI accepts absolute https URIs, and plain http for local development
+func validateRedirectURI(rawURI string) error {
+	uri, err := url.Parse(rawURI)
+	if err != nil || !uri.IsAbs() || uri.Host == "" {
+		return fmt.Errorf("redirect_uri %q must be an absolute URI", rawURI)
+	}
+	if uri.Fragment != "" {
+		return fmt.Errorf("redirect_uri %q must not contain a fragment", rawURI)
+	}
+
+	host := uri.Hostname()
+	if uri.Scheme != "https" && !(uri.Scheme == "http" && (host == "localhost" || host == "127.0.0.1")) {
+		return fmt.Errorf("redirect_uri %q must use https", rawURI)
+	}
+
+	return nil
+}
+
+type oauthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	Confidential bool      `json:"confidential"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func newOAuthClientResponse(client db.OauthClient) oauthClientResponse {
+	return oauthClientResponse{
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes:       client.Scopes,
+		Confidential: client.ClientSecretHash.Valid,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+type registerOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris" binding:"required,min=1"`
+	Scopes       []string `json:"scopes" binding:"required,min=1"`
+	Confidential bool     `json:"confidential"`
+}
+
+// registerOAuthClient registers an OAuth client owned by the logged-in user. The secret of confidential clients is
+// only returned once.
+func (server *Server) registerOAuthClient(ctx *gin.Context) {
+	var req registerOAuthClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	for _, uri := range req.RedirectURIs {
+		if err := validateRedirectURI(uri); err != nil {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+	}
+	for _, scope := range req.Scopes {
+		if !supportedScopes[scope] {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("unsupported scope: %s", scope)))
+			return
+		}
+	}
+
+	owner, ok := server.getAuthenticatedUser(ctx, "user-registerOAuthClient")
+	if !ok {
+		return
+	}
+
+	arg := db.CreateOAuthClientParams{
+		ClientID:     server.createUUID(),
+		Name:         req.Name,
+		RedirectUris: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		OwnerID:      owner.UserID,
+	}
+
+	var secret string
+	if req.Confidential {
+		var secretHash string
+		var err error
+		secret, secretHash, err = generateToken()
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+			return
+		}
+		arg.ClientSecretHash = sql.NullString{String: secretHash, Valid: true}
+	}
+
+	client, err := server.store.CreateOAuthClient(ctx, arg)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-registerOAuthClient", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newOAuthClientResponse(client)
+	resp.ClientSecret = secret
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+// listOAuthClients returns the OAuth clients registered by the logged-in user
+func (server *Server) listOAuthClients(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	clients, err := server.store.ListOAuthClientsByOwner(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-listOAuthClients", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]oauthClientResponse, 0, len(clients))
+	for _, client := range clients {
+		resp = append(resp, newOAuthClientResponse(client))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type authorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope" binding:"required"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge" binding:"required"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" binding:"required"`
+}
+
+// validateAuthorizeRequest checks an authorization request against the registered client and returns the client
+// together with the requested scopes
+func (server *Server) validateAuthorizeRequest(ctx *gin.Context, req authorizeRequest) (db.OauthClient, []string, bool) {
+	client, err := server.store.GetOAuthClient(ctx, req.ClientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidClient, errUnknownClient))
+			return db.OauthClient{}, nil, false
+		}
+		server.logger.ErrorCtx(ctx, "user-authorizeClient", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return db.OauthClient{}, nil, false
+	}
+
+	registered := false
+	for _, uri := range client.RedirectUris {
+		if uri == req.RedirectURI {
+			registered = true
+			break
+		}
+	}
+	if !registered {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, errInvalidRedirectURI))
+		return db.OauthClient{}, nil, false
+	}
+
+	if req.ResponseType != "code" {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthUnsupportedResponse, errors.New("response_type must be code")))
+		return db.OauthClient{}, nil, false
+	}
+	// a S256 challenge is the unpadded base64url encoding of a sha256 hash
+	if req.CodeChallengeMethod != codeChallengeMethodS256 || len(req.CodeChallenge) != 43 {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, errors.New("a S256 code_challenge is required")))
+		return db.OauthClient{}, nil, false
+	}
+
+	scopes, err := parseScopes(req.Scope, client)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidScope, err))
+		return db.OauthClient{}, nil, false
+	}
+
+	return client, scopes, true
+}
+
+type authorizationResponse struct {
+	ClientID   string   `json:"client_id"`
+	ClientName string   `json:"client_name"`
+	Scopes     []string `json:"scopes"`
+	Granted    bool     `json:"granted"`
+}
+
+// getAuthorization validates an authorization request for the consent screen of the logged-in user. Granted is
+// true when the user already consented to all requested scopes.
+func (server *Server) getAuthorization(ctx *gin.Context) {
+	var req authorizeRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+		return
+	}
+
+	client, scopes, ok := server.validateAuthorizeRequest(ctx, req)
+	if !ok {
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	consent, err := server.store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		UserID:   authPayload.UserID,
+		ClientID: client.ClientID,
+	})
+	if err != nil && err != sql.ErrNoRows {
+		server.logger.ErrorCtx(ctx, "user-getAuthorization", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return
+	}
+
+	resp := authorizationResponse{
+		ClientID:   client.ClientID,
+		ClientName: client.Name,
+		Scopes:     scopes,
+		Granted:    err == nil && containsScopes(consent.Scopes, scopes),
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type authorizeClientRequest struct {
+	authorizeRequest
+	Approve *bool `json:"approve" binding:"required"`
+}
+
+type authorizeClientResponse struct {
+	RedirectTo string `json:"redirect_to"`
+}
+
+// redirectWith appends the parameters to the query of a redirect_uri
+func redirectWith(redirectURI string, params url.Values) string {
+	uri, _ := url.Parse(redirectURI)
+	query := uri.Query()
+	for key, values := range params {
+		for _, value := range values {
+			query.Add(key, value)
+		}
+	}
+	uri.RawQuery = query.Encode()
+	return uri.String()
+}
+
+// authorizeClient records the decision of the logged-in user on an authorization request. The response contains
+// the URI the user agent has to be redirected to, carrying either an authorization code or an access_denied error.
+func (server *Server) authorizeClient(ctx *gin.Context) {
+	var req authorizeClientRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+		return
+	}
+
+	client, scopes, ok := server.validateAuthorizeRequest(ctx, req.authorizeRequest)
+	if !ok {
+		return
+	}
+
+	params := url.Values{}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	if !*req.Approve {
+		params.Set("error", oauthAccessDenied)
+		ctx.JSON(http.StatusOK, authorizeClientResponse{RedirectTo: redirectWith(req.RedirectURI, params)})
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-authorizeClient")
+	if !ok {
+		return
+	}
+
+	code, codeHash, err := generateToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return
+	}
+
+	_, err = server.store.AuthorizeClientTx(ctx, db.CreateOAuthAuthorizationCodeParams{
+		CodeHash:      codeHash,
+		ClientID:      client.ClientID,
+		UserID:        user.UserID,
+		RedirectUri:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+		ExpiresAt:     time.Now().Add(authorizationCodeDuration),
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-authorizeClient", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return
+	}
+
+	params.Set("code", code)
+	ctx.JSON(http.StatusOK, authorizeClientResponse{RedirectTo: redirectWith(req.RedirectURI, params)})
+}
+
+type tokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code" binding:"required"`
+	RedirectURI  string `form:"redirect_uri" binding:"required"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	CodeVerifier string `form:"code_verifier" binding:"required"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// authenticateClient identifies the client of a token request. Confidential clients have to present their secret,
+// either with HTTP Basic authentication or in the form.
+func (server *Server) authenticateClient(ctx *gin.Context, req tokenRequest) (db.OauthClient, bool) {
+	clientID, clientSecret := req.ClientID, req.ClientSecret
+	if id, secret, ok := ctx.Request.BasicAuth(); ok {
+		clientID, clientSecret = id, secret
+	}
+
+	client, err := server.store.GetOAuthClient(ctx, clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthInvalidClient, errUnknownClient))
+			return db.OauthClient{}, false
+		}
+		server.logger.ErrorCtx(ctx, "user-exchangeToken", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return db.OauthClient{}, false
+	}
+
+	if client.ClientSecretHash.Valid {
+		secretHash := hashToken(clientSecret)
+		if subtle.ConstantTimeCompare([]byte(secretHash), []byte(client.ClientSecretHash.String)) != 1 {
+			ctx.JSON(http.StatusUnauthorized, oauthErrorResponse(oauthInvalidClient, errors.New("client authentication failed")))
+			return db.OauthClient{}, false
+		}
+	}
+
+	return client, true
+}
+
+// verifyCodeChallenge checks a PKCE code_verifier against the S256 code_challenge of the authorization request
+func verifyCodeChallenge(verifier, challenge string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// exchangeToken is the token endpoint. It exchanges an authorization code for an access token that is limited to
+// the scopes the user consented to.
+func (server *Server) exchangeToken(ctx *gin.Context) {
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Header("Pragma", "no-cache")
+
+	var req tokenRequest
+	if err := ctx.ShouldBind(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidRequest, err))
+		return
+	}
+	if req.GrantType != grantTypeAuthCode {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthUnsupportedGrantType, fmt.Errorf("grant_type must be %s", grantTypeAuthCode)))
+		return
+	}
+
+	client, ok := server.authenticateClient(ctx, req)
+	if !ok {
+		return
+	}
+
+	code, err := server.store.UseOAuthAuthorizationCode(ctx, hashToken(req.Code))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, errInvalidAuthCode))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-exchangeToken", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return
+	}
+	if code.ClientID != client.ClientID || code.RedirectUri != req.RedirectURI {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, errInvalidAuthCode))
+		return
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge) {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, errInvalidCodeVerifier))
+		return
+	}
+
+	_, err = server.store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		UserID:   code.UserID,
+		ClientID: client.ClientID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, errConsentRevoked))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-exchangeToken", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, code.UserID)
+	if err != nil || !user.IsActive {
+		ctx.JSON(http.StatusBadRequest, oauthErrorResponse(oauthInvalidGrant, errInvalidAuthCode))
+		return
+	}
+
+	accessToken, err := server.tokenMaker.CreateDelegatedToken(user.UserID, user.Email, client.ClientID, code.Scopes, delegatedTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, oauthErrorResponse(oauthServerError, err))
+		return
+	}
+
+	resp := tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(delegatedTokenDuration.Seconds()),
+		Scope:       strings.Join(code.Scopes, " "),
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// verifyDelegatedToken makes sure the consent a delegated token was issued under is still in place
+func (server *Server) verifyDelegatedToken(ctx *gin.Context, payload *token.Payload) bool {
+	err := server.checkConsent(ctx, payload)
+	if err != nil {
+		if err == errConsentRevoked {
+			ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+			return false
+		}
+		server.logger.ErrorCtx(ctx, "user-verifyDelegatedToken", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return false
+	}
+
+	return true
+}
+
+// checkConsent returns errConsentRevoked unless the user still consents to every scope of the delegated token
+func (server *Server) checkConsent(ctx context.Context, payload *token.Payload) error {
+	consent, err := server.store.GetOAuthConsent(ctx, db.GetOAuthConsentParams{
+		UserID:   payload.UserID,
+		ClientID: payload.ClientID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return errConsentRevoked
+		}
+		return err
+	}
+	if !containsScopes(consent.Scopes, payload.Scopes) {
+		return errConsentRevoked
+	}
+
+	return nil
+}
+
+type consentResponse struct {
+	ClientID   string     `json:"client_id"`
+	ClientName string     `json:"client_name,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	GrantedAt  time.Time  `json:"granted_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// listOAuthConsents returns the OAuth clients the logged-in user has granted access to
+func (server *Server) listOAuthConsents(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+
+	resp, err := server.oauthConsents(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-listOAuthConsents", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// oauthConsents collects the consents a user has given, together with the names of their clients
+func (server *Server) oauthConsents(ctx context.Context, userID string) ([]consentResponse, error) {
+	consents, err := server.store.ListOAuthConsents(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]consentResponse, 0, len(consents))
+	for _, consent := range consents {
+		client, err := server.store.GetOAuthClient(ctx, consent.ClientID)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+
+		resp = append(resp, consentResponse{
+			ClientID:   consent.ClientID,
+			ClientName: client.Name,
+			Scopes:     consent.Scopes,
+			GrantedAt:  consent.CreatedAt,
+			UpdatedAt:  consent.UpdatedAt,
+		})
+	}
+	return resp, nil
+}
+
+type revokeOAuthConsentRequest struct {
+	ClientID string `uri:"client_id" binding:"required"`
+}
+
+// revokeOAuthConsent withdraws the access of an OAuth client. Tokens issued to it stop working immediately, since
+// they are checked against the consent on every request.
+func (server *Server) revokeOAuthConsent(ctx *gin.Context) {
+	var req revokeOAuthConsentRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	consent, err := server.store.RevokeConsentTx(ctx, db.RevokeOAuthConsentParams{
+		UserID:   authPayload.UserID,
+		ClientID: req.ClientID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-revokeOAuthConsent", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := consentResponse{
+		ClientID:  consent.ClientID,
+		Scopes:    consent.Scopes,
+		GrantedAt: consent.CreatedAt,
+		UpdatedAt: consent.UpdatedAt,
+		RevokedAt: &consent.RevokedAt.Time,
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
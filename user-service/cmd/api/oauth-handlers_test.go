@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/slog"
+)
+
+// fakeOAuthStore serves the oauth clients it was given, the other queries of the store aren't faked
+type fakeOAuthStore struct {
+	db.Store
+	clients map[string]db.OauthClient
+}
+
+func (s *fakeOAuthStore) GetOAuthClient(_ context.Context, clientID string) (db.OauthClient, error) {
+	client, ok := s.clients[clientID]
+	if !ok {
+		return db.OauthClient{}, sql.ErrNoRows
+	}
+	return client, nil
+}
+
+func s256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func TestVerifyCodeChallenge(t *testing.T) {
+	verifier := strings.Repeat("v", 43)
+
+	testCases := []struct {
+		name      string
+		verifier  string
+		challenge string
+		ok        bool
+	}{
+		{name: "S256", verifier: verifier, challenge: s256Challenge(verifier), ok: true},
+		{name: "OtherVerifier", verifier: strings.Repeat("w", 43), challenge: s256Challenge(verifier)},
+		{name: "PaddedChallenge", verifier: verifier, challenge: base64.URLEncoding.EncodeToString([]byte(s256Challenge(verifier)))},
+		// a plain challenge is the verifier itself, which must not match
+		{name: "Plain", verifier: verifier, challenge: verifier},
+		{name: "EmptyVerifier", verifier: "", challenge: s256Challenge(verifier)},
+		{name: "EmptyChallenge", verifier: verifier, challenge: ""},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.ok, verifyCodeChallenge(tc.verifier, tc.challenge))
+		})
+	}
+}
+
+func TestValidateRedirectURI(t *testing.T) {
+	testCases := []struct {
+		name string
+		uri  string
+		ok   bool
+	}{
+		{name: "HTTPS", uri: "https://app.example.com/callback", ok: true},
+		{name: "HTTPSWithQuery", uri: "https://app.example.com/callback?tenant=1", ok: true},
+		{name: "Localhost", uri: "http://localhost:8080/callback", ok: true},
+		{name: "Loopback", uri: "http://127.0.0.1/callback", ok: true},
+		{name: "HTTP", uri: "http://app.example.com/callback"},
+		{name: "HTTPLookalike", uri: "http://localhost.example.com/callback"},
+		{name: "Relative", uri: "/callback"},
+		{name: "NoHost", uri: "https:///callback"},
+		{name: "Fragment", uri: "https://app.example.com/callback#token"},
+		{name: "CustomScheme", uri: "javascript://app.example.com/%0aalert(1)"},
+		{name: "Invalid", uri: "https://app.example.com/%zz"},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateRedirectURI(tc.uri)
+			if tc.ok {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	client := db.OauthClient{Scopes: []string{scopeAccountsRead, scopeTransfersWrite, scopeAccountsWrite}}
+
+	testCases := []struct {
+		name   string
+		scope  string
+		scopes []string
+	}{
+		{name: "Single", scope: scopeAccountsRead, scopes: []string{scopeAccountsRead}},
+		{name: "Several", scope: scopeTransfersWrite + "  " + scopeAccountsRead, scopes: []string{scopeTransfersWrite, scopeAccountsRead}},
+		{name: "Duplicate", scope: scopeAccountsRead + " " + scopeAccountsRead, scopes: []string{scopeAccountsRead}},
+		{name: "Unknown", scope: scopeAccountsRead + " admin"},
+		// registered for the client, but not a scope oauth clients can ask for
+		{name: "Unsupported", scope: scopeAccountsWrite},
+		{name: "CaseSensitive", scope: "Accounts:Read"},
+		{name: "Empty", scope: " "},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			scopes, err := parseScopes(tc.scope, client)
+			if tc.scopes == nil {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.scopes, scopes)
+		})
+	}
+
+	// supported scopes are only granted to clients they were registered for
+	_, err := parseScopes(scopeTransfersWrite, db.OauthClient{Scopes: []string{scopeAccountsRead}})
+	require.Error(t, err)
+}
+
+func TestValidateAuthorizeRequest(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	server := &Server{
+		store: &fakeOAuthStore{clients: map[string]db.OauthClient{
+			"budget-app": {
+				ClientID:     "budget-app",
+				RedirectUris: []string{"https://app.example.com/callback", "http://localhost:8080/callback"},
+				Scopes:       []string{scopeAccountsRead},
+			},
+		}},
+		logger: slog.New(slog.HandlerOptions{}.NewJSONHandler(io.Discard)),
+	}
+	valid := authorizeRequest{
+		ResponseType:        "code",
+		ClientID:            "budget-app",
+		RedirectURI:         "https://app.example.com/callback",
+		Scope:               scopeAccountsRead,
+		CodeChallenge:       s256Challenge(strings.Repeat("v", 43)),
+		CodeChallengeMethod: codeChallengeMethodS256,
+	}
+
+	testCases := []struct {
+		name   string
+		change func(req *authorizeRequest)
+		error  string
+	}{
+		{name: "Valid", change: func(req *authorizeRequest) {}},
+		{name: "UnknownClient", change: func(req *authorizeRequest) { req.ClientID = "other-app" }, error: oauthInvalidClient},
+		// the redirect_uri has to be one of the registered ones exactly
+		{name: "RedirectPrefix", change: func(req *authorizeRequest) { req.RedirectURI = "https://app.example.com/call" }, error: oauthInvalidRequest},
+		{name: "RedirectSubpath", change: func(req *authorizeRequest) { req.RedirectURI = "https://app.example.com/callback/evil" }, error: oauthInvalidRequest},
+		{name: "RedirectQuery", change: func(req *authorizeRequest) { req.RedirectURI = "https://app.example.com/callback?next=evil" }, error: oauthInvalidRequest},
+		{name: "RedirectHost", change: func(req *authorizeRequest) { req.RedirectURI = "https://app.example.com.evil.com/callback" }, error: oauthInvalidRequest},
+		{name: "RedirectPort", change: func(req *authorizeRequest) { req.RedirectURI = "http://localhost:9090/callback" }, error: oauthInvalidRequest},
+		{name: "ResponseType", change: func(req *authorizeRequest) { req.ResponseType = "token" }, error: oauthUnsupportedResponse},
+		{name: "PlainChallenge", change: func(req *authorizeRequest) { req.CodeChallengeMethod = "plain" }, error: oauthInvalidRequest},
+		{name: "MissingChallengeMethod", change: func(req *authorizeRequest) { req.CodeChallengeMethod = "" }, error: oauthInvalidRequest},
+		{name: "MissingChallenge", change: func(req *authorizeRequest) { req.CodeChallenge = "" }, error: oauthInvalidRequest},
+		{name: "ShortChallenge", change: func(req *authorizeRequest) { req.CodeChallenge = "abc" }, error: oauthInvalidRequest},
+		{name: "UnknownScope", change: func(req *authorizeRequest) { req.Scope = scopeAccountsRead + " admin" }, error: oauthInvalidScope},
+		{name: "UnregisteredScope", change: func(req *authorizeRequest) { req.Scope = scopeTransfersWrite }, error: oauthInvalidScope},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			req := valid
+			tc.change(&req)
+
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest(http.MethodGet, "/oauth/authorize", nil)
+
+			client, scopes, ok := server.validateAuthorizeRequest(ctx, req)
+			if tc.error != "" {
+				require.False(t, ok)
+				require.Equal(t, http.StatusBadRequest, w.Code)
+				require.Contains(t, w.Body.String(), `"error":"`+tc.error+`"`)
+				return
+			}
+			require.True(t, ok)
+			require.Equal(t, "budget-app", client.ClientID)
+			require.Equal(t, []string{scopeAccountsRead}, scopes)
+		})
+	}
+}
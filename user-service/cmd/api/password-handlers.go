@@ -0,0 +1,149 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const passwordResetTokenDuration = 30 * time.Minute
+
+type changePasswordRequest struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func (server *Server) changePassword(ctx *gin.Context) {
+	var req changePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-changePassword", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = CheckPassword(user.Password, req.OldPassword)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("old password is incorrect")))
+		return
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.UpdateUserPassword(ctx, db.UpdateUserPasswordParams{
+		UserID:      user.UserID,
+		NewPassword: hashedPassword,
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-changePassword", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// forgotPassword mails a password reset token. It responds the same way whether the email exists or not,
+// so it can't be used to find out which emails are registered.
+func (server *Server) forgotPassword(ctx *gin.Context) {
+	var req forgotPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	resp := gin.H{"status": "success", "message": "if the email is registered, a password reset token has been sent"}
+
+	user, err := server.store.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			server.logger.ErrorCtx(ctx, "user-forgotPassword", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		}
+		ctx.JSON(http.StatusOK, resp)
+		return
+	}
+
+	resetToken, tokenHash, err := generateToken()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.CreateUserToken(ctx, db.CreateUserTokenParams{
+		TokenHash: tokenHash,
+		UserID:    user.UserID,
+		Purpose:   db.TokenPurposePasswordReset,
+		ExpiresAt: time.Now().Add(passwordResetTokenDuration),
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-forgotPassword", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\nUse the following token to reset your password: %s\n\nThe token expires in %v. "+
+		"If you did not request a password reset, you can ignore this email.\n", user.Firstname, resetToken, passwordResetTokenDuration)
+	err = server.mailer.SendEmail(user.Email, "Reset your password", body)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-forgotPassword", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("cannot send password reset email: %w", err)))
+	}
+
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type resetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=6"`
+}
+
+func (server *Server) resetPassword(ctx *gin.Context) {
+	var req resetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	hashedPassword, err := HashPassword(req.NewPassword)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.ResetPasswordTx(ctx, db.ResetPasswordTxParams{
+		TokenHash:   hashToken(req.Token),
+		NewPassword: hashedPassword,
+	})
+	if err != nil {
+		if err == db.ErrInvalidToken {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-resetPassword", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
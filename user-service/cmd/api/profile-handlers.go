@@ -0,0 +1,264 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+var (
+	errIncorrectPassword = errors.New("password is incorrect")
+	errUserInactive      = errors.New("user is deactivated")
+	errEmailInUse        = errors.New("email is already in use")
+)
+
+// getAuthenticatedUser fetches the user the request is authenticated as. Deactivated users are rejected with 401.
+func (server *Server) getAuthenticatedUser(ctx *gin.Context, name string) (db.User, bool) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(err))
+			return db.User{}, false
+		}
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.User{}, false
+	}
+	if !user.IsActive {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(errUserInactive))
+		return db.User{}, false
+	}
+
+	return user, true
+}
+
+type updateProfileRequest struct {
+	Firstname *string `json:"firstname" binding:"omitempty,min=1"`
+	Lastname  *string `json:"lastname" binding:"omitempty,min=1"`
+}
+
+func (server *Server) updateProfile(ctx *gin.Context) {
+	var req updateProfileRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-updateProfile")
+	if !ok {
+		return
+	}
+
+	arg := db.UpdateUserProfileParams{UserID: user.UserID}
+	if req.Firstname != nil {
+		arg.Firstname = sql.NullString{String: *req.Firstname, Valid: true}
+	}
+	if req.Lastname != nil {
+		arg.Lastname = sql.NullString{String: *req.Lastname, Valid: true}
+	}
+
+	user, err := server.store.UpdateUserProfile(ctx, arg)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-updateProfile", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type updateEmailRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// updateEmail changes the email of the logged-in user. The new email is unverified until the token mailed to it
+// has been confirmed.
+func (server *Server) updateEmail(ctx *gin.Context) {
+	var req updateEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-updateEmail")
+	if !ok {
+		return
+	}
+
+	err := CheckPassword(user.Password, req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectPassword))
+		return
+	}
+
+	user, err = server.store.UpdateUserEmail(ctx, db.UpdateUserEmailParams{
+		UserID: user.UserID,
+		Email:  req.Email,
+	})
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Constraint == emailConstraint {
+			ctx.JSON(http.StatusConflict, errorResponse(errEmailInUse))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-updateEmail", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.InvalidateUserTokens(ctx, db.InvalidateUserTokensParams{
+		UserID:  user.UserID,
+		Purpose: db.TokenPurposeEmailVerification,
+	})
+	if err == nil {
+		err = server.sendVerificationEmail(ctx, user)
+	}
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-updateEmail", logging.StatusCode(http.StatusInternalServerError), logging.Err(fmt.Errorf("cannot send verification email: %w", err)))
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type confirmPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// deactivateUser disables the logged-in user. Deactivated users can't log in and their tokens are rejected.
+func (server *Server) deactivateUser(ctx *gin.Context) {
+	var req confirmPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-deactivateUser")
+	if !ok {
+		return
+	}
+
+	err := CheckPassword(user.Password, req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectPassword))
+		return
+	}
+
+	user, err = server.store.DeactivateUser(ctx, user.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-deactivateUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type userExportResponse struct {
+	userResponse
+	UpdatedAt     time.Time             `json:"updated_at"`
+	DeactivatedAt *time.Time            `json:"deactivated_at,omitempty"`
+	KYC           kycStatusResponse     `json:"kyc"`
+	OAuthConsents []consentResponse     `json:"oauth_consents"`
+	OAuthClients  []oauthClientResponse `json:"oauth_clients"`
+}
+
+// exportUser returns all personal data user-service stores about the logged-in user
+func (server *Server) exportUser(ctx *gin.Context) {
+	user, ok := server.getAuthenticatedUser(ctx, "user-exportUser")
+	if !ok {
+		return
+	}
+
+	kyc, err := server.kycStatus(ctx, user)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-exportUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	consents, err := server.oauthConsents(ctx, user.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-exportUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	clients, err := server.store.ListOAuthClientsByOwner(ctx, user.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-exportUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := userExportResponse{
+		userResponse:  newUserResponse(user),
+		UpdatedAt:     user.UpdatedAt,
+		KYC:           kyc,
+		OAuthConsents: consents,
+		OAuthClients:  make([]oauthClientResponse, 0, len(clients)),
+	}
+	for _, client := range clients {
+		resp.OAuthClients = append(resp.OAuthClients, newOAuthClientResponse(client))
+	}
+	if user.DeactivatedAt.Valid {
+		resp.DeactivatedAt = &user.DeactivatedAt.Time
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// eraseUser anonymizes the logged-in user. The user_id is kept, so financial records in account-service still
+// reference a valid user, but names, email, password, second factors and kyc documents are removed.
+func (server *Server) eraseUser(ctx *gin.Context) {
+	var req confirmPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, ok := server.getAuthenticatedUser(ctx, "user-eraseUser")
+	if !ok {
+		return
+	}
+
+	err := CheckPassword(user.Password, req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errIncorrectPassword))
+		return
+	}
+
+	result, err := server.store.EraseUserTx(ctx, db.EraseUserTxParams{
+		UserID:           user.UserID,
+		AnonymizedEmail:  fmt.Sprintf("deleted-%s@anonymized.invalid", user.UserID),
+		LoginAttemptKeys: []string{emailAttemptKey(user.Email)},
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-eraseUser", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	// the files of the documents are only removed once the transaction committed, so that a failed erasure keeps
+	// them. A file that can't be removed is logged with its key, to be removed by hand.
+	for _, document := range result.KYCDocuments {
+		err = server.blobStore.Delete(ctx, document.StorageKey)
+		if err != nil {
+			server.logger.ErrorCtx(ctx, "user-eraseUser", logging.StatusCode(http.StatusInternalServerError),
+				logging.Err(fmt.Errorf("cannot delete kyc document %s: %w", document.StorageKey, err)))
+		}
+	}
+
+	resp := newUserResponse(result.User)
+	ctx.JSON(http.StatusOK, resp)
+}
@@ -1,48 +1,173 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"github.com/bugrakocabay/dummy-bank-microservice/health"
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/logship"
+	"github.com/bugrakocabay/dummy-bank-microservice/metrics"
+	"github.com/bugrakocabay/dummy-bank-microservice/requestid"
+	"github.com/bugrakocabay/dummy-bank-microservice/shutdown"
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/mail"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/storage"
 	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
 	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
 	"github.com/gin-gonic/gin"
-	"log"
+	"golang.org/x/exp/slog"
+	"net/http"
+	"time"
 )
 
 type Server struct {
+	config     EnvConfig
 	store      db.Store
 	tokenMaker token.Maker
+	mailer     mail.Mailer
+	blobStore  storage.BlobStore
 	router     *gin.Engine
+	logs       *logship.Shipper
+	logger     *slog.Logger
+	// sleep delays the responses to failed logins
+	sleep func(d time.Duration)
 }
 
-func NewServer(store db.Store) (*Server, error) {
-	config, err := LoadConfig()
-	if err != nil {
-		log.Fatal("Error with loading env: ", err)
-	}
-
+func NewServer(config EnvConfig, store db.Store) (*Server, error) {
 	tokenMaker, err := token.NewPasetoMaker(config.SymmetricKey)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create token maker: %w", err)
 	}
+	mailer, err := mail.NewFileMailer(config.MailOutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create mailer: %w", err)
+	}
+	blobStore, err := storage.NewLocalBlobStore(config.KYCStorageDir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create blob store: %w", err)
+	}
 	server := &Server{
+		config:     config,
 		store:      store,
 		tokenMaker: tokenMaker,
+		mailer:     mailer,
+		blobStore:  blobStore,
+		logs:       logship.New(logship.HTTPSink(nil, config.LoggerServiceURL), config.logShipConfig()),
+		sleep:      time.Sleep,
 	}
+	stdoutLevel, shipLevel := config.logLevels()
+	server.logger = logging.New("user-service", logging.Stdout(stdoutLevel), logging.Ship(server.logs, shipLevel))
 	router := gin.Default()
+	// handlers pass their *gin.Context on as a context.Context, which has to reach the values of the request context
+	router.ContextWithFallback = true
+	router.Use(traceRequest, observeRequest, requestID, logFields)
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
 
 	router.POST("/users/create", server.createUser)
 	router.GET("/users/:user_id", server.getUser)
 	router.POST("/users/login", server.loginUser)
-	router.GET("/users/authenticate", server.authenticateUser)
+	router.POST("/users/login/2fa", server.loginTwoFactor)
+	router.POST("/users/verify-email", server.verifyEmail)
+	router.POST("/users/forgot-password", server.forgotPassword)
+	router.POST("/users/reset-password", server.resetPassword)
+
+	router.POST("/oauth/token", server.exchangeToken)
+	router.GET("/api-keys/authenticate", server.authenticateAPIKey)
+
+	// the gateway authenticates every request here, including those made with tokens issued to oauth clients
+	router.GET("/users/authenticate", authMiddleware(server.tokenMaker), server.authenticateUser)
+	// step-up for large transfers, which oauth clients with the transfers:write scope make as well
+	router.POST("/users/2fa/verify", authMiddleware(server.tokenMaker), server.verifyOTP)
+
+	authRoutes := router.Group("/").Use(authMiddleware(server.tokenMaker), firstPartyMiddleware())
+	authRoutes.POST("/users/change-password", server.changePassword)
+	authRoutes.POST("/users/resend-verification", server.resendVerificationEmail)
+	authRoutes.POST("/users/2fa/enroll", server.enrollTwoFactor)
+	authRoutes.POST("/users/2fa/confirm", server.confirmTwoFactor)
+	authRoutes.POST("/users/2fa/disable", server.disableTwoFactor)
+	authRoutes.POST("/users/2fa/recovery-codes", server.regenerateRecoveryCodes)
+	authRoutes.PUT("/users/update", server.updateProfile)
+	authRoutes.PUT("/users/update-email", server.updateEmail)
+	authRoutes.POST("/users/deactivate", server.deactivateUser)
+	authRoutes.GET("/users/export", server.exportUser)
+	authRoutes.POST("/users/erase", server.eraseUser)
+	authRoutes.GET("/users/kyc", server.getKYCStatus)
+	authRoutes.POST("/users/kyc/documents", server.uploadKYCDocument)
+	authRoutes.GET("/users/kyc/documents/:document_id", server.downloadKYCDocument)
+	authRoutes.GET("/users/kyc/reviews", server.listKYCReviews)
+	authRoutes.GET("/users/kyc/reviews/:user_id", server.getKYCReview)
+	authRoutes.POST("/users/kyc/reviews/:user_id/approve", server.approveKYC)
+	authRoutes.POST("/users/kyc/reviews/:user_id/reject", server.rejectKYC)
+	authRoutes.POST("/oauth/clients", server.registerOAuthClient)
+	authRoutes.GET("/oauth/clients", server.listOAuthClients)
+	authRoutes.GET("/oauth/authorize", server.getAuthorization)
+	authRoutes.POST("/oauth/authorize", server.authorizeClient)
+	authRoutes.GET("/oauth/consents", server.listOAuthConsents)
+	authRoutes.DELETE("/oauth/consents/:client_id", server.revokeOAuthConsent)
+	authRoutes.POST("/api-keys", server.createAPIKey)
+	authRoutes.GET("/api-keys", server.listAPIKeys)
+	authRoutes.DELETE("/api-keys/:key_id", server.revokeAPIKey)
+	authRoutes.POST("/service-accounts", server.createServiceAccount)
+	authRoutes.GET("/service-accounts", server.listServiceAccounts)
+	authRoutes.DELETE("/service-accounts/:service_account_id", server.disableServiceAccount)
+	authRoutes.POST("/service-accounts/:service_account_id/api-keys", server.createServiceAccountAPIKey)
+	authRoutes.GET("/service-accounts/:service_account_id/api-keys", server.listServiceAccountAPIKeys)
+	authRoutes.DELETE("/service-accounts/:service_account_id/api-keys/:key_id", server.revokeServiceAccountAPIKey)
 
 	server.router = router
 	return server, nil
 }
 
-func (server *Server) Start(address string) error {
-	return server.router.Run(address)
+// ServeHealth serves the liveness of the service at /healthz and the checks of checker at /readyz
+func (server *Server) ServeHealth(checker *health.Checker) {
+	server.router.GET("/healthz", gin.WrapH(health.LiveHandler()))
+	server.router.GET("/readyz", gin.WrapH(checker.ReadyHandler()))
+}
+
+// Start serves the HTTP api on address until ctx is done, then lets the requests in flight finish
+func (server *Server) Start(ctx context.Context, address string) error {
+	srv := &http.Server{Addr: address, Handler: server.router}
+	return shutdown.ServeHTTP(ctx, srv, server.config.ShutdownTimeout)
 }
 
 func errorResponse(err error) gin.H {
 	return gin.H{"error": err.Error()}
 }
+
+// traceRequest starts the span of the request, which continues the trace of the caller
+func traceRequest(ctx *gin.Context) {
+	spanCtx, span := tracing.StartHTTPServer(ctx.Request, "user-service", ctx.FullPath())
+	ctx.Request = ctx.Request.WithContext(spanCtx)
+	ctx.Next()
+	tracing.EndHTTPServer(span, ctx.Writer.Status())
+}
+
+// observeRequest counts the request and the time taken to serve it, by the route it matched
+func observeRequest(ctx *gin.Context) {
+	start := time.Now()
+	ctx.Next()
+	metrics.ObserveHTTP(ctx.Request.Method, ctx.FullPath(), ctx.Writer.Status(), time.Since(start))
+}
+
+// requestID puts the id of the X-Request-ID header, or a new one, into the context of the request and the header of
+// its response, so that the logs of the request can be matched with the ones of the other services
+func requestID(ctx *gin.Context) {
+	id := requestid.FromRequest(ctx.Request)
+	ctx.Request = ctx.Request.WithContext(requestid.NewContext(ctx.Request.Context(), id))
+	ctx.Header(requestid.Header, id)
+	ctx.Next()
+}
+
+// logFields puts the route of the request into the context of its logs, together with the account or user its path
+// names
+func logFields(ctx *gin.Context) {
+	attrs := []slog.Attr{logging.Route(ctx.FullPath())}
+	if id := ctx.Param("account_id"); id != "" {
+		attrs = append(attrs, logging.AccountID(id))
+	}
+	if id := ctx.Param("user_id"); id != "" {
+		attrs = append(attrs, logging.UserID(id))
+	}
+	ctx.Request = ctx.Request.WithContext(logging.NewContext(ctx.Request.Context(), attrs...))
+	ctx.Next()
+}
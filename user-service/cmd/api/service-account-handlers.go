@@ -0,0 +1,231 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+// serviceAccountScopes are the permissions an api key of a service account can be limited to. Service accounts act
+// for back-office jobs rather than for a user, so they can read the accounts of every user but can't move money.
+var serviceAccountScopes = map[string]bool{
+	scopeAccountsRead: true,
+}
+
+var (
+	errNotAdmin                = errors.New("only admins can manage service accounts")
+	errServiceAccountNotFound  = errors.New("service account not found")
+	errServiceAccountForbidden = errors.New("service accounts can only read accounts")
+)
+
+type serviceAccountResponse struct {
+	ServiceAccountID string    `json:"service_account_id"`
+	Name             string    `json:"name"`
+	CreatedBy        string    `json:"created_by"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+func newServiceAccountResponse(serviceAccount db.ServiceAccount) serviceAccountResponse {
+	return serviceAccountResponse{
+		ServiceAccountID: serviceAccount.ServiceAccountID,
+		Name:             serviceAccount.Name,
+		CreatedBy:        serviceAccount.CreatedBy,
+		CreatedAt:        serviceAccount.CreatedAt,
+	}
+}
+
+// getAdmin returns the logged-in user if they are an admin, and responds with 403 otherwise
+func (server *Server) getAdmin(ctx *gin.Context, name string) (db.User, bool) {
+	admin, ok := server.getAuthenticatedUser(ctx, name)
+	if !ok {
+		return db.User{}, false
+	}
+	if admin.Role != db.RoleAdmin {
+		ctx.JSON(http.StatusForbidden, errorResponse(errNotAdmin))
+		return db.User{}, false
+	}
+
+	return admin, true
+}
+
+// getActiveServiceAccount returns a service account that isn't disabled, and responds with 404 otherwise
+func (server *Server) getActiveServiceAccount(ctx *gin.Context, name, serviceAccountID string) (db.ServiceAccount, bool) {
+	serviceAccount, err := server.store.GetServiceAccount(ctx, serviceAccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errServiceAccountNotFound))
+			return db.ServiceAccount{}, false
+		}
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return db.ServiceAccount{}, false
+	}
+	if serviceAccount.DisabledAt.Valid {
+		ctx.JSON(http.StatusNotFound, errorResponse(errServiceAccountNotFound))
+		return db.ServiceAccount{}, false
+	}
+
+	return serviceAccount, true
+}
+
+type createServiceAccountRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// createServiceAccount creates a service account, which owns the api keys of a back-office job
+func (server *Server) createServiceAccount(ctx *gin.Context) {
+	var req createServiceAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	admin, ok := server.getAdmin(ctx, "user-createServiceAccount")
+	if !ok {
+		return
+	}
+
+	serviceAccount, err := server.store.CreateServiceAccount(ctx, db.CreateServiceAccountParams{
+		ServiceAccountID: server.createUUID(),
+		Name:             req.Name,
+		CreatedBy:        admin.UserID,
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-createServiceAccount", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, newServiceAccountResponse(serviceAccount))
+}
+
+// listServiceAccounts returns the service accounts that aren't disabled
+func (server *Server) listServiceAccounts(ctx *gin.Context) {
+	if _, ok := server.getAdmin(ctx, "user-listServiceAccounts"); !ok {
+		return
+	}
+
+	serviceAccounts, err := server.store.ListServiceAccounts(ctx)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-listServiceAccounts", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := make([]serviceAccountResponse, 0, len(serviceAccounts))
+	for _, serviceAccount := range serviceAccounts {
+		resp = append(resp, newServiceAccountResponse(serviceAccount))
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type serviceAccountRequest struct {
+	ServiceAccountID string `uri:"service_account_id" binding:"required"`
+}
+
+// disableServiceAccount disables a service account and revokes its api keys, which the gateway rejects right away
+func (server *Server) disableServiceAccount(ctx *gin.Context) {
+	var req serviceAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getAdmin(ctx, "user-disableServiceAccount"); !ok {
+		return
+	}
+	if _, ok := server.getActiveServiceAccount(ctx, "user-disableServiceAccount", req.ServiceAccountID); !ok {
+		return
+	}
+
+	serviceAccount, err := server.store.DisableServiceAccountTx(ctx, req.ServiceAccountID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusNotFound, errorResponse(errServiceAccountNotFound))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-disableServiceAccount", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, newServiceAccountResponse(serviceAccount))
+}
+
+// createServiceAccountAPIKey issues an api key for a service account. The key itself is only returned once.
+func (server *Server) createServiceAccountAPIKey(ctx *gin.Context) {
+	var uri serviceAccountRequest
+	if err := ctx.ShouldBindUri(&uri); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+	var req createAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if !apiKeyScopes[scope] {
+			ctx.JSON(http.StatusBadRequest, errorResponse(fmt.Errorf("unsupported scope: %s", scope)))
+			return
+		}
+		if !serviceAccountScopes[scope] {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errServiceAccountForbidden))
+			return
+		}
+	}
+
+	if _, ok := server.getAdmin(ctx, "user-createServiceAccountAPIKey"); !ok {
+		return
+	}
+	if _, ok := server.getActiveServiceAccount(ctx, "user-createServiceAccountAPIKey", uri.ServiceAccountID); !ok {
+		return
+	}
+
+	server.issueAPIKey(ctx, "user-createServiceAccountAPIKey", db.APIKeyOwnerServiceAccount, uri.ServiceAccountID, req)
+}
+
+// listServiceAccountAPIKeys returns the api keys of a service account that haven't been revoked
+func (server *Server) listServiceAccountAPIKeys(ctx *gin.Context) {
+	var req serviceAccountRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getAdmin(ctx, "user-listServiceAccountAPIKeys"); !ok {
+		return
+	}
+	if _, ok := server.getActiveServiceAccount(ctx, "user-listServiceAccountAPIKeys", req.ServiceAccountID); !ok {
+		return
+	}
+
+	server.listOwnerAPIKeys(ctx, "user-listServiceAccountAPIKeys", db.APIKeyOwnerServiceAccount, req.ServiceAccountID)
+}
+
+type revokeServiceAccountAPIKeyRequest struct {
+	ServiceAccountID string `uri:"service_account_id" binding:"required"`
+	KeyID            string `uri:"key_id" binding:"required"`
+}
+
+// revokeServiceAccountAPIKey revokes an api key of a service account
+func (server *Server) revokeServiceAccountAPIKey(ctx *gin.Context) {
+	var req revokeServiceAccountAPIKeyRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	if _, ok := server.getAdmin(ctx, "user-revokeServiceAccountAPIKey"); !ok {
+		return
+	}
+
+	server.revokeOwnerAPIKey(ctx, "user-revokeServiceAccountAPIKey", db.APIKeyOwnerServiceAccount, req.ServiceAccountID, req.KeyID)
+}
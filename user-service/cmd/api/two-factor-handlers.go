@@ -0,0 +1,424 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpIssuer                  = "DummyBank"
+	totpPeriod                  = 30
+	totpSkew                    = 1
+	recoveryCodeCount           = 10
+	loginChallengeTokenDuration = 5 * time.Minute
+)
+
+var (
+	errInvalidOTP          = errors.New("one-time password is invalid")
+	errInvalidRecoveryCode = errors.New("recovery code is invalid")
+	errTwoFactorDisabled   = errors.New("two-factor authentication is not enabled")
+	errTwoFactorIsEnabled  = errors.New("two-factor authentication is already enabled")
+)
+
+// validateTOTP checks the code against the secret at now, allowing one period of clock skew in both directions.
+// It returns the time step counter of the matching code, which must be greater than lastCounter so that
+// a code can't be replayed.
+func validateTOTP(secret, code string, lastCounter int64, now time.Time) (int64, bool) {
+	for i := -totpSkew; i <= totpSkew; i++ {
+		t := now.Add(time.Duration(i*totpPeriod) * time.Second)
+		expected, err := totp.GenerateCodeCustom(secret, t, totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+
+		counter := t.Unix() / totpPeriod
+		if expected == code && counter > lastCounter {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// verifyUserOTP validates the code for a user with two-factor enabled and marks it as used
+func (server *Server) verifyUserOTP(ctx *gin.Context, user db.User, code string) error {
+	if !user.TotpEnabled || !user.TotpSecret.Valid {
+		return errTwoFactorDisabled
+	}
+
+	counter, ok := validateTOTP(user.TotpSecret.String, code, user.TotpLastCounter, time.Now())
+	if !ok {
+		return errInvalidOTP
+	}
+
+	_, err := server.store.UpdateUserTOTPCounter(ctx, db.UpdateUserTOTPCounterParams{
+		UserID:  user.UserID,
+		Counter: counter,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// the code has been used by a concurrent request
+			return errInvalidOTP
+		}
+		return err
+	}
+
+	return nil
+}
+
+// generateRecoveryCodes creates a new set of recovery codes and returns them together with their hashes
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		buf := make([]byte, 5)
+		_, err := rand.Read(buf)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+
+		code := hex.EncodeToString(buf)
+		codes[i] = fmt.Sprintf("%s-%s", code[:5], code[5:])
+		hashes[i] = hashToken(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+type enrollTwoFactorResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// enrollTwoFactor generates a new TOTP secret for the logged-in user. Two-factor authentication is only turned on
+// after the first code generated from the secret has been confirmed.
+func (server *Server) enrollTwoFactor(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-enrollTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errTwoFactorIsEnabled))
+		return
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      totpIssuer,
+		AccountName: user.Email,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.SetUserTOTPSecret(ctx, db.SetUserTOTPSecretParams{
+		UserID:     user.UserID,
+		TotpSecret: sql.NullString{String: key.Secret(), Valid: true},
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-enrollTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := enrollTwoFactorResponse{
+		Secret: key.Secret(),
+		URI:    key.URL(),
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type confirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (server *Server) confirmTwoFactor(ctx *gin.Context) {
+	var req confirmTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-confirmTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.TotpEnabled {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errTwoFactorIsEnabled))
+		return
+	}
+	if !user.TotpSecret.Valid {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("two-factor enrollment has not been started")))
+		return
+	}
+
+	counter, ok := validateTOTP(user.TotpSecret.String, req.Code, user.TotpLastCounter, time.Now())
+	if !ok {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOTP))
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	_, err = server.store.EnableTwoFactorTx(ctx, db.EnableTwoFactorTxParams{
+		UserID:             user.UserID,
+		Counter:            counter,
+		RecoveryCodeHashes: hashes,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			ctx.JSON(http.StatusBadRequest, errorResponse(errInvalidOTP))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-confirmTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := recoveryCodesResponse{RecoveryCodes: codes}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type disableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,len=6,numeric"`
+}
+
+func (server *Server) disableTwoFactor(ctx *gin.Context) {
+	var req disableTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-disableTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = CheckPassword(user.Password, req.Password)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("password is incorrect")))
+		return
+	}
+
+	err = server.verifyUserOTP(ctx, user, req.Code)
+	if err != nil {
+		server.otpErrorResponse(ctx, "user-disableTwoFactor", err)
+		return
+	}
+
+	user, err = server.store.DisableTwoFactorTx(ctx, user.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-disableTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+type verifyOTPRequest struct {
+	Code string `json:"code" binding:"required,len=6,numeric"`
+}
+
+// regenerateRecoveryCodes replaces all recovery codes of the logged-in user after checking a fresh OTP
+func (server *Server) regenerateRecoveryCodes(ctx *gin.Context) {
+	var req verifyOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-regenerateRecoveryCodes", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.verifyUserOTP(ctx, user, req.Code)
+	if err != nil {
+		server.otpErrorResponse(ctx, "user-regenerateRecoveryCodes", err)
+		return
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.store.ReplaceRecoveryCodesTx(ctx, db.ReplaceRecoveryCodesTxParams{
+		UserID:     user.UserID,
+		CodeHashes: hashes,
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-regenerateRecoveryCodes", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := recoveryCodesResponse{RecoveryCodes: codes}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// verifyOTP checks a fresh OTP of the logged-in user. The gateway uses it as a step-up check before
+// high-value operations.
+func (server *Server) verifyOTP(ctx *gin.Context) {
+	var req verifyOTPRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-verifyOTP", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.verifyUserOTP(ctx, user, req.Code)
+	if err != nil {
+		server.otpErrorResponse(ctx, "user-verifyOTP", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
+}
+
+type loginTwoFactorRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode"`
+	RecoveryCode   string `json:"recovery_code" binding:"required_without=Code"`
+}
+
+// loginTwoFactor is the second login step for users with two-factor enabled. It exchanges the challenge token
+// returned by loginUser and either a TOTP code or an unused recovery code for an access token.
+func (server *Server) loginTwoFactor(ctx *gin.Context) {
+	var req loginTwoFactorRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	challengeHash := hashToken(req.ChallengeToken)
+	challenge, err := server.store.GetUserToken(ctx, challengeHash)
+	if err != nil || challenge.Purpose != db.TokenPurposeLoginChallenge ||
+		challenge.UsedAt.Valid || time.Now().After(challenge.ExpiresAt) {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(db.ErrInvalidToken))
+		return
+	}
+
+	user, err := server.store.GetUser(ctx, challenge.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-loginTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	lockedUntil, err := server.loginLockedUntil(ctx, emailAttemptKey(user.Email), ipAttemptKey(ctx.ClientIP()))
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-loginTwoFactor", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+	if !lockedUntil.IsZero() {
+		loginLockedResponse(ctx, lockedUntil)
+		return
+	}
+
+	if req.Code != "" {
+		err = server.verifyUserOTP(ctx, user, req.Code)
+	} else {
+		_, err = server.store.UseRecoveryCode(ctx, db.UseRecoveryCodeParams{
+			UserID:   user.UserID,
+			CodeHash: hashToken(req.RecoveryCode),
+		})
+		if err == sql.ErrNoRows {
+			err = errInvalidRecoveryCode
+		}
+	}
+	if err != nil {
+		if err == errInvalidOTP || err == errInvalidRecoveryCode {
+			server.recordLoginFailure(ctx, user.Email, loginFailureSecondFactor)
+		}
+		server.otpErrorResponse(ctx, "user-loginTwoFactor", err)
+		return
+	}
+
+	_, err = server.store.UseUserToken(ctx, challengeHash)
+	if err != nil {
+		ctx.JSON(http.StatusUnauthorized, errorResponse(db.ErrInvalidToken))
+		return
+	}
+
+	server.resetLoginFailures(ctx, user.Email)
+
+	accessToken, err := server.tokenMaker.CreateToken(user.UserID, user.Email, server.config.AccessTokenDuration)
+	if err != nil {
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := loginUserResponse{
+		AccessToken: accessToken,
+		User:        newUserResponse(user),
+	}
+	ctx.JSON(http.StatusOK, resp)
+}
+
+// otpErrorResponse maps errors of a second factor check to a response
+func (server *Server) otpErrorResponse(ctx *gin.Context, name string, err error) {
+	switch {
+	case err == errTwoFactorDisabled:
+		ctx.JSON(http.StatusForbidden, errorResponse(err))
+	case err == errInvalidOTP || err == errInvalidRecoveryCode:
+		ctx.JSON(http.StatusUnauthorized, errorResponse(err))
+	default:
+		server.logger.ErrorCtx(ctx, name, logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+	}
+}
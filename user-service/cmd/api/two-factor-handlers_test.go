@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTOTP(t *testing.T) {
+	const secret = "JBSWY3DPEHPK3PXP"
+	// halfway through a time step, so that the skew can't cross into another one
+	now := time.Unix(1700000010+totpPeriod/2, 0)
+	step := now.Unix() / totpPeriod
+
+	codeAt := func(t *testing.T, offset int) string {
+		code, err := totp.GenerateCodeCustom(secret, now.Add(time.Duration(offset*totpPeriod)*time.Second), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		require.NoError(t, err)
+		return code
+	}
+
+	testCases := []struct {
+		name        string
+		offset      int
+		wrong       bool
+		lastCounter int64
+		counter     int64
+		ok          bool
+	}{
+		{name: "Current", offset: 0, counter: step, ok: true},
+		{name: "PreviousStep", offset: -1, counter: step - 1, ok: true},
+		{name: "NextStep", offset: 1, counter: step + 1, ok: true},
+		{name: "TooOld", offset: -2},
+		{name: "TooNew", offset: 2},
+		{name: "WrongCode", offset: 0, wrong: true},
+		{name: "ReplayedInSameStep", offset: 0, lastCounter: step},
+		{name: "ReplayedPreviousStep", offset: -1, lastCounter: step - 1},
+		{name: "OlderThanLastUsed", offset: 0, lastCounter: step + 1},
+		{name: "NewerThanLastUsed", offset: -1, lastCounter: step - 2, counter: step - 1, ok: true},
+	}
+
+	for i := range testCases {
+		tc := testCases[i]
+
+		t.Run(tc.name, func(t *testing.T) {
+			code := codeAt(t, tc.offset)
+			if tc.wrong {
+				// change the last digit, and make sure no code of the skew window is the result
+				code = code[:5] + string('0'+(code[5]-'0'+1)%10)
+				for offset := -totpSkew; offset <= totpSkew; offset++ {
+					require.NotEqual(t, codeAt(t, offset), code)
+				}
+			}
+
+			counter, ok := validateTOTP(secret, code, tc.lastCounter, now)
+			require.Equal(t, tc.ok, ok)
+			require.Equal(t, tc.counter, counter)
+		})
+	}
+}
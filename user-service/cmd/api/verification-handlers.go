@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/logging"
+	"github.com/bugrakocabay/dummy-bank-microservice/user-service/cmd/token"
+	db "github.com/bugrakocabay/dummy-bank-microservice/user-service/db/sqlc"
+	"github.com/gin-gonic/gin"
+)
+
+const emailVerificationTokenDuration = 24 * time.Hour
+
+// sendVerificationEmail creates an email verification token for the user and mails it
+func (server *Server) sendVerificationEmail(ctx context.Context, user db.User) error {
+	verificationToken, tokenHash, err := generateToken()
+	if err != nil {
+		return err
+	}
+
+	_, err = server.store.CreateUserToken(ctx, db.CreateUserTokenParams{
+		TokenHash: tokenHash,
+		UserID:    user.UserID,
+		Purpose:   db.TokenPurposeEmailVerification,
+		ExpiresAt: time.Now().Add(emailVerificationTokenDuration),
+	})
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hello %s,\n\nUse the following token to verify your email address: %s\n\nThe token expires in %v.\n",
+		user.Firstname, verificationToken, emailVerificationTokenDuration)
+	return server.mailer.SendEmail(user.Email, "Verify your email address", body)
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+func (server *Server) verifyEmail(ctx *gin.Context) {
+	var req verifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		return
+	}
+
+	user, err := server.store.VerifyEmailTx(ctx, hashToken(req.Token))
+	if err != nil {
+		if err == db.ErrInvalidToken {
+			ctx.JSON(http.StatusBadRequest, errorResponse(err))
+			return
+		}
+		server.logger.ErrorCtx(ctx, "user-verifyEmail", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	resp := newUserResponse(user)
+	ctx.JSON(http.StatusOK, resp)
+}
+
+func (server *Server) resendVerificationEmail(ctx *gin.Context) {
+	authPayload := ctx.MustGet(authorizationPayloadKey).(*token.Payload)
+	user, err := server.store.GetUser(ctx, authPayload.UserID)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-resendVerificationEmail", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	if user.EmailVerified {
+		ctx.JSON(http.StatusBadRequest, errorResponse(errors.New("email is already verified")))
+		return
+	}
+
+	err = server.store.InvalidateUserTokens(ctx, db.InvalidateUserTokensParams{
+		UserID:  user.UserID,
+		Purpose: db.TokenPurposeEmailVerification,
+	})
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-resendVerificationEmail", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	err = server.sendVerificationEmail(ctx, user)
+	if err != nil {
+		server.logger.ErrorCtx(ctx, "user-resendVerificationEmail", logging.StatusCode(http.StatusInternalServerError), logging.Err(err))
+		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"status": "success"})
+}
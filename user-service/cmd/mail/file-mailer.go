@@ -0,0 +1,57 @@
+package mail
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FileMailer is a Mailer for local development that writes emails to disk instead of delivering them
+type FileMailer struct {
+	outputDir string
+}
+
+// NewFileMailer creates a FileMailer writing into outputDir. If outputDir is empty, emails are only logged.
+func NewFileMailer(outputDir string) (Mailer, error) {
+	if outputDir != "" {
+		if err := os.MkdirAll(outputDir, 0o755); err != nil {
+			return nil, fmt.Errorf("cannot create mail output dir: %w", err)
+		}
+	}
+
+	return &FileMailer{outputDir: outputDir}, nil
+}
+
+// SendEmail writes the email as a .eml file and logs its recipient and subject
+func (mailer *FileMailer) SendEmail(to string, subject string, body string) error {
+	log.Printf("sending email to %s: %s", to, subject)
+	if mailer.outputDir == "" {
+		log.Println(body)
+		return nil
+	}
+
+	now := time.Now()
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "To: %s\r\n", to)
+	fmt.Fprintf(&sb, "Subject: %s\r\n", subject)
+	fmt.Fprintf(&sb, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	sb.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	sb.WriteString(body)
+
+	fileName := fmt.Sprintf("%d-%s.eml", now.UnixNano(), sanitizeFileName(to))
+	return os.WriteFile(filepath.Join(mailer.outputDir, fileName), []byte(sb.String()), 0o644)
+}
+
+func sanitizeFileName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_', r == '@':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
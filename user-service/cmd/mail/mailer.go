@@ -0,0 +1,6 @@
+package mail
+
+type Mailer interface {
+	// SendEmail delivers an email with the given subject and plain text body to a single recipient
+	SendEmail(to string, subject string, body string) error
+}
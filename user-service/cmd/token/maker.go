@@ -8,6 +8,9 @@ type Maker interface {
 	// CreateToken creates a new token for a specific user id and duration
 	CreateToken(userID string, email string, duration time.Duration) (string, error)
 
+	// CreateDelegatedToken creates a token that lets an OAuth client act for a user within the granted scopes
+	CreateDelegatedToken(userID string, email string, clientID string, scopes []string, duration time.Duration) (string, error)
+
 	// VerifyToken checks if the given token is valid
 	VerifyToken(token string) (*Payload, error)
 }
@@ -33,6 +33,15 @@ func (maker *PasetoMaker) CreateToken(userID string, email string, duration time
 	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
 }
 
+// CreateDelegatedToken creates a token that lets an OAuth client act for a user within the granted scopes
+func (maker *PasetoMaker) CreateDelegatedToken(userID string, email string, clientID string, scopes []string, duration time.Duration) (string, error) {
+	payload := NewPayload(userID, email, duration)
+	payload.ClientID = clientID
+	payload.Scopes = scopes
+
+	return maker.paseto.Encrypt(maker.symmetricKey, payload, nil)
+}
+
 // VerifyToken checks if the given token is valid
 func (maker *PasetoMaker) VerifyToken(token string) (*Payload, error) {
 	payload := &Payload{}
@@ -16,6 +16,9 @@ type Payload struct {
 	Email     string    `json:"email"`
 	IssuedAt  time.Time `json:"issued_at"`
 	ExpiredAt time.Time `json:"expired_at"`
+	// ClientID and Scopes are only set for tokens issued to OAuth clients
+	ClientID string   `json:"client_id,omitempty"`
+	Scopes   []string `json:"scopes,omitempty"`
 }
 
 // NewPayload creates a token payload with specific user id and duration
@@ -41,6 +44,21 @@ func (payload *Payload) Valid() error {
 	return nil
 }
 
+// IsDelegated reports whether the token was issued to an OAuth client rather than to the user
+func (payload *Payload) IsDelegated() bool {
+	return payload.ClientID != ""
+}
+
+// HasScope reports whether a delegated token was granted the scope
+func (payload *Payload) HasScope(scope string) bool {
+	for _, granted := range payload.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
 func createUUID() string {
 	// Generate a new UUID
 	uuid := make([]byte, 16)
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS user_tokens;
+ALTER TABLE "users" DROP COLUMN IF EXISTS "email_verified";
\ No newline at end of file
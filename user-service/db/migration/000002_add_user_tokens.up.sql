@@ -0,0 +1,13 @@
+ALTER TABLE "users" ADD COLUMN "email_verified" boolean NOT NULL DEFAULT false;
+
+CREATE TABLE "user_tokens" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "token_hash" varchar UNIQUE NOT NULL,
+    "user_id" varchar NOT NULL,
+    "purpose" varchar NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "user_tokens" ("user_id", "purpose");
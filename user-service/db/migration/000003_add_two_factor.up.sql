@@ -0,0 +1,13 @@
+ALTER TABLE "users" ADD COLUMN "totp_secret" varchar;
+ALTER TABLE "users" ADD COLUMN "totp_enabled" boolean NOT NULL DEFAULT false;
+ALTER TABLE "users" ADD COLUMN "totp_last_counter" bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE "recovery_codes" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" varchar NOT NULL,
+    "code_hash" varchar NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE UNIQUE INDEX ON "recovery_codes" ("user_id", "code_hash");
@@ -0,0 +1,6 @@
+CREATE TABLE "login_attempts" (
+    "key" varchar PRIMARY KEY,
+    "failed_count" int NOT NULL DEFAULT 0,
+    "locked_until" timestamptz,
+    "last_failed_at" timestamptz NOT NULL DEFAULT (now())
+);
@@ -0,0 +1,3 @@
+ALTER TABLE "users" DROP COLUMN IF EXISTS "anonymized_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "deactivated_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "is_active";
\ No newline at end of file
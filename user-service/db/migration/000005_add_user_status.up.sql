@@ -0,0 +1,3 @@
+ALTER TABLE "users" ADD COLUMN "is_active" boolean NOT NULL DEFAULT true;
+ALTER TABLE "users" ADD COLUMN "deactivated_at" timestamptz;
+ALTER TABLE "users" ADD COLUMN "anonymized_at" timestamptz;
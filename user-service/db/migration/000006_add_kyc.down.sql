@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS kyc_documents;
+DROP INDEX IF EXISTS users_kyc_status_idx;
+ALTER TABLE "users" DROP COLUMN IF EXISTS "kyc_reviewed_at";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "kyc_reviewed_by";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "kyc_rejection_reason";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "kyc_status";
+ALTER TABLE "users" DROP COLUMN IF EXISTS "role";
@@ -0,0 +1,21 @@
+ALTER TABLE "users" ADD COLUMN "role" varchar NOT NULL DEFAULT 'customer';
+ALTER TABLE "users" ADD COLUMN "kyc_status" varchar NOT NULL DEFAULT 'pending';
+ALTER TABLE "users" ADD COLUMN "kyc_rejection_reason" varchar;
+ALTER TABLE "users" ADD COLUMN "kyc_reviewed_by" varchar;
+ALTER TABLE "users" ADD COLUMN "kyc_reviewed_at" timestamptz;
+
+CREATE INDEX ON "users" ("kyc_status");
+
+CREATE TABLE "kyc_documents" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "document_id" varchar UNIQUE NOT NULL,
+    "user_id" varchar NOT NULL,
+    "document_type" varchar NOT NULL,
+    "file_name" varchar NOT NULL,
+    "content_type" varchar NOT NULL,
+    "size_bytes" bigint NOT NULL,
+    "storage_key" varchar NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "kyc_documents" ("user_id");
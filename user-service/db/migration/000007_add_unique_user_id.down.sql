@@ -0,0 +1 @@
+ALTER TABLE "users" DROP CONSTRAINT IF EXISTS "users_user_id_key";
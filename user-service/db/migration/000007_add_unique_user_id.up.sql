@@ -0,0 +1,18 @@
+-- user_id is how the other services refer to a user, so users sharing a user_id aren't merged or deleted here: the
+-- migration fails instead, before adding the constraint. To go on, list the duplicates with
+--   SELECT user_id, array_agg(id ORDER BY id) FROM users GROUP BY user_id HAVING count(*) > 1;
+-- merge or delete the rows by hand, clear the failed migration with `migrate ... force 6` and migrate up again.
+DO $$
+DECLARE
+    duplicates text;
+BEGIN
+    SELECT string_agg(user_id, ', ' ORDER BY user_id) INTO duplicates
+    FROM (SELECT user_id FROM users GROUP BY user_id HAVING count(*) > 1 ORDER BY user_id LIMIT 10) AS duplicated;
+
+    IF duplicates IS NOT NULL THEN
+        RAISE EXCEPTION 'users share the user_id of: %', duplicates
+            USING HINT = 'merge or delete the duplicated users before adding users_user_id_key, see 000007_add_unique_user_id.up.sql';
+    END IF;
+END $$;
+
+ALTER TABLE "users" ADD CONSTRAINT "users_user_id_key" UNIQUE ("user_id");
@@ -0,0 +1,38 @@
+CREATE TABLE "oauth_clients" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "client_id" varchar UNIQUE NOT NULL,
+    "client_secret_hash" varchar,
+    "name" varchar NOT NULL,
+    "redirect_uris" varchar[] NOT NULL,
+    "scopes" varchar[] NOT NULL,
+    "owner_id" varchar NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oauth_clients" ("owner_id");
+
+CREATE TABLE "oauth_authorization_codes" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "code_hash" varchar UNIQUE NOT NULL,
+    "client_id" varchar NOT NULL,
+    "user_id" varchar NOT NULL,
+    "redirect_uri" varchar NOT NULL,
+    "scopes" varchar[] NOT NULL,
+    "code_challenge" varchar NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "oauth_authorization_codes" ("user_id", "client_id");
+
+CREATE TABLE "oauth_consents" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "user_id" varchar NOT NULL,
+    "client_id" varchar NOT NULL,
+    "scopes" varchar[] NOT NULL,
+    "created_at" timestamptz NOT NULL DEFAULT (now()),
+    "updated_at" timestamptz NOT NULL DEFAULT (now()),
+    "revoked_at" timestamptz,
+    UNIQUE ("user_id", "client_id")
+);
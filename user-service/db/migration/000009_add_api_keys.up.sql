@@ -0,0 +1,16 @@
+CREATE TABLE "api_keys" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "key_id" varchar UNIQUE NOT NULL,
+    "key_hash" varchar UNIQUE NOT NULL,
+    "prefix" varchar NOT NULL,
+    "user_id" varchar NOT NULL,
+    "name" varchar NOT NULL,
+    "scopes" varchar[] NOT NULL,
+    "expires_at" timestamptz,
+    "last_used_at" timestamptz,
+    "last_used_ip" varchar,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+CREATE INDEX ON "api_keys" ("user_id");
@@ -0,0 +1,5 @@
+DELETE FROM "api_keys" WHERE "owner_type" = 'service_account';
+ALTER TABLE "api_keys" DROP COLUMN IF EXISTS "owner_type";
+ALTER TABLE "api_keys" RENAME COLUMN "owner_id" TO "user_id";
+
+DROP TABLE IF EXISTS "service_accounts";
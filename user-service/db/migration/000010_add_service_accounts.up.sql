@@ -0,0 +1,12 @@
+CREATE TABLE "service_accounts" (
+    "id" BIGSERIAL PRIMARY KEY,
+    "service_account_id" varchar UNIQUE NOT NULL,
+    "name" varchar NOT NULL,
+    "created_by" varchar NOT NULL,
+    "disabled_at" timestamptz,
+    "created_at" timestamptz NOT NULL DEFAULT (now())
+);
+
+-- api keys are owned by a user or by a service account, owner_id is the user_id or the service_account_id
+ALTER TABLE "api_keys" RENAME COLUMN "user_id" TO "owner_id";
+ALTER TABLE "api_keys" ADD COLUMN "owner_type" varchar NOT NULL DEFAULT 'user';
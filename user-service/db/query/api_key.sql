@@ -0,0 +1,34 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (key_id, key_hash, prefix, owner_type, owner_id, name, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: ListAPIKeys :many
+SELECT *
+FROM api_keys
+WHERE owner_type = $1
+  AND owner_id = $2
+  AND revoked_at IS NULL
+ORDER BY id;
+
+-- name: UseAPIKey :one
+UPDATE api_keys
+set last_used_at = now(),
+    last_used_ip = $2
+WHERE key_hash = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > now()) RETURNING *;
+
+-- name: RevokeAPIKey :one
+UPDATE api_keys
+set revoked_at = now()
+WHERE key_id = $1
+  AND owner_type = $2
+  AND owner_id = $3
+  AND revoked_at IS NULL RETURNING *;
+
+-- name: RevokeOwnerAPIKeys :exec
+UPDATE api_keys
+set revoked_at = now()
+WHERE owner_type = $1
+  AND owner_id = $2
+  AND revoked_at IS NULL;
@@ -0,0 +1,45 @@
+-- name: CreateKYCDocument :one
+INSERT INTO kyc_documents (document_id, user_id, document_type, file_name, content_type, size_bytes, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: GetKYCDocument :one
+SELECT *
+FROM kyc_documents
+WHERE document_id = $1 LIMIT 1;
+
+-- name: ListKYCDocuments :many
+SELECT *
+FROM kyc_documents
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: SubmitUserKYC :one
+UPDATE users
+set kyc_status           = 'pending',
+    kyc_rejection_reason = NULL,
+    updated_at           = now()
+WHERE user_id = $1
+  AND kyc_status <> 'verified' RETURNING *;
+
+-- name: ReviewUserKYC :one
+UPDATE users
+set kyc_status           = sqlc.arg(kyc_status),
+    kyc_rejection_reason = sqlc.narg(rejection_reason),
+    kyc_reviewed_by      = sqlc.arg(reviewed_by),
+    kyc_reviewed_at      = now(),
+    updated_at           = now()
+WHERE user_id = sqlc.arg(user_id)
+  AND kyc_status = 'pending' RETURNING *;
+
+-- name: ListKYCReviewQueue :many
+SELECT *
+FROM users u
+WHERE u.kyc_status = 'pending'
+  AND EXISTS (SELECT 1 FROM kyc_documents d WHERE d.user_id = u.user_id)
+ORDER BY u.id
+LIMIT $1 OFFSET $2;
+
+-- name: DeleteKYCDocuments :many
+DELETE
+FROM kyc_documents
+WHERE user_id = $1 RETURNING *;
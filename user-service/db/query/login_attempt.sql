@@ -0,0 +1,23 @@
+-- name: GetLoginAttempt :one
+SELECT *
+FROM login_attempts
+WHERE key = $1 LIMIT 1;
+
+-- name: RecordFailedLoginAttempt :one
+INSERT INTO login_attempts (key, failed_count, last_failed_at)
+VALUES (sqlc.arg(key), 1, now())
+ON CONFLICT (key) DO UPDATE
+SET failed_count = CASE
+        WHEN login_attempts.last_failed_at < sqlc.arg(window_start) THEN 1
+        ELSE login_attempts.failed_count + 1
+    END,
+    last_failed_at = now() RETURNING *;
+
+-- name: LockLoginAttempt :one
+UPDATE login_attempts
+set locked_until = $2
+WHERE key = $1 RETURNING *;
+
+-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts
+WHERE key = $1;
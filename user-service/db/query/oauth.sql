@@ -0,0 +1,91 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, scopes, owner_id)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetOAuthClient :one
+SELECT *
+FROM oauth_clients
+WHERE client_id = $1 LIMIT 1;
+
+-- name: ListOAuthClientsByOwner :many
+SELECT *
+FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY id;
+
+-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+set used_at = now()
+WHERE code_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now() RETURNING *;
+
+-- name: DeleteOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE user_id = $1
+  AND client_id = $2;
+
+-- name: GrantOAuthConsent :one
+INSERT INTO oauth_consents (user_id, client_id, scopes)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, client_id) DO UPDATE
+    SET scopes     = CASE
+                         WHEN oauth_consents.revoked_at IS NULL
+                             THEN ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes))
+                         ELSE EXCLUDED.scopes
+        END,
+        updated_at = now(),
+        revoked_at = NULL
+RETURNING *;
+
+-- name: GetOAuthConsent :one
+SELECT *
+FROM oauth_consents
+WHERE user_id = $1
+  AND client_id = $2
+  AND revoked_at IS NULL LIMIT 1;
+
+-- name: ListOAuthConsents :many
+SELECT *
+FROM oauth_consents
+WHERE user_id = $1
+  AND revoked_at IS NULL
+ORDER BY id;
+
+-- name: RevokeOAuthConsent :one
+UPDATE oauth_consents
+set revoked_at = now(),
+    updated_at = now()
+WHERE user_id = $1
+  AND client_id = $2
+  AND revoked_at IS NULL RETURNING *;
+
+-- name: DeleteUserOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE user_id = $1;
+
+-- name: DeleteClientOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE client_id = $1;
+
+-- name: RevokeUserOAuthConsents :exec
+UPDATE oauth_consents
+set revoked_at = now(),
+    updated_at = now()
+WHERE user_id = $1
+  AND revoked_at IS NULL;
+
+-- name: RevokeClientOAuthConsents :exec
+UPDATE oauth_consents
+set revoked_at = now(),
+    updated_at = now()
+WHERE client_id = $1
+  AND revoked_at IS NULL;
+
+-- name: DeleteOAuthClientsByOwner :many
+DELETE FROM oauth_clients
+WHERE owner_id = $1 RETURNING *;
@@ -0,0 +1,20 @@
+-- name: CreateRecoveryCode :one
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES ($1, $2) RETURNING *;
+
+-- name: ListRecoveryCodes :many
+SELECT *
+FROM recovery_codes
+WHERE user_id = $1
+ORDER BY id;
+
+-- name: UseRecoveryCode :one
+UPDATE recovery_codes
+set used_at = now()
+WHERE user_id = $1
+  AND code_hash = $2
+  AND used_at IS NULL RETURNING *;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM recovery_codes
+WHERE user_id = $1;
@@ -0,0 +1,20 @@
+-- name: CreateServiceAccount :one
+INSERT INTO service_accounts (service_account_id, name, created_by)
+VALUES ($1, $2, $3) RETURNING *;
+
+-- name: GetServiceAccount :one
+SELECT *
+FROM service_accounts
+WHERE service_account_id = $1 LIMIT 1;
+
+-- name: ListServiceAccounts :many
+SELECT *
+FROM service_accounts
+WHERE disabled_at IS NULL
+ORDER BY id;
+
+-- name: DisableServiceAccount :one
+UPDATE service_accounts
+set disabled_at = now()
+WHERE service_account_id = $1
+  AND disabled_at IS NULL RETURNING *;
@@ -15,4 +15,67 @@ WHERE email = $1 LIMIT 1;
 -- name: UpdateUserPassword :exec
 UPDATE users
 set password = sqlc.arg(new_password)
-WHERE user_id = sqlc.arg(user_id);
\ No newline at end of file
+WHERE user_id = sqlc.arg(user_id);
+
+-- name: SetUserEmailVerified :one
+UPDATE users
+set email_verified = true
+WHERE user_id = $1 RETURNING *;
+
+-- name: SetUserTOTPSecret :one
+UPDATE users
+set totp_secret = $2, totp_enabled = false, totp_last_counter = 0
+WHERE user_id = $1 RETURNING *;
+
+-- name: EnableUserTOTP :one
+UPDATE users
+set totp_enabled = true
+WHERE user_id = $1 RETURNING *;
+
+-- name: DisableUserTOTP :one
+UPDATE users
+set totp_secret = NULL, totp_enabled = false, totp_last_counter = 0
+WHERE user_id = $1 RETURNING *;
+
+-- name: UpdateUserTOTPCounter :one
+UPDATE users
+set totp_last_counter = sqlc.arg(counter)
+WHERE user_id = sqlc.arg(user_id)
+  AND totp_last_counter < sqlc.arg(counter) RETURNING *;
+
+-- name: UpdateUserProfile :one
+UPDATE users
+set firstname  = COALESCE(sqlc.narg(firstname), firstname),
+    lastname   = COALESCE(sqlc.narg(lastname), lastname),
+    updated_at = now()
+WHERE user_id = sqlc.arg(user_id) RETURNING *;
+
+-- name: UpdateUserEmail :one
+UPDATE users
+set email = $2, email_verified = false, updated_at = now()
+WHERE user_id = $1 RETURNING *;
+
+-- name: DeactivateUser :one
+UPDATE users
+set is_active = false, deactivated_at = now(), updated_at = now()
+WHERE user_id = $1 RETURNING *;
+
+-- name: AnonymizeUser :one
+UPDATE users
+set firstname            = 'Deleted',
+    lastname             = 'User',
+    email                = sqlc.arg(email),
+    password             = '',
+    email_verified       = false,
+    totp_secret          = NULL,
+    totp_enabled         = false,
+    totp_last_counter    = 0,
+    is_active            = false,
+    deactivated_at       = COALESCE(deactivated_at, now()),
+    anonymized_at        = now(),
+    kyc_status           = 'pending',
+    kyc_rejection_reason = NULL,
+    kyc_reviewed_by      = NULL,
+    kyc_reviewed_at      = NULL,
+    updated_at           = now()
+WHERE user_id = sqlc.arg(user_id) RETURNING *;
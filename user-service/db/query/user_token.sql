@@ -0,0 +1,27 @@
+-- name: CreateUserToken :one
+INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetUserToken :one
+SELECT *
+FROM user_tokens
+WHERE token_hash = $1 LIMIT 1;
+
+-- name: UseUserToken :one
+UPDATE user_tokens
+set used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now() RETURNING *;
+
+-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+set used_at = now()
+WHERE user_id = $1
+  AND purpose = $2
+  AND used_at IS NULL;
+
+
+-- name: DeleteUserTokens :exec
+DELETE FROM user_tokens
+WHERE user_id = $1;
@@ -0,0 +1,199 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: api_key.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+const createAPIKey = `-- name: CreateAPIKey :one
+INSERT INTO api_keys (key_id, key_hash, prefix, owner_type, owner_id, name, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, key_id, key_hash, prefix, owner_id, name, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at, owner_type
+`
+
+type CreateAPIKeyParams struct {
+	KeyID     string       `json:"key_id"`
+	KeyHash   string       `json:"key_hash"`
+	Prefix    string       `json:"prefix"`
+	OwnerType string       `json:"owner_type"`
+	OwnerID   string       `json:"owner_id"`
+	Name      string       `json:"name"`
+	Scopes    []string     `json:"scopes"`
+	ExpiresAt sql.NullTime `json:"expires_at"`
+}
+
+func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, createAPIKey,
+		arg.KeyID,
+		arg.KeyHash,
+		arg.Prefix,
+		arg.OwnerType,
+		arg.OwnerID,
+		arg.Name,
+		pq.Array(arg.Scopes),
+		arg.ExpiresAt,
+	)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.OwnerID,
+		&i.Name,
+		pq.Array(&i.Scopes),
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.LastUsedIp,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.OwnerType,
+	)
+	return i, err
+}
+
+const listAPIKeys = `-- name: ListAPIKeys :many
+SELECT id, key_id, key_hash, prefix, owner_id, name, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at, owner_type
+FROM api_keys
+WHERE owner_type = $1
+  AND owner_id = $2
+  AND revoked_at IS NULL
+ORDER BY id
+`
+
+type ListAPIKeysParams struct {
+	OwnerType string `json:"owner_type"`
+	OwnerID   string `json:"owner_id"`
+}
+
+func (q *Queries) ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error) {
+	rows, err := q.db.QueryContext(ctx, listAPIKeys, arg.OwnerType, arg.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ApiKey{}
+	for rows.Next() {
+		var i ApiKey
+		if err := rows.Scan(
+			&i.ID,
+			&i.KeyID,
+			&i.KeyHash,
+			&i.Prefix,
+			&i.OwnerID,
+			&i.Name,
+			pq.Array(&i.Scopes),
+			&i.ExpiresAt,
+			&i.LastUsedAt,
+			&i.LastUsedIp,
+			&i.RevokedAt,
+			&i.CreatedAt,
+			&i.OwnerType,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAPIKey = `-- name: RevokeAPIKey :one
+UPDATE api_keys
+set revoked_at = now()
+WHERE key_id = $1
+  AND owner_type = $2
+  AND owner_id = $3
+  AND revoked_at IS NULL RETURNING id, key_id, key_hash, prefix, owner_id, name, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at, owner_type
+`
+
+type RevokeAPIKeyParams struct {
+	KeyID     string `json:"key_id"`
+	OwnerType string `json:"owner_type"`
+	OwnerID   string `json:"owner_id"`
+}
+
+func (q *Queries) RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, revokeAPIKey, arg.KeyID, arg.OwnerType, arg.OwnerID)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.OwnerID,
+		&i.Name,
+		pq.Array(&i.Scopes),
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.LastUsedIp,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.OwnerType,
+	)
+	return i, err
+}
+
+const revokeOwnerAPIKeys = `-- name: RevokeOwnerAPIKeys :exec
+UPDATE api_keys
+set revoked_at = now()
+WHERE owner_type = $1
+  AND owner_id = $2
+  AND revoked_at IS NULL
+`
+
+type RevokeOwnerAPIKeysParams struct {
+	OwnerType string `json:"owner_type"`
+	OwnerID   string `json:"owner_id"`
+}
+
+func (q *Queries) RevokeOwnerAPIKeys(ctx context.Context, arg RevokeOwnerAPIKeysParams) error {
+	_, err := q.db.ExecContext(ctx, revokeOwnerAPIKeys, arg.OwnerType, arg.OwnerID)
+	return err
+}
+
+const useAPIKey = `-- name: UseAPIKey :one
+UPDATE api_keys
+set last_used_at = now(),
+    last_used_ip = $2
+WHERE key_hash = $1
+  AND revoked_at IS NULL
+  AND (expires_at IS NULL OR expires_at > now()) RETURNING id, key_id, key_hash, prefix, owner_id, name, scopes, expires_at, last_used_at, last_used_ip, revoked_at, created_at, owner_type
+`
+
+type UseAPIKeyParams struct {
+	KeyHash    string         `json:"key_hash"`
+	LastUsedIp sql.NullString `json:"last_used_ip"`
+}
+
+func (q *Queries) UseAPIKey(ctx context.Context, arg UseAPIKeyParams) (ApiKey, error) {
+	row := q.db.QueryRowContext(ctx, useAPIKey, arg.KeyHash, arg.LastUsedIp)
+	var i ApiKey
+	err := row.Scan(
+		&i.ID,
+		&i.KeyID,
+		&i.KeyHash,
+		&i.Prefix,
+		&i.OwnerID,
+		&i.Name,
+		pq.Array(&i.Scopes),
+		&i.ExpiresAt,
+		&i.LastUsedAt,
+		&i.LastUsedIp,
+		&i.RevokedAt,
+		&i.CreatedAt,
+		&i.OwnerType,
+	)
+	return i, err
+}
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomAPIKey(t *testing.T, user User, expiresAt sql.NullTime) ApiKey {
+	return createRandomOwnerAPIKey(t, APIKeyOwnerUser, user.UserID, expiresAt)
+}
+
+func createRandomOwnerAPIKey(t *testing.T, ownerType, ownerID string, expiresAt sql.NullTime) ApiKey {
+	keyHash := RandomString(32)
+	arg := CreateAPIKeyParams{
+		KeyID:     RandomString(12),
+		KeyHash:   keyHash,
+		Prefix:    keyHash[:12],
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		Name:      RandomString(8),
+		Scopes:    []string{"accounts:read", "transfers:write"},
+		ExpiresAt: expiresAt,
+	}
+
+	apiKey, err := testQueries.CreateAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, apiKey)
+
+	require.Equal(t, arg.KeyID, apiKey.KeyID)
+	require.Equal(t, arg.KeyHash, apiKey.KeyHash)
+	require.Equal(t, arg.OwnerType, apiKey.OwnerType)
+	require.Equal(t, arg.OwnerID, apiKey.OwnerID)
+	require.Equal(t, arg.Scopes, apiKey.Scopes)
+	require.False(t, apiKey.LastUsedAt.Valid)
+	require.False(t, apiKey.RevokedAt.Valid)
+	require.NotZero(t, apiKey.CreatedAt)
+
+	return apiKey
+}
+
+func TestUseAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey := createRandomAPIKey(t, user, sql.NullTime{})
+
+	arg := UseAPIKeyParams{
+		KeyHash:    apiKey.KeyHash,
+		LastUsedIp: sql.NullString{String: "10.0.0.1", Valid: true},
+	}
+	usedKey, err := testQueries.UseAPIKey(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, apiKey.KeyID, usedKey.KeyID)
+	require.True(t, usedKey.LastUsedAt.Valid)
+	require.Equal(t, arg.LastUsedIp, usedKey.LastUsedIp)
+}
+
+func TestUseExpiredAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey := createRandomAPIKey(t, user, sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true})
+
+	_, err := testQueries.UseAPIKey(context.Background(), UseAPIKeyParams{KeyHash: apiKey.KeyHash})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	user := createRandomUser(t)
+	apiKey1 := createRandomAPIKey(t, user, sql.NullTime{})
+	apiKey2 := createRandomAPIKey(t, user, sql.NullTime{})
+
+	// keys can only be revoked by their owner
+	_, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		KeyID:     apiKey1.KeyID,
+		OwnerType: APIKeyOwnerUser,
+		OwnerID:   createRandomUser(t).UserID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	revokedKey, err := testQueries.RevokeAPIKey(context.Background(), RevokeAPIKeyParams{
+		KeyID:     apiKey1.KeyID,
+		OwnerType: APIKeyOwnerUser,
+		OwnerID:   user.UserID,
+	})
+	require.NoError(t, err)
+	require.True(t, revokedKey.RevokedAt.Valid)
+
+	_, err = testQueries.UseAPIKey(context.Background(), UseAPIKeyParams{KeyHash: apiKey1.KeyHash})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), ListAPIKeysParams{
+		OwnerType: APIKeyOwnerUser,
+		OwnerID:   user.UserID,
+	})
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	require.Equal(t, apiKey2.KeyID, apiKeys[0].KeyID)
+}
@@ -0,0 +1,299 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: kyc.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+const createKYCDocument = `-- name: CreateKYCDocument :one
+INSERT INTO kyc_documents (document_id, user_id, document_type, file_name, content_type, size_bytes, storage_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, document_id, user_id, document_type, file_name, content_type, size_bytes, storage_key, created_at
+`
+
+type CreateKYCDocumentParams struct {
+	DocumentID   string `json:"document_id"`
+	UserID       string `json:"user_id"`
+	DocumentType string `json:"document_type"`
+	FileName     string `json:"file_name"`
+	ContentType  string `json:"content_type"`
+	SizeBytes    int64  `json:"size_bytes"`
+	StorageKey   string `json:"storage_key"`
+}
+
+func (q *Queries) CreateKYCDocument(ctx context.Context, arg CreateKYCDocumentParams) (KycDocument, error) {
+	row := q.db.QueryRowContext(ctx, createKYCDocument,
+		arg.DocumentID,
+		arg.UserID,
+		arg.DocumentType,
+		arg.FileName,
+		arg.ContentType,
+		arg.SizeBytes,
+		arg.StorageKey,
+	)
+	var i KycDocument
+	err := row.Scan(
+		&i.ID,
+		&i.DocumentID,
+		&i.UserID,
+		&i.DocumentType,
+		&i.FileName,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.StorageKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteKYCDocuments = `-- name: DeleteKYCDocuments :many
+DELETE
+FROM kyc_documents
+WHERE user_id = $1 RETURNING id, document_id, user_id, document_type, file_name, content_type, size_bytes, storage_key, created_at
+`
+
+func (q *Queries) DeleteKYCDocuments(ctx context.Context, userID string) ([]KycDocument, error) {
+	rows, err := q.db.QueryContext(ctx, deleteKYCDocuments, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []KycDocument{}
+	for rows.Next() {
+		var i KycDocument
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentID,
+			&i.UserID,
+			&i.DocumentType,
+			&i.FileName,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.StorageKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getKYCDocument = `-- name: GetKYCDocument :one
+SELECT id, document_id, user_id, document_type, file_name, content_type, size_bytes, storage_key, created_at
+FROM kyc_documents
+WHERE document_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetKYCDocument(ctx context.Context, documentID string) (KycDocument, error) {
+	row := q.db.QueryRowContext(ctx, getKYCDocument, documentID)
+	var i KycDocument
+	err := row.Scan(
+		&i.ID,
+		&i.DocumentID,
+		&i.UserID,
+		&i.DocumentType,
+		&i.FileName,
+		&i.ContentType,
+		&i.SizeBytes,
+		&i.StorageKey,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listKYCDocuments = `-- name: ListKYCDocuments :many
+SELECT id, document_id, user_id, document_type, file_name, content_type, size_bytes, storage_key, created_at
+FROM kyc_documents
+WHERE user_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListKYCDocuments(ctx context.Context, userID string) ([]KycDocument, error) {
+	rows, err := q.db.QueryContext(ctx, listKYCDocuments, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []KycDocument{}
+	for rows.Next() {
+		var i KycDocument
+		if err := rows.Scan(
+			&i.ID,
+			&i.DocumentID,
+			&i.UserID,
+			&i.DocumentType,
+			&i.FileName,
+			&i.ContentType,
+			&i.SizeBytes,
+			&i.StorageKey,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listKYCReviewQueue = `-- name: ListKYCReviewQueue :many
+SELECT id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+FROM users u
+WHERE u.kyc_status = 'pending'
+  AND EXISTS (SELECT 1 FROM kyc_documents d WHERE d.user_id = u.user_id)
+ORDER BY u.id
+LIMIT $1 OFFSET $2
+`
+
+type ListKYCReviewQueueParams struct {
+	Limit  int32 `json:"limit"`
+	Offset int32 `json:"offset"`
+}
+
+func (q *Queries) ListKYCReviewQueue(ctx context.Context, arg ListKYCReviewQueueParams) ([]User, error) {
+	rows, err := q.db.QueryContext(ctx, listKYCReviewQueue, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []User{}
+	for rows.Next() {
+		var i User
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Firstname,
+			&i.Lastname,
+			&i.Password,
+			&i.Email,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EmailVerified,
+			&i.TotpSecret,
+			&i.TotpEnabled,
+			&i.TotpLastCounter,
+			&i.IsActive,
+			&i.DeactivatedAt,
+			&i.AnonymizedAt,
+			&i.Role,
+			&i.KycStatus,
+			&i.KycRejectionReason,
+			&i.KycReviewedBy,
+			&i.KycReviewedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const reviewUserKYC = `-- name: ReviewUserKYC :one
+UPDATE users
+set kyc_status           = $1,
+    kyc_rejection_reason = $2,
+    kyc_reviewed_by      = $3,
+    kyc_reviewed_at      = now(),
+    updated_at           = now()
+WHERE user_id = $4
+  AND kyc_status = 'pending' RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+type ReviewUserKYCParams struct {
+	KycStatus       string         `json:"kyc_status"`
+	RejectionReason sql.NullString `json:"rejection_reason"`
+	ReviewedBy      sql.NullString `json:"reviewed_by"`
+	UserID          string         `json:"user_id"`
+}
+
+func (q *Queries) ReviewUserKYC(ctx context.Context, arg ReviewUserKYCParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, reviewUserKYC,
+		arg.KycStatus,
+		arg.RejectionReason,
+		arg.ReviewedBy,
+		arg.UserID,
+	)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const submitUserKYC = `-- name: SubmitUserKYC :one
+UPDATE users
+set kyc_status           = 'pending',
+    kyc_rejection_reason = NULL,
+    updated_at           = now()
+WHERE user_id = $1
+  AND kyc_status <> 'verified' RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+func (q *Queries) SubmitUserKYC(ctx context.Context, userID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, submitUserKYC, userID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
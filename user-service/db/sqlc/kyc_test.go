@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func submitRandomKYCDocument(t *testing.T, user User) KycDocument {
+	store := NewStore(testDB)
+	documentID := RandomString(10)
+	arg := CreateKYCDocumentParams{
+		DocumentID:   documentID,
+		UserID:       user.UserID,
+		DocumentType: "passport",
+		FileName:     "passport.pdf",
+		ContentType:  "application/pdf",
+		SizeBytes:    RandomInt(1, 1000),
+		StorageKey:   "kyc/" + user.UserID + "/" + documentID,
+	}
+
+	document, err := store.SubmitKYCDocumentTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, document)
+
+	require.Equal(t, arg.DocumentID, document.DocumentID)
+	require.Equal(t, arg.UserID, document.UserID)
+	require.Equal(t, arg.DocumentType, document.DocumentType)
+	require.Equal(t, arg.SizeBytes, document.SizeBytes)
+	require.Equal(t, arg.StorageKey, document.StorageKey)
+	require.NotZero(t, document.CreatedAt)
+
+	return document
+}
+
+func TestSubmitKYCDocumentTx(t *testing.T) {
+	user := createRandomUser(t)
+	require.Equal(t, KYCStatusPending, user.KycStatus)
+
+	document1 := submitRandomKYCDocument(t, user)
+
+	document2, err := testQueries.GetKYCDocument(context.Background(), document1.DocumentID)
+	require.NoError(t, err)
+	require.Equal(t, document1, document2)
+
+	documents, err := testQueries.ListKYCDocuments(context.Background(), user.UserID)
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+}
+
+func TestReviewUserKYC(t *testing.T) {
+	reviewer := createRandomUser(t)
+	user := createRandomUser(t)
+	submitRandomKYCDocument(t, user)
+
+	arg := ReviewUserKYCParams{
+		UserID:          user.UserID,
+		KycStatus:       KYCStatusRejected,
+		RejectionReason: sql.NullString{String: "document is unreadable", Valid: true},
+		ReviewedBy:      sql.NullString{String: reviewer.UserID, Valid: true},
+	}
+	user, err := testQueries.ReviewUserKYC(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, KYCStatusRejected, user.KycStatus)
+	require.Equal(t, arg.RejectionReason, user.KycRejectionReason)
+	require.True(t, user.KycReviewedAt.Valid)
+
+	// a closed review can't be reviewed again
+	_, err = testQueries.ReviewUserKYC(context.Background(), arg)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// submitting a new document reopens the review
+	submitRandomKYCDocument(t, user)
+	user, err = testQueries.GetUser(context.Background(), user.UserID)
+	require.NoError(t, err)
+	require.Equal(t, KYCStatusPending, user.KycStatus)
+	require.False(t, user.KycRejectionReason.Valid)
+
+	arg.KycStatus = KYCStatusVerified
+	arg.RejectionReason = sql.NullString{}
+	user, err = testQueries.ReviewUserKYC(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, KYCStatusVerified, user.KycStatus)
+
+	_, err = NewStore(testDB).SubmitKYCDocumentTx(context.Background(), CreateKYCDocumentParams{
+		DocumentID: RandomString(10),
+		UserID:     user.UserID,
+	})
+	require.ErrorIs(t, err, ErrKYCAlreadyVerified)
+}
+
+func TestListKYCReviewQueue(t *testing.T) {
+	user1 := createRandomUser(t)
+	user2 := createRandomUser(t)
+	submitRandomKYCDocument(t, user1)
+
+	users, err := testQueries.ListKYCReviewQueue(context.Background(), ListKYCReviewQueueParams{
+		Limit:  1000,
+		Offset: 0,
+	})
+	require.NoError(t, err)
+
+	var ids []string
+	for _, user := range users {
+		require.Equal(t, KYCStatusPending, user.KycStatus)
+		ids = append(ids, user.UserID)
+	}
+	require.Contains(t, ids, user1.UserID)
+	require.NotContains(t, ids, user2.UserID)
+}
@@ -0,0 +1,91 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: login_attempt.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+const deleteLoginAttempt = `-- name: DeleteLoginAttempt :exec
+DELETE FROM login_attempts
+WHERE key = $1
+`
+
+func (q *Queries) DeleteLoginAttempt(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, deleteLoginAttempt, key)
+	return err
+}
+
+const getLoginAttempt = `-- name: GetLoginAttempt :one
+SELECT key, failed_count, locked_until, last_failed_at
+FROM login_attempts
+WHERE key = $1 LIMIT 1
+`
+
+func (q *Queries) GetLoginAttempt(ctx context.Context, key string) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, getLoginAttempt, key)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.FailedCount,
+		&i.LockedUntil,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const lockLoginAttempt = `-- name: LockLoginAttempt :one
+UPDATE login_attempts
+set locked_until = $2
+WHERE key = $1 RETURNING key, failed_count, locked_until, last_failed_at
+`
+
+type LockLoginAttemptParams struct {
+	Key         string       `json:"key"`
+	LockedUntil sql.NullTime `json:"locked_until"`
+}
+
+func (q *Queries) LockLoginAttempt(ctx context.Context, arg LockLoginAttemptParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, lockLoginAttempt, arg.Key, arg.LockedUntil)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.FailedCount,
+		&i.LockedUntil,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
+
+const recordFailedLoginAttempt = `-- name: RecordFailedLoginAttempt :one
+INSERT INTO login_attempts (key, failed_count, last_failed_at)
+VALUES ($1, 1, now())
+ON CONFLICT (key) DO UPDATE
+SET failed_count = CASE
+        WHEN login_attempts.last_failed_at < $2 THEN 1
+        ELSE login_attempts.failed_count + 1
+    END,
+    last_failed_at = now() RETURNING key, failed_count, locked_until, last_failed_at
+`
+
+type RecordFailedLoginAttemptParams struct {
+	Key         string    `json:"key"`
+	WindowStart time.Time `json:"window_start"`
+}
+
+func (q *Queries) RecordFailedLoginAttempt(ctx context.Context, arg RecordFailedLoginAttemptParams) (LoginAttempt, error) {
+	row := q.db.QueryRowContext(ctx, recordFailedLoginAttempt, arg.Key, arg.WindowStart)
+	var i LoginAttempt
+	err := row.Scan(
+		&i.Key,
+		&i.FailedCount,
+		&i.LockedUntil,
+		&i.LastFailedAt,
+	)
+	return i, err
+}
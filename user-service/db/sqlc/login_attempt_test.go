@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createFailedLoginAttempt(t *testing.T, key string, windowStart time.Time) LoginAttempt {
+	attempt, err := testQueries.RecordFailedLoginAttempt(context.Background(), RecordFailedLoginAttemptParams{
+		Key:         key,
+		WindowStart: windowStart,
+	})
+	require.NoError(t, err)
+	require.Equal(t, key, attempt.Key)
+	require.NotZero(t, attempt.LastFailedAt)
+
+	return attempt
+}
+
+func TestRecordFailedLoginAttempt(t *testing.T) {
+	key := "email:" + RandomEmail()
+	windowStart := time.Now().Add(-time.Hour)
+
+	for i := 1; i <= 3; i++ {
+		attempt := createFailedLoginAttempt(t, key, windowStart)
+		require.Equal(t, int32(i), attempt.FailedCount)
+	}
+
+	// failures before the window start are forgotten
+	attempt := createFailedLoginAttempt(t, key, time.Now().Add(time.Minute))
+	require.Equal(t, int32(1), attempt.FailedCount)
+}
+
+func TestLockLoginAttempt(t *testing.T) {
+	key := "ip:" + RandomString(8)
+	createFailedLoginAttempt(t, key, time.Now().Add(-time.Hour))
+
+	lockedUntil := sql.NullTime{Time: time.Now().Add(time.Minute), Valid: true}
+	attempt1, err := testQueries.LockLoginAttempt(context.Background(), LockLoginAttemptParams{
+		Key:         key,
+		LockedUntil: lockedUntil,
+	})
+	require.NoError(t, err)
+	require.True(t, attempt1.LockedUntil.Valid)
+	require.WithinDuration(t, lockedUntil.Time, attempt1.LockedUntil.Time, time.Second)
+
+	attempt2, err := testQueries.GetLoginAttempt(context.Background(), key)
+	require.NoError(t, err)
+	require.Equal(t, attempt1.FailedCount, attempt2.FailedCount)
+	require.WithinDuration(t, attempt1.LockedUntil.Time, attempt2.LockedUntil.Time, time.Second)
+}
+
+func TestDeleteLoginAttempt(t *testing.T) {
+	key := "email:" + RandomEmail()
+	createFailedLoginAttempt(t, key, time.Now().Add(-time.Hour))
+
+	err := testQueries.DeleteLoginAttempt(context.Background(), key)
+	require.NoError(t, err)
+
+	_, err = testQueries.GetLoginAttempt(context.Background(), key)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
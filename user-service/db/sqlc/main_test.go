@@ -3,6 +3,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"os"
 	"testing"
@@ -16,14 +17,16 @@ const (
 )
 
 var testQueries *Queries
+var testDB *sql.DB
 
 func TestMain(m *testing.M) {
-	conn, err := sql.Open(dbDriver, dbSource)
+	var err error
+	testDB, err = sql.Open(dbDriver, dbSource)
 	if err != nil {
 		log.Fatalf("cannot connect to db: %v", err)
 	}
 
-	testQueries = New(conn)
+	testQueries = New(testDB)
 	cleanDB(testQueries)
 
 	os.Exit(m.Run())
@@ -35,4 +38,35 @@ func cleanDB(queries *Queries) {
 	if err != nil {
 		log.Printf("error cleaning accounts table: %v", err)
 	}
+
+	query2 := "DELETE FROM user_tokens;"
+	_, err = queries.db.QueryContext(context.Background(), query2)
+	if err != nil {
+		log.Printf("error cleaning user_tokens table: %v", err)
+	}
+
+	query3 := "DELETE FROM recovery_codes;"
+	_, err = queries.db.QueryContext(context.Background(), query3)
+	if err != nil {
+		log.Printf("error cleaning recovery_codes table: %v", err)
+	}
+
+	query4 := "DELETE FROM login_attempts;"
+	_, err = queries.db.QueryContext(context.Background(), query4)
+	if err != nil {
+		log.Printf("error cleaning login_attempts table: %v", err)
+	}
+
+	query5 := "DELETE FROM kyc_documents;"
+	_, err = queries.db.QueryContext(context.Background(), query5)
+	if err != nil {
+		log.Printf("error cleaning kyc_documents table: %v", err)
+	}
+
+	for _, table := range []string{"oauth_authorization_codes", "oauth_consents", "oauth_clients", "api_keys"} {
+		_, err = queries.db.QueryContext(context.Background(), fmt.Sprintf("DELETE FROM %s;", table))
+		if err != nil {
+			log.Printf("error cleaning %s table: %v", table, err)
+		}
+	}
 }
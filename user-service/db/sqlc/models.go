@@ -5,16 +5,125 @@
 package db
 
 import (
+	"database/sql"
 	"time"
 )
 
+type ApiKey struct {
+	ID         int64          `json:"id"`
+	KeyID      string         `json:"key_id"`
+	KeyHash    string         `json:"key_hash"`
+	Prefix     string         `json:"prefix"`
+	OwnerID    string         `json:"owner_id"`
+	Name       string         `json:"name"`
+	Scopes     []string       `json:"scopes"`
+	ExpiresAt  sql.NullTime   `json:"expires_at"`
+	LastUsedAt sql.NullTime   `json:"last_used_at"`
+	LastUsedIp sql.NullString `json:"last_used_ip"`
+	RevokedAt  sql.NullTime   `json:"revoked_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	OwnerType  string         `json:"owner_type"`
+}
+
+type KycDocument struct {
+	ID           int64     `json:"id"`
+	DocumentID   string    `json:"document_id"`
+	UserID       string    `json:"user_id"`
+	DocumentType string    `json:"document_type"`
+	FileName     string    `json:"file_name"`
+	ContentType  string    `json:"content_type"`
+	SizeBytes    int64     `json:"size_bytes"`
+	StorageKey   string    `json:"storage_key"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type LoginAttempt struct {
+	Key          string       `json:"key"`
+	FailedCount  int32        `json:"failed_count"`
+	LockedUntil  sql.NullTime `json:"locked_until"`
+	LastFailedAt time.Time    `json:"last_failed_at"`
+}
+
+type OauthAuthorizationCode struct {
+	ID            int64        `json:"id"`
+	CodeHash      string       `json:"code_hash"`
+	ClientID      string       `json:"client_id"`
+	UserID        string       `json:"user_id"`
+	RedirectUri   string       `json:"redirect_uri"`
+	Scopes        []string     `json:"scopes"`
+	CodeChallenge string       `json:"code_challenge"`
+	ExpiresAt     time.Time    `json:"expires_at"`
+	UsedAt        sql.NullTime `json:"used_at"`
+	CreatedAt     time.Time    `json:"created_at"`
+}
+
+type OauthClient struct {
+	ID               int64          `json:"id"`
+	ClientID         string         `json:"client_id"`
+	ClientSecretHash sql.NullString `json:"client_secret_hash"`
+	Name             string         `json:"name"`
+	RedirectUris     []string       `json:"redirect_uris"`
+	Scopes           []string       `json:"scopes"`
+	OwnerID          string         `json:"owner_id"`
+	CreatedAt        time.Time      `json:"created_at"`
+}
+
+type OauthConsent struct {
+	ID        int64        `json:"id"`
+	UserID    string       `json:"user_id"`
+	ClientID  string       `json:"client_id"`
+	Scopes    []string     `json:"scopes"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+	RevokedAt sql.NullTime `json:"revoked_at"`
+}
+
+type RecoveryCode struct {
+	ID        int64        `json:"id"`
+	UserID    string       `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ServiceAccount struct {
+	ID               int64        `json:"id"`
+	ServiceAccountID string       `json:"service_account_id"`
+	Name             string       `json:"name"`
+	CreatedBy        string       `json:"created_by"`
+	DisabledAt       sql.NullTime `json:"disabled_at"`
+	CreatedAt        time.Time    `json:"created_at"`
+}
+
 type User struct {
-	ID        int64     `json:"id"`
-	UserID    string    `json:"user_id"`
-	Firstname string    `json:"firstname"`
-	Lastname  string    `json:"lastname"`
-	Password  string    `json:"password"`
-	Email     string    `json:"email"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 int64          `json:"id"`
+	UserID             string         `json:"user_id"`
+	Firstname          string         `json:"firstname"`
+	Lastname           string         `json:"lastname"`
+	Password           string         `json:"password"`
+	Email              string         `json:"email"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	EmailVerified      bool           `json:"email_verified"`
+	TotpSecret         sql.NullString `json:"totp_secret"`
+	TotpEnabled        bool           `json:"totp_enabled"`
+	TotpLastCounter    int64          `json:"totp_last_counter"`
+	IsActive           bool           `json:"is_active"`
+	DeactivatedAt      sql.NullTime   `json:"deactivated_at"`
+	AnonymizedAt       sql.NullTime   `json:"anonymized_at"`
+	Role               string         `json:"role"`
+	KycStatus          string         `json:"kyc_status"`
+	KycRejectionReason sql.NullString `json:"kyc_rejection_reason"`
+	KycReviewedBy      sql.NullString `json:"kyc_reviewed_by"`
+	KycReviewedAt      sql.NullTime   `json:"kyc_reviewed_at"`
+}
+
+type UserToken struct {
+	ID        int64        `json:"id"`
+	TokenHash string       `json:"token_hash"`
+	UserID    string       `json:"user_id"`
+	Purpose   string       `json:"purpose"`
+	ExpiresAt time.Time    `json:"expires_at"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt time.Time    `json:"created_at"`
 }
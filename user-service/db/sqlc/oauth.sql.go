@@ -0,0 +1,409 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: oauth.sql
+
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const createOAuthAuthorizationCode = `-- name: CreateOAuthAuthorizationCode :one
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, used_at, created_at
+`
+
+type CreateOAuthAuthorizationCodeParams struct {
+	CodeHash      string    `json:"code_hash"`
+	ClientID      string    `json:"client_id"`
+	UserID        string    `json:"user_id"`
+	RedirectUri   string    `json:"redirect_uri"`
+	Scopes        []string  `json:"scopes"`
+	CodeChallenge string    `json:"code_challenge"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthAuthorizationCode,
+		arg.CodeHash,
+		arg.ClientID,
+		arg.UserID,
+		arg.RedirectUri,
+		pq.Array(arg.Scopes),
+		arg.CodeChallenge,
+		arg.ExpiresAt,
+	)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.ID,
+		&i.CodeHash,
+		&i.ClientID,
+		&i.UserID,
+		&i.RedirectUri,
+		pq.Array(&i.Scopes),
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createOAuthClient = `-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (client_id, client_secret_hash, name, redirect_uris, scopes, owner_id)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, client_id, client_secret_hash, name, redirect_uris, scopes, owner_id, created_at
+`
+
+type CreateOAuthClientParams struct {
+	ClientID         string         `json:"client_id"`
+	ClientSecretHash sql.NullString `json:"client_secret_hash"`
+	Name             string         `json:"name"`
+	RedirectUris     []string       `json:"redirect_uris"`
+	Scopes           []string       `json:"scopes"`
+	OwnerID          string         `json:"owner_id"`
+}
+
+func (q *Queries) CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error) {
+	row := q.db.QueryRowContext(ctx, createOAuthClient,
+		arg.ClientID,
+		arg.ClientSecretHash,
+		arg.Name,
+		pq.Array(arg.RedirectUris),
+		pq.Array(arg.Scopes),
+		arg.OwnerID,
+	)
+	var i OauthClient
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.ClientSecretHash,
+		&i.Name,
+		pq.Array(&i.RedirectUris),
+		pq.Array(&i.Scopes),
+		&i.OwnerID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteClientOAuthAuthorizationCodes = `-- name: DeleteClientOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE client_id = $1
+`
+
+func (q *Queries) DeleteClientOAuthAuthorizationCodes(ctx context.Context, clientID string) error {
+	_, err := q.db.ExecContext(ctx, deleteClientOAuthAuthorizationCodes, clientID)
+	return err
+}
+
+const deleteOAuthAuthorizationCodes = `-- name: DeleteOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE user_id = $1
+  AND client_id = $2
+`
+
+type DeleteOAuthAuthorizationCodesParams struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id"`
+}
+
+func (q *Queries) DeleteOAuthAuthorizationCodes(ctx context.Context, arg DeleteOAuthAuthorizationCodesParams) error {
+	_, err := q.db.ExecContext(ctx, deleteOAuthAuthorizationCodes, arg.UserID, arg.ClientID)
+	return err
+}
+
+const deleteOAuthClientsByOwner = `-- name: DeleteOAuthClientsByOwner :many
+DELETE FROM oauth_clients
+WHERE owner_id = $1 RETURNING id, client_id, client_secret_hash, name, redirect_uris, scopes, owner_id, created_at
+`
+
+func (q *Queries) DeleteOAuthClientsByOwner(ctx context.Context, ownerID string) ([]OauthClient, error) {
+	rows, err := q.db.QueryContext(ctx, deleteOAuthClientsByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OauthClient{}
+	for rows.Next() {
+		var i OauthClient
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClientID,
+			&i.ClientSecretHash,
+			&i.Name,
+			pq.Array(&i.RedirectUris),
+			pq.Array(&i.Scopes),
+			&i.OwnerID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const deleteUserOAuthAuthorizationCodes = `-- name: DeleteUserOAuthAuthorizationCodes :exec
+DELETE FROM oauth_authorization_codes
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserOAuthAuthorizationCodes(ctx context.Context, userID string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserOAuthAuthorizationCodes, userID)
+	return err
+}
+
+const getOAuthClient = `-- name: GetOAuthClient :one
+SELECT id, client_id, client_secret_hash, name, redirect_uris, scopes, owner_id, created_at
+FROM oauth_clients
+WHERE client_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetOAuthClient(ctx context.Context, clientID string) (OauthClient, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthClient, clientID)
+	var i OauthClient
+	err := row.Scan(
+		&i.ID,
+		&i.ClientID,
+		&i.ClientSecretHash,
+		&i.Name,
+		pq.Array(&i.RedirectUris),
+		pq.Array(&i.Scopes),
+		&i.OwnerID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getOAuthConsent = `-- name: GetOAuthConsent :one
+SELECT id, user_id, client_id, scopes, created_at, updated_at, revoked_at
+FROM oauth_consents
+WHERE user_id = $1
+  AND client_id = $2
+  AND revoked_at IS NULL LIMIT 1
+`
+
+type GetOAuthConsentParams struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id"`
+}
+
+func (q *Queries) GetOAuthConsent(ctx context.Context, arg GetOAuthConsentParams) (OauthConsent, error) {
+	row := q.db.QueryRowContext(ctx, getOAuthConsent, arg.UserID, arg.ClientID)
+	var i OauthConsent
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const grantOAuthConsent = `-- name: GrantOAuthConsent :one
+INSERT INTO oauth_consents (user_id, client_id, scopes)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id, client_id) DO UPDATE
+    SET scopes     = CASE
+                         WHEN oauth_consents.revoked_at IS NULL
+                             THEN ARRAY(SELECT DISTINCT unnest(oauth_consents.scopes || EXCLUDED.scopes))
+                         ELSE EXCLUDED.scopes
+        END,
+        updated_at = now(),
+        revoked_at = NULL
+RETURNING id, user_id, client_id, scopes, created_at, updated_at, revoked_at
+`
+
+type GrantOAuthConsentParams struct {
+	UserID   string   `json:"user_id"`
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+func (q *Queries) GrantOAuthConsent(ctx context.Context, arg GrantOAuthConsentParams) (OauthConsent, error) {
+	row := q.db.QueryRowContext(ctx, grantOAuthConsent, arg.UserID, arg.ClientID, pq.Array(arg.Scopes))
+	var i OauthConsent
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const listOAuthClientsByOwner = `-- name: ListOAuthClientsByOwner :many
+SELECT id, client_id, client_secret_hash, name, redirect_uris, scopes, owner_id, created_at
+FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY id
+`
+
+func (q *Queries) ListOAuthClientsByOwner(ctx context.Context, ownerID string) ([]OauthClient, error) {
+	rows, err := q.db.QueryContext(ctx, listOAuthClientsByOwner, ownerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OauthClient{}
+	for rows.Next() {
+		var i OauthClient
+		if err := rows.Scan(
+			&i.ID,
+			&i.ClientID,
+			&i.ClientSecretHash,
+			&i.Name,
+			pq.Array(&i.RedirectUris),
+			pq.Array(&i.Scopes),
+			&i.OwnerID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listOAuthConsents = `-- name: ListOAuthConsents :many
+SELECT id, user_id, client_id, scopes, created_at, updated_at, revoked_at
+FROM oauth_consents
+WHERE user_id = $1
+  AND revoked_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListOAuthConsents(ctx context.Context, userID string) ([]OauthConsent, error) {
+	rows, err := q.db.QueryContext(ctx, listOAuthConsents, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OauthConsent{}
+	for rows.Next() {
+		var i OauthConsent
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.ClientID,
+			pq.Array(&i.Scopes),
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeClientOAuthConsents = `-- name: RevokeClientOAuthConsents :exec
+UPDATE oauth_consents
+set revoked_at = now(),
+    updated_at = now()
+WHERE client_id = $1
+  AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeClientOAuthConsents(ctx context.Context, clientID string) error {
+	_, err := q.db.ExecContext(ctx, revokeClientOAuthConsents, clientID)
+	return err
+}
+
+const revokeOAuthConsent = `-- name: RevokeOAuthConsent :one
+UPDATE oauth_consents
+set revoked_at = now(),
+    updated_at = now()
+WHERE user_id = $1
+  AND client_id = $2
+  AND revoked_at IS NULL RETURNING id, user_id, client_id, scopes, created_at, updated_at, revoked_at
+`
+
+type RevokeOAuthConsentParams struct {
+	UserID   string `json:"user_id"`
+	ClientID string `json:"client_id"`
+}
+
+func (q *Queries) RevokeOAuthConsent(ctx context.Context, arg RevokeOAuthConsentParams) (OauthConsent, error) {
+	row := q.db.QueryRowContext(ctx, revokeOAuthConsent, arg.UserID, arg.ClientID)
+	var i OauthConsent
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ClientID,
+		pq.Array(&i.Scopes),
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const revokeUserOAuthConsents = `-- name: RevokeUserOAuthConsents :exec
+UPDATE oauth_consents
+set revoked_at = now(),
+    updated_at = now()
+WHERE user_id = $1
+  AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeUserOAuthConsents(ctx context.Context, userID string) error {
+	_, err := q.db.ExecContext(ctx, revokeUserOAuthConsents, userID)
+	return err
+}
+
+const useOAuthAuthorizationCode = `-- name: UseOAuthAuthorizationCode :one
+UPDATE oauth_authorization_codes
+set used_at = now()
+WHERE code_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now() RETURNING id, code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, used_at, created_at
+`
+
+func (q *Queries) UseOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error) {
+	row := q.db.QueryRowContext(ctx, useOAuthAuthorizationCode, codeHash)
+	var i OauthAuthorizationCode
+	err := row.Scan(
+		&i.ID,
+		&i.CodeHash,
+		&i.ClientID,
+		&i.UserID,
+		&i.RedirectUri,
+		pq.Array(&i.Scopes),
+		&i.CodeChallenge,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
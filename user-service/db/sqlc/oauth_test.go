@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomOAuthClient(t *testing.T, owner User) OauthClient {
+	arg := CreateOAuthClientParams{
+		ClientID:     RandomString(12),
+		Name:         RandomString(8),
+		RedirectUris: []string{"https://example.com/callback"},
+		Scopes:       []string{"accounts:read", "transfers:write"},
+		OwnerID:      owner.UserID,
+	}
+
+	client, err := testQueries.CreateOAuthClient(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, client)
+
+	require.Equal(t, arg.ClientID, client.ClientID)
+	require.Equal(t, arg.Name, client.Name)
+	require.Equal(t, arg.RedirectUris, client.RedirectUris)
+	require.Equal(t, arg.Scopes, client.Scopes)
+	require.False(t, client.ClientSecretHash.Valid)
+	require.NotZero(t, client.CreatedAt)
+
+	return client
+}
+
+func authorizeRandomClient(t *testing.T, user User, client OauthClient, scopes []string) OauthAuthorizationCode {
+	store := NewStore(testDB)
+	arg := CreateOAuthAuthorizationCodeParams{
+		CodeHash:      RandomString(32),
+		ClientID:      client.ClientID,
+		UserID:        user.UserID,
+		RedirectUri:   client.RedirectUris[0],
+		Scopes:        scopes,
+		CodeChallenge: RandomString(43),
+		ExpiresAt:     time.Now().Add(time.Minute),
+	}
+
+	code, err := store.AuthorizeClientTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.CodeHash, code.CodeHash)
+	require.Equal(t, arg.Scopes, code.Scopes)
+	require.False(t, code.UsedAt.Valid)
+
+	return code
+}
+
+func TestListOAuthClientsByOwner(t *testing.T) {
+	owner := createRandomUser(t)
+	client := createRandomOAuthClient(t, owner)
+
+	clients, err := testQueries.ListOAuthClientsByOwner(context.Background(), owner.UserID)
+	require.NoError(t, err)
+	require.Len(t, clients, 1)
+	require.Equal(t, client, clients[0])
+}
+
+func TestAuthorizeClientTx(t *testing.T) {
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, createRandomUser(t))
+
+	authorizeRandomClient(t, user, client, []string{"accounts:read"})
+	code := authorizeRandomClient(t, user, client, []string{"transfers:write"})
+
+	consent, err := testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams{
+		UserID:   user.UserID,
+		ClientID: client.ClientID,
+	})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"accounts:read", "transfers:write"}, consent.Scopes)
+
+	usedCode, err := testQueries.UseOAuthAuthorizationCode(context.Background(), code.CodeHash)
+	require.NoError(t, err)
+	require.True(t, usedCode.UsedAt.Valid)
+
+	// codes can only be exchanged once
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), code.CodeHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestRevokeConsentTx(t *testing.T) {
+	store := NewStore(testDB)
+	user := createRandomUser(t)
+	client := createRandomOAuthClient(t, createRandomUser(t))
+	code := authorizeRandomClient(t, user, client, []string{"accounts:read", "transfers:write"})
+
+	arg := RevokeOAuthConsentParams{
+		UserID:   user.UserID,
+		ClientID: client.ClientID,
+	}
+	consent, err := store.RevokeConsentTx(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, consent.RevokedAt.Valid)
+
+	_, err = testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams(arg))
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), code.CodeHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	consents, err := testQueries.ListOAuthConsents(context.Background(), user.UserID)
+	require.NoError(t, err)
+	require.Empty(t, consents)
+
+	// granting again after a revocation starts over with the new scopes
+	authorizeRandomClient(t, user, client, []string{"accounts:read"})
+	consent, err = testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams(arg))
+	require.NoError(t, err)
+	require.Equal(t, []string{"accounts:read"}, consent.Scopes)
+}
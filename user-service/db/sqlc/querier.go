@@ -9,10 +9,63 @@ import (
 )
 
 type Querier interface {
+	AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) (User, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	CreateKYCDocument(ctx context.Context, arg CreateKYCDocumentParams) (KycDocument, error)
+	CreateOAuthAuthorizationCode(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	CreateOAuthClient(ctx context.Context, arg CreateOAuthClientParams) (OauthClient, error)
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) (RecoveryCode, error)
+	CreateServiceAccount(ctx context.Context, arg CreateServiceAccountParams) (ServiceAccount, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserToken(ctx context.Context, arg CreateUserTokenParams) (UserToken, error)
+	DeactivateUser(ctx context.Context, userID string) (User, error)
+	DeleteClientOAuthAuthorizationCodes(ctx context.Context, clientID string) error
+	DeleteKYCDocuments(ctx context.Context, userID string) ([]KycDocument, error)
+	DeleteLoginAttempt(ctx context.Context, key string) error
+	DeleteOAuthAuthorizationCodes(ctx context.Context, arg DeleteOAuthAuthorizationCodesParams) error
+	DeleteOAuthClientsByOwner(ctx context.Context, ownerID string) ([]OauthClient, error)
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+	DeleteUserOAuthAuthorizationCodes(ctx context.Context, userID string) error
+	DeleteUserTokens(ctx context.Context, userID string) error
+	DisableServiceAccount(ctx context.Context, serviceAccountID string) (ServiceAccount, error)
+	DisableUserTOTP(ctx context.Context, userID string) (User, error)
+	EnableUserTOTP(ctx context.Context, userID string) (User, error)
+	GetKYCDocument(ctx context.Context, documentID string) (KycDocument, error)
+	GetLoginAttempt(ctx context.Context, key string) (LoginAttempt, error)
+	GetOAuthClient(ctx context.Context, clientID string) (OauthClient, error)
+	GetOAuthConsent(ctx context.Context, arg GetOAuthConsentParams) (OauthConsent, error)
+	GetServiceAccount(ctx context.Context, serviceAccountID string) (ServiceAccount, error)
 	GetUser(ctx context.Context, userID string) (User, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserToken(ctx context.Context, tokenHash string) (UserToken, error)
+	GrantOAuthConsent(ctx context.Context, arg GrantOAuthConsentParams) (OauthConsent, error)
+	InvalidateUserTokens(ctx context.Context, arg InvalidateUserTokensParams) error
+	ListAPIKeys(ctx context.Context, arg ListAPIKeysParams) ([]ApiKey, error)
+	ListKYCDocuments(ctx context.Context, userID string) ([]KycDocument, error)
+	ListKYCReviewQueue(ctx context.Context, arg ListKYCReviewQueueParams) ([]User, error)
+	ListOAuthClientsByOwner(ctx context.Context, ownerID string) ([]OauthClient, error)
+	ListOAuthConsents(ctx context.Context, userID string) ([]OauthConsent, error)
+	ListRecoveryCodes(ctx context.Context, userID string) ([]RecoveryCode, error)
+	ListServiceAccounts(ctx context.Context) ([]ServiceAccount, error)
+	LockLoginAttempt(ctx context.Context, arg LockLoginAttemptParams) (LoginAttempt, error)
+	RecordFailedLoginAttempt(ctx context.Context, arg RecordFailedLoginAttemptParams) (LoginAttempt, error)
+	ReviewUserKYC(ctx context.Context, arg ReviewUserKYCParams) (User, error)
+	RevokeAPIKey(ctx context.Context, arg RevokeAPIKeyParams) (ApiKey, error)
+	RevokeClientOAuthConsents(ctx context.Context, clientID string) error
+	RevokeOAuthConsent(ctx context.Context, arg RevokeOAuthConsentParams) (OauthConsent, error)
+	RevokeOwnerAPIKeys(ctx context.Context, arg RevokeOwnerAPIKeysParams) error
+	RevokeUserOAuthConsents(ctx context.Context, userID string) error
+	SetUserEmailVerified(ctx context.Context, userID string) (User, error)
+	SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error)
+	SubmitUserKYC(ctx context.Context, userID string) (User, error)
+	UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
+	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error)
+	UpdateUserTOTPCounter(ctx context.Context, arg UpdateUserTOTPCounterParams) (User, error)
+	UseAPIKey(ctx context.Context, arg UseAPIKeyParams) (ApiKey, error)
+	UseOAuthAuthorizationCode(ctx context.Context, codeHash string) (OauthAuthorizationCode, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (RecoveryCode, error)
+	UseUserToken(ctx context.Context, tokenHash string) (UserToken, error)
 }
 
 var _ Querier = (*Queries)(nil)
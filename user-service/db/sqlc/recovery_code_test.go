@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomRecoveryCode(t *testing.T, user User) RecoveryCode {
+	arg := CreateRecoveryCodeParams{
+		UserID:   user.UserID,
+		CodeHash: RandomString(32),
+	}
+
+	code, err := testQueries.CreateRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, code)
+
+	require.Equal(t, arg.UserID, code.UserID)
+	require.Equal(t, arg.CodeHash, code.CodeHash)
+	require.False(t, code.UsedAt.Valid)
+	require.NotZero(t, code.CreatedAt)
+
+	return code
+}
+
+func TestCreateRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomRecoveryCode(t, user)
+}
+
+func TestListRecoveryCodes(t *testing.T) {
+	user := createRandomUser(t)
+	for i := 0; i < 5; i++ {
+		createRandomRecoveryCode(t, user)
+	}
+
+	codes, err := testQueries.ListRecoveryCodes(context.Background(), user.UserID)
+	require.NoError(t, err)
+	require.Len(t, codes, 5)
+
+	for _, code := range codes {
+		require.Equal(t, user.UserID, code.UserID)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	user := createRandomUser(t)
+	code1 := createRandomRecoveryCode(t, user)
+
+	arg := UseRecoveryCodeParams{
+		UserID:   user.UserID,
+		CodeHash: code1.CodeHash,
+	}
+	code2, err := testQueries.UseRecoveryCode(context.Background(), arg)
+	require.NoError(t, err)
+	require.True(t, code2.UsedAt.Valid)
+
+	// a recovery code can only be used once
+	_, err = testQueries.UseRecoveryCode(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestDeleteRecoveryCodes(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomRecoveryCode(t, user)
+
+	err := testQueries.DeleteRecoveryCodes(context.Background(), user.UserID)
+	require.NoError(t, err)
+
+	codes, err := testQueries.ListRecoveryCodes(context.Background(), user.UserID)
+	require.NoError(t, err)
+	require.Empty(t, codes)
+}
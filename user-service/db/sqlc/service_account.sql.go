@@ -0,0 +1,113 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: service_account.sql
+
+package db
+
+import (
+	"context"
+)
+
+const createServiceAccount = `-- name: CreateServiceAccount :one
+INSERT INTO service_accounts (service_account_id, name, created_by)
+VALUES ($1, $2, $3) RETURNING id, service_account_id, name, created_by, disabled_at, created_at
+`
+
+type CreateServiceAccountParams struct {
+	ServiceAccountID string `json:"service_account_id"`
+	Name             string `json:"name"`
+	CreatedBy        string `json:"created_by"`
+}
+
+func (q *Queries) CreateServiceAccount(ctx context.Context, arg CreateServiceAccountParams) (ServiceAccount, error) {
+	row := q.db.QueryRowContext(ctx, createServiceAccount, arg.ServiceAccountID, arg.Name, arg.CreatedBy)
+	var i ServiceAccount
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceAccountID,
+		&i.Name,
+		&i.CreatedBy,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const disableServiceAccount = `-- name: DisableServiceAccount :one
+UPDATE service_accounts
+set disabled_at = now()
+WHERE service_account_id = $1
+  AND disabled_at IS NULL RETURNING id, service_account_id, name, created_by, disabled_at, created_at
+`
+
+func (q *Queries) DisableServiceAccount(ctx context.Context, serviceAccountID string) (ServiceAccount, error) {
+	row := q.db.QueryRowContext(ctx, disableServiceAccount, serviceAccountID)
+	var i ServiceAccount
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceAccountID,
+		&i.Name,
+		&i.CreatedBy,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getServiceAccount = `-- name: GetServiceAccount :one
+SELECT id, service_account_id, name, created_by, disabled_at, created_at
+FROM service_accounts
+WHERE service_account_id = $1 LIMIT 1
+`
+
+func (q *Queries) GetServiceAccount(ctx context.Context, serviceAccountID string) (ServiceAccount, error) {
+	row := q.db.QueryRowContext(ctx, getServiceAccount, serviceAccountID)
+	var i ServiceAccount
+	err := row.Scan(
+		&i.ID,
+		&i.ServiceAccountID,
+		&i.Name,
+		&i.CreatedBy,
+		&i.DisabledAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listServiceAccounts = `-- name: ListServiceAccounts :many
+SELECT id, service_account_id, name, created_by, disabled_at, created_at
+FROM service_accounts
+WHERE disabled_at IS NULL
+ORDER BY id
+`
+
+func (q *Queries) ListServiceAccounts(ctx context.Context) ([]ServiceAccount, error) {
+	rows, err := q.db.QueryContext(ctx, listServiceAccounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ServiceAccount{}
+	for rows.Next() {
+		var i ServiceAccount
+		if err := rows.Scan(
+			&i.ID,
+			&i.ServiceAccountID,
+			&i.Name,
+			&i.CreatedBy,
+			&i.DisabledAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
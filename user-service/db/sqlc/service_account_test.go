@@ -0,0 +1,73 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomServiceAccount(t *testing.T) ServiceAccount {
+	arg := CreateServiceAccountParams{
+		ServiceAccountID: RandomString(12),
+		Name:             RandomString(8),
+		CreatedBy:        createRandomUser(t).UserID,
+	}
+
+	serviceAccount, err := testQueries.CreateServiceAccount(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, serviceAccount)
+
+	require.Equal(t, arg.ServiceAccountID, serviceAccount.ServiceAccountID)
+	require.Equal(t, arg.Name, serviceAccount.Name)
+	require.Equal(t, arg.CreatedBy, serviceAccount.CreatedBy)
+	require.False(t, serviceAccount.DisabledAt.Valid)
+	require.NotZero(t, serviceAccount.CreatedAt)
+
+	return serviceAccount
+}
+
+func TestServiceAccountAPIKey(t *testing.T) {
+	serviceAccount := createRandomServiceAccount(t)
+	apiKey := createRandomOwnerAPIKey(t, APIKeyOwnerServiceAccount, serviceAccount.ServiceAccountID, sql.NullTime{})
+
+	// a user with the same id as the service account doesn't own its keys
+	apiKeys, err := testQueries.ListAPIKeys(context.Background(), ListAPIKeysParams{
+		OwnerType: APIKeyOwnerUser,
+		OwnerID:   serviceAccount.ServiceAccountID,
+	})
+	require.NoError(t, err)
+	require.Empty(t, apiKeys)
+
+	apiKeys, err = testQueries.ListAPIKeys(context.Background(), ListAPIKeysParams{
+		OwnerType: APIKeyOwnerServiceAccount,
+		OwnerID:   serviceAccount.ServiceAccountID,
+	})
+	require.NoError(t, err)
+	require.Len(t, apiKeys, 1)
+	require.Equal(t, apiKey.KeyID, apiKeys[0].KeyID)
+}
+
+func TestDisableServiceAccountTx(t *testing.T) {
+	store := NewStore(testDB)
+	serviceAccount := createRandomServiceAccount(t)
+	apiKey := createRandomOwnerAPIKey(t, APIKeyOwnerServiceAccount, serviceAccount.ServiceAccountID, sql.NullTime{})
+
+	disabled, err := store.DisableServiceAccountTx(context.Background(), serviceAccount.ServiceAccountID)
+	require.NoError(t, err)
+	require.True(t, disabled.DisabledAt.Valid)
+
+	// the keys of the service account are revoked with it
+	_, err = testQueries.UseAPIKey(context.Background(), UseAPIKeyParams{KeyHash: apiKey.KeyHash})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	serviceAccounts, err := testQueries.ListServiceAccounts(context.Background())
+	require.NoError(t, err)
+	for _, account := range serviceAccounts {
+		require.NotEqual(t, serviceAccount.ServiceAccountID, account.ServiceAccountID)
+	}
+
+	_, err = store.DisableServiceAccountTx(context.Background(), serviceAccount.ServiceAccountID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
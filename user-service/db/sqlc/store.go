@@ -1,12 +1,56 @@
 package db
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/bugrakocabay/dummy-bank-microservice/tracing"
+)
+
+// ErrInvalidToken is returned when a user token is unknown, expired, already used or issued for another purpose
+var ErrInvalidToken = errors.New("token is invalid or has expired")
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeLoginChallenge    = "login_challenge"
+)
+
+// ErrKYCAlreadyVerified is returned when documents are submitted for a user whose identity is already verified
+var ErrKYCAlreadyVerified = errors.New("identity is already verified")
+
+const (
+	KYCStatusPending  = "pending"
+	KYCStatusVerified = "verified"
+	KYCStatusRejected = "rejected"
+
+	RoleCustomer    = "customer"
+	RoleKYCReviewer = "kyc_reviewer"
+	// RoleAdmin manages the service accounts of back-office jobs
+	RoleAdmin = "admin"
+)
+
+// Owners of api keys, the owner_id of a key is the user_id or the service_account_id
+const (
+	APIKeyOwnerUser           = "user"
+	APIKeyOwnerServiceAccount = "service_account"
 )
 
 // Store provides all functions to execute db queries and transactions
 type Store interface {
 	Querier
+	ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error)
+	VerifyEmailTx(ctx context.Context, tokenHash string) (User, error)
+	EnableTwoFactorTx(ctx context.Context, arg EnableTwoFactorTxParams) (User, error)
+	DisableTwoFactorTx(ctx context.Context, userID string) (User, error)
+	ReplaceRecoveryCodesTx(ctx context.Context, arg ReplaceRecoveryCodesTxParams) error
+	EraseUserTx(ctx context.Context, arg EraseUserTxParams) (EraseUserTxResult, error)
+	SubmitKYCDocumentTx(ctx context.Context, arg CreateKYCDocumentParams) (KycDocument, error)
+	AuthorizeClientTx(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error)
+	RevokeConsentTx(ctx context.Context, arg RevokeOAuthConsentParams) (OauthConsent, error)
+	DisableServiceAccountTx(ctx context.Context, serviceAccountID string) (ServiceAccount, error)
 }
 
 type SQLStore struct {
@@ -17,6 +61,357 @@ type SQLStore struct {
 func NewStore(db *sql.DB) Store {
 	return &SQLStore{
 		db:      db,
-		Queries: New(db),
+		Queries: New(tracing.WrapDB(db, "postgresql")),
+	}
+}
+
+// execTx executes a function within a database transaction
+func (store *SQLStore) execTx(ctx context.Context, fn func(*Queries) error) error {
+	tx, err := store.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
+
+	q := New(tracing.WrapDB(tx, "postgresql"))
+	err = fn(q)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx err: %v, rb err: %v", err, rbErr)
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// useToken consumes a single-use token of the given purpose
+func useToken(ctx context.Context, q *Queries, tokenHash, purpose string) (UserToken, error) {
+	token, err := q.UseUserToken(ctx, tokenHash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return UserToken{}, ErrInvalidToken
+		}
+		return UserToken{}, err
+	}
+	if token.Purpose != purpose {
+		return UserToken{}, ErrInvalidToken
+	}
+
+	return token, nil
+}
+
+// ResetPasswordTxParams contains the input parameters of the reset password transaction
+type ResetPasswordTxParams struct {
+	TokenHash   string `json:"token_hash"`
+	NewPassword string `json:"new_password"`
+}
+
+// ResetPasswordTx consumes a password reset token and sets the new password of its user.
+// Any other outstanding reset tokens of the user are invalidated within the same db transaction.
+func (store *SQLStore) ResetPasswordTx(ctx context.Context, arg ResetPasswordTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		token, err := useToken(ctx, q, arg.TokenHash, TokenPurposePasswordReset)
+		if err != nil {
+			return err
+		}
+
+		err = q.UpdateUserPassword(ctx, UpdateUserPasswordParams{
+			UserID:      token.UserID,
+			NewPassword: arg.NewPassword,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.InvalidateUserTokens(ctx, InvalidateUserTokensParams{
+			UserID:  token.UserID,
+			Purpose: TokenPurposePasswordReset,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.GetUser(ctx, token.UserID)
+		return err
+	})
+
+	return user, err
+}
+
+// VerifyEmailTx consumes an email verification token and marks the email of its user as verified
+func (store *SQLStore) VerifyEmailTx(ctx context.Context, tokenHash string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		token, err := useToken(ctx, q, tokenHash, TokenPurposeEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		user, err = q.SetUserEmailVerified(ctx, token.UserID)
+		return err
+	})
+
+	return user, err
+}
+
+// ReplaceRecoveryCodesTxParams contains the input parameters of the replace recovery codes transaction
+type ReplaceRecoveryCodesTxParams struct {
+	UserID     string   `json:"user_id"`
+	CodeHashes []string `json:"code_hashes"`
+}
+
+// ReplaceRecoveryCodesTx deletes all recovery codes of the user and stores the given ones instead
+func (store *SQLStore) ReplaceRecoveryCodesTx(ctx context.Context, arg ReplaceRecoveryCodesTxParams) error {
+	return store.execTx(ctx, func(q *Queries) error {
+		return replaceRecoveryCodes(ctx, q, arg.UserID, arg.CodeHashes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *Queries, userID string, codeHashes []string) error {
+	err := q.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, codeHash := range codeHashes {
+		_, err = q.CreateRecoveryCode(ctx, CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: codeHash,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EnableTwoFactorTxParams contains the input parameters of the enable two-factor transaction
+type EnableTwoFactorTxParams struct {
+	UserID             string   `json:"user_id"`
+	Counter            int64    `json:"counter"`
+	RecoveryCodeHashes []string `json:"recovery_code_hashes"`
+}
+
+// EnableTwoFactorTx turns on TOTP for the user once the first code has been confirmed.
+// The confirming code is marked as used and a fresh set of recovery codes is stored within the same db transaction.
+func (store *SQLStore) EnableTwoFactorTx(ctx context.Context, arg EnableTwoFactorTxParams) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.UpdateUserTOTPCounter(ctx, UpdateUserTOTPCounterParams{
+			UserID:  arg.UserID,
+			Counter: arg.Counter,
+		})
+		if err != nil {
+			return err
+		}
+
+		user, err = q.EnableUserTOTP(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		return replaceRecoveryCodes(ctx, q, arg.UserID, arg.RecoveryCodeHashes)
+	})
+
+	return user, err
+}
+
+// DisableTwoFactorTx turns off TOTP for the user and removes the recovery codes
+func (store *SQLStore) DisableTwoFactorTx(ctx context.Context, userID string) (User, error) {
+	var user User
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		user, err = q.DisableUserTOTP(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteRecoveryCodes(ctx, userID)
+	})
+
+	return user, err
+}
+
+// EraseUserTxParams contains the input parameters of the erase user transaction
+type EraseUserTxParams struct {
+	UserID           string   `json:"user_id"`
+	AnonymizedEmail  string   `json:"anonymized_email"`
+	LoginAttemptKeys []string `json:"login_attempt_keys"`
+}
+
+// EraseUserTxResult is the result of the erase user transaction
+type EraseUserTxResult struct {
+	User User `json:"user"`
+	// KYCDocuments are the deleted kyc documents, whose files are left to be removed from the blob store
+	KYCDocuments []KycDocument `json:"kyc_documents"`
+	// OAuthClients are the deleted oauth clients the user owned
+	OAuthClients []OauthClient `json:"oauth_clients"`
+}
+
+// EraseUserTx removes the personal data of a user. The user row itself is kept in anonymized form, so that the
+// user_id referenced by financial records stays valid. Tokens, recovery codes, kyc documents and login attempts are
+// deleted, the kyc status is reset and api keys are revoked within the same db transaction. So are the oauth consents
+// of the user, together with its authorization codes, and the oauth clients the user owns are deleted, withdrawing
+// the consents other users gave them.
+func (store *SQLStore) EraseUserTx(ctx context.Context, arg EraseUserTxParams) (EraseUserTxResult, error) {
+	var result EraseUserTxResult
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		result.User, err = q.AnonymizeUser(ctx, AnonymizeUserParams{
+			UserID: arg.UserID,
+			Email:  arg.AnonymizedEmail,
+		})
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserTokens(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteRecoveryCodes(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		result.KYCDocuments, err = q.DeleteKYCDocuments(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeOwnerAPIKeys(ctx, RevokeOwnerAPIKeysParams{OwnerType: APIKeyOwnerUser, OwnerID: arg.UserID})
+		if err != nil {
+			return err
+		}
+
+		err = q.RevokeUserOAuthConsents(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		err = q.DeleteUserOAuthAuthorizationCodes(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		result.OAuthClients, err = q.DeleteOAuthClientsByOwner(ctx, arg.UserID)
+		if err != nil {
+			return err
+		}
+
+		for _, client := range result.OAuthClients {
+			err = q.RevokeClientOAuthConsents(ctx, client.ClientID)
+			if err != nil {
+				return err
+			}
+
+			err = q.DeleteClientOAuthAuthorizationCodes(ctx, client.ClientID)
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, key := range arg.LoginAttemptKeys {
+			err = q.DeleteLoginAttempt(ctx, key)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// SubmitKYCDocumentTx stores the metadata of an uploaded identity document and puts the user back into the review
+// queue, so that a rejected user can submit new documents. Users that are already verified can't submit documents.
+func (store *SQLStore) SubmitKYCDocumentTx(ctx context.Context, arg CreateKYCDocumentParams) (KycDocument, error) {
+	var document KycDocument
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.SubmitUserKYC(ctx, arg.UserID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return ErrKYCAlreadyVerified
+			}
+			return err
+		}
+
+		document, err = q.CreateKYCDocument(ctx, arg)
+		return err
+	})
+
+	return document, err
+}
+
+// AuthorizeClientTx records the consent of a user to the scopes requested by an OAuth client and issues the
+// authorization code the client exchanges for an access token. Scopes granted earlier are kept.
+func (store *SQLStore) AuthorizeClientTx(ctx context.Context, arg CreateOAuthAuthorizationCodeParams) (OauthAuthorizationCode, error) {
+	var code OauthAuthorizationCode
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		_, err := q.GrantOAuthConsent(ctx, GrantOAuthConsentParams{
+			UserID:   arg.UserID,
+			ClientID: arg.ClientID,
+			Scopes:   arg.Scopes,
+		})
+		if err != nil {
+			return err
+		}
+
+		code, err = q.CreateOAuthAuthorizationCode(ctx, arg)
+		return err
+	})
+
+	return code, err
+}
+
+// RevokeConsentTx withdraws the consent of a user to an OAuth client. Authorization codes that were issued but not
+// exchanged yet are deleted within the same db transaction.
+func (store *SQLStore) RevokeConsentTx(ctx context.Context, arg RevokeOAuthConsentParams) (OauthConsent, error) {
+	var consent OauthConsent
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		consent, err = q.RevokeOAuthConsent(ctx, arg)
+		if err != nil {
+			return err
+		}
+
+		return q.DeleteOAuthAuthorizationCodes(ctx, DeleteOAuthAuthorizationCodesParams{
+			UserID:   arg.UserID,
+			ClientID: arg.ClientID,
+		})
+	})
+
+	return consent, err
+}
+
+// DisableServiceAccountTx disables a service account and revokes its api keys within a single db transaction
+func (store *SQLStore) DisableServiceAccountTx(ctx context.Context, serviceAccountID string) (ServiceAccount, error) {
+	var serviceAccount ServiceAccount
+
+	err := store.execTx(ctx, func(q *Queries) error {
+		var err error
+		serviceAccount, err = q.DisableServiceAccount(ctx, serviceAccountID)
+		if err != nil {
+			return err
+		}
+
+		return q.RevokeOwnerAPIKeys(ctx, RevokeOwnerAPIKeysParams{
+			OwnerType: APIKeyOwnerServiceAccount,
+			OwnerID:   serviceAccountID,
+		})
+	})
+
+	return serviceAccount, err
 }
@@ -7,11 +7,66 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
+const anonymizeUser = `-- name: AnonymizeUser :one
+UPDATE users
+set firstname            = 'Deleted',
+    lastname             = 'User',
+    email                = $1,
+    password             = '',
+    email_verified       = false,
+    totp_secret          = NULL,
+    totp_enabled         = false,
+    totp_last_counter    = 0,
+    is_active            = false,
+    deactivated_at       = COALESCE(deactivated_at, now()),
+    anonymized_at        = now(),
+    kyc_status           = 'pending',
+    kyc_rejection_reason = NULL,
+    kyc_reviewed_by      = NULL,
+    kyc_reviewed_at      = NULL,
+    updated_at           = now()
+WHERE user_id = $2 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+type AnonymizeUserParams struct {
+	Email  string `json:"email"`
+	UserID string `json:"user_id"`
+}
+
+func (q *Queries) AnonymizeUser(ctx context.Context, arg AnonymizeUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, anonymizeUser, arg.Email, arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (user_id, firstname, lastname, password, email)
-VALUES ($1, $2, $3, $4, $5) RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5) RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
 `
 
 type CreateUserParams struct {
@@ -40,12 +95,126 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const deactivateUser = `-- name: DeactivateUser :one
+UPDATE users
+set is_active = false, deactivated_at = now(), updated_at = now()
+WHERE user_id = $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+func (q *Queries) DeactivateUser(ctx context.Context, userID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, deactivateUser, userID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const disableUserTOTP = `-- name: DisableUserTOTP :one
+UPDATE users
+set totp_secret = NULL, totp_enabled = false, totp_last_counter = 0
+WHERE user_id = $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+func (q *Queries) DisableUserTOTP(ctx context.Context, userID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, disableUserTOTP, userID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const enableUserTOTP = `-- name: EnableUserTOTP :one
+UPDATE users
+set totp_enabled = true
+WHERE user_id = $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+func (q *Queries) EnableUserTOTP(ctx context.Context, userID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, enableUserTOTP, userID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
 	)
 	return i, err
 }
 
 const getUser = `-- name: GetUser :one
-SELECT id, user_id, firstname, lastname, password, email, created_at, updated_at
+SELECT id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
 FROM users
 WHERE user_id = $1 LIMIT 1
 `
@@ -62,12 +231,24 @@ func (q *Queries) GetUser(ctx context.Context, userID string) (User, error) {
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, user_id, firstname, lastname, password, email, created_at, updated_at
+SELECT id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
 FROM users
 WHERE email = $1 LIMIT 1
 `
@@ -84,6 +265,130 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.Email,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const setUserEmailVerified = `-- name: SetUserEmailVerified :one
+UPDATE users
+set email_verified = true
+WHERE user_id = $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+func (q *Queries) SetUserEmailVerified(ctx context.Context, userID string) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserEmailVerified, userID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const setUserTOTPSecret = `-- name: SetUserTOTPSecret :one
+UPDATE users
+set totp_secret = $2, totp_enabled = false, totp_last_counter = 0
+WHERE user_id = $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+type SetUserTOTPSecretParams struct {
+	UserID     string         `json:"user_id"`
+	TotpSecret sql.NullString `json:"totp_secret"`
+}
+
+func (q *Queries) SetUserTOTPSecret(ctx context.Context, arg SetUserTOTPSecretParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserTOTPSecret, arg.UserID, arg.TotpSecret)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const updateUserEmail = `-- name: UpdateUserEmail :one
+UPDATE users
+set email = $2, email_verified = false, updated_at = now()
+WHERE user_id = $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+type UpdateUserEmailParams struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+func (q *Queries) UpdateUserEmail(ctx context.Context, arg UpdateUserEmailParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserEmail, arg.UserID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
 	)
 	return i, err
 }
@@ -103,3 +408,85 @@ func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPassword
 	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.NewPassword, arg.UserID)
 	return err
 }
+
+const updateUserProfile = `-- name: UpdateUserProfile :one
+UPDATE users
+set firstname  = COALESCE($1, firstname),
+    lastname   = COALESCE($2, lastname),
+    updated_at = now()
+WHERE user_id = $3 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+type UpdateUserProfileParams struct {
+	Firstname sql.NullString `json:"firstname"`
+	Lastname  sql.NullString `json:"lastname"`
+	UserID    string         `json:"user_id"`
+}
+
+func (q *Queries) UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserProfile, arg.Firstname, arg.Lastname, arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
+
+const updateUserTOTPCounter = `-- name: UpdateUserTOTPCounter :one
+UPDATE users
+set totp_last_counter = $1
+WHERE user_id = $2
+  AND totp_last_counter < $1 RETURNING id, user_id, firstname, lastname, password, email, created_at, updated_at, email_verified, totp_secret, totp_enabled, totp_last_counter, is_active, deactivated_at, anonymized_at, role, kyc_status, kyc_rejection_reason, kyc_reviewed_by, kyc_reviewed_at
+`
+
+type UpdateUserTOTPCounterParams struct {
+	Counter int64  `json:"counter"`
+	UserID  string `json:"user_id"`
+}
+
+func (q *Queries) UpdateUserTOTPCounter(ctx context.Context, arg UpdateUserTOTPCounterParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUserTOTPCounter, arg.Counter, arg.UserID)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Firstname,
+		&i.Lastname,
+		&i.Password,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerified,
+		&i.TotpSecret,
+		&i.TotpEnabled,
+		&i.TotpLastCounter,
+		&i.IsActive,
+		&i.DeactivatedAt,
+		&i.AnonymizedAt,
+		&i.Role,
+		&i.KycStatus,
+		&i.KycRejectionReason,
+		&i.KycReviewedBy,
+		&i.KycReviewedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,115 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.16.0
+// source: user_token.sql
+
+package db
+
+import (
+	"context"
+	"time"
+)
+
+const createUserToken = `-- name: CreateUserToken :one
+INSERT INTO user_tokens (token_hash, user_id, purpose, expires_at)
+VALUES ($1, $2, $3, $4) RETURNING id, token_hash, user_id, purpose, expires_at, used_at, created_at
+`
+
+type CreateUserTokenParams struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    string    `json:"user_id"`
+	Purpose   string    `json:"purpose"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) CreateUserToken(ctx context.Context, arg CreateUserTokenParams) (UserToken, error) {
+	row := q.db.QueryRowContext(ctx, createUserToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.Purpose,
+		arg.ExpiresAt,
+	)
+	var i UserToken
+	err := row.Scan(
+		&i.ID,
+		&i.TokenHash,
+		&i.UserID,
+		&i.Purpose,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteUserTokens = `-- name: DeleteUserTokens :exec
+DELETE FROM user_tokens
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserTokens(ctx context.Context, userID string) error {
+	_, err := q.db.ExecContext(ctx, deleteUserTokens, userID)
+	return err
+}
+
+const getUserToken = `-- name: GetUserToken :one
+SELECT id, token_hash, user_id, purpose, expires_at, used_at, created_at
+FROM user_tokens
+WHERE token_hash = $1 LIMIT 1
+`
+
+func (q *Queries) GetUserToken(ctx context.Context, tokenHash string) (UserToken, error) {
+	row := q.db.QueryRowContext(ctx, getUserToken, tokenHash)
+	var i UserToken
+	err := row.Scan(
+		&i.ID,
+		&i.TokenHash,
+		&i.UserID,
+		&i.Purpose,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const invalidateUserTokens = `-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+set used_at = now()
+WHERE user_id = $1
+  AND purpose = $2
+  AND used_at IS NULL
+`
+
+type InvalidateUserTokensParams struct {
+	UserID  string `json:"user_id"`
+	Purpose string `json:"purpose"`
+}
+
+func (q *Queries) InvalidateUserTokens(ctx context.Context, arg InvalidateUserTokensParams) error {
+	_, err := q.db.ExecContext(ctx, invalidateUserTokens, arg.UserID, arg.Purpose)
+	return err
+}
+
+const useUserToken = `-- name: UseUserToken :one
+UPDATE user_tokens
+set used_at = now()
+WHERE token_hash = $1
+  AND used_at IS NULL
+  AND expires_at > now() RETURNING id, token_hash, user_id, purpose, expires_at, used_at, created_at
+`
+
+func (q *Queries) UseUserToken(ctx context.Context, tokenHash string) (UserToken, error) {
+	row := q.db.QueryRowContext(ctx, useUserToken, tokenHash)
+	var i UserToken
+	err := row.Scan(
+		&i.ID,
+		&i.TokenHash,
+		&i.UserID,
+		&i.Purpose,
+		&i.ExpiresAt,
+		&i.UsedAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func createRandomUserToken(t *testing.T, user User, purpose string, expiresAt time.Time) UserToken {
+	arg := CreateUserTokenParams{
+		TokenHash: RandomString(32),
+		UserID:    user.UserID,
+		Purpose:   purpose,
+		ExpiresAt: expiresAt,
+	}
+
+	token, err := testQueries.CreateUserToken(context.Background(), arg)
+	require.NoError(t, err)
+	require.NotEmpty(t, token)
+
+	require.Equal(t, arg.TokenHash, token.TokenHash)
+	require.Equal(t, arg.UserID, token.UserID)
+	require.Equal(t, arg.Purpose, token.Purpose)
+	require.WithinDuration(t, arg.ExpiresAt, token.ExpiresAt, time.Second)
+	require.False(t, token.UsedAt.Valid)
+	require.NotZero(t, token.CreatedAt)
+
+	return token
+}
+
+func TestCreateUserToken(t *testing.T) {
+	user := createRandomUser(t)
+	createRandomUserToken(t, user, TokenPurposePasswordReset, time.Now().Add(time.Hour))
+}
+
+func TestGetUserToken(t *testing.T) {
+	user := createRandomUser(t)
+	token1 := createRandomUserToken(t, user, TokenPurposeEmailVerification, time.Now().Add(time.Hour))
+
+	token2, err := testQueries.GetUserToken(context.Background(), token1.TokenHash)
+	require.NoError(t, err)
+	require.NotEmpty(t, token2)
+
+	require.Equal(t, token1.TokenHash, token2.TokenHash)
+	require.Equal(t, token1.UserID, token2.UserID)
+	require.Equal(t, token1.Purpose, token2.Purpose)
+	require.WithinDuration(t, token1.ExpiresAt, token2.ExpiresAt, time.Second)
+}
+
+func TestUseUserToken(t *testing.T) {
+	user := createRandomUser(t)
+	token1 := createRandomUserToken(t, user, TokenPurposePasswordReset, time.Now().Add(time.Hour))
+
+	token2, err := testQueries.UseUserToken(context.Background(), token1.TokenHash)
+	require.NoError(t, err)
+	require.True(t, token2.UsedAt.Valid)
+
+	// a token can only be used once
+	_, err = testQueries.UseUserToken(context.Background(), token1.TokenHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUseExpiredUserToken(t *testing.T) {
+	user := createRandomUser(t)
+	token := createRandomUserToken(t, user, TokenPurposePasswordReset, time.Now().Add(-time.Minute))
+
+	_, err := testQueries.UseUserToken(context.Background(), token.TokenHash)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestInvalidateUserTokens(t *testing.T) {
+	user := createRandomUser(t)
+	token1 := createRandomUserToken(t, user, TokenPurposePasswordReset, time.Now().Add(time.Hour))
+	token2 := createRandomUserToken(t, user, TokenPurposeEmailVerification, time.Now().Add(time.Hour))
+
+	err := testQueries.InvalidateUserTokens(context.Background(), InvalidateUserTokensParams{
+		UserID:  user.UserID,
+		Purpose: TokenPurposePasswordReset,
+	})
+	require.NoError(t, err)
+
+	invalidated, err := testQueries.GetUserToken(context.Background(), token1.TokenHash)
+	require.NoError(t, err)
+	require.True(t, invalidated.UsedAt.Valid)
+
+	untouched, err := testQueries.GetUserToken(context.Background(), token2.TokenHash)
+	require.NoError(t, err)
+	require.False(t, untouched.UsedAt.Valid)
+}
+
+func TestSetUserEmailVerified(t *testing.T) {
+	user1 := createRandomUser(t)
+	require.False(t, user1.EmailVerified)
+
+	user2, err := testQueries.SetUserEmailVerified(context.Background(), user1.UserID)
+	require.NoError(t, err)
+	require.True(t, user2.EmailVerified)
+	require.Equal(t, user1.UserID, user2.UserID)
+}
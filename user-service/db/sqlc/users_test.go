@@ -2,6 +2,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
 	"testing"
 	"time"
@@ -34,6 +36,20 @@ func TestCreateUser(t *testing.T) {
 	createRandomUser(t)
 }
 
+func TestCreateUserDuplicateUserID(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	_, err := testQueries.CreateUser(context.Background(), CreateUserParams{
+		UserID:    user1.UserID,
+		Email:     RandomEmail(),
+		Password:  RandomString(10),
+		Firstname: RandomString(5),
+		Lastname:  RandomString(6),
+	})
+	require.Error(t, err)
+	require.Equal(t, "users_user_id_key", err.(*pq.Error).Constraint)
+}
+
 func TestGetUser(t *testing.T) {
 	user1 := createRandomUser(t)
 
@@ -84,4 +100,176 @@ func TestUpdateUserPassword(t *testing.T) {
 	require.Equal(t, user1.Email, user2.Email)
 	require.Equal(t, arg.NewPassword, user2.Password)
 	require.WithinDuration(t, user1.CreatedAt, user2.CreatedAt, time.Second)
-}
\ No newline at end of file
+}
+
+func TestUserTOTP(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	secret := sql.NullString{String: RandomString(32), Valid: true}
+	user2, err := testQueries.SetUserTOTPSecret(context.Background(), SetUserTOTPSecretParams{
+		UserID:     user1.UserID,
+		TotpSecret: secret,
+	})
+	require.NoError(t, err)
+	require.Equal(t, secret, user2.TotpSecret)
+	require.False(t, user2.TotpEnabled)
+
+	user3, err := testQueries.EnableUserTOTP(context.Background(), user1.UserID)
+	require.NoError(t, err)
+	require.True(t, user3.TotpEnabled)
+
+	user4, err := testQueries.DisableUserTOTP(context.Background(), user1.UserID)
+	require.NoError(t, err)
+	require.False(t, user4.TotpEnabled)
+	require.False(t, user4.TotpSecret.Valid)
+	require.Zero(t, user4.TotpLastCounter)
+}
+
+func TestUpdateUserTOTPCounter(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	arg := UpdateUserTOTPCounterParams{
+		UserID:  user1.UserID,
+		Counter: RandomInt(1, 1000),
+	}
+	user2, err := testQueries.UpdateUserTOTPCounter(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Counter, user2.TotpLastCounter)
+
+	// the same counter can't be used twice
+	_, err = testQueries.UpdateUserTOTPCounter(context.Background(), arg)
+	require.EqualError(t, err, sql.ErrNoRows.Error())
+}
+
+func TestUpdateUserProfile(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	arg := UpdateUserProfileParams{
+		UserID:    user1.UserID,
+		Firstname: sql.NullString{String: RandomString(5), Valid: true},
+	}
+	user2, err := testQueries.UpdateUserProfile(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Firstname.String, user2.Firstname)
+	require.Equal(t, user1.Lastname, user2.Lastname)
+}
+
+func TestUpdateUserEmail(t *testing.T) {
+	user1 := createRandomUser(t)
+
+	user1, err := testQueries.SetUserEmailVerified(context.Background(), user1.UserID)
+	require.NoError(t, err)
+	require.True(t, user1.EmailVerified)
+
+	arg := UpdateUserEmailParams{
+		UserID: user1.UserID,
+		Email:  RandomEmail(),
+	}
+	user2, err := testQueries.UpdateUserEmail(context.Background(), arg)
+	require.NoError(t, err)
+	require.Equal(t, arg.Email, user2.Email)
+	require.False(t, user2.EmailVerified)
+
+	user3 := createRandomUser(t)
+	_, err = testQueries.UpdateUserEmail(context.Background(), UpdateUserEmailParams{
+		UserID: user3.UserID,
+		Email:  arg.Email,
+	})
+	require.Error(t, err)
+}
+
+func TestDeactivateUser(t *testing.T) {
+	user1 := createRandomUser(t)
+	require.True(t, user1.IsActive)
+
+	user2, err := testQueries.DeactivateUser(context.Background(), user1.UserID)
+	require.NoError(t, err)
+	require.False(t, user2.IsActive)
+	require.True(t, user2.DeactivatedAt.Valid)
+}
+
+func TestEraseUserTx(t *testing.T) {
+	store := NewStore(testDB)
+	reviewer := createRandomUser(t)
+	user1 := createRandomUser(t)
+
+	document := submitRandomKYCDocument(t, user1)
+	_, err := testQueries.ReviewUserKYC(context.Background(), ReviewUserKYCParams{
+		UserID:     user1.UserID,
+		KycStatus:  KYCStatusVerified,
+		ReviewedBy: sql.NullString{String: reviewer.UserID, Valid: true},
+	})
+	require.NoError(t, err)
+
+	_, err = testQueries.CreateUserToken(context.Background(), CreateUserTokenParams{
+		TokenHash: RandomString(32),
+		UserID:    user1.UserID,
+		Purpose:   TokenPurposePasswordReset,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+
+	key := "email:" + user1.Email
+	_, err = testQueries.RecordFailedLoginAttempt(context.Background(), RecordFailedLoginAttemptParams{
+		Key:         key,
+		WindowStart: time.Now().Add(-time.Minute),
+	})
+	require.NoError(t, err)
+
+	// user1 authorized a client of another user, and other users authorized the client user1 owns
+	otherClient := createRandomOAuthClient(t, createRandomUser(t))
+	userCode := authorizeRandomClient(t, user1, otherClient, []string{"accounts:read"})
+	ownedClient := createRandomOAuthClient(t, user1)
+	otherUser := createRandomUser(t)
+	ownedClientCode := authorizeRandomClient(t, otherUser, ownedClient, []string{"accounts:read"})
+
+	arg := EraseUserTxParams{
+		UserID:           user1.UserID,
+		AnonymizedEmail:  RandomEmail(),
+		LoginAttemptKeys: []string{key},
+	}
+	result, err := store.EraseUserTx(context.Background(), arg)
+	require.NoError(t, err)
+	user2 := result.User
+	require.Equal(t, user1.UserID, user2.UserID)
+	require.Equal(t, arg.AnonymizedEmail, user2.Email)
+	require.NotEqual(t, user1.Firstname, user2.Firstname)
+	require.Empty(t, user2.Password)
+	require.False(t, user2.IsActive)
+	require.True(t, user2.AnonymizedAt.Valid)
+	require.Equal(t, KYCStatusPending, user2.KycStatus)
+	require.False(t, user2.KycReviewedAt.Valid)
+	require.Len(t, result.KYCDocuments, 1)
+	require.Equal(t, document.StorageKey, result.KYCDocuments[0].StorageKey)
+
+	documents, err := testQueries.ListKYCDocuments(context.Background(), user1.UserID)
+	require.NoError(t, err)
+	require.Empty(t, documents)
+
+	_, err = testQueries.GetLoginAttempt(context.Background(), key)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams{
+		UserID:   user1.UserID,
+		ClientID: otherClient.ClientID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), userCode.CodeHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.Len(t, result.OAuthClients, 1)
+	require.Equal(t, ownedClient.ClientID, result.OAuthClients[0].ClientID)
+	_, err = testQueries.GetOAuthClient(context.Background(), ownedClient.ClientID)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.GetOAuthConsent(context.Background(), GetOAuthConsentParams{
+		UserID:   otherUser.UserID,
+		ClientID: ownedClient.ClientID,
+	})
+	require.ErrorIs(t, err, sql.ErrNoRows)
+	_, err = testQueries.UseOAuthAuthorizationCode(context.Background(), ownedClientCode.CodeHash)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	// the client of the other user is left alone
+	_, err = testQueries.GetOAuthClient(context.Background(), otherClient.ClientID)
+	require.NoError(t, err)
+}